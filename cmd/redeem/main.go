@@ -1,12 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -16,20 +23,34 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/jmo/terminal-redeemer/internal/bundle"
 	"github.com/jmo/terminal-redeemer/internal/capture"
 	"github.com/jmo/terminal-redeemer/internal/collector"
+	"github.com/jmo/terminal-redeemer/internal/compositor"
 	"github.com/jmo/terminal-redeemer/internal/config"
+	"github.com/jmo/terminal-redeemer/internal/daemon"
 	"github.com/jmo/terminal-redeemer/internal/diff"
 	"github.com/jmo/terminal-redeemer/internal/doctor"
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/gitmeta"
+	"github.com/jmo/terminal-redeemer/internal/hooks"
+	"github.com/jmo/terminal-redeemer/internal/journal"
+	"github.com/jmo/terminal-redeemer/internal/kitty"
+	"github.com/jmo/terminal-redeemer/internal/layouts"
+	"github.com/jmo/terminal-redeemer/internal/metrics"
 	"github.com/jmo/terminal-redeemer/internal/model"
 	"github.com/jmo/terminal-redeemer/internal/niri"
+	"github.com/jmo/terminal-redeemer/internal/nvim"
 	"github.com/jmo/terminal-redeemer/internal/procmeta"
 	"github.com/jmo/terminal-redeemer/internal/prune"
+	"github.com/jmo/terminal-redeemer/internal/redact"
 	"github.com/jmo/terminal-redeemer/internal/replay"
 	"github.com/jmo/terminal-redeemer/internal/restore"
+	"github.com/jmo/terminal-redeemer/internal/seal"
 	"github.com/jmo/terminal-redeemer/internal/snapshots"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
 	"github.com/jmo/terminal-redeemer/internal/tui"
+	"github.com/jmo/terminal-redeemer/internal/wezterm"
 )
 
 func main() {
@@ -60,19 +81,37 @@ func run(args []string, stdout io.Writer, stderr io.Writer) int {
 		_, _ = fmt.Fprintf(stderr, "config load failed: %v\n", err)
 		return 2
 	}
+	if globalFlags.noDaemon {
+		resolvedConfig.Daemon.Connect = false
+	}
+	if globalFlags.logLevel != "" {
+		resolvedConfig.Log.Level = globalFlags.logLevel
+	}
+	if globalFlags.logFormat != "" {
+		resolvedConfig.Log.Format = globalFlags.logFormat
+	}
+	logger := newLogger(stderr, resolvedConfig.Log)
 
 	switch args[0] {
 	case "-h", "--help", "help":
 		printHelp(stdout)
 		return 0
 	case "capture":
-		return runCapture(args[1:], resolvedConfig, stdout, stderr)
+		return runCapture(args[1:], resolvedConfig, logger, stdout, stderr)
 	case "history":
 		return runHistory(args[1:], resolvedConfig, stdout, stderr)
 	case "restore":
-		return runRestore(args[1:], resolvedConfig, stdout, stderr)
+		return runRestore(args[1:], resolvedConfig, logger, stdout, stderr)
 	case "prune":
-		return runPrune(args[1:], resolvedConfig, stdout, stderr)
+		return runPrune(args[1:], resolvedConfig, logger, stdout, stderr)
+	case "daemon":
+		return runDaemon(args[1:], resolvedConfig, logger, stdout, stderr)
+	case "store":
+		return runStore(args[1:], resolvedConfig, stdout, stderr)
+	case "export":
+		return runExport(args[1:], resolvedConfig, stdout, stderr)
+	case "import":
+		return runImport(args[1:], resolvedConfig, stdout, stderr)
 	case "bottle":
 		_, _ = fmt.Fprintf(stderr, "subcommand '%s' scaffolded but not implemented yet\n", args[0])
 		return 2
@@ -88,17 +127,20 @@ func runDoctor(flags globalFlags, stdout io.Writer) int {
 	if err != nil {
 		resolvedConfig = config.Defaults()
 	}
+	matcher, err := terminalMatcher(resolvedConfig.Terminals)
+	if err != nil {
+		matcher = terminals.Default()
+	}
 
 	checks := []doctor.Check{
 		doctor.StateDirWritableCheck{StateDir: resolvedConfig.StateDir},
 		doctor.ConfigLoadCheck{Path: flags.configPath, Explicit: flags.explicitConfig},
-		doctor.NiriSourceCheck{
-			FixturePath: strings.TrimSpace(os.Getenv("REDEEM_NIRI_FIXTURE")),
-			Command:     captureNiriCommandDefault(resolvedConfig),
-		},
+		compositorSourceCheck(resolvedConfig),
 		doctor.CommandAvailableCheck{CheckName: "kitty_available", Command: resolvedConfig.Restore.Terminal.Command},
 		doctor.CommandAvailableCheck{CheckName: "zellij_available", Command: "zellij"},
+		terminalDetectionCheck(resolvedConfig, matcher),
 		doctor.LocalInstallCheck{Path: localInstallPath()},
+		doctor.EncryptionCheck{StateDir: resolvedConfig.StateDir},
 		doctor.EventsIntegrityCheck{StateDir: resolvedConfig.StateDir},
 		doctor.SnapshotsIntegrityCheck{StateDir: resolvedConfig.StateDir},
 	}
@@ -109,7 +151,7 @@ func runDoctor(flags globalFlags, stdout io.Writer) int {
 	}
 
 	summary := doctor.Summarize(results)
-	_, _ = fmt.Fprintf(stdout, "doctor_summary total=%d passed=%d failed=%d\n", summary.Total, summary.Passed, summary.Failed)
+	_, _ = fmt.Fprintf(stdout, "doctor_summary total=%d passed=%d failed=%d warned=%d\n", summary.Total, summary.Passed, summary.Failed, summary.Warned)
 
 	if doctor.HasFailures(results) {
 		return 1
@@ -117,17 +159,20 @@ func runDoctor(flags globalFlags, stdout io.Writer) int {
 	return 0
 }
 
-func runRestore(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+func runRestore(args []string, resolvedConfig config.Config, logger *slog.Logger, stdout io.Writer, stderr io.Writer) int {
 	if len(args) == 0 {
-		_, _ = fmt.Fprintln(stderr, "usage: redeem restore <apply|tui> [flags]")
+		_, _ = fmt.Fprintln(stderr, "usage: redeem restore <apply|tui|undo> [flags]")
 		return 2
 	}
 	if isHelpToken(args[0]) {
-		_, _ = fmt.Fprintln(stdout, "usage: redeem restore <apply|tui> [flags]")
+		_, _ = fmt.Fprintln(stdout, "usage: redeem restore <apply|tui|undo> [flags]")
 		return 0
 	}
 	if args[0] == "tui" {
-		return runRestoreTUI(args[1:], resolvedConfig, stdout, stderr)
+		return runRestoreTUI(args[1:], resolvedConfig, logger, stdout, stderr)
+	}
+	if args[0] == "undo" {
+		return runRestoreUndo(args[1:], resolvedConfig, stdout, stderr)
 	}
 	if args[0] != "apply" {
 		_, _ = fmt.Fprintf(stderr, "unknown restore subcommand: %s\n", args[0])
@@ -138,6 +183,8 @@ func runRestore(args []string, resolvedConfig config.Config, stdout io.Writer, s
 	fs.SetOutput(stderr)
 	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
 	atRaw := fs.String("at", "", "timestamp (RFC3339)")
+	host := fs.String("host", resolvedConfig.Host, "only replay events captured under this host (empty: every host)")
+	profile := fs.String("profile", resolvedConfig.Profile, "only replay events captured under this profile (empty: every profile)")
 	yes := fs.Bool("yes", false, "apply plan without prompt")
 	dryRun := fs.Bool("dry-run", false, "print restore actions without executing")
 	if err := fs.Parse(args[1:]); err != nil {
@@ -156,22 +203,40 @@ func runRestore(args []string, resolvedConfig config.Config, stdout io.Writer, s
 		return 2
 	}
 
+	restoreKey := restoreOptionsKey(resolvedConfig)
+	accept := func(status daemon.Status) bool {
+		return status.Host == *host && status.Profile == *profile && restoreKey != "" && status.RestoreKey == restoreKey
+	}
+	ctx, stop := restoreContext()
+	defer stop()
+	if client := connectDaemon(resolvedConfig, *stateDir, accept); client != nil {
+		defer func() {
+			_ = client.Close()
+		}()
+		return restoreApplyViaDaemon(ctx, client, at, *dryRun, *yes, stdout, stderr)
+	}
+
 	engine, err := replay.NewEngine(*stateDir)
 	if err != nil {
 		_, _ = fmt.Fprintf(stderr, "restore init failed: %v\n", err)
 		return 1
 	}
-	state, err := engine.At(at)
+	state, err := engine.Scoped(*host, *profile).At(at)
 	if err != nil {
 		_, _ = fmt.Fprintf(stderr, "restore replay failed: %v\n", err)
 		return 1
 	}
 
-	planner := restore.NewPlanner(restore.PlannerConfig{
-		Terminal:     restore.TerminalConfig{Command: resolvedConfig.Restore.Terminal.Command, ZellijAttachOrCreate: resolvedConfig.Restore.Terminal.ZellijAttachOrCreate},
-		AppAllowlist: resolvedConfig.Restore.AppAllowlist,
-		AppMode:      parseAppModes(resolvedConfig.Restore.AppMode),
-	})
+	planner, err := buildRestorePlanner(resolvedConfig, *stateDir)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "restore init failed: %v\n", err)
+		return 1
+	}
+	backend, err := compositorBackend(resolvedConfig)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "restore init failed: %v\n", err)
+		return 1
+	}
 	plan := planner.Build(state)
 	if *dryRun {
 		printRestoreDryRun(stdout, plan)
@@ -179,31 +244,42 @@ func runRestore(args []string, resolvedConfig config.Config, stdout io.Writer, s
 	}
 
 	if !*yes {
-		summary := summarizePlan(plan)
-		_, _ = fmt.Fprintf(stdout, "restore_plan ready=%d skipped=%d degraded=%d\n", summary.ready, summary.skipped, summary.degraded)
-		_, _ = fmt.Fprintln(stdout, "pass --yes to execute")
+		printRestorePreview(stdout, plan)
 		return 0
 	}
 
-	beforeState := tryReadNiriWindowsState(context.Background())
+	result := executeRestorePlan(ctx, stdout, logger, resolvedConfig, backend, *stateDir, at, plan)
+	printRestoreExecution(stdout, result)
+	return 0
+}
 
-	executor := restore.NewExecutor(restore.ShellRunner{})
-	result := executor.Execute(context.Background(), plan)
-	if resolvedConfig.Restore.ReconcileWorkspaceMoves {
-		time.Sleep(resolvedConfig.Restore.WorkspaceReconcileDelay)
-		afterState := tryReadNiriWindowsState(context.Background())
-		if beforeState != nil && afterState != nil {
-			requests := restore.BuildMoveRequests(plan, *beforeState, *afterState)
-			report := restore.ApplyMoveRequests(context.Background(), restore.NiriWindowMover{}, requests)
-			if len(requests) > 0 {
-				writef(stdout, "restore_workspace_moves moved=%d requested=%d failed=%d\n", report.Applied, len(requests), len(report.Failures))
-				for _, failure := range report.Failures {
-					writef(stdout, "restore_workspace_move_failed window_key=%s window_id=%d app_id=%s workspace=%s error=%q\n", failure.Request.WindowKey, failure.Request.WindowID, failure.Request.AppID, failure.Request.WorkspaceRef, failure.Err.Error())
-				}
-			}
+func printRestorePreview(stdout io.Writer, plan restore.Plan) {
+	summary := summarizePlan(plan)
+	_, _ = fmt.Fprintf(stdout, "restore_plan ready=%d skipped=%d degraded=%d\n", summary.ready, summary.skipped, summary.degraded)
+	_, _ = fmt.Fprintln(stdout, "pass --yes to execute")
+}
+
+func restoreApplyViaDaemon(ctx context.Context, client *daemon.Client, at time.Time, dryRun bool, yes bool, stdout io.Writer, stderr io.Writer) int {
+	if dryRun || !yes {
+		var plan restore.Plan
+		if err := client.Call(ctx, daemon.MethodRestorePlan, daemon.AtParams{At: &at}, &plan); err != nil {
+			_, _ = fmt.Fprintf(stderr, "restore replay failed: %v\n", err)
+			return 1
+		}
+		if dryRun {
+			printRestoreDryRun(stdout, plan)
+		} else {
+			printRestorePreview(stdout, plan)
 		}
+		return 0
 	}
-	printRestoreExecution(stdout, result)
+
+	var result daemon.ApplyResult
+	if err := client.Call(ctx, daemon.MethodRestoreApply, daemon.ApplyParams{At: &at}, &result); err != nil {
+		_, _ = fmt.Fprintf(stderr, "restore apply failed: %v\n", err)
+		return 1
+	}
+	_, _ = io.WriteString(stdout, result.Output)
 	return 0
 }
 
@@ -231,6 +307,10 @@ func printRestoreDryRun(stdout io.Writer, plan restore.Plan) {
 		for _, item := range readyItems {
 			writef(stdout, "- %s\n", item.WindowKey)
 			writef(stdout, "  command: %s\n", item.Command)
+			if item.Session != "" {
+				writef(stdout, "  session: %s\n", item.Session)
+			}
+			printRemapped(stdout, item)
 		}
 		_, _ = fmt.Fprintln(stdout, "")
 	}
@@ -240,6 +320,10 @@ func printRestoreDryRun(stdout io.Writer, plan restore.Plan) {
 		for _, item := range degradedItems {
 			writef(stdout, "- %s\n", item.WindowKey)
 			writef(stdout, "  reason: %s\n", item.Reason)
+			if item.Launchable() {
+				writef(stdout, "  command: %s\n", item.Command)
+			}
+			printRemapped(stdout, item)
 		}
 		_, _ = fmt.Fprintln(stdout, "")
 	}
@@ -257,11 +341,19 @@ func printRestoreDryRun(stdout io.Writer, plan restore.Plan) {
 	_, _ = fmt.Fprintln(stdout, "Run with --yes to execute.")
 }
 
-func runRestoreTUI(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+func printRemapped(stdout io.Writer, item restore.Item) {
+	for _, note := range item.Remapped {
+		writef(stdout, "  remapped: %s\n", note)
+	}
+}
+
+func runRestoreTUI(args []string, resolvedConfig config.Config, logger *slog.Logger, stdout io.Writer, stderr io.Writer) int {
 	fs := flag.NewFlagSet("restore tui", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
 	atRaw := fs.String("at", "", "timestamp (RFC3339, optional)")
+	host := fs.String("host", resolvedConfig.Host, "only replay events captured under this host (empty: every host)")
+	profile := fs.String("profile", resolvedConfig.Profile, "only replay events captured under this profile (empty: every profile)")
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
 			return 0
@@ -269,12 +361,18 @@ func runRestoreTUI(args []string, resolvedConfig config.Config, stdout io.Writer
 		return 2
 	}
 
+	engine, err := replay.NewEngine(*stateDir)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "restore tui init failed: %v\n", err)
+		return 1
+	}
+	engine = engine.Scoped(*host, *profile)
 	eventsList, err := replay.ListEvents(*stateDir, nil, nil)
 	if err != nil {
 		_, _ = fmt.Fprintf(stderr, "restore tui failed to list history: %v\n", err)
 		return 1
 	}
-	timestamps := uniqueEventTimestamps(eventsList)
+	timestamps := uniqueEventTimestamps(eventsFor(engine, eventsList))
 
 	at := time.Now().UTC()
 	if len(timestamps) > 0 {
@@ -290,16 +388,16 @@ func runRestoreTUI(args []string, resolvedConfig config.Config, stdout io.Writer
 	}
 	timestamps = ensureTimestampOption(timestamps, at)
 
-	engine, err := replay.NewEngine(*stateDir)
+	planner, err := buildRestorePlanner(resolvedConfig, *stateDir)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "restore tui init failed: %v\n", err)
+		return 1
+	}
+	backend, err := compositorBackend(resolvedConfig)
 	if err != nil {
 		_, _ = fmt.Fprintf(stderr, "restore tui init failed: %v\n", err)
 		return 1
 	}
-	planner := restore.NewPlanner(restore.PlannerConfig{
-		Terminal:     restore.TerminalConfig{Command: resolvedConfig.Restore.Terminal.Command, ZellijAttachOrCreate: resolvedConfig.Restore.Terminal.ZellijAttachOrCreate},
-		AppAllowlist: resolvedConfig.Restore.AppAllowlist,
-		AppMode:      parseAppModes(resolvedConfig.Restore.AppMode),
-	})
 	planAt := func(ts time.Time) (restore.Plan, error) {
 		state, err := engine.At(ts)
 		if err != nil {
@@ -314,7 +412,7 @@ func runRestoreTUI(args []string, resolvedConfig config.Config, stdout io.Writer
 		return 1
 	}
 
-	filteredPlan, confirmed, err := tui.RunWithPlanLoader(initialPlan, timestamps, at, planAt)
+	filteredPlan, selectedAt, confirmed, err := tui.RunWithPlanLoader(initialPlan, timestamps, at, planAt)
 	if err != nil {
 		writef(stderr, "restore tui failed: %v\n", err)
 		return 1
@@ -324,40 +422,300 @@ func runRestoreTUI(args []string, resolvedConfig config.Config, stdout io.Writer
 		return 0
 	}
 
-	beforeState := tryReadNiriWindowsState(context.Background())
+	ctx, stop := restoreContext()
+	defer stop()
+	result := executeRestorePlan(ctx, stdout, logger, resolvedConfig, backend, *stateDir, selectedAt, filteredPlan)
+	printRestoreExecution(stdout, result)
+	return 0
+}
+
+func restoreContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	return ctx, stop
+}
 
-	executor := restore.NewExecutor(restore.ShellRunner{})
-	result := executor.Execute(context.Background(), filteredPlan)
+func executeRestorePlan(ctx context.Context, stdout io.Writer, logger *slog.Logger, resolvedConfig config.Config, backend compositor.Backend, stateDir string, restoredAt time.Time, plan restore.Plan) restore.Result {
+	beforeState := tryReadWindowsState(context.Background(), backend)
+	executor := newRestoreExecutor(logger, resolvedConfig, backend)
+	result := runRestorePlan(ctx, stdout, resolvedConfig, backend, executor, plan, beforeState)
+	recordRestoreJournal(stdout, backend, stateDir, restoredAt, plan, result, beforeState)
+	executor.FireRunAfter(context.Background(), result)
+	return result
+}
+
+func newRestoreExecutor(logger *slog.Logger, resolvedConfig config.Config, backend compositor.Backend) *restore.Executor {
+	executorConfig := restore.ExecutorConfig{
+		MaxInFlight: resolvedConfig.Restore.Execution.MaxInFlight,
+		LaunchDelay: resolvedConfig.Restore.Execution.LaunchDelay,
+		AppPhases:   resolvedConfig.Restore.Execution.AppPhases,
+		Logger:      logger,
+		Hooks:       newHooks(resolvedConfig.Hooks, logger),
+	}
+	if resolvedConfig.Restore.Readiness.Enabled {
+		executorConfig.Readiness = restore.NewWindowReadiness(restore.ReadinessConfig{
+			Windows:        windowsReader{backend: backend},
+			Lineage:        procmeta.ProcReader{},
+			DefaultTimeout: resolvedConfig.Restore.Readiness.Timeout,
+			AppTimeouts:    resolvedConfig.Restore.Readiness.AppTimeouts,
+		})
+	}
+	return restore.NewExecutorWithConfig(restore.ShellRunner{}, executorConfig)
+}
+
+func runRestorePlan(ctx context.Context, stdout io.Writer, resolvedConfig config.Config, backend compositor.Backend, executor *restore.Executor, plan restore.Plan, beforeState *model.State) restore.Result {
+	if restore.ParseReconcileStrategy(resolvedConfig.Restore.ReconcileStrategy) == restore.ReconcileSpawn {
+		spawner := restore.NewWorkspaceSpawner(executor, restore.SpawnConfig{
+			Focuser:    backend,
+			Windows:    windowsReader{backend: backend},
+			Mover:      backend,
+			Lineage:    procmeta.ProcReader{},
+			WindowWait: resolvedConfig.Restore.SpawnWindowTimeout,
+		})
+		result, report := spawner.Execute(ctx, plan)
+		for _, failure := range report.FocusFailures {
+			writef(stdout, "restore_workspace_focus_failed workspace=%s error=%q\n", failure.WorkspaceRef, failure.Err.Error())
+		}
+		for _, workspaceRef := range report.WindowTimeouts {
+			writef(stdout, "restore_workspace_window_timeout workspace=%s\n", workspaceRef)
+		}
+		writeMoveReport(stdout, report.Moves, report.MoveReport)
+		return result
+	}
+
+	result := executor.Execute(ctx, plan)
 	if resolvedConfig.Restore.ReconcileWorkspaceMoves {
-		time.Sleep(resolvedConfig.Restore.WorkspaceReconcileDelay)
-		afterState := tryReadNiriWindowsState(context.Background())
-		if beforeState != nil && afterState != nil {
-			requests := restore.BuildMoveRequests(filteredPlan, *beforeState, *afterState)
-			report := restore.ApplyMoveRequests(context.Background(), restore.NiriWindowMover{}, requests)
-			if len(requests) > 0 {
-				writef(stdout, "restore_workspace_moves moved=%d requested=%d failed=%d\n", report.Applied, len(requests), len(report.Failures))
-				for _, failure := range report.Failures {
-					writef(stdout, "restore_workspace_move_failed window_key=%s window_id=%d app_id=%s workspace=%s error=%q\n", failure.Request.WindowKey, failure.Request.WindowID, failure.Request.AppID, failure.Request.WorkspaceRef, failure.Err.Error())
-				}
+		if !resolvedConfig.Restore.Readiness.Enabled {
+			select {
+			case <-ctx.Done():
+			case <-time.After(resolvedConfig.Restore.WorkspaceReconcileDelay):
 			}
 		}
+		reconcileWorkspaceMoves(stdout, backend, plan, result, beforeState)
 	}
-	printRestoreExecution(stdout, result)
-	return 0
+	return result
 }
 
-func tryReadNiriWindowsState(ctx context.Context) *model.State {
-	raw, err := niri.CommandSnapshotter{Command: "niri msg -j windows"}.Snapshot(ctx)
+func recordRestoreJournal(stdout io.Writer, backend compositor.Backend, stateDir string, restoredAt time.Time, plan restore.Plan, result restore.Result, beforeState *model.State) {
+	afterState := tryReadWindowsState(context.Background(), backend)
+	if beforeState == nil || afterState == nil {
+		return
+	}
+	created := restore.LaunchedWindows(plan, result, *beforeState, *afterState, procmeta.ProcReader{})
+	if len(created) == 0 {
+		return
+	}
+
+	store, err := journal.NewStore(stateDir)
 	if err != nil {
-		return nil
+		writef(stdout, "restore_journal_failed error=%q\n", err.Error())
+		return
 	}
-	state, err := niri.ParseSnapshot(raw)
+	now := time.Now().UTC()
+	run := journal.Run{V: 1, ID: journal.NewRunID(now), CreatedAt: now, RestoredAt: restoredAt.UTC()}
+	for _, window := range created {
+		run.Windows = append(run.Windows, journal.Window{Key: window.Key, WindowID: window.WindowID, AppID: window.AppID})
+	}
+	if _, err := store.Write(run); err != nil {
+		writef(stdout, "restore_journal_failed error=%q\n", err.Error())
+		return
+	}
+	writef(stdout, "restore_journal run=%s windows=%d\n", run.ID, len(run.Windows))
+}
+
+func runRestoreUndo(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("restore undo", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
+	runID := fs.String("run", "", "restore run id (defaults to latest run not yet undone)")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	store, err := journal.NewStore(*stateDir)
+	if err != nil {
+		writef(stderr, "restore undo init failed: %v\n", err)
+		return 1
+	}
+	var run journal.Run
+	if strings.TrimSpace(*runID) == "" {
+		run, err = store.LatestActive()
+	} else {
+		run, err = store.Read(*runID)
+	}
+	if err != nil {
+		writef(stderr, "restore undo failed: %v\n", err)
+		return 1
+	}
+	if run.UndoneAt != nil {
+		writef(stderr, "restore undo failed: run %s already undone at %s\n", run.ID, run.UndoneAt.Format(time.RFC3339))
+		return 1
+	}
+
+	windows := make([]restore.CreatedWindow, 0, len(run.Windows))
+	for _, window := range run.Windows {
+		windows = append(windows, restore.CreatedWindow{Key: window.Key, WindowID: window.WindowID, AppID: window.AppID})
+	}
+	backend, err := compositorBackend(resolvedConfig)
+	if err != nil {
+		writef(stderr, "restore undo init failed: %v\n", err)
+		return 1
+	}
+	report := restore.CloseWindows(context.Background(), backend, windows, tryReadWindowsState(context.Background(), backend))
+	writef(stdout, "restore_undo run=%s closed=%d requested=%d missing=%d failed=%d\n", run.ID, report.Closed, len(windows), report.Missing, len(report.Failures))
+	for _, failure := range report.Failures {
+		writef(stdout, "restore_undo_failed window_key=%s window_id=%d app_id=%s error=%q\n", failure.Window.Key, failure.Window.WindowID, failure.Window.AppID, failure.Err.Error())
+	}
+	if len(report.Failures) > 0 {
+		return 1
+	}
+	if err := store.MarkUndone(run.ID, time.Now().UTC()); err != nil {
+		writef(stderr, "restore undo failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func reconcileWorkspaceMoves(stdout io.Writer, backend compositor.Backend, plan restore.Plan, result restore.Result, beforeState *model.State) {
+	afterState := tryReadWindowsState(context.Background(), backend)
+	if beforeState == nil || afterState == nil {
+		return
+	}
+	requests := restore.BuildMoveRequests(plan, result, *beforeState, *afterState, procmeta.ProcReader{})
+	writeMoveReport(stdout, requests, restore.ApplyMoveRequests(context.Background(), backend, requests))
+}
+
+func writeMoveReport(stdout io.Writer, requests []restore.MoveRequest, report restore.MoveReport) {
+	if len(requests) == 0 {
+		return
+	}
+	writef(stdout, "restore_workspace_moves moved=%d requested=%d failed=%d\n", report.Applied, len(requests), len(report.Failures))
+	for _, request := range requests {
+		writef(stdout, "restore_workspace_move window_key=%s item_key=%s window_id=%d app_id=%s workspace=%s confidence=%s\n", request.WindowKey, request.ItemKey, request.WindowID, request.AppID, request.WorkspaceRef, request.Confidence)
+	}
+	for _, failure := range report.Failures {
+		writef(stdout, "restore_workspace_move_failed window_key=%s window_id=%d app_id=%s workspace=%s confidence=%s error=%q\n", failure.Request.WindowKey, failure.Request.WindowID, failure.Request.AppID, failure.Request.WorkspaceRef, failure.Request.Confidence, failure.Err.Error())
+	}
+}
+
+func compositorSourceCheck(resolvedConfig config.Config) doctor.Check {
+	check := doctor.NiriSourceCheck{FixturePath: strings.TrimSpace(os.Getenv("REDEEM_NIRI_FIXTURE"))}
+	backend, err := compositorBackend(resolvedConfig)
+	if err != nil || backend.Name() == compositor.Niri {
+		check.Command = captureNiriCommandDefault(resolvedConfig)
+		return check
+	}
+	check.Compositor = backend.Name()
+	check.Command = compositorCommand(backend.Name())
+	check.Parse = func(raw []byte) error {
+		_, err := backend.Parse(raw)
+		return err
+	}
+	return check
+}
+
+func terminalDetectionCheck(resolvedConfig config.Config, matcher *terminals.Matcher) doctor.Check {
+	check := doctor.TerminalDetectionCheck{
+		FixturePath: strings.TrimSpace(os.Getenv("REDEEM_NIRI_FIXTURE")),
+		Command:     captureNiriCommandDefault(resolvedConfig),
+		Terminals:   matcher,
+	}
+	backend, err := compositorBackend(resolvedConfig)
+	if err != nil || backend.Name() == compositor.Niri {
+		return check
+	}
+	if check.FixturePath == "" {
+		check.Snapshot = backend.Snapshot
+	}
+	check.Parse = backend.Parse
+	return check
+}
+
+func compositorCommand(name string) string {
+	switch name {
+	case compositor.Sway:
+		return "swaymsg"
+	case compositor.Hyprland:
+		return "hyprctl"
+	default:
+		return "niri"
+	}
+}
+
+func compositorBackend(resolvedConfig config.Config) (compositor.Backend, error) {
+	return compositor.New(compositor.Config{Name: resolvedConfig.Compositor})
+}
+
+func tryReadWindowsState(ctx context.Context, backend compositor.Backend) *model.State {
+	state, err := compositor.ReadState(ctx, backend)
 	if err != nil {
 		return nil
 	}
 	return &state
 }
 
+type windowsReader struct {
+	backend compositor.Backend
+}
+
+func (r windowsReader) ReadState(ctx context.Context) (model.State, error) {
+	state := tryReadWindowsState(ctx, r.backend)
+	if state == nil {
+		return model.State{}, fmt.Errorf("%s windows unavailable", r.backend.Name())
+	}
+	return *state, nil
+}
+
+func buildRestorePlanner(resolvedConfig config.Config, stateDir string) (*restore.Planner, error) {
+	matcher, err := terminalMatcher(resolvedConfig.Terminals)
+	if err != nil {
+		return nil, err
+	}
+	layoutStore, err := layouts.NewStore(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	return restore.NewPlanner(restore.PlannerConfig{
+		Terminal: restore.TerminalConfig{
+			Command:              resolvedConfig.Restore.Terminal.Command,
+			ZellijAttachOrCreate: resolvedConfig.Restore.Terminal.ZellijAttachOrCreate,
+			TmuxAttachOrCreate:   resolvedConfig.Restore.Terminal.TmuxAttachOrCreate,
+			RestoreNvim:          resolvedConfig.Restore.Terminal.RestoreNvim,
+			MissingCWD:           restore.MissingCWD(resolvedConfig.Restore.Terminal.MissingCWD),
+			VerifyGitBranch:      resolvedConfig.Restore.Terminal.VerifyGitBranch,
+			RestoreGitWorktree:   resolvedConfig.Restore.Terminal.RestoreGitWorktree,
+		},
+		Terminals:        matcher,
+		AppAllowlist:     resolvedConfig.Restore.AppAllowlist,
+		AppMode:          parseAppModes(resolvedConfig.Restore.AppMode),
+		AppTitlePatterns: resolvedConfig.Restore.AppTitlePatterns,
+		Relaunch:         resolvedConfig.Restore.Relaunch,
+		Layouts:          layoutStore,
+		Remap:            restoreRemap(resolvedConfig.Restore.Remap),
+		Git:              gitmeta.Command{},
+		FilesDir:         filepath.Join(stateDir, "launch"),
+	}), nil
+}
+
+func restoreRemap(remap config.RemapConfig) restore.Remap {
+	prefixes := make([]restore.CWDRemap, 0, len(remap.CWDPrefixes))
+	for _, rule := range remap.CWDPrefixes {
+		prefixes = append(prefixes, restore.CWDRemap{From: rule.From, To: rule.To})
+	}
+	return restore.Remap{CWDPrefixes: prefixes, Workspaces: remap.Workspaces, AppIDs: remap.AppIDs}
+}
+
+func terminalMatcher(rules []config.TerminalRule) (*terminals.Matcher, error) {
+	converted := make([]terminals.Rule, 0, len(rules))
+	for _, rule := range rules {
+		converted = append(converted, terminals.Rule{Pattern: rule.AppID, Kind: rule.Kind})
+	}
+	return terminals.NewMatcher(converted)
+}
+
 func parseAppModes(input map[string]string) map[string]restore.AppMode {
 	out := make(map[string]restore.AppMode, len(input))
 	for appID, rawMode := range input {
@@ -374,13 +732,13 @@ func parseAppModes(input map[string]string) map[string]restore.AppMode {
 func printRestoreExecution(stdout io.Writer, result restore.Result) {
 	for _, item := range result.Items {
 		switch item.Status {
-		case restore.StatusFailed:
+		case restore.StatusFailed, restore.StatusTimeout:
 			writef(stdout, "restore_item window_key=%s status=%s error=%q\n", item.WindowKey, item.Status, item.Error)
 		case restore.StatusDegraded, restore.StatusSkipped:
 			writef(stdout, "restore_item window_key=%s status=%s reason=%q\n", item.WindowKey, item.Status, item.Reason)
 		}
 	}
-	writef(stdout, "restore_summary restored=%d skipped=%d failed=%d\n", result.Summary.Restored, result.Summary.Skipped, result.Summary.Failed)
+	writef(stdout, "restore_summary restored=%d skipped=%d failed=%d timed_out=%d\n", result.Summary.Restored, result.Summary.Skipped, result.Summary.Failed, result.Summary.TimedOut)
 }
 
 func uniqueEventTimestamps(eventsList []events.Event) []time.Time {
@@ -415,7 +773,7 @@ func ensureTimestampOption(timestamps []time.Time, ts time.Time) []time.Time {
 	return out
 }
 
-func runPrune(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+func runPrune(args []string, resolvedConfig config.Config, logger *slog.Logger, stdout io.Writer, stderr io.Writer) int {
 	if len(args) > 0 && isHelpToken(args[0]) {
 		_, _ = fmt.Fprintln(stdout, "usage: redeem prune run [--state-dir <path>] [--days <n>]")
 		return 0
@@ -435,35 +793,305 @@ func runPrune(args []string, resolvedConfig config.Config, stdout io.Writer, std
 		return 2
 	}
 
-	runner := prune.NewRunner(*stateDir, *days, time.Now)
+	runner := prune.NewRunnerWithConfig(prune.Config{Root: *stateDir, Days: *days, Now: time.Now, Logger: logger, Hooks: newHooks(resolvedConfig.Hooks, logger)})
 	summary, err := runner.Run()
 	if err != nil {
 		writef(stderr, "prune run failed: %v\n", err)
 		return 1
 	}
-	writef(stdout, "prune_summary events_pruned=%d snapshots_pruned=%d\n", summary.EventsPruned, summary.SnapshotsPruned)
+	writef(stdout, "prune_summary events_pruned=%d snapshots_pruned=%d layouts_pruned=%d\n", summary.EventsPruned, summary.SnapshotsPruned, summary.LayoutsPruned)
 	return 0
 }
 
+func runStore(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 && isHelpToken(args[0]) {
+		_, _ = fmt.Fprintln(stdout, "usage: redeem store [redact|encrypt|decrypt] [flags]")
+		return 0
+	}
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(stderr, "usage: redeem store [redact|encrypt|decrypt] [flags]")
+		return 2
+	}
+	switch args[0] {
+	case "redact":
+		return runStoreRedact(args[1:], resolvedConfig, stdout, stderr)
+	case "encrypt":
+		return runStoreEncrypt(args[1:], resolvedConfig, stdout, stderr)
+	case "decrypt":
+		return runStoreDecrypt(args[1:], resolvedConfig, stdout, stderr)
+	default:
+		writef(stderr, "unknown store subcommand: %s\n", args[0])
+		return 2
+	}
+}
+
+func runStoreRedact(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("store redact", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
+	dryRun := fs.Bool("dry-run", false, "report what would change without rewriting the store")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	codec, err := seal.Load(*stateDir)
+	if err != nil {
+		writef(stderr, "store redact failed: %v\n", err)
+		return 1
+	}
+	redactor, err := newRedactor(resolvedConfig.Redact, *stateDir, codec)
+	if err != nil {
+		writef(stderr, "store redact failed: %v\n", err)
+		return 2
+	}
+	if !redactor.Enabled() {
+		writeln(stderr, "store redact failed: no redact rules configured")
+		return 2
+	}
+	summary, err := redact.Rewrite(*stateDir, redactor, *dryRun)
+	if err != nil {
+		writef(stderr, "store redact failed: %v\n", err)
+		return 1
+	}
+	writef(stdout, "store_redact events_rewritten=%d events_dropped=%d snapshots_rewritten=%d layouts_rewritten=%d dry_run=%t\n", summary.EventsRewritten, summary.EventsDropped, summary.SnapshotsRewritten, summary.LayoutsRewritten, *dryRun)
+	return 0
+}
+
+func runStoreEncrypt(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("store encrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
+	keyFile := fs.String("key-file", "", "file holding a 32-byte key (raw, hex or base64)")
+	keyring := fs.String("keyring", "", "name of a user key in the kernel keyring")
+	generateKey := fs.Bool("generate-key", false, "create --key-file with a new random key")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if (*keyFile == "") == (*keyring == "") {
+		writeln(stderr, "store encrypt requires exactly one of --key-file or --keyring")
+		return 2
+	}
+	if *generateKey && *keyFile == "" {
+		writeln(stderr, "--generate-key requires --key-file")
+		return 2
+	}
+
+	source := seal.KeySource{Keyring: *keyring}
+	if *keyFile != "" {
+		absKeyFile, err := filepath.Abs(*keyFile)
+		if err != nil {
+			writef(stderr, "store encrypt failed: %v\n", err)
+			return 1
+		}
+		source.File = absKeyFile
+	}
+	if *generateKey {
+		if err := seal.GenerateKeyFile(source.File); err != nil {
+			writef(stderr, "store encrypt failed: %v\n", err)
+			return 1
+		}
+		writef(stdout, "key_generated path=%s\n", source.File)
+	}
+
+	summary, err := withStoreLock(*stateDir, func() (seal.Summary, error) {
+		return seal.Encrypt(*stateDir, source, seal.Keyctl{})
+	})
+	if err != nil {
+		writef(stderr, "store encrypt failed: %v\n", err)
+		return 1
+	}
+	writef(stdout, "store_encrypt events_rewritten=%d snapshots_rewritten=%d layouts_rewritten=%d restores_rewritten=%d cipher=%s key=%s\n", summary.EventsRewritten, summary.SnapshotsRewritten, summary.LayoutsRewritten, summary.RestoresRewritten, seal.Cipher, source)
+	return 0
+}
+
+func runStoreDecrypt(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("store decrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	summary, err := withStoreLock(*stateDir, func() (seal.Summary, error) {
+		return seal.Decrypt(*stateDir, seal.Keyctl{})
+	})
+	if err != nil {
+		writef(stderr, "store decrypt failed: %v\n", err)
+		return 1
+	}
+	writef(stdout, "store_decrypt events_rewritten=%d snapshots_rewritten=%d layouts_rewritten=%d restores_rewritten=%d\n", summary.EventsRewritten, summary.SnapshotsRewritten, summary.LayoutsRewritten, summary.RestoresRewritten)
+	return 0
+}
+
+func withStoreLock(stateDir string, migrate func() (seal.Summary, error)) (seal.Summary, error) {
+	store, err := events.NewStore(stateDir)
+	if err != nil {
+		return seal.Summary{}, err
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		return seal.Summary{}, err
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+	return migrate()
+}
+
 type planSummary struct {
 	ready    int
 	skipped  int
 	degraded int
 }
 
-func summarizePlan(plan restore.Plan) planSummary {
-	s := planSummary{}
-	for _, item := range plan.Items {
-		switch item.Status {
-		case restore.StatusReady:
-			s.ready++
-		case restore.StatusDegraded:
-			s.degraded++
-		default:
-			s.skipped++
-		}
-	}
-	return s
+func summarizePlan(plan restore.Plan) planSummary {
+	s := planSummary{}
+	for _, item := range plan.Items {
+		switch item.Status {
+		case restore.StatusReady:
+			s.ready++
+		case restore.StatusDegraded:
+			s.degraded++
+		default:
+			s.skipped++
+		}
+	}
+	return s
+}
+
+func runExport(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
+	fromRaw := fs.String("from", "", "start timestamp (RFC3339, default: first event)")
+	toRaw := fs.String("to", "", "end timestamp (RFC3339, default: last event)")
+	host := fs.String("host", "", "only export events captured under this host")
+	profile := fs.String("profile", "", "only export events captured under this profile")
+	out := fs.String("out", "-", "bundle path, or - for stdout")
+	compress := fs.String("compress", "zstd", "bundle compression: zstd or none")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	from, err := parseOptionalTimestamp(*fromRaw)
+	if err != nil {
+		writef(stderr, "invalid --from: %v\n", err)
+		return 2
+	}
+	to, err := parseOptionalTimestamp(*toRaw)
+	if err != nil {
+		writef(stderr, "invalid --to: %v\n", err)
+		return 2
+	}
+	if *compress != "zstd" && *compress != "none" {
+		writef(stderr, "invalid --compress: %s (expected zstd or none)\n", *compress)
+		return 2
+	}
+
+	target, report := stdout, stderr
+	if *out != "-" {
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			writef(stderr, "export failed: %v\n", err)
+			return 1
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		target, report = f, stdout
+	}
+
+	var sink io.WriteCloser = nopWriteCloser{target}
+	if *compress == "zstd" {
+		sink, err = bundle.ZstdCommand{}.Compress(target)
+		if err != nil {
+			writef(stderr, "export failed: %v\n", err)
+			return 1
+		}
+	}
+	manifest, err := bundle.Export(*stateDir, sink, bundle.ExportOptions{From: from, To: to, Host: *host, Profile: *profile})
+	if errClose := sink.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		writef(stderr, "export failed: %v\n", err)
+		return 1
+	}
+	writef(report, "export events=%d snapshots=%d host=%s profile=%s from=%s to=%s base_state_hash=%s\n", manifest.Events, manifest.Snapshots, manifest.Host, manifest.Profile, manifest.From.Format(time.RFC3339), manifest.To.Format(time.RFC3339), manifest.BaseStateHash)
+	return 0
+}
+
+func runImport(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory to merge into")
+	host := fs.String("host", "", "host to record imported events under (default: bundle host)")
+	profile := fs.String("profile", "", "profile to record imported events under (default: bundle profile)")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		writeln(stderr, "usage: redeem import [flags] <bundle|->")
+		return 2
+	}
+
+	var source io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			writef(stderr, "import failed: %v\n", err)
+			return 1
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		source = f
+	}
+
+	b, err := bundle.Open(source, bundle.ZstdCommand{})
+	if err != nil {
+		writef(stderr, "import failed: %v\n", err)
+		return 1
+	}
+	summary, err := bundle.Import(*stateDir, b, bundle.ImportOptions{
+		Host:         *host,
+		Profile:      *profile,
+		LocalHost:    resolvedConfig.Host,
+		LocalProfile: resolvedConfig.Profile,
+	})
+	if errors.Is(err, bundle.ErrLocalIdentity) {
+		writef(stderr, "import failed: %v (pass --host or --profile)\n", err)
+		return 2
+	}
+	if err != nil {
+		writef(stderr, "import failed: %v\n", err)
+		return 1
+	}
+	writef(stdout, "import events=%d snapshots=%d snapshots_skipped=%d host=%s profile=%s from=%s to=%s\n", summary.EventsImported, summary.SnapshotsImported, summary.SnapshotsSkipped, summary.Host, summary.Profile, b.Manifest.From.Format(time.RFC3339), b.Manifest.To.Format(time.RFC3339))
+	return 0
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
 }
 
 func runHistory(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
@@ -511,7 +1139,15 @@ func runHistoryList(args []string, resolvedConfig config.Config, stdout io.Write
 		return 2
 	}
 
-	eventsList, err := replay.ListEvents(*stateDir, from, to)
+	var eventsList []events.Event
+	if client := connectDaemon(resolvedConfig, *stateDir, nil); client != nil {
+		defer func() {
+			_ = client.Close()
+		}()
+		err = client.Call(context.Background(), daemon.MethodHistoryList, daemon.HistoryParams{From: from, To: to}, &eventsList)
+	} else {
+		eventsList, err = replay.ListEvents(*stateDir, from, to)
+	}
 	if err != nil {
 		writef(stderr, "history list failed: %v\n", err)
 		return 1
@@ -528,12 +1164,30 @@ func runHistoryInspect(args []string, resolvedConfig config.Config, stdout io.Wr
 	fs.SetOutput(stderr)
 	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
 	atRaw := fs.String("at", "", "timestamp (RFC3339)")
+	host := fs.String("host", resolvedConfig.Host, "only replay events captured under this host (empty: every host)")
+	profile := fs.String("profile", resolvedConfig.Profile, "only replay events captured under this profile (empty: every profile)")
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
 			return 0
 		}
 		return 2
 	}
+	accept := func(status daemon.Status) bool {
+		return status.Host == *host && status.Profile == *profile
+	}
+	if client := connectDaemon(resolvedConfig, *stateDir, accept); client != nil {
+		defer func() {
+			_ = client.Close()
+		}()
+		return historyInspectViaDaemon(client, *atRaw, stdout, stderr)
+	}
+
+	engine, err := replay.NewEngine(*stateDir)
+	if err != nil {
+		writef(stderr, "history init failed: %v\n", err)
+		return 1
+	}
+	engine = engine.Scoped(*host, *profile)
 	var at time.Time
 	if strings.TrimSpace(*atRaw) == "" {
 		eventsList, err := replay.ListEvents(*stateDir, nil, nil)
@@ -541,13 +1195,17 @@ func runHistoryInspect(args []string, resolvedConfig config.Config, stdout io.Wr
 			writef(stderr, "history inspect failed: %v\n", err)
 			return 1
 		}
+		eventsList = eventsFor(engine, eventsList)
 		if len(eventsList) == 0 {
 			_, _ = fmt.Fprintln(stderr, "history inspect found no events")
 			return 1
 		}
-		at = eventsList[len(eventsList)-1].TS
+		for _, event := range eventsList {
+			if event.TS.After(at) {
+				at = event.TS
+			}
+		}
 	} else {
-		var err error
 		at, err = parseAtSpec(*atRaw, time.Now().UTC())
 		if err != nil {
 			writef(stderr, "invalid --at: %v\n", err)
@@ -555,17 +1213,46 @@ func runHistoryInspect(args []string, resolvedConfig config.Config, stdout io.Wr
 		}
 	}
 
-	engine, err := replay.NewEngine(*stateDir)
+	state, err := engine.At(at)
 	if err != nil {
-		writef(stderr, "history init failed: %v\n", err)
+		writef(stderr, "history inspect failed: %v\n", err)
 		return 1
 	}
-	state, err := engine.At(at)
+
+	payload, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
-		writef(stderr, "history inspect failed: %v\n", err)
+		writef(stderr, "history encode failed: %v\n", err)
 		return 1
 	}
+	_, _ = fmt.Fprintln(stdout, string(payload))
+	return 0
+}
+
+func eventsFor(engine *replay.Engine, eventsList []events.Event) []events.Event {
+	out := make([]events.Event, 0, len(eventsList))
+	for _, event := range eventsList {
+		if engine.Matches(event.Host, event.Profile) {
+			out = append(out, event)
+		}
+	}
+	return out
+}
 
+func historyInspectViaDaemon(client *daemon.Client, atRaw string, stdout io.Writer, stderr io.Writer) int {
+	params := daemon.AtParams{}
+	if strings.TrimSpace(atRaw) != "" {
+		at, err := parseAtSpec(atRaw, time.Now().UTC())
+		if err != nil {
+			writef(stderr, "invalid --at: %v\n", err)
+			return 2
+		}
+		params.At = &at
+	}
+	var state model.State
+	if err := client.Call(context.Background(), daemon.MethodState, params, &state); err != nil {
+		writef(stderr, "history inspect failed: %v\n", err)
+		return 1
+	}
 	payload, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		writef(stderr, "history encode failed: %v\n", err)
@@ -665,7 +1352,7 @@ func relativeUnitMultiplier(unit byte) (time.Duration, bool) {
 	}
 }
 
-func runCapture(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+func runCapture(args []string, resolvedConfig config.Config, logger *slog.Logger, stdout io.Writer, stderr io.Writer) int {
 	if len(args) == 0 {
 		_, _ = fmt.Fprintln(stderr, "usage: redeem capture <once|run> [flags]")
 		return 2
@@ -677,16 +1364,16 @@ func runCapture(args []string, resolvedConfig config.Config, stdout io.Writer, s
 
 	switch args[0] {
 	case "once":
-		return runCaptureOnce(args[1:], resolvedConfig, stdout, stderr)
+		return runCaptureOnce(args[1:], resolvedConfig, logger, stdout, stderr)
 	case "run":
-		return runCaptureRun(args[1:], resolvedConfig, stdout, stderr)
+		return runCaptureRun(args[1:], resolvedConfig, logger, stdout, stderr)
 	default:
 		writef(stderr, "unknown capture subcommand: %s\n", args[0])
 		return 2
 	}
 }
 
-func runCaptureOnce(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+func runCaptureOnce(args []string, resolvedConfig config.Config, logger *slog.Logger, stdout io.Writer, stderr io.Writer) int {
 	fs := flag.NewFlagSet("capture once", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
@@ -698,29 +1385,68 @@ func runCaptureOnce(args []string, resolvedConfig config.Config, stdout io.Write
 	processWhitelist := fs.String("process-whitelist", strings.Join(resolvedConfig.ProcessMetadata.Whitelist, ","), "comma-separated process tags")
 	processWhitelistExtra := fs.String("process-whitelist-extra", strings.Join(resolvedConfig.ProcessMetadata.WhitelistExtra, ","), "comma-separated extra process tags")
 	includeSessionTag := fs.Bool("include-session-tag", resolvedConfig.ProcessMetadata.IncludeSessionTag, "capture terminal session tags")
+	kittyTabs := fs.Bool("kitty-tabs", resolvedConfig.ProcessMetadata.KittyTabs, "capture kitty tabs and splits via remote control")
+	wezTermPanes := fs.Bool("wezterm-panes", resolvedConfig.ProcessMetadata.WezTermPanes, "capture wezterm tabs and panes via wezterm cli")
+	zellijLayouts := fs.Bool("zellij-layouts", resolvedConfig.ProcessMetadata.ZellijLayouts, "capture zellij session layouts")
+	nvimState := fs.Bool("nvim", resolvedConfig.ProcessMetadata.Nvim, "capture open nvim buffers and sessions over rpc")
+	gitState := fs.Bool("git", resolvedConfig.ProcessMetadata.Git, "capture git repository, branch and dirty state for terminal cwds")
+	metricsTextfile := fs.String("metrics-textfile", resolvedConfig.Metrics.Textfile, "write Prometheus textfile-collector metrics to this path")
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
 			return 0
 		}
 		return 2
 	}
-	if strings.TrimSpace(*fixture) == "" && strings.TrimSpace(*niriCmd) == "" {
+	if strings.TrimSpace(*fixture) == "" && strings.TrimSpace(*niriCmd) == "" && resolvedConfig.Compositor == compositor.Niri {
 		_, _ = fmt.Fprintln(stderr, "capture once requires --fixture or --niri-cmd")
 		return 2
 	}
-
-	runner, err := buildCaptureRunner(captureBuildConfig{
+	buildConfig := captureBuildConfig{
 		stateDir:              *stateDir,
 		host:                  *host,
 		profile:               *profile,
 		snapshotEvery:         *snapshotEvery,
+		compositor:            resolvedConfig.Compositor,
 		fixture:               *fixture,
 		niriCmd:               *niriCmd,
 		processWhitelist:      splitCSV(*processWhitelist),
 		processWhitelistExtra: splitCSV(*processWhitelistExtra),
 		includeSessionTag:     *includeSessionTag,
-		stderr:                stderr,
-	})
+		kittyTabs:             *kittyTabs,
+		wezTermPanes:          *wezTermPanes,
+		zellijLayouts:         *zellijLayouts,
+		nvim:                  *nvimState,
+		git:                   *gitState,
+		terminals:             resolvedConfig.Terminals,
+		source:                "capture.cli",
+		redact:                resolvedConfig.Redact,
+	}
+	if strings.TrimSpace(*fixture) == "" {
+		captureKey := captureOptionsKey(buildConfig, resolvedConfig.Hooks, *metricsTextfile)
+		accept := func(status daemon.Status) bool {
+			return captureKey != "" && status.CaptureKey == captureKey
+		}
+		if client := connectDaemon(resolvedConfig, *stateDir, accept); client != nil {
+			defer func() {
+				_ = client.Close()
+			}()
+			var result daemon.CaptureResult
+			if err := client.Call(context.Background(), daemon.MethodCapture, daemon.CaptureParams{Full: true}, &result); err != nil {
+				writef(stderr, "capture once failed: %v\n", err)
+				return 1
+			}
+			writef(stdout, "events_written=%d state_hash=%s\n", result.EventsWritten, result.StateHash)
+			if result.SnapshotPath != "" {
+				writef(stdout, "snapshot=%s\n", result.SnapshotPath)
+			}
+			return 0
+		}
+	}
+
+	buildConfig.metrics = newMetricsRegistry(*stateDir, "", *metricsTextfile, logger)
+	buildConfig.logger = logger
+	buildConfig.hooks = newHooks(resolvedConfig.Hooks, logger)
+	runner, err := buildCaptureRunner(buildConfig)
 	if err != nil {
 		writef(stderr, "capture init failed: %v\n", err)
 		return 1
@@ -740,7 +1466,7 @@ func runCaptureOnce(args []string, resolvedConfig config.Config, stdout io.Write
 	return 0
 }
 
-func runCaptureRun(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+func runCaptureRun(args []string, resolvedConfig config.Config, logger *slog.Logger, stdout io.Writer, stderr io.Writer) int {
 	fs := flag.NewFlagSet("capture run", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
@@ -753,28 +1479,49 @@ func runCaptureRun(args []string, resolvedConfig config.Config, stdout io.Writer
 	processWhitelist := fs.String("process-whitelist", strings.Join(resolvedConfig.ProcessMetadata.Whitelist, ","), "comma-separated process tags")
 	processWhitelistExtra := fs.String("process-whitelist-extra", strings.Join(resolvedConfig.ProcessMetadata.WhitelistExtra, ","), "comma-separated extra process tags")
 	includeSessionTag := fs.Bool("include-session-tag", resolvedConfig.ProcessMetadata.IncludeSessionTag, "capture terminal session tags")
+	kittyTabs := fs.Bool("kitty-tabs", resolvedConfig.ProcessMetadata.KittyTabs, "capture kitty tabs and splits via remote control")
+	wezTermPanes := fs.Bool("wezterm-panes", resolvedConfig.ProcessMetadata.WezTermPanes, "capture wezterm tabs and panes via wezterm cli")
+	zellijLayouts := fs.Bool("zellij-layouts", resolvedConfig.ProcessMetadata.ZellijLayouts, "capture zellij session layouts")
+	nvimState := fs.Bool("nvim", resolvedConfig.ProcessMetadata.Nvim, "capture open nvim buffers and sessions over rpc")
+	gitState := fs.Bool("git", resolvedConfig.ProcessMetadata.Git, "capture git repository, branch and dirty state for terminal cwds")
+	onEvents := fs.Bool("on-events", resolvedConfig.Capture.OnEvents, "also capture after compositor window/workspace events")
+	eventDebounce := fs.Duration("event-debounce", resolvedConfig.Capture.EventDebounce, "quiet period after compositor events before capturing")
+	metricsListen := fs.String("metrics-listen", resolvedConfig.Metrics.Listen, "serve Prometheus metrics on this address (for example 127.0.0.1:9464)")
+	metricsTextfile := fs.String("metrics-textfile", resolvedConfig.Metrics.Textfile, "write Prometheus textfile-collector metrics to this path")
 	if err := fs.Parse(args); err != nil {
 		if err == flag.ErrHelp {
 			return 0
 		}
 		return 2
 	}
-	if strings.TrimSpace(*fixture) == "" && strings.TrimSpace(*niriCmd) == "" {
+	if strings.TrimSpace(*fixture) == "" && strings.TrimSpace(*niriCmd) == "" && resolvedConfig.Compositor == compositor.Niri {
 		_, _ = fmt.Fprintln(stderr, "capture run requires --fixture or --niri-cmd")
 		return 2
 	}
 
+	registry := newMetricsRegistry(*stateDir, *metricsListen, *metricsTextfile, logger)
 	runner, err := buildCaptureRunner(captureBuildConfig{
 		stateDir:              *stateDir,
 		host:                  *host,
 		profile:               *profile,
 		snapshotEvery:         *snapshotEvery,
+		compositor:            resolvedConfig.Compositor,
 		fixture:               *fixture,
 		niriCmd:               *niriCmd,
 		processWhitelist:      splitCSV(*processWhitelist),
 		processWhitelistExtra: splitCSV(*processWhitelistExtra),
 		includeSessionTag:     *includeSessionTag,
-		stderr:                stderr,
+		kittyTabs:             *kittyTabs,
+		wezTermPanes:          *wezTermPanes,
+		zellijLayouts:         *zellijLayouts,
+		nvim:                  *nvimState,
+		git:                   *gitState,
+		terminals:             resolvedConfig.Terminals,
+		source:                "capture.cli",
+		metrics:               registry,
+		logger:                logger,
+		hooks:                 newHooks(resolvedConfig.Hooks, logger),
+		redact:                resolvedConfig.Redact,
 	})
 	if err != nil {
 		writef(stderr, "capture init failed: %v\n", err)
@@ -785,64 +1532,506 @@ func runCaptureRun(args []string, resolvedConfig config.Config, stdout io.Writer
 	defer ticker.Stop()
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
+	if err := serveMetrics(ctx, *metricsListen, registry, logger, stdout); err != nil {
+		writef(stderr, "capture init failed: %v\n", err)
+		return 1
+	}
+	ticks := ticker.C
+	if *onEvents && strings.TrimSpace(*fixture) == "" {
+		ticks, err = withCompositorEvents(ctx, resolvedConfig, ticker.C, *eventDebounce, logger)
+		if err != nil {
+			writef(stderr, "capture init failed: %v\n", err)
+			return 1
+		}
+	}
 	writef(stdout, "capture_run_started interval=%s\n", interval.String())
-	if err := runner.CaptureRun(ctx, ticker.C); err != nil {
+	if err := runner.CaptureRun(ctx, ticks); err != nil {
 		writef(stderr, "capture run failed: %v\n", err)
 		return 1
 	}
 	return 0
 }
 
+func withCompositorEvents(ctx context.Context, resolvedConfig config.Config, ticks <-chan time.Time, debounce time.Duration, logger *slog.Logger) (<-chan time.Time, error) {
+	backend, err := compositorBackend(resolvedConfig)
+	if err != nil {
+		return nil, err
+	}
+	compositorEvents, err := backend.Events(ctx)
+	if err != nil {
+		logger.Warn("capture_events_unavailable", "compositor", backend.Name(), "err", err)
+		return ticks, nil
+	}
+	return mergeTicks(ctx, ticks, compositor.Debounce(ctx, compositorEvents, debounce)), nil
+}
+
 type captureBuildConfig struct {
 	stateDir              string
 	host                  string
 	profile               string
 	snapshotEvery         int
+	compositor            string
 	fixture               string
 	niriCmd               string
 	processWhitelist      []string
 	processWhitelistExtra []string
 	includeSessionTag     bool
-	stderr                io.Writer
+	kittyTabs             bool
+	wezTermPanes          bool
+	zellijLayouts         bool
+	nvim                  bool
+	git                   bool
+	terminals             []config.TerminalRule
+	source                string
+	metrics               *metrics.Registry
+	logger                *slog.Logger
+	hooks                 *hooks.Dispatcher
+	redact                config.RedactConfig
 }
 
 func buildCaptureRunner(cfg captureBuildConfig) (*capture.Runner, error) {
-	eventStore, err := events.NewStore(cfg.stateDir)
+	codec, err := seal.Load(cfg.stateDir)
+	if err != nil {
+		return nil, err
+	}
+	eventStore, err := events.NewStoreWithCodec(cfg.stateDir, codec)
 	if err != nil {
 		return nil, err
 	}
-	snapshotStore, err := snapshots.NewStore(cfg.stateDir)
+	snapshotStore, err := snapshots.NewStoreWithCodec(cfg.stateDir, codec)
 	if err != nil {
 		return nil, err
 	}
 
-	var snapshotter collector.Snapshotter
+	backend, err := compositor.New(compositor.Config{Name: cfg.compositor, NiriCommand: cfg.niriCmd})
+	if err != nil {
+		return nil, err
+	}
+	var snapshotter collector.Snapshotter = backend
 	if strings.TrimSpace(cfg.fixture) != "" {
 		snapshotter = niri.FileSnapshotter{Path: cfg.fixture}
-	} else {
-		snapshotter = niri.CommandSnapshotter{Command: cfg.niriCmd}
 	}
 
-	enricher := procmeta.NewEnricher(procmeta.ProcReader{}, procmeta.Config{
+	matcher, err := terminalMatcher(cfg.terminals)
+	if err != nil {
+		return nil, err
+	}
+	reader := procmeta.ProcReader{}
+	var enricher collector.Enricher = procmeta.NewEnricher(reader, procmeta.Config{
 		Whitelist:         cfg.processWhitelist,
 		WhitelistExtra:    cfg.processWhitelistExtra,
 		IncludeSessionTag: cfg.includeSessionTag,
+		Terminals:         matcher,
 	})
-	stateCollector := collector.New(snapshotter, enricher)
+	enrichers := []collector.Enricher{enricher}
+	if cfg.kittyTabs {
+		enrichers = append(enrichers, kitty.NewEnricher(kitty.CommandLister{}, reader, kitty.Config{Terminals: matcher}))
+	}
+	if cfg.wezTermPanes {
+		enrichers = append(enrichers, wezterm.NewEnricher(wezterm.CommandLister{}, reader, wezterm.Config{Terminals: matcher}))
+	}
+	if cfg.nvim {
+		enrichers = append(enrichers, nvim.NewEnricher(reader, reader, nvim.RPCQuerier{}, nvim.Config{Terminals: matcher}))
+	}
+	if cfg.git {
+		enrichers = append(enrichers, gitmeta.NewEnricher(gitmeta.Command{}))
+	}
+	redactor, err := newRedactor(cfg.redact, cfg.stateDir, codec)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.includeSessionTag && cfg.zellijLayouts {
+		layoutStore, err := layouts.NewStoreWithCodec(cfg.stateDir, codec)
+		if err != nil {
+			return nil, err
+		}
+		enrichers = append(enrichers, layouts.NewEnricher(layouts.ZellijDumper{}, redactor.MaskedLayouts(layoutStore)))
+	}
+	if len(enrichers) > 1 {
+		enricher = collector.Chain(enrichers...)
+	}
+	collectorConfig := collector.Config{Snapshotter: snapshotter, Parser: backend, Enricher: enricher, Logger: cfg.logger}
+	if redactor.Enabled() {
+		collectorConfig.Redactor = redactor
+	}
+	var runnerMetrics capture.Metrics
+	if cfg.metrics != nil {
+		collectorConfig.Observer = cfg.metrics
+		runnerMetrics = cfg.metrics
+	}
+	var runnerHooks capture.Hooks
+	if cfg.hooks != nil {
+		runnerHooks = cfg.hooks
+	}
 
 	return capture.NewRunner(capture.Config{
-		Collector:     stateCollector,
+		Collector:     collector.NewWithConfig(collectorConfig),
 		DiffEngine:    diff.NewEngine(),
 		EventStore:    eventStore,
 		SnapshotStore: snapshotStore,
 		SnapshotEvery: cfg.snapshotEvery,
 		Host:          cfg.host,
 		Profile:       cfg.profile,
-		Source:        "capture.cli",
-		Logger:        cfg.stderr,
+		Source:        cfg.source,
+		Logger:        cfg.logger,
+		Metrics:       runnerMetrics,
+		Hooks:         runnerHooks,
 	}), nil
 }
 
+func newHooks(rules []config.HookRule, logger *slog.Logger) *hooks.Dispatcher {
+	configured := make([]hooks.Hook, 0, len(rules))
+	for _, rule := range rules {
+		configured = append(configured, hooks.Hook{Event: rule.Event, Command: rule.Command, AppID: rule.AppID, Timeout: rule.Timeout})
+	}
+	return hooks.New(hooks.Config{Hooks: configured, Logger: logger})
+}
+
+func newRedactor(rules config.RedactConfig, stateDir string, codec *seal.Codec) (*redact.Redactor, error) {
+	redactConfig := redact.Config{ExcludeAppIDs: rules.ExcludeAppIDs}
+	for _, rule := range rules.Titles {
+		redactConfig.Titles = append(redactConfig.Titles, redact.TitleRule{AppID: rule.AppID, Mode: rule.Mode, Pattern: rule.Pattern, Replacement: rule.Replacement})
+		if strings.EqualFold(strings.TrimSpace(rule.Mode), redact.TitleHash) && redactConfig.TitleKey == nil {
+			key, err := redact.LoadTitleKey(stateDir)
+			if err != nil {
+				return nil, err
+			}
+			redactConfig.TitleKey = key
+		}
+	}
+	for _, rule := range rules.CWDPrefixes {
+		redactConfig.CWDPrefixes = append(redactConfig.CWDPrefixes, redact.CWDRule{Prefix: rule.Prefix, Replacement: rule.Replacement})
+	}
+	if len(redactConfig.CWDPrefixes) > 0 {
+		layoutStore, err := layouts.NewStoreWithCodec(stateDir, codec)
+		if err != nil {
+			return nil, err
+		}
+		redactConfig.Layouts = layoutStore
+	}
+	return redact.New(redactConfig)
+}
+
+func newMetricsRegistry(stateDir string, listen string, textfile string, logger *slog.Logger) *metrics.Registry {
+	if strings.TrimSpace(listen) == "" && strings.TrimSpace(textfile) == "" {
+		return nil
+	}
+	return metrics.NewRegistry(metrics.Config{
+		StateDir: stateDir,
+		Textfile: strings.TrimSpace(textfile),
+		Logger:   logger,
+	})
+}
+
+func serveMetrics(ctx context.Context, listen string, registry *metrics.Registry, logger *slog.Logger, stdout io.Writer) error {
+	if strings.TrimSpace(listen) == "" || registry == nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("metrics listen: %w", err)
+	}
+	server := &http.Server{Handler: registry.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("metrics_server_error", "err", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	writef(stdout, "metrics_listening addr=%s\n", listener.Addr().String())
+	return nil
+}
+
+func runDaemon(args []string, resolvedConfig config.Config, logger *slog.Logger, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 && isHelpToken(args[0]) {
+		_, _ = fmt.Fprintln(stdout, "usage: redeem daemon [run|status] [flags]")
+		return 0
+	}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "run":
+			return runDaemonRun(args[1:], resolvedConfig, logger, stdout, stderr)
+		case "status":
+			return runDaemonStatus(args[1:], resolvedConfig, stdout, stderr)
+		default:
+			writef(stderr, "unknown daemon subcommand: %s\n", args[0])
+			return 2
+		}
+	}
+	return runDaemonRun(args, resolvedConfig, logger, stdout, stderr)
+}
+
+func runDaemonRun(args []string, resolvedConfig config.Config, logger *slog.Logger, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("daemon run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
+	socket := fs.String("socket", resolvedConfig.Daemon.Socket, "control socket path (defaults to <state-dir>/daemon.sock)")
+	interval := fs.Duration("interval", resolvedConfig.Capture.Interval, "capture interval")
+	onEvents := fs.Bool("on-events", resolvedConfig.Capture.OnEvents, "also capture after compositor window/workspace events")
+	eventDebounce := fs.Duration("event-debounce", resolvedConfig.Capture.EventDebounce, "quiet period after compositor events before capturing")
+	metricsListen := fs.String("metrics-listen", resolvedConfig.Metrics.Listen, "serve Prometheus metrics on this address (for example 127.0.0.1:9464)")
+	metricsTextfile := fs.String("metrics-textfile", resolvedConfig.Metrics.Textfile, "write Prometheus textfile-collector metrics to this path")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	absStateDir, err := filepath.Abs(*stateDir)
+	if err != nil {
+		writef(stderr, "daemon init failed: %v\n", err)
+		return 1
+	}
+	socketPath := strings.TrimSpace(*socket)
+	if socketPath == "" {
+		socketPath = daemon.SocketPath(absStateDir)
+	}
+
+	buildConfig := daemonCaptureConfig(resolvedConfig, absStateDir)
+	captureKey := captureOptionsKey(buildConfig, resolvedConfig.Hooks, *metricsTextfile)
+	registry := newMetricsRegistry(absStateDir, *metricsListen, *metricsTextfile, logger)
+	buildConfig.metrics = registry
+	buildConfig.logger = logger
+	buildConfig.hooks = newHooks(resolvedConfig.Hooks, logger)
+	runner, err := buildCaptureRunner(buildConfig)
+	if err != nil {
+		writef(stderr, "daemon init failed: %v\n", err)
+		return 1
+	}
+	planner, err := buildRestorePlanner(resolvedConfig, absStateDir)
+	if err != nil {
+		writef(stderr, "daemon init failed: %v\n", err)
+		return 1
+	}
+	backend, err := compositorBackend(resolvedConfig)
+	if err != nil {
+		writef(stderr, "daemon init failed: %v\n", err)
+		return 1
+	}
+
+	service := daemon.NewService(daemon.Config{
+		StateDir:   absStateDir,
+		Host:       resolvedConfig.Host,
+		Profile:    resolvedConfig.Profile,
+		CaptureKey: captureKey,
+		RestoreKey: restoreOptionsKey(resolvedConfig),
+		Capturer:   runner,
+		Planner:    planner,
+		Apply: func(ctx context.Context, restoredAt time.Time, plan restore.Plan) (string, error) {
+			var out bytes.Buffer
+			result := executeRestorePlan(ctx, &out, logger, resolvedConfig, backend, absStateDir, restoredAt, plan)
+			printRestoreExecution(&out, result)
+			return out.String(), nil
+		},
+		Logger: logger,
+	})
+	server := daemon.NewServer()
+	service.Register(server)
+	listener, err := daemon.Listen(socketPath)
+	if err != nil {
+		writef(stderr, "daemon init failed: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ctx, listener)
+	}()
+
+	if err := serveMetrics(ctx, *metricsListen, registry, logger, stdout); err != nil {
+		writef(stderr, "daemon init failed: %v\n", err)
+		stop()
+		<-serveErr
+		return 1
+	}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	ticks := ticker.C
+	if *onEvents {
+		ticks, err = withCompositorEvents(ctx, resolvedConfig, ticker.C, *eventDebounce, logger)
+		if err != nil {
+			writef(stderr, "daemon init failed: %v\n", err)
+			stop()
+			<-serveErr
+			return 1
+		}
+	}
+	writef(stdout, "daemon_started socket=%s interval=%s\n", socketPath, interval.String())
+	if _, err := service.Capture(ctx); err != nil {
+		logger.Error("capture_once_error", "err", err)
+	}
+	service.Run(ctx, ticks)
+
+	stop()
+	if err := <-serveErr; err != nil {
+		writef(stderr, "daemon failed: %v\n", err)
+		return 1
+	}
+	writef(stdout, "daemon_stopped socket=%s\n", socketPath)
+	return 0
+}
+
+func daemonCaptureConfig(resolvedConfig config.Config, stateDir string) captureBuildConfig {
+	return captureBuildConfig{
+		stateDir:              stateDir,
+		host:                  resolvedConfig.Host,
+		profile:               resolvedConfig.Profile,
+		snapshotEvery:         resolvedConfig.Capture.SnapshotEvery,
+		compositor:            resolvedConfig.Compositor,
+		niriCmd:               captureNiriCommandDefault(resolvedConfig),
+		processWhitelist:      resolvedConfig.ProcessMetadata.Whitelist,
+		processWhitelistExtra: resolvedConfig.ProcessMetadata.WhitelistExtra,
+		includeSessionTag:     resolvedConfig.ProcessMetadata.IncludeSessionTag,
+		kittyTabs:             resolvedConfig.ProcessMetadata.KittyTabs,
+		wezTermPanes:          resolvedConfig.ProcessMetadata.WezTermPanes,
+		zellijLayouts:         resolvedConfig.ProcessMetadata.ZellijLayouts,
+		nvim:                  resolvedConfig.ProcessMetadata.Nvim,
+		git:                   resolvedConfig.ProcessMetadata.Git,
+		terminals:             resolvedConfig.Terminals,
+		source:                "capture.daemon",
+		redact:                resolvedConfig.Redact,
+	}
+}
+
+func runDaemonStatus(args []string, resolvedConfig config.Config, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("daemon status", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	stateDir := fs.String("state-dir", resolvedConfig.StateDir, "state directory")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	resolvedConfig.Daemon.Connect = true
+	client := connectDaemon(resolvedConfig, *stateDir, nil)
+	if client == nil {
+		_, _ = fmt.Fprintln(stdout, "daemon_status running=false")
+		return 1
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	var status daemon.Status
+	if err := client.Call(context.Background(), daemon.MethodStatus, nil, &status); err != nil {
+		writef(stderr, "daemon status failed: %v\n", err)
+		return 1
+	}
+	lastCapture := "never"
+	if status.LastCaptureAt != nil {
+		lastCapture = status.LastCaptureAt.Format(time.RFC3339)
+	}
+	writef(stdout, "daemon_status running=true pid=%d state_dir=%s started_at=%s last_capture=%s captures=%d windows=%d state_hash=%s\n", status.PID, status.StateDir, status.StartedAt.Format(time.RFC3339), lastCapture, status.Captures, status.Windows, status.StateHash)
+	if status.LastError != "" {
+		writef(stdout, "daemon_last_error error=%q\n", status.LastError)
+	}
+	return 0
+}
+
+func daemonSocketPath(resolvedConfig config.Config, stateDir string) string {
+	if socket := strings.TrimSpace(resolvedConfig.Daemon.Socket); socket != "" {
+		return socket
+	}
+	return daemon.SocketPath(stateDir)
+}
+
+func connectDaemon(resolvedConfig config.Config, stateDir string, accept func(daemon.Status) bool) *daemon.Client {
+	if !resolvedConfig.Daemon.Connect {
+		return nil
+	}
+	absStateDir, err := filepath.Abs(stateDir)
+	if err != nil {
+		return nil
+	}
+	client, err := daemon.Dial(daemonSocketPath(resolvedConfig, absStateDir))
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var status daemon.Status
+	if err := client.Call(ctx, daemon.MethodStatus, nil, &status); err != nil || filepath.Clean(status.StateDir) != absStateDir {
+		_ = client.Close()
+		return nil
+	}
+	if accept != nil && !accept(status) {
+		_ = client.Close()
+		return nil
+	}
+	return client
+}
+
+func captureOptionsKey(cfg captureBuildConfig, hookRules []config.HookRule, metricsTextfile string) string {
+	return optionsKey(struct {
+		Host                  string
+		Profile               string
+		SnapshotEvery         int
+		Compositor            string
+		NiriCmd               string
+		ProcessWhitelist      string
+		ProcessWhitelistExtra string
+		IncludeSessionTag     bool
+		KittyTabs             bool
+		WezTermPanes          bool
+		ZellijLayouts         bool
+		Nvim                  bool
+		Git                   bool
+		Terminals             []config.TerminalRule
+		Hooks                 []config.HookRule
+		Redact                config.RedactConfig
+		MetricsTextfile       string
+	}{
+		Host:                  cfg.host,
+		Profile:               cfg.profile,
+		SnapshotEvery:         cfg.snapshotEvery,
+		Compositor:            cfg.compositor,
+		NiriCmd:               cfg.niriCmd,
+		ProcessWhitelist:      strings.Join(cfg.processWhitelist, ","),
+		ProcessWhitelistExtra: strings.Join(cfg.processWhitelistExtra, ","),
+		IncludeSessionTag:     cfg.includeSessionTag,
+		KittyTabs:             cfg.kittyTabs,
+		WezTermPanes:          cfg.wezTermPanes,
+		ZellijLayouts:         cfg.zellijLayouts,
+		Nvim:                  cfg.nvim,
+		Git:                   cfg.git,
+		Terminals:             cfg.terminals,
+		Hooks:                 hookRules,
+		Redact:                cfg.redact,
+		MetricsTextfile:       metricsTextfile,
+	})
+}
+
+func restoreOptionsKey(resolvedConfig config.Config) string {
+	return optionsKey(struct {
+		Compositor string
+		Restore    config.RestoreConfig
+		Terminals  []config.TerminalRule
+		Hooks      []config.HookRule
+	}{
+		Compositor: resolvedConfig.Compositor,
+		Restore:    resolvedConfig.Restore,
+		Terminals:  resolvedConfig.Terminals,
+		Hooks:      resolvedConfig.Hooks,
+	})
+}
+
+func optionsKey(value any) string {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(payload)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
 func splitCSV(raw string) []string {
 	parts := strings.Split(raw, ",")
 	out := make([]string, 0, len(parts))
@@ -865,6 +2054,9 @@ func envOrDefault(name string, fallback string) string {
 type globalFlags struct {
 	configPath     string
 	explicitConfig bool
+	noDaemon       bool
+	logLevel       string
+	logFormat      string
 }
 
 func parseGlobalFlags(args []string) (globalFlags, []string, error) {
@@ -891,6 +2083,33 @@ func parseGlobalFlags(args []string) (globalFlags, []string, error) {
 			i += 2
 			continue
 		}
+		if arg == "--no-daemon" {
+			flags.noDaemon = true
+			i++
+			continue
+		}
+		if consumed, value, ok := globalValueFlag(args, i, "--log-level"); ok {
+			if strings.TrimSpace(value) == "" {
+				return globalFlags{}, nil, fmt.Errorf("--log-level requires a value")
+			}
+			if err := config.ValidateLogLevel(value); err != nil {
+				return globalFlags{}, nil, err
+			}
+			flags.logLevel = value
+			i += consumed
+			continue
+		}
+		if consumed, value, ok := globalValueFlag(args, i, "--log-format"); ok {
+			if strings.TrimSpace(value) == "" {
+				return globalFlags{}, nil, fmt.Errorf("--log-format requires a value")
+			}
+			if err := config.ValidateLogFormat(value); err != nil {
+				return globalFlags{}, nil, err
+			}
+			flags.logFormat = value
+			i += consumed
+			continue
+		}
 		if strings.HasPrefix(arg, "--config=") {
 			flags.configPath = strings.TrimPrefix(arg, "--config=")
 			if strings.TrimSpace(flags.configPath) == "" {
@@ -906,6 +2125,31 @@ func parseGlobalFlags(args []string) (globalFlags, []string, error) {
 	return flags, args[i:], nil
 }
 
+func globalValueFlag(args []string, i int, name string) (int, string, bool) {
+	if args[i] == name {
+		if i+1 >= len(args) {
+			return 1, "", true
+		}
+		return 2, args[i+1], true
+	}
+	if value, ok := strings.CutPrefix(args[i], name+"="); ok {
+		return 1, value, true
+	}
+	return 0, "", false
+}
+
+func newLogger(stderr io.Writer, logConfig config.LogConfig) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(logConfig.Level))); err != nil {
+		level = slog.LevelInfo
+	}
+	options := &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(strings.TrimSpace(logConfig.Format), "json") {
+		return slog.New(slog.NewJSONHandler(stderr, options))
+	}
+	return slog.New(slog.NewTextHandler(stderr, options))
+}
+
 func captureNiriCommandDefault(resolvedConfig config.Config) string {
 	configured := strings.TrimSpace(resolvedConfig.Capture.NiriCommand)
 	defaults := strings.TrimSpace(config.Defaults().Capture.NiriCommand)
@@ -933,11 +2177,18 @@ func printHelp(w io.Writer) {
 	writeln(w, "  restore   Restore from history")
 	writeln(w, "  history   Inspect timeline")
 	writeln(w, "  prune     Prune old events/snapshots")
+	writeln(w, "  daemon    Run capture in the background and serve a control socket")
+	writeln(w, "  store     Maintain the state store (redact, encrypt, decrypt)")
+	writeln(w, "  export    Write a timeline bundle (tar.zst) for another machine")
+	writeln(w, "  import    Merge a timeline bundle into a state dir")
 	writeln(w, "  bottle    Bottle workflows (V2)")
 	writeln(w, "  doctor    Basic environment checks")
 	writeln(w)
 	writeln(w, "Flags:")
 	writeln(w, "  --config <path>  Path to YAML config file")
+	writeln(w, "  --no-daemon      Access the store directly even when a daemon is running")
+	writeln(w, "  --log-level <l>  Diagnostic log level: debug, info, warn or error")
+	writeln(w, "  --log-format <f> Diagnostic log format on stderr: text or json")
 	writeln(w, "  -h, --help  Show help")
 }
 
@@ -966,3 +2217,27 @@ func writef(w io.Writer, format string, args ...any) {
 func writeln(w io.Writer, args ...any) {
 	_, _ = fmt.Fprintln(w, args...)
 }
+
+func mergeTicks(ctx context.Context, sources ...<-chan time.Time) <-chan time.Time {
+	out := make(chan time.Time)
+	for _, source := range sources {
+		go func(source <-chan time.Time) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case at, ok := <-source:
+					if !ok {
+						return
+					}
+					select {
+					case out <- at:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(source)
+	}
+	return out
+}
@@ -2,14 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/jmo/terminal-redeemer/internal/capture"
 	"github.com/jmo/terminal-redeemer/internal/config"
+	"github.com/jmo/terminal-redeemer/internal/daemon"
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/redact"
 )
 
 func TestHelpByDefault(t *testing.T) {
@@ -58,6 +65,7 @@ func TestSubcommandHelpExitCodes(t *testing.T) {
 		{name: "history inspect", args: []string{"history", "inspect", "--help"}},
 		{name: "restore apply", args: []string{"restore", "apply", "--help"}},
 		{name: "restore tui", args: []string{"restore", "tui", "--help"}},
+		{name: "restore undo", args: []string{"restore", "undo", "--help"}},
 		{name: "prune run", args: []string{"prune", "run", "--help"}},
 	}
 
@@ -115,6 +123,21 @@ func TestInvalidUsageExitCodesRemainTwo(t *testing.T) {
 	}
 }
 
+func TestRestoreUndoWithoutJournalFails(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"restore", "undo", "--state-dir", t.TempDir()}, &out, &stderr)
+
+	if code != 1 {
+		t.Fatalf("expected code 1, got %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "no restore run recorded") {
+		t.Fatalf("expected missing run error, got %q", stderr.String())
+	}
+}
+
 func TestHistoryInspectDefaultsToLatest(t *testing.T) {
 	t.Parallel()
 
@@ -132,10 +155,10 @@ func TestHistoryInspectDefaultsToLatest(t *testing.T) {
 	}()
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "old"}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "old"}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append old event: %v", err)
 	}
-	if _, err := writer.Append(events.Event{V: 1, TS: t0.Add(2 * time.Second), Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "new"}, StateHash: "sha256:b"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0.Add(2 * time.Second), Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "new"}, StateHash: "sha256:b"}); err != nil {
 		t.Fatalf("append new event: %v", err)
 	}
 
@@ -150,6 +173,39 @@ func TestHistoryInspectDefaultsToLatest(t *testing.T) {
 	}
 }
 
+func TestHistoryInspectShowsGitMetadata(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	store, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+
+	terminal := map[string]any{"cwd": "/src/app-feature", "git": map[string]any{"root": "/src/app", "branch": "feature", "worktree": "/src/app-feature", "dirty": true}}
+	if _, err := writer.Append(events.Event{V: 1, TS: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "terminal": terminal}, StateHash: "sha256:a"}); err != nil {
+		t.Fatalf("append event: %v", err)
+	}
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	if code := run([]string{"history", "inspect", "--state-dir", root}, &out, &stderr); code != 0 {
+		t.Fatalf("expected code 0, got %d stderr=%q", code, stderr.String())
+	}
+	for _, want := range []string{`"branch": "feature"`, `"worktree": "/src/app-feature"`, `"dirty": true`} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %s in inspect output, got %q", want, out.String())
+		}
+	}
+}
+
 func TestCaptureOnceEndToEndWithFixture(t *testing.T) {
 	t.Parallel()
 
@@ -185,6 +241,66 @@ func TestCaptureOnceEndToEndWithFixture(t *testing.T) {
 	}
 }
 
+func TestCaptureOnceWritesMetricsTextfile(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	fixturePath := filepath.Join(root, "niri.json")
+	err := os.WriteFile(fixturePath, []byte(`{
+		"workspaces": [{"id": "ws-1", "idx": 1, "name": "main"}],
+		"windows": [{"id": 101, "app_id": "firefox", "title": "docs", "workspace_id": "ws-1", "pid": 4242}]
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	textfile := filepath.Join(root, "textfile", "redeem.prom")
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"capture", "once", "--state-dir", filepath.Join(root, "state"), "--fixture", fixturePath, "--metrics-textfile", textfile}, &out, &stderr)
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d stderr=%q", code, stderr.String())
+	}
+
+	payload, err := os.ReadFile(textfile)
+	if err != nil {
+		t.Fatalf("read metrics textfile: %v", err)
+	}
+	for _, want := range []string{"redeem_captures_succeeded_total 1\n", "redeem_events_written_total 1\n", "redeem_windows 1\n", "redeem_workspaces 1\n"} {
+		if !strings.Contains(string(payload), want) {
+			t.Fatalf("expected %q in metrics textfile:\n%s", want, payload)
+		}
+	}
+}
+
+func TestCaptureOnceParsesFixtureWithConfiguredCompositor(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	fixturePath := filepath.Join("..", "..", "internal", "compositor", "testdata", "sway_tree.json")
+	stateDir := filepath.Join(root, "state")
+	configPath := filepath.Join(root, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("compositor: sway\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"--config", configPath, "capture", "once", "--state-dir", stateDir, "--fixture", fixturePath}, &out, &stderr)
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d stderr=%q", code, stderr.String())
+	}
+
+	out.Reset()
+	code = run([]string{"history", "inspect", "--state-dir", stateDir}, &out, &stderr)
+	if code != 0 {
+		t.Fatalf("expected inspect code 0, got %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "\"key\": \"w:thunderbird:20\"") || !strings.Contains(out.String(), "\"name\": \"mail\"") {
+		t.Fatalf("expected sway windows and workspaces in state, got %q", out.String())
+	}
+}
+
 func TestCaptureOnceEndToEndWithCommandSnapshotter(t *testing.T) {
 	root := t.TempDir()
 	stateDir := filepath.Join(root, "state")
@@ -228,7 +344,7 @@ func TestHistoryInspectAtTimestamp(t *testing.T) {
 	}()
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "shell"}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "shell"}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append event: %v", err)
 	}
 
@@ -260,7 +376,7 @@ func TestRestoreApplyPreview(t *testing.T) {
 	}()
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "shell"}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "shell"}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append event: %v", err)
 	}
 
@@ -288,7 +404,7 @@ func TestPruneRunCommand(t *testing.T) {
 		t.Fatalf("acquire writer: %v", err)
 	}
 	t0 := time.Now().UTC().AddDate(0, 0, -40)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "old"}, StateHash: "sha256:old"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "old"}, StateHash: "sha256:old"}); err != nil {
 		t.Fatalf("append old event: %v", err)
 	}
 	_ = writer.Close()
@@ -304,6 +420,208 @@ func TestPruneRunCommand(t *testing.T) {
 	}
 }
 
+func TestPruneRunFiresConfiguredHooks(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	hookOutput := filepath.Join(root, "hooks.log")
+	configPath := filepath.Join(root, "config.yaml")
+	configYAML := "hooks:\n" +
+		"  - event: prune.before\n    command: \"echo before >> '" + hookOutput + "'\"\n" +
+		"  - event: prune.after\n    command: \"cat >> '" + hookOutput + "'\"\n" +
+		"  - event: prune.after\n    command: \"exit 7\"\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"--config", configPath, "prune", "run", "--state-dir", filepath.Join(root, "state"), "--days", "30"}, &out, &stderr)
+	if code != 0 {
+		t.Fatalf("expected failing hook to be non-fatal, got %d stderr=%q", code, stderr.String())
+	}
+	payload, err := os.ReadFile(hookOutput)
+	if err != nil {
+		t.Fatalf("read hook output: %v", err)
+	}
+	if !strings.HasPrefix(string(payload), "before\n{\"event\":\"prune.after\"") || !strings.Contains(string(payload), `"days":30`) {
+		t.Fatalf("unexpected hook output: %q", payload)
+	}
+	if !strings.Contains(stderr.String(), "msg=hook_failed event=prune.after") {
+		t.Fatalf("expected hook failure to be logged, got %q", stderr.String())
+	}
+}
+
+func TestStoreRedactRewritesCapturedHistory(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	fixturePath := filepath.Join(root, "niri.json")
+	err := os.WriteFile(fixturePath, []byte(`{
+		"workspaces": [{"id": "ws-1", "idx": 1, "name": "main"}],
+		"windows": [
+			{"id": 101, "app_id": "kitty", "title": "ssh prod-db-1", "workspace_id": "ws-1", "pid": 4242},
+			{"id": 102, "app_id": "org.keepassxc.KeePassXC", "title": "Passwords.kdbx", "workspace_id": "ws-1", "pid": 4243}
+		]
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	configPath := filepath.Join(root, "config.yaml")
+	configYAML := "redact:\n" +
+		"  titles:\n    - appId: kitty\n      mode: hash\n" +
+		"  excludeAppIds:\n    - org.keepassxc.*\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	plainConfigPath := filepath.Join(root, "empty.yaml")
+	if err := os.WriteFile(plainConfigPath, []byte("host: local\n"), 0o600); err != nil {
+		t.Fatalf("write plain config: %v", err)
+	}
+	stateDir := filepath.Join(root, "state")
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	if code := run([]string{"--config", plainConfigPath, "capture", "once", "--state-dir", stateDir, "--fixture", fixturePath}, &out, &stderr); code != 0 {
+		t.Fatalf("capture once: code %d stderr=%q", code, stderr.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "store", "redact", "--state-dir", stateDir}, &out, &stderr); code != 0 {
+		t.Fatalf("store redact: code %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "store_redact events_rewritten=1 events_dropped=0 snapshots_rewritten=0 layouts_rewritten=0 dry_run=false") {
+		t.Fatalf("unexpected store redact output %q", out.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "capture", "once", "--state-dir", stateDir, "--fixture", fixturePath}, &out, &stderr); code != 0 {
+		t.Fatalf("redacted capture once: code %d stderr=%q", code, stderr.String())
+	}
+
+	payload, err := os.ReadFile(filepath.Join(stateDir, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	if strings.Count(string(payload), "\n") != 2 {
+		t.Fatalf("expected two events, got %q", payload)
+	}
+	for _, secret := range []string{"prod-db-1", "KeePassXC", "Passwords"} {
+		if strings.Contains(string(payload), secret) {
+			t.Fatalf("expected %q to be redacted, got %q", secret, payload)
+		}
+	}
+	key, err := redact.LoadTitleKey(stateDir)
+	if err != nil {
+		t.Fatalf("load title key: %v", err)
+	}
+	if strings.Count(string(payload), redact.HashTitle(key, "ssh prod-db-1")) != 2 {
+		t.Fatalf("expected hashed kitty title in both events, got %q", payload)
+	}
+}
+
+func TestStoreRedactRequiresRules(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	configPath := filepath.Join(root, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("host: local\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"--config", configPath, "store", "redact", "--state-dir", root}, &out, &stderr)
+	if code != 2 || !strings.Contains(stderr.String(), "no redact rules configured") {
+		t.Fatalf("expected usage error without rules, got %d stderr=%q", code, stderr.String())
+	}
+}
+
+func TestStoreEncryptAndDecryptKeepHistoryReadable(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	fixturePath := filepath.Join(root, "niri.json")
+	err := os.WriteFile(fixturePath, []byte(`{
+		"workspaces": [{"id": "ws-1", "idx": 1, "name": "main"}],
+		"windows": [{"id": 101, "app_id": "kitty", "title": "private notes", "workspace_id": "ws-1", "pid": 4242}]
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	configPath := filepath.Join(root, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("host: local\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	stateDir := filepath.Join(root, "state")
+	keyFile := filepath.Join(root, "keys", "redeem.key")
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	if code := run([]string{"--config", configPath, "capture", "once", "--state-dir", stateDir, "--fixture", fixturePath}, &out, &stderr); code != 0 {
+		t.Fatalf("capture once: code %d stderr=%q", code, stderr.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "store", "encrypt", "--state-dir", stateDir, "--key-file", keyFile, "--generate-key"}, &out, &stderr); code != 0 {
+		t.Fatalf("store encrypt: code %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "key_generated path="+keyFile) || !strings.Contains(out.String(), "store_encrypt events_rewritten=1 snapshots_rewritten=0 layouts_rewritten=0 restores_rewritten=0 cipher=nacl-secretbox key=file:"+keyFile) {
+		t.Fatalf("unexpected store encrypt output %q", out.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "capture", "once", "--state-dir", stateDir, "--fixture", fixturePath}, &out, &stderr); code != 0 {
+		t.Fatalf("encrypted capture once: code %d stderr=%q", code, stderr.String())
+	}
+	payload, err := os.ReadFile(filepath.Join(stateDir, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	if strings.Contains(string(payload), "private notes") {
+		t.Fatalf("expected encrypted events, got %q", payload)
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "--no-daemon", "history", "list", "--state-dir", stateDir}, &out, &stderr); code != 0 {
+		t.Fatalf("history list: code %d stderr=%q", code, stderr.String())
+	}
+	if strings.Count(out.String(), " state_full") != 2 {
+		t.Fatalf("expected both events listed, got %q", out.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "store", "decrypt", "--state-dir", stateDir}, &out, &stderr); code != 0 {
+		t.Fatalf("store decrypt: code %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "store_decrypt events_rewritten=2 snapshots_rewritten=0 layouts_rewritten=0 restores_rewritten=0") {
+		t.Fatalf("unexpected store decrypt output %q", out.String())
+	}
+	payload, err = os.ReadFile(filepath.Join(stateDir, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	if strings.Count(string(payload), "private notes") != 2 {
+		t.Fatalf("expected plaintext events after decrypt, got %q", payload)
+	}
+}
+
+func TestStoreEncryptRequiresOneKeySource(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"store", "encrypt", "--state-dir", t.TempDir()}, &out, &stderr)
+	if code != 2 || !strings.Contains(stderr.String(), "exactly one of --key-file or --keyring") {
+		t.Fatalf("expected usage error, got %d stderr=%q", code, stderr.String())
+	}
+}
+
 func TestHistoryInspectInvalidTimestamp(t *testing.T) {
 	t.Parallel()
 
@@ -379,10 +697,10 @@ func TestHistoryInspectRelativeAt(t *testing.T) {
 	}()
 
 	now := time.Now().UTC()
-	if _, err := writer.Append(events.Event{V: 1, TS: now.Add(-2 * time.Minute), Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "older"}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: now.Add(-2 * time.Minute), Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "older"}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append older event: %v", err)
 	}
-	if _, err := writer.Append(events.Event{V: 1, TS: now.Add(-20 * time.Second), Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "newer"}, StateHash: "sha256:b"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: now.Add(-20 * time.Second), Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "newer"}, StateHash: "sha256:b"}); err != nil {
 		t.Fatalf("append newer event: %v", err)
 	}
 
@@ -428,7 +746,7 @@ func TestRestoreApplyPreviewAndApplyParityForSkippedOnlyPlan(t *testing.T) {
 	}()
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "firefox", "workspace_id": "ws-1", "title": "web"}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "firefox", "workspace_id": "ws-1", "title": "web"}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append event: %v", err)
 	}
 
@@ -476,13 +794,13 @@ func TestRestoreApplyReportsDegradedSkippedAndFailedItems(t *testing.T) {
 	}()
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-term", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "terminal": map[string]any{"cwd": "/tmp/project"}}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-term", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "terminal": map[string]any{"cwd": "/tmp/project"}}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append terminal event: %v", err)
 	}
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-skip", Patch: map[string]any{"app_id": "firefox", "workspace_id": "ws-1"}, StateHash: "sha256:b"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-skip", Patch: map[string]any{"app_id": "firefox", "workspace_id": "ws-1"}, StateHash: "sha256:b"}); err != nil {
 		t.Fatalf("append skipped event: %v", err)
 	}
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-fail", Patch: map[string]any{"app_id": "code", "workspace_id": "ws-1"}, StateHash: "sha256:c"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-fail", Patch: map[string]any{"app_id": "code", "workspace_id": "ws-1"}, StateHash: "sha256:c"}); err != nil {
 		t.Fatalf("append failed event: %v", err)
 	}
 
@@ -512,6 +830,69 @@ func TestRestoreApplyReportsDegradedSkippedAndFailedItems(t *testing.T) {
 	}
 }
 
+func TestRestoreApplyInterruptReportsTimeoutAndCancelledItems(t *testing.T) {
+	root := t.TempDir()
+	store, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	for _, key := range []string{"w-1", "w-2"} {
+		if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: key, Patch: map[string]any{"app_id": "alpha", "workspace_id": "ws-1"}, StateHash: "sha256:a"}); err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+	}
+	_ = writer.Close()
+
+	configPath := filepath.Join(root, "config.yaml")
+	configPayload := []byte("stateDir: " + root + "\nrestore:\n  appAllowlist:\n    alpha: \"true\"\n  readiness:\n    enabled: true\n    timeout: 1m\n  execution:\n    maxInFlight: 1\n")
+	if err := os.WriteFile(configPath, configPayload, 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	signals := make(chan os.Signal, 8)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	done := make(chan int, 1)
+	go func() {
+		done <- run([]string{"--config", configPath, "--no-daemon", "restore", "apply", "--at", "2026-02-15T10:00:00Z", "--yes"}, &out, &stderr)
+	}()
+	time.Sleep(300 * time.Millisecond)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(10 * time.Second)
+	var code int
+	for waiting := true; waiting; {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+			t.Fatalf("signal: %v", err)
+		}
+		select {
+		case code = <-done:
+			waiting = false
+		case <-ticker.C:
+		case <-deadline:
+			t.Fatal("restore did not stop after interrupt")
+		}
+	}
+
+	if code != 0 {
+		t.Fatalf("expected interrupted restore to report results, got %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "restore_item window_key=w-1 status=timeout") {
+		t.Fatalf("expected the waiting item to time out, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "restore_item window_key=w-2 status=skipped reason=\"restore cancelled\"") {
+		t.Fatalf("expected the pending item to be skipped, got %q", out.String())
+	}
+}
+
 func TestRestoreApplyPreviewUsesConfiguredRestoreSettings(t *testing.T) {
 	t.Parallel()
 
@@ -529,10 +910,10 @@ func TestRestoreApplyPreviewUsesConfiguredRestoreSettings(t *testing.T) {
 	}()
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-term", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "terminal": map[string]any{"cwd": "/tmp/project"}}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-term", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "terminal": map[string]any{"cwd": root}}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append terminal event: %v", err)
 	}
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-app", Patch: map[string]any{"app_id": "code", "workspace_id": "ws-1"}, StateHash: "sha256:b"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-app", Patch: map[string]any{"app_id": "code", "workspace_id": "ws-1"}, StateHash: "sha256:b"}); err != nil {
 		t.Fatalf("append app event: %v", err)
 	}
 
@@ -568,7 +949,7 @@ func TestRestoreApplyDryRunPrintsActionsWithoutExecuting(t *testing.T) {
 	defer func() { _ = writer.Close() }()
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-app", Patch: map[string]any{"app_id": "code", "workspace_id": "ws-1"}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-app", Patch: map[string]any{"app_id": "code", "workspace_id": "ws-1"}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append app event: %v", err)
 	}
 
@@ -592,6 +973,52 @@ func TestRestoreApplyDryRunPrintsActionsWithoutExecuting(t *testing.T) {
 	}
 }
 
+func TestRestoreApplyDryRunShowsRemappedItems(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	store, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+
+	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-app", Patch: map[string]any{"app_id": "org.gnome.Nautilus", "workspace_id": "ws-1"}, StateHash: "sha256:a"}); err != nil {
+		t.Fatalf("append app event: %v", err)
+	}
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-term", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "terminal": map[string]any{"cwd": "/home/desk/gone"}}, StateHash: "sha256:a"}); err != nil {
+		t.Fatalf("append terminal event: %v", err)
+	}
+
+	configPath := filepath.Join(root, "config.yaml")
+	configPayload := []byte("stateDir: " + root + "\nrestore:\n  appAllowlist:\n    thunar: thunar\n  terminal:\n    zellijAttachOrCreate: false\n" +
+		"  remap:\n    cwdPrefixes:\n      - from: /home/desk\n        to: " + filepath.Join(root, "laptop") + "\n    appIds:\n      org.gnome.Nautilus: thunar\n")
+	if err := os.WriteFile(configPath, configPayload, 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"--config", configPath, "restore", "apply", "--at", "2026-02-15T10:00:00Z", "--dry-run"}, &out, &stderr)
+	if code != 0 {
+		t.Fatalf("expected dry-run code 0, got %d stderr=%q", code, stderr.String())
+	}
+	for _, want := range []string{
+		"  command: thunar\n  remapped: app_id org.gnome.Nautilus -> thunar\n",
+		"  reason: remapped cwd " + filepath.Join(root, "laptop", "gone") + " does not exist, using parent " + root + "\n  command: kitty --directory '" + root + "'\n  remapped: cwd /home/desk/gone -> " + filepath.Join(root, "laptop", "gone") + "\n",
+		"Summary: would_restore=1 skipped=0 degraded=1",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in dry-run output, got %q", want, out.String())
+		}
+	}
+}
+
 func TestGlobalConfigAppliesCaptureDefaultsAndCLIOverrides(t *testing.T) {
 	root := t.TempDir()
 	fixturePath := filepath.Join(root, "niri.json")
@@ -656,6 +1083,54 @@ func TestGlobalConfigAppliesCaptureDefaultsAndCLIOverrides(t *testing.T) {
 	}
 }
 
+func TestGlobalLogFlagsSelectLevelAndFormat(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	fixturePath := filepath.Join(root, "niri.json")
+	err := os.WriteFile(fixturePath, []byte(`{
+		"workspaces": [{"id": "ws-1", "idx": 1}],
+		"windows": [{"id": 101, "app_id": "firefox", "workspace_id": "ws-1", "pid": 4242}]
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"--no-daemon", "--log-level", "debug", "--log-format=json", "capture", "once", "--state-dir", filepath.Join(root, "state"), "--fixture", fixturePath}, &out, &stderr)
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), `"level":"DEBUG","msg":"capture_written"`) {
+		t.Fatalf("expected json debug capture log, got %q", stderr.String())
+	}
+
+	stderr.Reset()
+	code = run([]string{"--no-daemon", "capture", "once", "--state-dir", filepath.Join(root, "state"), "--fixture", fixturePath}, &out, &stderr)
+	if code != 0 {
+		t.Fatalf("expected code 0, got %d stderr=%q", code, stderr.String())
+	}
+	if strings.Contains(stderr.String(), "capture_written") {
+		t.Fatalf("expected debug logs hidden at default level, got %q", stderr.String())
+	}
+}
+
+func TestGlobalLogFlagsRejectUnknownValues(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{{"--log-level", "trace", "history", "list"}, {"--log-format=xml", "history", "list"}, {"--log-level"}} {
+		var out bytes.Buffer
+		var stderr bytes.Buffer
+		if code := run(args, &out, &stderr); code != 2 {
+			t.Fatalf("expected usage error for %v, got %d", args, code)
+		}
+		if !strings.Contains(stderr.String(), "invalid global flags") {
+			t.Fatalf("expected invalid global flags message, got %q", stderr.String())
+		}
+	}
+}
+
 func TestGlobalConfigExplicitMissingFileErrors(t *testing.T) {
 	pathDir := t.TempDir()
 	for _, cmd := range []string{"kitty", "zellij", "niri"} {
@@ -714,7 +1189,7 @@ func TestDoctorPassExitCode(t *testing.T) {
 	if code != 0 {
 		t.Fatalf("expected code 0, got %d output=%q", code, out.String())
 	}
-	if !strings.Contains(out.String(), "doctor_summary total=8 passed=8 failed=0") {
+	if !strings.Contains(out.String(), "doctor_summary total=10 passed=10 failed=0 warned=0") {
 		t.Fatalf("unexpected doctor summary: %q", out.String())
 	}
 	if stderrWithoutWarning(stderr.String()) != "" {
@@ -766,7 +1241,7 @@ func TestRestoreApplyStateDirFlagOverridesConfig(t *testing.T) {
 	}()
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "code", "workspace_id": "ws-1"}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "code", "workspace_id": "ws-1"}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append event: %v", err)
 	}
 
@@ -805,10 +1280,10 @@ func TestHistoryListFromToBoundaryInclusive(t *testing.T) {
 
 	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
 	t1 := t0.Add(1 * time.Second)
-	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "a"}, StateHash: "sha256:a"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "a"}, StateHash: "sha256:a"}); err != nil {
 		t.Fatalf("append t0: %v", err)
 	}
-	if _, err := writer.Append(events.Event{V: 1, TS: t1, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "b"}, StateHash: "sha256:b"}); err != nil {
+	if _, err := writer.Append(events.Event{V: 1, TS: t1, Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "b"}, StateHash: "sha256:b"}); err != nil {
 		t.Fatalf("append t1: %v", err)
 	}
 
@@ -848,3 +1323,299 @@ func stderrWithoutWarning(s string) string {
 	}
 	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
+
+func TestCommandsUseRunningDaemonForStateDir(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	stateDir := filepath.Join(root, "state")
+	configPath := filepath.Join(root, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("capture:\n  niriCommand: \"false\"\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	resolvedConfig, err := config.Load(configPath, true)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	store, err := events.NewStore(stateDir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	if _, err := writer.Append(events.Event{V: 1, TS: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Host: "local", Profile: "default", EventType: "window_patch", WindowKey: "w-persisted", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1"}, StateHash: "sha256:a"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	_ = writer.Close()
+
+	capturer := &daemonTestCapturer{state: model.State{Windows: []model.Window{{Key: "w-daemon", AppID: "kitty", WorkspaceID: "ws-1"}}}}
+	captureKey := captureOptionsKey(daemonCaptureConfig(resolvedConfig, stateDir), resolvedConfig.Hooks, resolvedConfig.Metrics.Textfile)
+	startTestDaemon(t, stateDir, capturer, daemon.Config{Host: resolvedConfig.Host, Profile: resolvedConfig.Profile, CaptureKey: captureKey, RestoreKey: restoreOptionsKey(resolvedConfig)})
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	if code := run([]string{"--config", configPath, "capture", "once", "--state-dir", stateDir}, &out, &stderr); code != 0 {
+		t.Fatalf("expected capture via daemon, got %d stderr=%q", code, stderr.String())
+	}
+	if out.String() != "events_written=1 state_hash=sha256:daemon\n" || capturer.calls != 1 || !capturer.full {
+		t.Fatalf("unexpected capture output %q (calls %d full %t)", out.String(), capturer.calls, capturer.full)
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "capture", "once", "--state-dir", stateDir, "--host", "other-host"}, &out, &stderr); code != 1 {
+		t.Fatalf("expected in-process capture with the failing niri command, got %d out=%q", code, out.String())
+	}
+	if capturer.calls != 1 {
+		t.Fatalf("expected capture with different options to bypass the daemon, got %d calls", capturer.calls)
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "history", "inspect", "--state-dir", stateDir}, &out, &stderr); code != 0 {
+		t.Fatalf("expected inspect via daemon, got %d stderr=%q", code, stderr.String())
+	}
+	viaDaemon := out.String()
+	if !strings.Contains(viaDaemon, "w-daemon") || strings.Contains(viaDaemon, "w-persisted") {
+		t.Fatalf("expected the daemon's in-memory state, got %q", viaDaemon)
+	}
+
+	out.Reset()
+	if code := run([]string{"--config", configPath, "history", "inspect", "--state-dir", stateDir, "--at", "2026-02-15T10:00:00Z"}, &out, &stderr); code != 0 {
+		t.Fatalf("expected historical inspect via daemon, got %d stderr=%q", code, stderr.String())
+	}
+	historical := out.String()
+	if !strings.Contains(historical, "w-persisted") || strings.Contains(historical, "w-daemon") {
+		t.Fatalf("expected --at to replay the store, got %q", historical)
+	}
+
+	out.Reset()
+	if code := run([]string{"--config", configPath, "--no-daemon", "history", "inspect", "--state-dir", stateDir, "--at", "2026-02-15T10:00:00Z"}, &out, &stderr); code != 0 {
+		t.Fatalf("expected direct inspect, got %d stderr=%q", code, stderr.String())
+	}
+	if out.String() != historical {
+		t.Fatalf("expected daemon and direct historical inspect to match:\n%s\n%s", historical, out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--config", configPath, "daemon", "status", "--state-dir", stateDir}, &out, &stderr); code != 0 {
+		t.Fatalf("expected daemon status 0, got %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "daemon_status running=true") || !strings.Contains(out.String(), "captures=1 windows=1") {
+		t.Fatalf("unexpected daemon status: %q", out.String())
+	}
+}
+
+func TestRestoreApplyUsesDaemonOnlyWithMatchingRestoreConfig(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	configPath := filepath.Join(root, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("restore:\n  terminal:\n    command: kitty\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	resolvedConfig, err := config.Load(configPath, true)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		restoreKey string
+		code       int
+		want       string
+	}{
+		{name: "mismatch", restoreKey: "sha256:other", code: 0, want: "Restore Dry Run"},
+		{name: "match", restoreKey: restoreOptionsKey(resolvedConfig), code: 1, want: "restore planning is not configured"},
+	} {
+		stateDir := filepath.Join(root, tc.name)
+		if err := os.MkdirAll(stateDir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		startTestDaemon(t, stateDir, &daemonTestCapturer{}, daemon.Config{Host: resolvedConfig.Host, Profile: resolvedConfig.Profile, RestoreKey: tc.restoreKey})
+
+		var out bytes.Buffer
+		var stderr bytes.Buffer
+		code := run([]string{"--config", configPath, "restore", "apply", "--state-dir", stateDir, "--at", "2026-02-15T10:00:00Z", "--dry-run"}, &out, &stderr)
+		if code != tc.code || !strings.Contains(out.String()+stderr.String(), tc.want) {
+			t.Fatalf("%s: unexpected result %d out=%q stderr=%q", tc.name, code, out.String(), stderr.String())
+		}
+	}
+}
+
+func TestDaemonStatusWhenNotRunning(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	code := run([]string{"daemon", "status", "--state-dir", t.TempDir()}, &out, &stderr)
+	if code != 1 || out.String() != "daemon_status running=false\n" {
+		t.Fatalf("unexpected status %d %q", code, out.String())
+	}
+}
+
+func startTestDaemon(t *testing.T, stateDir string, capturer daemon.Capturer, daemonConfig daemon.Config) {
+	t.Helper()
+	server := daemon.NewServer()
+	daemonConfig.StateDir = stateDir
+	daemonConfig.Capturer = capturer
+	daemon.NewService(daemonConfig).Register(server)
+	listener, err := daemon.Listen(daemon.SocketPath(stateDir))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+type daemonTestCapturer struct {
+	state model.State
+	calls int
+	full  bool
+}
+
+func (c *daemonTestCapturer) Capture(context.Context) (capture.Result, error) {
+	c.calls++
+	return capture.Result{EventsWritten: 1, StateHash: "sha256:daemon"}, nil
+}
+
+func (c *daemonTestCapturer) CaptureOnce(ctx context.Context) (capture.Result, error) {
+	c.full = true
+	return c.Capture(ctx)
+}
+
+func (c *daemonTestCapturer) State() (model.State, bool) {
+	return c.state, c.calls > 0
+}
+
+func TestExportImportMovesTimelineToAnotherStateDir(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	fixturePath := filepath.Join(root, "niri.json")
+	err := os.WriteFile(fixturePath, []byte(`{
+		"workspaces": [{"id": "ws-1", "idx": 1, "name": "main"}],
+		"windows": [{"id": 101, "app_id": "kitty", "title": "shell", "workspace_id": "ws-1", "pid": 4242}]
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	configPath := filepath.Join(root, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("host: local\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	stateDir := filepath.Join(root, "state")
+	targetDir := filepath.Join(root, "target")
+	bundlePath := filepath.Join(root, "bundle.tar")
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	if code := run([]string{"--config", configPath, "capture", "once", "--state-dir", stateDir, "--fixture", fixturePath}, &out, &stderr); code != 0 {
+		t.Fatalf("capture once: code %d stderr=%q", code, stderr.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "export", "--state-dir", stateDir, "--out", bundlePath, "--compress", "none"}, &out, &stderr); code != 0 {
+		t.Fatalf("export: code %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "export events=1 snapshots=0 host=local profile=default") {
+		t.Fatalf("unexpected export output %q", out.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "import", "--state-dir", targetDir, bundlePath}, &out, &stderr); code != 2 {
+		t.Fatalf("expected import under the local identity to exit 2, got %d stderr=%q", code, stderr.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "import", "--state-dir", targetDir, "--host", "desk", bundlePath}, &out, &stderr); code != 0 {
+		t.Fatalf("import: code %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "import events=1 snapshots=0 snapshots_skipped=0 host=desk profile=default") {
+		t.Fatalf("unexpected import output %q", out.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", configPath, "--no-daemon", "history", "inspect", "--state-dir", targetDir, "--host", "desk"}, &out, &stderr); code != 0 {
+		t.Fatalf("history inspect: code %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), `"app_id": "kitty"`) {
+		t.Fatalf("expected imported window in history, got %q", out.String())
+	}
+}
+
+func TestRestoreApplyAfterImportReplaysOnlyLocalTimeline(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	localFixture := filepath.Join(root, "local.json")
+	remoteFixture := filepath.Join(root, "remote.json")
+	if err := os.WriteFile(localFixture, []byte(`{
+		"workspaces": [{"id": "ws-1", "idx": 1, "name": "main"}],
+		"windows": [{"id": 101, "app_id": "kitty", "title": "local-shell", "workspace_id": "ws-1", "pid": 4242}]
+	}`), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	if err := os.WriteFile(remoteFixture, []byte(`{
+		"workspaces": [{"id": "ws-1", "idx": 1, "name": "main"}],
+		"windows": [{"id": 202, "app_id": "kitty", "title": "remote-shell", "workspace_id": "ws-1", "pid": 4343}]
+	}`), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	localConfig := filepath.Join(root, "local.yaml")
+	if err := os.WriteFile(localConfig, []byte("host: laptop\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	remoteConfig := filepath.Join(root, "remote.yaml")
+	if err := os.WriteFile(remoteConfig, []byte("host: desk\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	stateDir := filepath.Join(root, "state")
+	remoteDir := filepath.Join(root, "remote")
+	bundlePath := filepath.Join(root, "bundle.tar")
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	if code := run([]string{"--config", localConfig, "capture", "once", "--state-dir", stateDir, "--fixture", localFixture}, &out, &stderr); code != 0 {
+		t.Fatalf("local capture: code %d stderr=%q", code, stderr.String())
+	}
+	if code := run([]string{"--config", remoteConfig, "capture", "once", "--state-dir", remoteDir, "--fixture", remoteFixture}, &out, &stderr); code != 0 {
+		t.Fatalf("remote capture: code %d stderr=%q", code, stderr.String())
+	}
+	if code := run([]string{"--config", remoteConfig, "export", "--state-dir", remoteDir, "--out", bundlePath, "--compress", "none"}, &out, &stderr); code != 0 {
+		t.Fatalf("export: code %d stderr=%q", code, stderr.String())
+	}
+	if code := run([]string{"--config", localConfig, "import", "--state-dir", stateDir, bundlePath}, &out, &stderr); code != 0 {
+		t.Fatalf("import: code %d stderr=%q", code, stderr.String())
+	}
+
+	out.Reset()
+	stderr.Reset()
+	if code := run([]string{"--config", localConfig, "--no-daemon", "restore", "apply", "--state-dir", stateDir, "--at", time.Now().UTC().Add(time.Minute).Format(time.RFC3339), "--dry-run"}, &out, &stderr); code != 0 {
+		t.Fatalf("restore dry-run: code %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "w:kitty:101") || strings.Contains(out.String(), "w:kitty:202") {
+		t.Fatalf("expected only the local window to be planned, got %q", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"--config", localConfig, "--no-daemon", "history", "inspect", "--state-dir", stateDir}, &out, &stderr); code != 0 {
+		t.Fatalf("history inspect: code %d stderr=%q", code, stderr.String())
+	}
+	if !strings.Contains(out.String(), "local-shell") || strings.Contains(out.String(), "remote-shell") {
+		t.Fatalf("expected local state, got %q", out.String())
+	}
+}
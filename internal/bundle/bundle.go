@@ -0,0 +1,567 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/replay"
+	"github.com/jmo/terminal-redeemer/internal/seal"
+	"github.com/jmo/terminal-redeemer/internal/snapshots"
+)
+
+const (
+	Kind         = "terminal-redeemer-bundle"
+	ManifestName = "manifest.json"
+	EventsName   = "events.jsonl"
+	SnapshotsDir = "snapshots"
+)
+
+var (
+	ErrEmpty         = errors.New("no events in export range")
+	ErrHashMismatch  = errors.New("bundle hash mismatch")
+	ErrLocalIdentity = errors.New("import host/profile must differ from the local host/profile")
+	ErrOverlap       = errors.New("bundle overlaps existing history")
+)
+
+type Manifest struct {
+	V             int               `json:"v"`
+	Kind          string            `json:"kind"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Host          string            `json:"host"`
+	Profile       string            `json:"profile"`
+	From          time.Time         `json:"from"`
+	To            time.Time         `json:"to"`
+	Events        int               `json:"events"`
+	Snapshots     int               `json:"snapshots"`
+	BaseStateHash string            `json:"base_state_hash"`
+	Files         map[string]string `json:"files"`
+}
+
+type Bundle struct {
+	Manifest  Manifest
+	Events    []events.Event
+	Snapshots []snapshots.Snapshot
+	ends      []int64
+}
+
+type ExportOptions struct {
+	From    *time.Time
+	To      *time.Time
+	Host    string
+	Profile string
+	Now     time.Time
+}
+
+type ImportOptions struct {
+	Host         string
+	Profile      string
+	LocalHost    string
+	LocalProfile string
+}
+
+type ImportSummary struct {
+	Host              string
+	Profile           string
+	EventsImported    int
+	SnapshotsImported int
+	SnapshotsSkipped  int
+}
+
+type sourceLine struct {
+	end   int64
+	event *events.Event
+}
+
+type offsetPair struct {
+	old int64
+	new int64
+}
+
+func Export(root string, w io.Writer, opts ExportOptions) (Manifest, error) {
+	codec, err := seal.Load(root)
+	if err != nil {
+		return Manifest{}, err
+	}
+	lines, err := readSource(filepath.Join(root, "events.jsonl"), codec)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	matches := func(host string, profile string) bool {
+		return (opts.Host == "" || host == opts.Host) && (opts.Profile == "" || profile == opts.Profile)
+	}
+	var first *events.Event
+	for _, line := range lines {
+		if line.event != nil && matches(line.event.Host, line.event.Profile) {
+			first = line.event
+			break
+		}
+	}
+	if first == nil {
+		return Manifest{}, ErrEmpty
+	}
+
+	from := first.TS
+	if opts.From != nil {
+		from = *opts.From
+	}
+	if opts.To != nil && opts.To.Before(from) {
+		return Manifest{}, fmt.Errorf("--to %s is before --from %s", opts.To.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+	host, profile := first.Host, first.Profile
+	if opts.Host != "" {
+		host = opts.Host
+	}
+	if opts.Profile != "" {
+		profile = opts.Profile
+	}
+
+	engine, err := replay.NewEngineWithCodec(root, codec)
+	if err != nil {
+		return Manifest{}, err
+	}
+	base, err := engine.Scoped(opts.Host, opts.Profile).At(from)
+	if err != nil {
+		return Manifest{}, err
+	}
+	baseHash, err := base.Hash()
+	if err != nil {
+		return Manifest{}, fmt.Errorf("hash base state: %w", err)
+	}
+	baseState := map[string]any{}
+	payload, err := json.Marshal(base)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("marshal base state: %w", err)
+	}
+	if err := json.Unmarshal(payload, &baseState); err != nil {
+		return Manifest{}, fmt.Errorf("decode base state: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := writeLine(&out, events.Event{
+		V:         1,
+		TS:        from,
+		Host:      host,
+		Profile:   profile,
+		EventType: "state_full",
+		State:     baseState,
+		Source:    "export",
+		StateHash: baseHash,
+	}); err != nil {
+		return Manifest{}, err
+	}
+
+	to := from
+	count := 1
+	offsets := make([]offsetPair, 0, len(lines))
+	for _, line := range lines {
+		event := line.event
+		if event != nil && matches(event.Host, event.Profile) && event.TS.After(from) && (opts.To == nil || !event.TS.After(*opts.To)) {
+			if err := writeLine(&out, *event); err != nil {
+				return Manifest{}, err
+			}
+			count++
+			if event.TS.After(to) {
+				to = event.TS
+			}
+		}
+		offsets = append(offsets, offsetPair{old: line.end, new: int64(out.Len())})
+	}
+	if opts.To != nil {
+		to = *opts.To
+	}
+
+	included, err := exportSnapshots(root, codec, from, to, host, profile, matches, offsets)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	files := map[string][]byte{EventsName: out.Bytes()}
+	names := []string{EventsName}
+	for _, snapshot := range included {
+		payload, err := json.Marshal(snapshot)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("marshal snapshot: %w", err)
+		}
+		name := path.Join(SnapshotsDir, fmt.Sprintf("%d.json", snapshot.CreatedAt.Unix()))
+		files[name] = payload
+		names = append(names, name)
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+	manifest := Manifest{
+		V:             1,
+		Kind:          Kind,
+		CreatedAt:     now,
+		Host:          host,
+		Profile:       profile,
+		From:          from,
+		To:            to,
+		Events:        count,
+		Snapshots:     len(included),
+		BaseStateHash: baseHash,
+		Files:         make(map[string]string, len(files)),
+	}
+	for name, payload := range files {
+		manifest.Files[name] = digest(payload)
+	}
+	manifestPayload, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	if err := writeEntry(tw, ManifestName, manifestPayload, now); err != nil {
+		return Manifest{}, err
+	}
+	for _, name := range names {
+		if err := writeEntry(tw, name, files[name], now); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("close archive: %w", err)
+	}
+	return manifest, nil
+}
+
+func Read(r io.Reader) (*Bundle, error) {
+	files := map[string][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		payload, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", header.Name, err)
+		}
+		files[path.Clean(header.Name)] = payload
+	}
+
+	raw, ok := files[ManifestName]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no %s", ManifestName)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if manifest.V != 1 || manifest.Kind != Kind {
+		return nil, fmt.Errorf("unsupported bundle: kind=%q v=%d", manifest.Kind, manifest.V)
+	}
+	if strings.TrimSpace(manifest.Host) == "" || strings.TrimSpace(manifest.Profile) == "" {
+		return nil, errors.New("manifest host and profile are required")
+	}
+	for name, want := range manifest.Files {
+		payload, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("bundle is missing %s", name)
+		}
+		if got := digest(payload); got != want {
+			return nil, fmt.Errorf("%w: %s digest %s, manifest has %s", ErrHashMismatch, name, got, want)
+		}
+	}
+
+	b := &Bundle{Manifest: manifest}
+	if err := b.decodeEvents(files[EventsName]); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(manifest.Files))
+	for name := range manifest.Files {
+		if path.Dir(name) == SnapshotsDir {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var snapshot snapshots.Snapshot
+		if err := json.Unmarshal(files[name], &snapshot); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", name, err)
+		}
+		if err := snapshot.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := verifyState(snapshot.State, snapshot.StateHash); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		b.Snapshots = append(b.Snapshots, snapshot)
+	}
+	if len(b.Events) != manifest.Events || len(b.Snapshots) != manifest.Snapshots {
+		return nil, fmt.Errorf("bundle holds %d events and %d snapshots, manifest lists %d and %d", len(b.Events), len(b.Snapshots), manifest.Events, manifest.Snapshots)
+	}
+	return b, nil
+}
+
+func (b *Bundle) decodeEvents(payload []byte) error {
+	var offset int64
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(payload)+1)
+	for scanner.Scan() {
+		offset += int64(len(scanner.Bytes())) + 1
+		var event events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("decode event %d: %w", len(b.Events)+1, err)
+		}
+		if err := event.Validate(); err != nil {
+			return fmt.Errorf("event %d: %w", len(b.Events)+1, err)
+		}
+		if event.EventType == "state_full" {
+			if err := verifyState(event.State, event.StateHash); err != nil {
+				return fmt.Errorf("event %d: %w", len(b.Events)+1, err)
+			}
+		}
+		b.Events = append(b.Events, event)
+		b.ends = append(b.ends, offset)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan events: %w", err)
+	}
+	if len(b.Events) == 0 || b.Events[0].EventType != "state_full" {
+		return errors.New("bundle events must start with a state_full event")
+	}
+	if b.Events[0].StateHash != b.Manifest.BaseStateHash {
+		return fmt.Errorf("%w: base state %s, manifest has %s", ErrHashMismatch, b.Events[0].StateHash, b.Manifest.BaseStateHash)
+	}
+	return nil
+}
+
+func Import(root string, b *Bundle, opts ImportOptions) (ImportSummary, error) {
+	host, profile := b.Manifest.Host, b.Manifest.Profile
+	if opts.Host != "" {
+		host = opts.Host
+	}
+	if opts.Profile != "" {
+		profile = opts.Profile
+	}
+	if host == opts.LocalHost && profile == opts.LocalProfile {
+		return ImportSummary{}, fmt.Errorf("%w: %s/%s", ErrLocalIdentity, host, profile)
+	}
+
+	codec, err := seal.Load(root)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+	store, err := events.NewStoreWithCodec(root, codec)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		return ImportSummary{}, err
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+
+	existing, err := replay.ListEventsWithCodec(root, codec, nil, nil)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+	var last time.Time
+	for _, event := range existing {
+		if event.Host != host || event.Profile != profile {
+			continue
+		}
+		if event.TS.After(last) {
+			last = event.TS
+		}
+	}
+	if !last.IsZero() && b.Events[0].TS.Before(last) {
+		return ImportSummary{}, fmt.Errorf("%w: bundle starts at %s, history for %s/%s ends at %s", ErrOverlap, b.Events[0].TS.Format(time.RFC3339), host, profile, last.Format(time.RFC3339))
+	}
+
+	info, err := os.Stat(filepath.Join(root, "events.jsonl"))
+	if err != nil {
+		return ImportSummary{}, fmt.Errorf("stat events file: %w", err)
+	}
+	summary := ImportSummary{Host: host, Profile: profile}
+	offsets := make([]offsetPair, 0, len(b.Events)+1)
+	offsets = append(offsets, offsetPair{old: 0, new: info.Size()})
+	for i, event := range b.Events {
+		event.Host = host
+		event.Profile = profile
+		offset, err := writer.Append(event)
+		if err != nil {
+			return summary, err
+		}
+		offsets = append(offsets, offsetPair{old: b.ends[i], new: offset})
+		summary.EventsImported++
+	}
+
+	snapshotStore, err := snapshots.NewStoreWithCodec(root, codec)
+	if err != nil {
+		return summary, err
+	}
+	for _, snapshot := range b.Snapshots {
+		target := filepath.Join(root, "snapshots", fmt.Sprintf("%d.json", snapshot.CreatedAt.Unix()))
+		if _, err := os.Stat(target); err == nil {
+			summary.SnapshotsSkipped++
+			continue
+		}
+		snapshot.Host = host
+		snapshot.Profile = profile
+		snapshot.LastEventOffset = remapOffset(offsets, snapshot.LastEventOffset)
+		if _, err := snapshotStore.Write(snapshot); err != nil {
+			return summary, err
+		}
+		summary.SnapshotsImported++
+	}
+	return summary, nil
+}
+
+func readSource(path string, codec *seal.Codec) ([]sourceLine, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open events file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var (
+		lines  []sourceLine
+		offset int64
+	)
+	scanner := seal.NewScanner(f)
+	for scanner.Scan() {
+		offset += int64(len(scanner.Bytes())) + 1
+		line := sourceLine{end: offset}
+		plain, err := codec.Open(scanner.Bytes())
+		if errors.Is(err, seal.ErrKeyRequired) {
+			return nil, err
+		}
+		if err == nil {
+			var event events.Event
+			if json.Unmarshal(plain, &event) == nil && event.Validate() == nil {
+				line.event = &event
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan events: %w", err)
+	}
+	return lines, nil
+}
+
+func exportSnapshots(root string, codec *seal.Codec, from time.Time, to time.Time, host string, profile string, matches func(string, string) bool, offsets []offsetPair) ([]snapshots.Snapshot, error) {
+	dir := filepath.Join(root, "snapshots")
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshots dir: %w", err)
+	}
+	store, err := snapshots.NewStoreWithCodec(root, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []snapshots.Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		snapshot, err := store.Read(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, seal.ErrKeyRequired) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		if !matches(snapshot.Host, snapshot.Profile) {
+			continue
+		}
+		if !snapshot.CreatedAt.After(from) || snapshot.CreatedAt.After(to) {
+			continue
+		}
+		snapshot.Host = host
+		snapshot.Profile = profile
+		snapshot.LastEventOffset = remapOffset(offsets, snapshot.LastEventOffset)
+		out = append(out, snapshot)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func verifyState(raw map[string]any, want string) error {
+	got, err := replay.DecodeState(raw).Hash()
+	if err != nil {
+		return fmt.Errorf("hash state: %w", err)
+	}
+	if got != want {
+		return fmt.Errorf("%w: state hashes to %s, recorded %s", ErrHashMismatch, got, want)
+	}
+	return nil
+}
+
+func writeLine(out *bytes.Buffer, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	out.Write(payload)
+	out.WriteByte('\n')
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, name string, payload []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(payload)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("write %s header: %w", name, err)
+	}
+	if _, err := tw.Write(payload); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+func digest(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func remapOffset(offsets []offsetPair, offset int64) int64 {
+	if len(offsets) == 0 {
+		return offset
+	}
+	i := sort.Search(len(offsets), func(i int) bool { return offsets[i].old > offset })
+	if i == 0 {
+		return 0
+	}
+	return offsets[i-1].new
+}
@@ -0,0 +1,386 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/replay"
+	"github.com/jmo/terminal-redeemer/internal/snapshots"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	source, base := writeTimeline(t)
+	from, to := base.Add(90*time.Second), base.Add(4*time.Minute)
+
+	var archive bytes.Buffer
+	manifest, err := Export(source, &archive, ExportOptions{From: &from, To: &to, Now: base.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if manifest.Host != "desk" || manifest.Profile != "default" || manifest.Events != 3 || manifest.Snapshots != 1 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+
+	b, err := Read(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("read bundle: %v", err)
+	}
+	if b.Events[0].EventType != "state_full" || !b.Events[0].TS.Equal(from) || b.Events[0].Source != "export" {
+		t.Fatalf("expected a base state_full at --from, got %+v", b.Events[0])
+	}
+
+	target := t.TempDir()
+	summary, err := Import(target, b, ImportOptions{Host: "laptop-import", LocalHost: "laptop", LocalProfile: "default"})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if summary.Host != "laptop-import" || summary.Profile != "default" || summary.EventsImported != 3 || summary.SnapshotsImported != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	imported, err := replay.ListEvents(target, nil, nil)
+	if err != nil {
+		t.Fatalf("list imported events: %v", err)
+	}
+	for _, event := range imported {
+		if event.Host != "laptop-import" || event.Profile != "default" {
+			t.Fatalf("expected imported events relabelled, got %s/%s", event.Host, event.Profile)
+		}
+	}
+
+	sourceEngine, err := replay.NewEngine(source)
+	if err != nil {
+		t.Fatalf("new source engine: %v", err)
+	}
+	targetEngine, err := replay.NewEngine(target)
+	if err != nil {
+		t.Fatalf("new target engine: %v", err)
+	}
+	for _, at := range []time.Time{from, base.Add(2 * time.Minute), base.Add(3 * time.Minute), to} {
+		want, err := sourceEngine.At(at)
+		if err != nil {
+			t.Fatalf("source replay: %v", err)
+		}
+		got, err := targetEngine.At(at)
+		if err != nil {
+			t.Fatalf("target replay: %v", err)
+		}
+		if mustHash(t, got) != mustHash(t, want) {
+			t.Fatalf("state at %s differs after import:\nwant %#v\ngot  %#v", at, want.Windows, got.Windows)
+		}
+	}
+
+	snapStore, err := snapshots.NewStore(target)
+	if err != nil {
+		t.Fatalf("new snapshot store: %v", err)
+	}
+	snapshot, _, err := snapStore.LoadNearest(to)
+	if err != nil {
+		t.Fatalf("load imported snapshot: %v", err)
+	}
+	if snapshot.Host != "laptop-import" || snapshot.LastEventOffset <= 0 {
+		t.Fatalf("unexpected imported snapshot %+v", snapshot)
+	}
+}
+
+func TestReadRejectsTamperedState(t *testing.T) {
+	t.Parallel()
+
+	source, _ := writeTimeline(t)
+	var archive bytes.Buffer
+	if _, err := Export(source, &archive, ExportOptions{}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	tampered := rewriteEntries(t, archive.Bytes(), func(name string, payload []byte) []byte {
+		if name != EventsName {
+			return payload
+		}
+		return bytes.Replace(payload, []byte(`"title":"build"`), []byte(`"title":"evil"`), 1)
+	})
+	if _, err := Read(bytes.NewReader(tampered)); !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("expected ErrHashMismatch, got %v", err)
+	}
+}
+
+func TestImportRefusesLocalIdentityAndOverlap(t *testing.T) {
+	t.Parallel()
+
+	source, base := writeTimeline(t)
+	var archive bytes.Buffer
+	if _, err := Export(source, &archive, ExportOptions{}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	b, err := Read(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("read bundle: %v", err)
+	}
+
+	if _, err := Import(t.TempDir(), b, ImportOptions{LocalHost: "desk", LocalProfile: "default"}); !errors.Is(err, ErrLocalIdentity) {
+		t.Fatalf("expected ErrLocalIdentity, got %v", err)
+	}
+	target := t.TempDir()
+	if _, err := Import(target, b, ImportOptions{Host: "desk-copy"}); err != nil {
+		t.Fatalf("first import: %v", err)
+	}
+	if _, err := Import(target, b, ImportOptions{Host: "desk-copy"}); !errors.Is(err, ErrOverlap) {
+		t.Fatalf("expected ErrOverlap, got %v", err)
+	}
+
+	later := base.Add(3 * time.Minute)
+	archive.Reset()
+	if _, err := Export(source, &archive, ExportOptions{From: &later}); err != nil {
+		t.Fatalf("export tail: %v", err)
+	}
+	tail, err := Read(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("read tail bundle: %v", err)
+	}
+	if _, err := Import(target, tail, ImportOptions{Host: "desk-copy"}); err != nil {
+		t.Fatalf("expected a later bundle to extend the imported timeline: %v", err)
+	}
+}
+
+func TestImportMergesIntoSharedStateDir(t *testing.T) {
+	t.Parallel()
+
+	source, base := writeTimeline(t)
+	var archive bytes.Buffer
+	if _, err := Export(source, &archive, ExportOptions{}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	b, err := Read(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("read bundle: %v", err)
+	}
+	summary, err := Import(source, b, ImportOptions{Host: "laptop", LocalHost: "desk", LocalProfile: "default"})
+	if err != nil {
+		t.Fatalf("import into shared dir: %v", err)
+	}
+	if summary.EventsImported != len(b.Events) || summary.SnapshotsSkipped != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	store, err := events.NewStore(source)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	_, err = writer.Append(events.Event{V: 1, TS: base.Add(90 * time.Second), Host: "desk", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "desk only"}, StateHash: "sha256:desk"})
+	_ = writer.Close()
+	if err != nil {
+		t.Fatalf("append desk event: %v", err)
+	}
+
+	engine, err := replay.NewEngine(source)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	at := base.Add(2 * time.Minute)
+	desk, err := engine.Scoped("desk", "default").At(at)
+	if err != nil {
+		t.Fatalf("replay desk: %v", err)
+	}
+	laptop, err := engine.Scoped("laptop", "default").At(at)
+	if err != nil {
+		t.Fatalf("replay laptop: %v", err)
+	}
+	if len(desk.Windows) != 2 || desk.Windows[0].Title != "desk only" {
+		t.Fatalf("expected desk replay to include its own later event, got %#v", desk.Windows)
+	}
+	if len(laptop.Windows) != 2 || laptop.Windows[0].Title != "tests" {
+		t.Fatalf("expected laptop replay to ignore desk events, got %#v", laptop.Windows)
+	}
+
+	archive.Reset()
+	manifest, err := Export(source, &archive, ExportOptions{Host: "laptop", From: &at})
+	if err != nil {
+		t.Fatalf("export laptop: %v", err)
+	}
+	if manifest.BaseStateHash != mustHash(t, laptop) {
+		t.Fatalf("expected base state filtered to laptop, got %s want %s", manifest.BaseStateHash, mustHash(t, laptop))
+	}
+}
+
+func TestOpenHandlesPlainAndZstdArchives(t *testing.T) {
+	t.Parallel()
+
+	source, _ := writeTimeline(t)
+	var archive bytes.Buffer
+	manifest, err := Export(source, &archive, ExportOptions{})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	b, err := Open(bytes.NewReader(archive.Bytes()), nil)
+	if err != nil {
+		t.Fatalf("open plain archive: %v", err)
+	}
+	if b.Manifest.BaseStateHash != manifest.BaseStateHash {
+		t.Fatalf("unexpected manifest %+v", b.Manifest)
+	}
+
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	var compressed bytes.Buffer
+	w, err := ZstdCommand{}.Compress(&compressed)
+	if err != nil {
+		t.Fatalf("start compressor: %v", err)
+	}
+	if _, err := w.Write(archive.Bytes()); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close compressor: %v", err)
+	}
+	if !bytes.HasPrefix(compressed.Bytes(), zstdMagic) {
+		t.Fatalf("expected zstd frame")
+	}
+	b, err = Open(bytes.NewReader(compressed.Bytes()), ZstdCommand{})
+	if err != nil {
+		t.Fatalf("open zstd archive: %v", err)
+	}
+	if len(b.Events) != manifest.Events {
+		t.Fatalf("expected %d events, got %d", manifest.Events, len(b.Events))
+	}
+}
+
+func writeTimeline(t *testing.T) (string, time.Time) {
+	t.Helper()
+
+	root := t.TempDir()
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	writer, err := eventStore.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	workspaces := []model.Workspace{{ID: "ws-1", Index: 1}}
+	editor := model.Window{Key: "w-1", AppID: "kitty", WorkspaceID: "ws-1", Title: "build", Terminal: &model.Terminal{CWD: "/home/me/src"}}
+	shell := model.Window{Key: "w-2", AppID: "kitty", WorkspaceID: "ws-1", Title: "shell"}
+	initial := model.State{Workspaces: workspaces, Windows: []model.Window{editor}}
+	opened := model.State{Workspaces: workspaces, Windows: []model.Window{editor, shell}}
+	editor.Title = "tests"
+	renamed := model.State{Workspaces: workspaces, Windows: []model.Window{editor, shell}}
+	closed := model.State{Workspaces: workspaces, Windows: []model.Window{editor}}
+
+	appendEvent := func(event events.Event) int64 {
+		t.Helper()
+		event.V, event.Host, event.Profile = 1, "desk", "default"
+		offset, err := writer.Append(event)
+		if err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+		return offset
+	}
+	appendEvent(events.Event{TS: base, EventType: "state_full", State: stateAsMap(t, initial), StateHash: mustHash(t, initial)})
+	appendEvent(events.Event{TS: base.Add(time.Minute), EventType: "window_patch", WindowKey: "w-2", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "shell", "terminal": nil}, StateHash: mustHash(t, opened)})
+	offset := appendEvent(events.Event{TS: base.Add(2 * time.Minute), EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "tests"}, StateHash: mustHash(t, renamed)})
+	appendEvent(events.Event{TS: base.Add(3 * time.Minute), EventType: "window_patch", WindowKey: "w-2", Patch: map[string]any{"deleted": true}, StateHash: mustHash(t, closed)})
+
+	snapStore, err := snapshots.NewStore(root)
+	if err != nil {
+		t.Fatalf("new snapshot store: %v", err)
+	}
+	if _, err := snapStore.Write(snapshots.Snapshot{V: 1, CreatedAt: base.Add(2 * time.Minute), Host: "desk", Profile: "default", LastEventOffset: offset, StateHash: mustHash(t, renamed), State: stateAsMap(t, renamed)}); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	return root, base
+}
+
+func rewriteEntries(t *testing.T, archive []byte, rewrite func(name string, payload []byte) []byte) []byte {
+	t.Helper()
+
+	files := map[string][]byte{}
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read archive: %v", err)
+		}
+		payload, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("read entry: %v", err)
+		}
+		files[header.Name] = rewrite(header.Name, payload)
+		names = append(names, header.Name)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(files[ManifestName], &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	for name := range manifest.Files {
+		manifest.Files[name] = digest(files[name])
+	}
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("encode manifest: %v", err)
+	}
+	files[ManifestName] = payload
+
+	var out bytes.Buffer
+	tw := tar.NewWriter(&out)
+	for _, name := range names {
+		if err := writeEntry(tw, name, files[name], time.Now()); err != nil {
+			t.Fatalf("write entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close archive: %v", err)
+	}
+	if strings.Contains(out.String(), `"title":"build"`) {
+		t.Fatalf("expected tampered archive")
+	}
+	return out.Bytes()
+}
+
+func mustHash(t *testing.T, state model.State) string {
+	t.Helper()
+
+	hash, err := state.Hash()
+	if err != nil {
+		t.Fatalf("hash state: %v", err)
+	}
+	return hash
+}
+
+func stateAsMap(t *testing.T, state model.State) map[string]any {
+	t.Helper()
+
+	payload, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("marshal state: %v", err)
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(payload, &out); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	return out
+}
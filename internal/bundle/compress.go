@@ -0,0 +1,99 @@
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+type Compressor interface {
+	Compress(w io.Writer) (io.WriteCloser, error)
+	Decompress(r io.Reader) (io.ReadCloser, error)
+}
+
+type ZstdCommand struct {
+	Binary string
+}
+
+func (z ZstdCommand) binary() string {
+	if z.Binary == "" {
+		return "zstd"
+	}
+	return z.Binary
+}
+
+func (z ZstdCommand) Compress(w io.Writer) (io.WriteCloser, error) {
+	cmd := exec.Command(z.binary(), "-q", "-c")
+	cmd.Stdout = w
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("zstd stdin: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start zstd: %w", err)
+	}
+	return &commandWriter{WriteCloser: stdin, cmd: cmd}, nil
+}
+
+func (z ZstdCommand) Decompress(r io.Reader) (io.ReadCloser, error) {
+	cmd := exec.Command(z.binary(), "-q", "-d", "-c")
+	cmd.Stdin = r
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("zstd stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start zstd: %w", err)
+	}
+	return &commandReader{Reader: stdout, cmd: cmd}, nil
+}
+
+type commandWriter struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func (w *commandWriter) Close() error {
+	errClose := w.WriteCloser.Close()
+	if err := w.cmd.Wait(); err != nil {
+		return fmt.Errorf("zstd: %w", err)
+	}
+	return errClose
+}
+
+type commandReader struct {
+	io.Reader
+	cmd *exec.Cmd
+}
+
+func (r *commandReader) Close() error {
+	_, _ = io.Copy(io.Discard, r.Reader)
+	if err := r.cmd.Wait(); err != nil {
+		return fmt.Errorf("zstd: %w", err)
+	}
+	return nil
+}
+
+func Open(r io.Reader, compressor Compressor) (*Bundle, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(len(zstdMagic))
+	if !bytes.Equal(head, zstdMagic) {
+		return Read(buffered)
+	}
+	if compressor == nil {
+		return nil, fmt.Errorf("bundle is zstd-compressed and no decompressor is configured")
+	}
+	decompressed, err := compressor.Decompress(buffered)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Read(decompressed)
+	if errClose := decompressed.Close(); err == nil && errClose != nil {
+		return nil, errClose
+	}
+	return b, err
+}
@@ -3,12 +3,12 @@ package capture
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"io"
+	"log/slog"
 	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/diff"
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/hooks"
 	"github.com/jmo/terminal-redeemer/internal/model"
 	"github.com/jmo/terminal-redeemer/internal/snapshots"
 )
@@ -25,6 +25,14 @@ type SnapshotStore interface {
 	Write(snapshot snapshots.Snapshot) (string, error)
 }
 
+type Metrics interface {
+	ObserveCapture(result Result, err error)
+}
+
+type Hooks interface {
+	Fire(ctx context.Context, event hooks.Event) error
+}
+
 type Config struct {
 	Collector     Collector
 	DiffEngine    *diff.Engine
@@ -35,7 +43,10 @@ type Config struct {
 	Profile       string
 	Source        string
 	Now           func() time.Time
-	Logger        io.Writer
+	Logger        *slog.Logger
+	Metrics       Metrics
+	Hooks         Hooks
+	HookBudget    time.Duration
 }
 
 type Runner struct {
@@ -48,7 +59,10 @@ type Runner struct {
 	profile       string
 	source        string
 	now           func() time.Time
-	logger        io.Writer
+	logger        *slog.Logger
+	metrics       Metrics
+	hooks         Hooks
+	hookBudget    time.Duration
 
 	lastState  model.State
 	hasLast    bool
@@ -68,7 +82,11 @@ func NewRunner(config Config) *Runner {
 	}
 	logger := config.Logger
 	if logger == nil {
-		logger = io.Discard
+		logger = slog.New(slog.DiscardHandler)
+	}
+	hookBudget := config.HookBudget
+	if hookBudget <= 0 {
+		hookBudget = hooks.DefaultTimeout
 	}
 
 	return &Runner{
@@ -82,11 +100,42 @@ func NewRunner(config Config) *Runner {
 		source:        config.Source,
 		now:           now,
 		logger:        logger,
+		metrics:       config.Metrics,
+		hooks:         config.Hooks,
+		hookBudget:    hookBudget,
 	}
 }
 
 func (r *Runner) CaptureOnce(ctx context.Context) (Result, error) {
-	return r.captureStateFull(ctx)
+	previous, hadPrevious := r.lastState, r.hasLast
+	result, err := r.observe(r.captureStateFull(ctx))
+	if err == nil {
+		r.fireHooks(ctx, previous, hadPrevious, result)
+	}
+	return result, err
+}
+
+func (r *Runner) Capture(ctx context.Context) (Result, error) {
+	previous, hadPrevious := r.lastState, r.hasLast
+	result, err := r.observe(r.captureDiff(ctx))
+	if err == nil {
+		r.fireHooks(ctx, previous, hadPrevious, result)
+	}
+	return result, err
+}
+
+func (r *Runner) observe(result Result, err error) (Result, error) {
+	if err == nil {
+		r.logger.Debug("capture_written", "source", r.source, "events_written", result.EventsWritten, "state_hash", result.StateHash, "snapshot", result.SnapshotPath)
+	}
+	if r.metrics != nil {
+		r.metrics.ObserveCapture(result, err)
+	}
+	return result, err
+}
+
+func (r *Runner) State() (model.State, bool) {
+	return r.lastState, r.hasLast
 }
 
 func (r *Runner) captureStateFull(ctx context.Context) (Result, error) {
@@ -226,6 +275,62 @@ func (r *Runner) captureDiff(ctx context.Context) (Result, error) {
 	return result, nil
 }
 
+func (r *Runner) fireHooks(ctx context.Context, previous model.State, hadPrevious bool, result Result) {
+	if r.hooks == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.hookBudget)
+	defer cancel()
+	previousHash := ""
+	if hadPrevious {
+		previousHash, _ = previous.Hash()
+	}
+	if result.EventsWritten > 0 && result.StateHash != previousHash {
+		_ = r.hooks.Fire(ctx, hooks.Event{Name: hooks.CaptureStateChanged, Data: hooks.StateChanged{
+			Host:              r.host,
+			Profile:           r.profile,
+			Source:            r.source,
+			StateHash:         result.StateHash,
+			PreviousStateHash: previousHash,
+			EventsWritten:     result.EventsWritten,
+			Windows:           len(r.lastState.Windows),
+			Workspaces:        len(r.lastState.Workspaces),
+		}})
+	}
+	if result.SnapshotPath != "" {
+		_ = r.hooks.Fire(ctx, hooks.Event{Name: hooks.CaptureSnapshotWritten, Data: hooks.SnapshotWritten{
+			Host:      r.host,
+			Profile:   r.profile,
+			Path:      result.SnapshotPath,
+			StateHash: result.StateHash,
+		}})
+	}
+	if !hadPrevious {
+		return
+	}
+	known := make(map[string]struct{}, len(previous.Windows))
+	for _, window := range previous.Windows {
+		known[window.Key] = struct{}{}
+	}
+	appeared := make([]model.Window, 0)
+	for _, window := range r.lastState.Windows {
+		if _, ok := known[window.Key]; !ok {
+			appeared = append(appeared, window)
+		}
+	}
+	for i, window := range appeared {
+		if ctx.Err() != nil {
+			r.logger.Warn("hook_budget_exhausted", "event", hooks.CaptureWindowAppeared, "budget", r.hookBudget, "skipped", len(appeared)-i)
+			return
+		}
+		_ = r.hooks.Fire(ctx, hooks.Event{Name: hooks.CaptureWindowAppeared, AppID: window.AppID, Data: hooks.WindowAppeared{
+			Host:    r.host,
+			Profile: r.profile,
+			Window:  window,
+		}})
+	}
+}
+
 func (r *Runner) CaptureRun(ctx context.Context, ticks <-chan time.Time) error {
 	for {
 		select {
@@ -235,8 +340,8 @@ func (r *Runner) CaptureRun(ctx context.Context, ticks <-chan time.Time) error {
 			if !ok {
 				return nil
 			}
-			if _, err := r.captureDiff(ctx); err != nil {
-				_, _ = fmt.Fprintf(r.logger, "capture_once_error err=%q\n", err.Error())
+			if _, err := r.Capture(ctx); err != nil {
+				r.logger.Error("capture_once_error", "err", err)
 			}
 		}
 	}
@@ -4,13 +4,15 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"io"
+	"log/slog"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/diff"
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/hooks"
 	"github.com/jmo/terminal-redeemer/internal/model"
 	"github.com/jmo/terminal-redeemer/internal/snapshots"
 )
@@ -44,7 +46,7 @@ func TestCaptureOnceWritesStateFullEveryTime(t *testing.T) {
 		Profile:       "default",
 		Source:        "test",
 		Now:           func() time.Time { return time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC) },
-		Logger:        io.Discard,
+		Logger:        slog.New(slog.DiscardHandler),
 	})
 
 	if _, err := runner.CaptureOnce(context.Background()); err != nil {
@@ -68,6 +70,51 @@ func TestCaptureOnceWritesStateFullEveryTime(t *testing.T) {
 	}
 }
 
+func TestCaptureWritesOnlyChangesAndExposesState(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	snapStore, err := snapshots.NewStore(root)
+	if err != nil {
+		t.Fatalf("new snapshot store: %v", err)
+	}
+
+	state := model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty", WorkspaceID: "ws-1"}}}
+	runner := NewRunner(Config{
+		Collector:     &sequenceCollector{states: []model.State{state, state}},
+		DiffEngine:    diff.NewEngine(),
+		EventStore:    eventStore,
+		SnapshotStore: snapStore,
+		SnapshotEvery: 100,
+		Host:          "host-a",
+		Profile:       "default",
+		Source:        "test",
+	})
+
+	if _, ok := runner.State(); ok {
+		t.Fatal("expected no state before first capture")
+	}
+	first, err := runner.Capture(context.Background())
+	if err != nil {
+		t.Fatalf("first capture: %v", err)
+	}
+	second, err := runner.Capture(context.Background())
+	if err != nil {
+		t.Fatalf("second capture: %v", err)
+	}
+	if first.EventsWritten != 1 || second.EventsWritten != 0 || first.StateHash != second.StateHash {
+		t.Fatalf("unexpected capture results: %+v %+v", first, second)
+	}
+	current, ok := runner.State()
+	if !ok || len(current.Windows) != 1 || current.Windows[0].Key != "w-1" {
+		t.Fatalf("unexpected current state: %#v", current)
+	}
+}
+
 func TestCaptureRunLoopsAndContinuesOnRecoverableErrors(t *testing.T) {
 	t.Parallel()
 
@@ -85,6 +132,7 @@ func TestCaptureRunLoopsAndContinuesOnRecoverableErrors(t *testing.T) {
 	stateB := model.State{Workspaces: []model.Workspace{{ID: "ws-1", Index: 1}}, Windows: []model.Window{{Key: "w-1", AppID: "kitty", WorkspaceID: "ws-1", Title: "b"}}}
 
 	var logs bytes.Buffer
+	metrics := &recordingMetrics{}
 	collector := &sequenceCollector{sequence: []collectResult{{state: stateA}, {err: errors.New("temporary niri error")}, {state: stateB}}}
 	runner := NewRunner(Config{
 		Collector:     collector,
@@ -96,7 +144,8 @@ func TestCaptureRunLoopsAndContinuesOnRecoverableErrors(t *testing.T) {
 		Profile:       "default",
 		Source:        "test",
 		Now:           func() time.Time { return time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC) },
-		Logger:        &logs,
+		Logger:        slog.New(slog.NewTextHandler(&logs, nil)),
+		Metrics:       metrics,
 	})
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -125,6 +174,9 @@ func TestCaptureRunLoopsAndContinuesOnRecoverableErrors(t *testing.T) {
 	if !bytes.Contains(logs.Bytes(), []byte("capture_once_error")) {
 		t.Fatalf("expected recoverable error log, got %q", logs.String())
 	}
+	if metrics.succeeded != 2 || metrics.failed != 1 || metrics.events != 2 {
+		t.Fatalf("unexpected capture metrics: %+v", metrics)
+	}
 }
 
 func TestSnapshotCadenceHonored(t *testing.T) {
@@ -155,7 +207,7 @@ func TestSnapshotCadenceHonored(t *testing.T) {
 		Profile:       "default",
 		Source:        "test",
 		Now:           func() time.Time { return time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC) },
-		Logger:        io.Discard,
+		Logger:        slog.New(slog.DiscardHandler),
 	})
 
 	if _, err := runner.CaptureOnce(context.Background()); err != nil {
@@ -177,6 +229,130 @@ func TestSnapshotCadenceHonored(t *testing.T) {
 	}
 }
 
+func TestCaptureFiresHooksOnChangesSnapshotsAndNewWindows(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	snapStore, err := snapshots.NewStore(root)
+	if err != nil {
+		t.Fatalf("new snapshot store: %v", err)
+	}
+
+	stateA := model.State{Workspaces: []model.Workspace{{ID: "ws-1", Index: 1}}, Windows: []model.Window{{Key: "w-1", AppID: "kitty", WorkspaceID: "ws-1"}}}
+	stateB := model.State{Workspaces: []model.Workspace{{ID: "ws-1", Index: 1}}, Windows: []model.Window{
+		{Key: "w-1", AppID: "kitty", WorkspaceID: "ws-1"},
+		{Key: "w-2", AppID: "firefox", WorkspaceID: "ws-1"},
+	}}
+
+	recorder := &recordingHooks{}
+	runner := NewRunner(Config{
+		Collector:     &sequenceCollector{states: []model.State{stateA, stateA, stateB}},
+		DiffEngine:    diff.NewEngine(),
+		EventStore:    eventStore,
+		SnapshotStore: snapStore,
+		SnapshotEvery: 2,
+		Host:          "host-a",
+		Profile:       "default",
+		Source:        "test",
+		Now:           func() time.Time { return time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC) },
+		Hooks:         recorder,
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := runner.Capture(context.Background()); err != nil {
+			t.Fatalf("capture %d: %v", i, err)
+		}
+	}
+
+	want := []string{hooks.CaptureStateChanged, hooks.CaptureStateChanged, hooks.CaptureSnapshotWritten, hooks.CaptureWindowAppeared}
+	if len(recorder.events) != len(want) {
+		t.Fatalf("unexpected hook events: %+v", recorder.events)
+	}
+	for i, name := range want {
+		if recorder.events[i].Name != name {
+			t.Fatalf("event %d = %s, want %s (%+v)", i, recorder.events[i].Name, name, recorder.events)
+		}
+	}
+	appeared := recorder.events[3]
+	if appeared.AppID != "firefox" || appeared.Data.(hooks.WindowAppeared).Window.Key != "w-2" {
+		t.Fatalf("unexpected window appeared event: %+v", appeared)
+	}
+	changed := recorder.events[1].Data.(hooks.StateChanged)
+	if changed.PreviousStateHash == "" || changed.PreviousStateHash == changed.StateHash || changed.Windows != 2 {
+		t.Fatalf("unexpected state changed payload: %+v", changed)
+	}
+}
+
+func TestCaptureHooksShareOneBudget(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	before := model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty"}}}
+	after := model.State{Windows: []model.Window{
+		{Key: "w-1", AppID: "kitty"},
+		{Key: "w-2", AppID: "kitty"},
+		{Key: "w-3", AppID: "kitty"},
+		{Key: "w-4", AppID: "kitty"},
+	}}
+
+	var logs bytes.Buffer
+	slow := &blockingHooks{}
+	runner := NewRunner(Config{
+		Collector:  &sequenceCollector{states: []model.State{before, after}},
+		DiffEngine: diff.NewEngine(),
+		EventStore: eventStore,
+		Host:       "host-a",
+		Profile:    "default",
+		Source:     "test",
+		Now:        func() time.Time { return time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC) },
+		Logger:     slog.New(slog.NewTextHandler(&logs, nil)),
+		Hooks:      slow,
+		HookBudget: 30 * time.Millisecond,
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := runner.Capture(context.Background()); err != nil {
+			t.Fatalf("capture %d: %v", i, err)
+		}
+	}
+	if slow.windows != 1 {
+		t.Fatalf("expected only the first window hook to run within the budget, got %d", slow.windows)
+	}
+	if !strings.Contains(logs.String(), "msg=hook_budget_exhausted event=capture.window_appeared budget=30ms skipped=2") {
+		t.Fatalf("expected budget log, got %q", logs.String())
+	}
+}
+
+type blockingHooks struct {
+	windows int
+}
+
+func (h *blockingHooks) Fire(ctx context.Context, event hooks.Event) error {
+	if event.Name != hooks.CaptureWindowAppeared {
+		return nil
+	}
+	h.windows++
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+type recordingHooks struct {
+	events []hooks.Event
+}
+
+func (h *recordingHooks) Fire(_ context.Context, event hooks.Event) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
 type collectResult struct {
 	state model.State
 	err   error
@@ -205,3 +381,18 @@ func (s *sequenceCollector) Collect(_ context.Context) (model.State, error) {
 	s.index++
 	return state, nil
 }
+
+type recordingMetrics struct {
+	succeeded int
+	failed    int
+	events    int
+}
+
+func (m *recordingMetrics) ObserveCapture(result Result, err error) {
+	if err != nil {
+		m.failed++
+		return
+	}
+	m.succeeded++
+	m.events += result.EventsWritten
+}
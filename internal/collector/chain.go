@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"errors"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type ChainEnricher struct {
+	enrichers []Enricher
+}
+
+func Chain(enrichers ...Enricher) ChainEnricher {
+	out := make([]Enricher, 0, len(enrichers))
+	for _, enricher := range enrichers {
+		if enricher != nil {
+			out = append(out, enricher)
+		}
+	}
+	return ChainEnricher{enrichers: out}
+}
+
+func (c ChainEnricher) EnrichWindow(window model.Window) (model.Window, error) {
+	current := window
+	succeeded := false
+	var errs []error
+	for _, enricher := range c.enrichers {
+		enriched, err := enricher.EnrichWindow(current)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		current = enriched
+		succeeded = true
+	}
+	if len(errs) == 0 {
+		return current, nil
+	}
+	if !succeeded {
+		return model.Window{}, errs[0]
+	}
+	return current, &PartialError{Errs: errs}
+}
+
+type PartialError struct {
+	Errs []error
+}
+
+func (e *PartialError) Error() string {
+	return "some enrichers failed: " + errors.Join(e.Errs...).Error()
+}
+
+func (e *PartialError) Unwrap() []error {
+	return e.Errs
+}
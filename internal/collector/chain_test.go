@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestChainKeepsPreviousResultWhenLaterEnricherFails(t *testing.T) {
+	t.Parallel()
+
+	chain := Chain(
+		stubEnricher{window: model.Window{Key: "w-1", Terminal: &model.Terminal{CWD: "/tmp"}}},
+		nil,
+		stubEnricher{err: errors.New("kitty socket unavailable")},
+	)
+
+	got, err := chain.EnrichWindow(model.Window{Key: "w-1"})
+	var partial *PartialError
+	if !errors.As(err, &partial) || len(partial.Errs) != 1 || partial.Errs[0].Error() != "kitty socket unavailable" {
+		t.Fatalf("expected partial failure for the later enricher, got %v", err)
+	}
+	if got.Terminal == nil || got.Terminal.CWD != "/tmp" {
+		t.Fatalf("expected first enricher result, got %#v", got.Terminal)
+	}
+}
+
+func TestChainFailsWhenEveryEnricherFails(t *testing.T) {
+	t.Parallel()
+
+	chain := Chain(stubEnricher{err: errors.New("boom")}, stubEnricher{err: errors.New("bang")})
+	if _, err := chain.EnrichWindow(model.Window{Key: "w-1"}); err == nil || err.Error() != "boom" {
+		t.Fatalf("expected first error, got %v", err)
+	}
+}
@@ -2,6 +2,9 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/model"
 	"github.com/jmo/terminal-redeemer/internal/niri"
@@ -11,41 +14,157 @@ type Snapshotter interface {
 	Snapshot(ctx context.Context) ([]byte, error)
 }
 
+type Parser interface {
+	Parse(raw []byte) (model.State, error)
+}
+
+type ParserFunc func(raw []byte) (model.State, error)
+
+func (f ParserFunc) Parse(raw []byte) (model.State, error) {
+	return f(raw)
+}
+
 type Enricher interface {
 	EnrichWindow(window model.Window) (model.Window, error)
 }
 
+type Redactor interface {
+	Excluded(appID string) bool
+	RedactWindow(window model.Window) model.Window
+}
+
+type Observer interface {
+	CollectDone(duration time.Duration, state model.State, err error)
+	EnrichmentFailed(window model.Window, reason string, err error)
+}
+
+type Config struct {
+	Snapshotter Snapshotter
+	Parser      Parser
+	Enricher    Enricher
+	Redactor    Redactor
+	Observer    Observer
+	Logger      *slog.Logger
+	Now         func() time.Time
+}
+
 type Collector struct {
 	snapshotter Snapshotter
+	parser      Parser
 	enricher    Enricher
+	redactor    Redactor
+	observer    Observer
+	logger      *slog.Logger
+	now         func() time.Time
 }
 
 func New(snapshotter Snapshotter, enricher Enricher) *Collector {
-	return &Collector{snapshotter: snapshotter, enricher: enricher}
+	return NewWithParser(snapshotter, ParserFunc(niri.ParseSnapshot), enricher)
+}
+
+func NewWithParser(snapshotter Snapshotter, parser Parser, enricher Enricher) *Collector {
+	return NewWithConfig(Config{Snapshotter: snapshotter, Parser: parser, Enricher: enricher})
+}
+
+func NewWithConfig(config Config) *Collector {
+	parser := config.Parser
+	if parser == nil {
+		parser = ParserFunc(niri.ParseSnapshot)
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Collector{
+		snapshotter: config.Snapshotter,
+		parser:      parser,
+		enricher:    config.Enricher,
+		redactor:    config.Redactor,
+		observer:    config.Observer,
+		logger:      logger,
+		now:         now,
+	}
 }
 
 func (c *Collector) Collect(ctx context.Context) (model.State, error) {
+	started := c.now()
+	state, err := c.collect(ctx)
+	duration := c.now().Sub(started)
+	if err != nil {
+		c.logger.Debug("collect_failed", "duration", duration, "err", err)
+	} else {
+		c.logger.Debug("collect_done", "duration", duration, "windows", len(state.Windows), "workspaces", len(state.Workspaces))
+	}
+	if c.observer != nil {
+		c.observer.CollectDone(duration, state, err)
+	}
+	return state, err
+}
+
+func (c *Collector) collect(ctx context.Context) (model.State, error) {
 	raw, err := c.snapshotter.Snapshot(ctx)
 	if err != nil {
 		return model.State{}, err
 	}
 
-	state, err := niri.ParseSnapshot(raw)
+	state, err := c.parser.Parse(raw)
 	if err != nil {
 		return model.State{}, err
 	}
 
+	if c.redactor != nil {
+		kept := state.Windows[:0]
+		for _, window := range state.Windows {
+			if !c.redactor.Excluded(window.AppID) {
+				kept = append(kept, window)
+			}
+		}
+		state.Windows = kept
+	}
+
 	if c.enricher == nil {
-		return state, nil
+		return c.redact(state), nil
 	}
 
 	for i := range state.Windows {
 		enriched, err := c.enricher.EnrichWindow(state.Windows[i])
+		var partial *PartialError
+		if errors.As(err, &partial) {
+			for _, failure := range partial.Errs {
+				c.enrichmentFailed(state.Windows[i], failure)
+			}
+			state.Windows[i] = enriched
+			continue
+		}
 		if err != nil {
+			c.enrichmentFailed(state.Windows[i], err)
 			continue
 		}
 		state.Windows[i] = enriched
 	}
 
-	return model.Normalize(state), nil
+	return model.Normalize(c.redact(state)), nil
+}
+
+func (c *Collector) redact(state model.State) model.State {
+	if c.redactor == nil {
+		return state
+	}
+	for i := range state.Windows {
+		state.Windows[i] = c.redactor.RedactWindow(state.Windows[i])
+	}
+	return state
+}
+
+func (c *Collector) enrichmentFailed(window model.Window, err error) {
+	reason := FailureReason(err)
+	c.logger.Debug("enrichment_failed", "window", window.Key, "app_id", window.AppID, "pid", window.PID, "reason", reason, "err", err)
+	if c.observer == nil {
+		return
+	}
+	c.observer.EnrichmentFailed(window, reason, err)
 }
@@ -1,9 +1,17 @@
 package collector
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os/exec"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/model"
 )
@@ -54,6 +62,162 @@ func TestCollectGracefullyDegradesOnMetadataError(t *testing.T) {
 	}
 }
 
+func TestCollectAppliesRedactorBeforeReturningState(t *testing.T) {
+	t.Parallel()
+
+	snapshotter := stubSnapshotter{raw: []byte(`{
+  "workspaces": [{"id": "ws-1", "idx": 1}],
+  "windows": [
+    {"id": 101, "app_id": "kitty", "title": "ssh prod", "workspace_id": "ws-1", "pid": 4242},
+    {"id": 102, "app_id": "org.keepassxc.KeePassXC", "title": "vault", "workspace_id": "ws-1", "pid": 4243}
+  ]
+}`)}
+	var enriched []string
+	enricher := enricherFunc(func(window model.Window) (model.Window, error) {
+		enriched = append(enriched, window.AppID)
+		window.Terminal = &model.Terminal{CWD: "/home/me/secret"}
+		return window, nil
+	})
+
+	c := NewWithConfig(Config{Snapshotter: snapshotter, Enricher: enricher, Redactor: stubRedactor{exclude: "org.keepassxc.KeePassXC"}})
+	state, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+
+	if len(enriched) != 1 || enriched[0] != "kitty" {
+		t.Fatalf("expected only kitty to be enriched, got %v", enriched)
+	}
+	if len(state.Windows) != 1 {
+		t.Fatalf("expected excluded window to be dropped, got %#v", state.Windows)
+	}
+	window := state.Windows[0]
+	if window.Title != "[redacted]" || window.Terminal == nil || window.Terminal.CWD != "/redacted" {
+		t.Fatalf("expected redacted window, got %#v (terminal %#v)", window, window.Terminal)
+	}
+}
+
+func TestCollectReportsToObserver(t *testing.T) {
+	t.Parallel()
+
+	snapshotter := stubSnapshotter{raw: []byte(`{
+  "workspaces": [{"id": "ws-1", "idx": 1}],
+  "windows": [
+    {"id": 101, "app_id": "kitty", "workspace_id": "ws-1", "pid": 4242},
+    {"id": 102, "app_id": "kitty", "workspace_id": "ws-1", "pid": 4343}
+  ]
+}`)}
+	chain := Chain(
+		stubEnricher{},
+		stubEnricher{err: fmt.Errorf("read cwd: %w", fs.ErrNotExist)},
+	)
+	observer := &recordingObserver{}
+	clock := []time.Time{time.Unix(100, 0), time.Unix(100, int64(40*time.Millisecond))}
+	c := NewWithConfig(Config{
+		Snapshotter: snapshotter,
+		Enricher:    chain,
+		Observer:    observer,
+		Now: func() time.Time {
+			next := clock[0]
+			clock = clock[1:]
+			return next
+		},
+	})
+
+	state, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if len(state.Windows) != 2 {
+		t.Fatalf("expected partially enriched windows to be kept, got %d", len(state.Windows))
+	}
+	if observer.duration != 40*time.Millisecond || observer.windows != 2 || observer.err != nil {
+		t.Fatalf("unexpected collect observation: %+v", observer)
+	}
+	if len(observer.reasons) != 2 || observer.reasons[0] != ReasonNotFound || observer.pids[0] != 4242 || observer.pids[1] != 4343 {
+		t.Fatalf("unexpected enrichment failures: %+v", observer)
+	}
+}
+
+func TestCollectReportsSnapshotErrorToObserver(t *testing.T) {
+	t.Parallel()
+
+	observer := &recordingObserver{}
+	c := NewWithConfig(Config{Snapshotter: stubSnapshotter{err: errors.New("niri down")}, Observer: observer})
+	if _, err := c.Collect(context.Background()); err == nil {
+		t.Fatal("expected collect error")
+	}
+	if observer.err == nil {
+		t.Fatal("expected observer to see the collect error")
+	}
+}
+
+func TestCollectLogsEnrichmentFailuresAtDebug(t *testing.T) {
+	t.Parallel()
+
+	snapshotter := stubSnapshotter{raw: []byte(`{
+  "workspaces": [{"id": "ws-1", "idx": 1}],
+  "windows": [{"id": 101, "app_id": "kitty", "workspace_id": "ws-1", "pid": 4242}]
+}`)}
+	var logs bytes.Buffer
+	c := NewWithConfig(Config{
+		Snapshotter: snapshotter,
+		Enricher:    stubEnricher{err: fmt.Errorf("read cwd: %w", fs.ErrPermission)},
+		Logger:      slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	if _, err := c.Collect(context.Background()); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+
+	line := logs.String()
+	for _, want := range []string{"level=DEBUG", "msg=enrichment_failed", "pid=4242", "reason=permission_denied", "app_id=kitty"} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("expected %q in log output, got %q", want, line)
+		}
+	}
+}
+
+func TestFailureReason(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{err: fmt.Errorf("stat: %w", fs.ErrNotExist), want: ReasonNotFound},
+		{err: fmt.Errorf("open: %w", fs.ErrPermission), want: ReasonPermissionDenied},
+		{err: fmt.Errorf("kitty ls: %w", context.DeadlineExceeded), want: ReasonTimeout},
+		{err: &exec.Error{Name: "kitty", Err: exec.ErrNotFound}, want: ReasonCommandMissing},
+		{err: &exec.ExitError{}, want: ReasonCommandFailed},
+		{err: fmt.Errorf("decode: %w", &json.SyntaxError{}), want: ReasonDecode},
+		{err: errors.New("boom"), want: ReasonOther},
+	}
+	for _, tc := range cases {
+		if got := FailureReason(tc.err); got != tc.want {
+			t.Fatalf("FailureReason(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+type recordingObserver struct {
+	duration time.Duration
+	windows  int
+	err      error
+	reasons  []string
+	pids     []int
+}
+
+func (o *recordingObserver) CollectDone(duration time.Duration, state model.State, err error) {
+	o.duration = duration
+	o.windows = len(state.Windows)
+	o.err = err
+}
+
+func (o *recordingObserver) EnrichmentFailed(window model.Window, reason string, _ error) {
+	o.reasons = append(o.reasons, reason)
+	o.pids = append(o.pids, window.PID)
+}
+
 type stubSnapshotter struct {
 	raw []byte
 	err error
@@ -80,3 +244,27 @@ func (s stubEnricher) EnrichWindow(window model.Window) (model.Window, error) {
 	}
 	return s.window, nil
 }
+
+type enricherFunc func(window model.Window) (model.Window, error)
+
+func (f enricherFunc) EnrichWindow(window model.Window) (model.Window, error) {
+	return f(window)
+}
+
+type stubRedactor struct {
+	exclude string
+}
+
+func (s stubRedactor) Excluded(appID string) bool {
+	return appID == s.exclude
+}
+
+func (s stubRedactor) RedactWindow(window model.Window) model.Window {
+	window.Title = "[redacted]"
+	if window.Terminal != nil {
+		terminal := *window.Terminal
+		terminal.CWD = "/redacted"
+		window.Terminal = &terminal
+	}
+	return window
+}
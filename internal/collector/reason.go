@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+)
+
+const (
+	ReasonNotFound         = "not_found"
+	ReasonPermissionDenied = "permission_denied"
+	ReasonTimeout          = "timeout"
+	ReasonCommandMissing   = "command_missing"
+	ReasonCommandFailed    = "command_failed"
+	ReasonConnection       = "connection"
+	ReasonDecode           = "decode"
+	ReasonOther            = "other"
+)
+
+func FailureReason(err error) string {
+	var exitErr *exec.ExitError
+	var netErr net.Error
+	var opErr *net.OpError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ReasonTimeout
+	case errors.Is(err, exec.ErrNotFound):
+		return ReasonCommandMissing
+	case errors.As(err, &exitErr):
+		return ReasonCommandFailed
+	case errors.As(err, &opErr):
+		return ReasonConnection
+	case errors.Is(err, os.ErrPermission):
+		return ReasonPermissionDenied
+	case errors.Is(err, os.ErrNotExist):
+		return ReasonNotFound
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ReasonDecode
+	default:
+		return ReasonOther
+	}
+}
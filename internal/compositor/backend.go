@@ -0,0 +1,221 @@
+package compositor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+const (
+	Niri     = "niri"
+	Sway     = "sway"
+	Hyprland = "hyprland"
+)
+
+type Backend interface {
+	Name() string
+	Snapshot(ctx context.Context) ([]byte, error)
+	Parse(raw []byte) (model.State, error)
+	MoveToWorkspace(ctx context.Context, windowID int, workspaceRef string) error
+	FocusWorkspace(ctx context.Context, workspaceRef string) error
+	CloseWindow(ctx context.Context, windowID int) error
+	Events(ctx context.Context) (<-chan Event, error)
+}
+
+type Event struct {
+	Kind string
+	Raw  string
+}
+
+type Runner interface {
+	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+	Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error)
+}
+
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return out, fmt.Errorf("%w: %s", err, message)
+		}
+		if message := strings.TrimSpace(string(out)); message != "" {
+			return out, fmt.Errorf("%w: %s", err, message)
+		}
+	}
+	return out, err
+}
+
+func (ExecRunner) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &commandStream{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+type commandStream struct {
+	io.ReadCloser
+	cmd  *exec.Cmd
+	once sync.Once
+}
+
+func (s *commandStream) Close() error {
+	s.once.Do(func() {
+		_ = s.ReadCloser.Close()
+		_ = s.cmd.Process.Kill()
+		_ = s.cmd.Wait()
+	})
+	return nil
+}
+
+type Config struct {
+	Name        string
+	NiriCommand string
+	Runner      Runner
+}
+
+func New(config Config) (Backend, error) {
+	runner := config.Runner
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+	switch strings.ToLower(strings.TrimSpace(config.Name)) {
+	case "", Niri:
+		return &NiriBackend{Command: config.NiriCommand, Runner: runner}, nil
+	case Sway:
+		return &SwayBackend{Runner: runner}, nil
+	case Hyprland:
+		return &HyprlandBackend{Runner: runner}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compositor %q (want niri, sway or hyprland)", config.Name)
+	}
+}
+
+func ReadState(ctx context.Context, backend Backend) (model.State, error) {
+	raw, err := backend.Snapshot(ctx)
+	if err != nil {
+		return model.State{}, err
+	}
+	return backend.Parse(raw)
+}
+
+func streamLines(ctx context.Context, stream io.ReadCloser, parse func(line string) (Event, bool)) <-chan Event {
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	return pump(ctx, stream, func() (Event, bool, bool) {
+		if !scanner.Scan() {
+			return Event{}, false, false
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			return Event{}, false, true
+		}
+		event, ok := parse(line)
+		return event, ok, true
+	})
+}
+
+func streamJSON(ctx context.Context, stream io.ReadCloser, parse func(raw json.RawMessage) (Event, bool)) <-chan Event {
+	decoder := json.NewDecoder(stream)
+	return pump(ctx, stream, func() (Event, bool, bool) {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return Event{}, false, false
+		}
+		event, ok := parse(raw)
+		return event, ok, true
+	})
+}
+
+func pump(ctx context.Context, stream io.ReadCloser, next func() (Event, bool, bool)) <-chan Event {
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			_ = stream.Close()
+		}()
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = stream.Close()
+			case <-done:
+			}
+		}()
+
+		for {
+			event, ok, more := next()
+			if !more {
+				return
+			}
+			if !ok {
+				continue
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func Debounce(ctx context.Context, events <-chan Event, quiet time.Duration) <-chan time.Time {
+	out := make(chan time.Time)
+	go func() {
+		defer close(out)
+		var timer *time.Timer
+		var fire <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-events:
+				if !ok {
+					events = nil
+					if fire == nil {
+						return
+					}
+					continue
+				}
+				if timer == nil {
+					timer = time.NewTimer(quiet)
+				} else {
+					timer.Reset(quiet)
+				}
+				fire = timer.C
+			case at := <-fire:
+				fire = nil
+				select {
+				case out <- at:
+				case <-ctx.Done():
+					return
+				}
+				if events == nil {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,119 @@
+package compositor
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSelectsBackendByName(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]string{"": Niri, "niri": Niri, "Sway": Sway, "hyprland": Hyprland} {
+		backend, err := New(Config{Name: name})
+		if err != nil {
+			t.Fatalf("new %q: %v", name, err)
+		}
+		if backend.Name() != want {
+			t.Fatalf("expected %q backend for %q, got %q", want, name, backend.Name())
+		}
+	}
+	if _, err := New(Config{Name: "river"}); err == nil {
+		t.Fatal("expected unsupported compositor error")
+	}
+}
+
+type stubRunner struct {
+	outputs map[string]string
+	errs    map[string]error
+	stream  string
+	calls   []string
+}
+
+func (r *stubRunner) Run(_ context.Context, name string, args ...string) ([]byte, error) {
+	call := strings.Join(append([]string{name}, args...), " ")
+	r.calls = append(r.calls, call)
+	if err := r.errs[call]; err != nil {
+		return nil, err
+	}
+	return []byte(r.outputs[call]), nil
+}
+
+func (r *stubRunner) Stream(_ context.Context, name string, args ...string) (io.ReadCloser, error) {
+	r.calls = append(r.calls, strings.Join(append([]string{name}, args...), " "))
+	return io.NopCloser(strings.NewReader(r.stream)), nil
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return raw
+}
+
+func collectEvents(t *testing.T, events <-chan Event) []Event {
+	t.Helper()
+	var out []Event
+	for event := range events {
+		out = append(out, event)
+	}
+	return out
+}
+
+func TestCommandStreamClosesOnceWhenCancelledMidRead(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := ExecRunner{}.Stream(ctx, "sh", "-c", "while :; do echo tick; done")
+		if err != nil {
+			cancel()
+			t.Fatalf("stream: %v", err)
+		}
+		events := streamLines(ctx, stream, func(line string) (Event, bool) {
+			return Event{Kind: line}, true
+		})
+		if event := <-events; event.Kind != "tick" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+		cancel()
+		deadline := time.After(5 * time.Second)
+		for open := true; open; {
+			select {
+			case _, open = <-events:
+			case <-deadline:
+				t.Fatal("expected events to close after cancel")
+			}
+		}
+		if err := stream.Close(); err != nil {
+			t.Fatalf("close after cancel: %v", err)
+		}
+	}
+}
+
+func TestDebounceCoalescesBursts(t *testing.T) {
+	t.Parallel()
+
+	events := make(chan Event)
+	ticks := Debounce(context.Background(), events, 20*time.Millisecond)
+	for range 5 {
+		events <- Event{Kind: "WindowOpenedOrChanged"}
+	}
+	<-ticks
+	events <- Event{Kind: "WindowClosed"}
+	close(events)
+
+	count := 1
+	for range ticks {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("expected one tick per burst, got %d", count)
+	}
+}
@@ -0,0 +1,211 @@
+package compositor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type HyprlandBackend struct {
+	Runner     Runner
+	SocketPath string
+}
+
+type hyprlandSnapshot struct {
+	Workspaces []hyprlandWorkspace `json:"workspaces"`
+	Clients    []hyprlandClient    `json:"clients"`
+}
+
+type hyprlandWorkspace struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type hyprlandClient struct {
+	Address   string            `json:"address"`
+	Mapped    *bool             `json:"mapped"`
+	Workspace hyprlandWorkspace `json:"workspace"`
+	Class     string            `json:"class"`
+	Title     string            `json:"title"`
+	PID       int               `json:"pid"`
+}
+
+func (b *HyprlandBackend) Name() string {
+	return Hyprland
+}
+
+func (b *HyprlandBackend) Snapshot(ctx context.Context) ([]byte, error) {
+	clients, err := b.runner().Run(ctx, "hyprctl", "-j", "clients")
+	if err != nil {
+		return nil, fmt.Errorf("run hyprland clients command: %w", err)
+	}
+	workspaces, err := b.runner().Run(ctx, "hyprctl", "-j", "workspaces")
+	if err != nil {
+		return nil, fmt.Errorf("run hyprland workspaces command: %w", err)
+	}
+	var snapshot struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+		Clients    json.RawMessage `json:"clients"`
+	}
+	snapshot.Workspaces = workspaces
+	snapshot.Clients = clients
+	combined, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("encode hyprland snapshot: %w", err)
+	}
+	return combined, nil
+}
+
+func (b *HyprlandBackend) Parse(raw []byte) (model.State, error) {
+	var snapshot hyprlandSnapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		var clientsOnly []hyprlandClient
+		if clientsErr := json.Unmarshal(raw, &clientsOnly); clientsErr != nil {
+			return model.State{}, fmt.Errorf("decode hyprland snapshot: %w", err)
+		}
+		snapshot = hyprlandSnapshot{Clients: clientsOnly}
+	}
+
+	state := model.State{}
+	for _, workspace := range snapshot.Workspaces {
+		if isSpecialHyprlandWorkspace(workspace) {
+			continue
+		}
+		state.Workspaces = append(state.Workspaces, hyprlandModelWorkspace(workspace))
+	}
+	for _, client := range snapshot.Clients {
+		if client.Mapped != nil && !*client.Mapped {
+			continue
+		}
+		if isSpecialHyprlandWorkspace(client.Workspace) {
+			continue
+		}
+		id, err := hyprlandWindowID(client.Address)
+		if err != nil {
+			continue
+		}
+		state.Windows = append(state.Windows, model.Window{
+			Key:         fmt.Sprintf("w:%s:%d", client.Class, id),
+			AppID:       client.Class,
+			WorkspaceID: strconv.Itoa(client.Workspace.ID),
+			PID:         client.PID,
+			Title:       client.Title,
+		})
+	}
+
+	return model.Normalize(state), nil
+}
+
+func hyprlandModelWorkspace(workspace hyprlandWorkspace) model.Workspace {
+	out := model.Workspace{ID: strconv.Itoa(workspace.ID), Name: workspace.Name}
+	if workspace.ID > 0 {
+		out.Index = workspace.ID
+	}
+	return out
+}
+
+func isSpecialHyprlandWorkspace(workspace hyprlandWorkspace) bool {
+	return strings.HasPrefix(workspace.Name, "special")
+}
+
+func hyprlandWindowID(address string) (int, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(address), "0x"), 16, 63)
+	if err != nil {
+		return 0, fmt.Errorf("parse hyprland address %q: %w", address, err)
+	}
+	return int(value), nil
+}
+
+func (b *HyprlandBackend) MoveToWorkspace(ctx context.Context, windowID int, workspaceRef string) error {
+	workspaceRef = strings.TrimSpace(workspaceRef)
+	if windowID <= 0 || workspaceRef == "" {
+		return fmt.Errorf("invalid move request")
+	}
+	return b.dispatch(ctx, "movetoworkspacesilent", fmt.Sprintf("%s,address:0x%x", hyprlandWorkspaceRef(workspaceRef), windowID))
+}
+
+func (b *HyprlandBackend) FocusWorkspace(ctx context.Context, workspaceRef string) error {
+	workspaceRef = strings.TrimSpace(workspaceRef)
+	if workspaceRef == "" {
+		return fmt.Errorf("invalid focus request")
+	}
+	return b.dispatch(ctx, "workspace", hyprlandWorkspaceRef(workspaceRef))
+}
+
+func (b *HyprlandBackend) CloseWindow(ctx context.Context, windowID int) error {
+	if windowID <= 0 {
+		return fmt.Errorf("invalid close request")
+	}
+	return b.dispatch(ctx, "closewindow", fmt.Sprintf("address:0x%x", windowID))
+}
+
+func (b *HyprlandBackend) Events(ctx context.Context) (<-chan Event, error) {
+	path := b.SocketPath
+	if path == "" {
+		path = hyprlandEventSocket()
+	}
+	if path == "" {
+		return nil, fmt.Errorf("hyprland event socket unavailable: HYPRLAND_INSTANCE_SIGNATURE is not set")
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("connect hyprland event socket: %w", err)
+	}
+	return streamLines(ctx, io.ReadCloser(conn), parseHyprlandEvent), nil
+}
+
+func (b *HyprlandBackend) dispatch(ctx context.Context, dispatcher string, arg string) error {
+	out, err := b.runner().Run(ctx, "hyprctl", "dispatch", dispatcher, arg)
+	if err != nil {
+		return fmt.Errorf("hyprland dispatch %s failed: %w", dispatcher, err)
+	}
+	if result := strings.TrimSpace(string(out)); result != "" && result != "ok" {
+		return fmt.Errorf("hyprland dispatch %s failed: %s", dispatcher, result)
+	}
+	return nil
+}
+
+func (b *HyprlandBackend) runner() Runner {
+	if b.Runner == nil {
+		return ExecRunner{}
+	}
+	return b.Runner
+}
+
+func hyprlandWorkspaceRef(ref string) string {
+	if _, err := strconv.Atoi(ref); err == nil {
+		return ref
+	}
+	return "name:" + ref
+}
+
+func hyprlandEventSocket() string {
+	signature := strings.TrimSpace(os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"))
+	if signature == "" {
+		return ""
+	}
+	if runtimeDir := strings.TrimSpace(os.Getenv("XDG_RUNTIME_DIR")); runtimeDir != "" {
+		path := filepath.Join(runtimeDir, "hypr", signature, ".socket2.sock")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(os.TempDir(), "hypr", signature, ".socket2.sock")
+}
+
+func parseHyprlandEvent(line string) (Event, bool) {
+	kind, _, ok := strings.Cut(line, ">>")
+	if !ok || kind == "" {
+		return Event{}, false
+	}
+	return Event{Kind: kind, Raw: line}, true
+}
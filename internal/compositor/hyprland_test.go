@@ -0,0 +1,120 @@
+package compositor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestHyprlandSnapshotAndParseFixtures(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{outputs: map[string]string{
+		"hyprctl -j clients":    string(readFixture(t, "hyprland_clients.json")),
+		"hyprctl -j workspaces": string(readFixture(t, "hyprland_workspaces.json")),
+	}}
+	backend := &HyprlandBackend{Runner: runner}
+	raw, err := backend.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	state, err := backend.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if len(state.Workspaces) != 2 {
+		t.Fatalf("expected special workspace excluded, got %#v", state.Workspaces)
+	}
+	if state.Workspaces[0].ID != "-1337" || state.Workspaces[0].Name != "web" || state.Workspaces[0].Index != 0 {
+		t.Fatalf("unexpected named workspace: %#v", state.Workspaces[0])
+	}
+	if state.Workspaces[1].ID != "1" || state.Workspaces[1].Index != 1 {
+		t.Fatalf("unexpected numbered workspace: %#v", state.Workspaces[1])
+	}
+
+	if len(state.Windows) != 2 {
+		t.Fatalf("expected 2 windows, got %#v", state.Windows)
+	}
+	kitty := state.Windows[1]
+	if kitty.Key != "w:kitty:94367974994624" || kitty.WorkspaceID != "1" || kitty.PID != 4242 || kitty.Title != "~/src/redeemer" {
+		t.Fatalf("unexpected kitty window: %#v", kitty)
+	}
+}
+
+func TestHyprlandSnapshotReturnsWorkspacesError(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{
+		outputs: map[string]string{"hyprctl -j clients": string(readFixture(t, "hyprland_clients.json"))},
+		errs:    map[string]error{"hyprctl -j workspaces": errors.New("socket closed")},
+	}
+	if _, err := (&HyprlandBackend{Runner: runner}).Snapshot(context.Background()); err == nil {
+		t.Fatal("expected workspaces failure to be returned")
+	}
+}
+
+func TestHyprlandParseClientsOnly(t *testing.T) {
+	t.Parallel()
+
+	state, err := (&HyprlandBackend{}).Parse(readFixture(t, "hyprland_clients.json"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(state.Workspaces) != 0 || len(state.Windows) != 2 {
+		t.Fatalf("unexpected state: %#v", state)
+	}
+}
+
+func TestHyprlandBackendActions(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{outputs: map[string]string{
+		"hyprctl dispatch movetoworkspacesilent name:web,address:0x55d3c1a0b2c0": "ok",
+		"hyprctl dispatch workspace 2":                                           "ok",
+		"hyprctl dispatch closewindow address:0x55d3c1a0b2c0":                    "No such window",
+	}}
+	backend := &HyprlandBackend{Runner: runner}
+	ctx := context.Background()
+	if err := backend.MoveToWorkspace(ctx, 0x55d3c1a0b2c0, "web"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if err := backend.FocusWorkspace(ctx, "2"); err != nil {
+		t.Fatalf("focus: %v", err)
+	}
+	if err := backend.CloseWindow(ctx, 0x55d3c1a0b2c0); err == nil {
+		t.Fatal("expected dispatch failure to be reported")
+	}
+}
+
+func TestHyprlandBackendEventsFromSocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".socket2.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("openwindow>>55d3c1a0b2c0,1,kitty,~\nworkspace>>2\n\nclosewindow>>55d3c1a0b2c0\n"))
+		_ = conn.Close()
+	}()
+
+	events, err := (&HyprlandBackend{SocketPath: path}).Events(context.Background())
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	got := collectEvents(t, events)
+	if len(got) != 3 || got[0].Kind != "openwindow" || got[1].Kind != "workspace" || got[2].Kind != "closewindow" {
+		t.Fatalf("unexpected events: %#v", got)
+	}
+}
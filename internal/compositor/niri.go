@@ -0,0 +1,90 @@
+package compositor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/niri"
+)
+
+type NiriBackend struct {
+	Command string
+	Runner  Runner
+}
+
+func (b *NiriBackend) Name() string {
+	return Niri
+}
+
+func (b *NiriBackend) Snapshot(ctx context.Context) ([]byte, error) {
+	command := strings.TrimSpace(b.Command)
+	if command == "" {
+		command = "niri msg -j windows"
+	}
+	return niri.CommandSnapshotter{Command: command}.Snapshot(ctx)
+}
+
+func (b *NiriBackend) Parse(raw []byte) (model.State, error) {
+	return niri.ParseSnapshot(raw)
+}
+
+func (b *NiriBackend) MoveToWorkspace(ctx context.Context, windowID int, workspaceRef string) error {
+	workspaceRef = strings.TrimSpace(workspaceRef)
+	if windowID <= 0 || workspaceRef == "" {
+		return fmt.Errorf("invalid move request")
+	}
+	return b.action(ctx, "move-window-to-workspace", "--window-id", strconv.Itoa(windowID), workspaceRef)
+}
+
+func (b *NiriBackend) FocusWorkspace(ctx context.Context, workspaceRef string) error {
+	workspaceRef = strings.TrimSpace(workspaceRef)
+	if workspaceRef == "" {
+		return fmt.Errorf("invalid focus request")
+	}
+	return b.action(ctx, "focus-workspace", workspaceRef)
+}
+
+func (b *NiriBackend) CloseWindow(ctx context.Context, windowID int) error {
+	if windowID <= 0 {
+		return fmt.Errorf("invalid close request")
+	}
+	return b.action(ctx, "close-window", "--id", strconv.Itoa(windowID))
+}
+
+func (b *NiriBackend) Events(ctx context.Context) (<-chan Event, error) {
+	stream, err := b.runner().Stream(ctx, "niri", "msg", "-j", "event-stream")
+	if err != nil {
+		return nil, fmt.Errorf("start niri event stream: %w", err)
+	}
+	return streamLines(ctx, stream, parseNiriEvent), nil
+}
+
+func (b *NiriBackend) action(ctx context.Context, action string, args ...string) error {
+	cmdArgs := append([]string{"msg", "action", action}, args...)
+	if _, err := b.runner().Run(ctx, "niri", cmdArgs...); err != nil {
+		return fmt.Errorf("niri action %s failed: %w", action, err)
+	}
+	return nil
+}
+
+func (b *NiriBackend) runner() Runner {
+	if b.Runner == nil {
+		return ExecRunner{}
+	}
+	return b.Runner
+}
+
+func parseNiriEvent(line string) (Event, bool) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &payload); err != nil || len(payload) != 1 {
+		return Event{}, false
+	}
+	for kind := range payload {
+		return Event{Kind: kind, Raw: line}, true
+	}
+	return Event{}, false
+}
@@ -0,0 +1,60 @@
+package compositor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNiriBackendActions(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{}
+	backend := &NiriBackend{Runner: runner}
+	ctx := context.Background()
+	if err := backend.MoveToWorkspace(ctx, 101, "code"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if err := backend.FocusWorkspace(ctx, "2"); err != nil {
+		t.Fatalf("focus: %v", err)
+	}
+	if err := backend.CloseWindow(ctx, 101); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	want := []string{
+		"niri msg action move-window-to-workspace --window-id 101 code",
+		"niri msg action focus-workspace 2",
+		"niri msg action close-window --id 101",
+	}
+	if len(runner.calls) != len(want) {
+		t.Fatalf("unexpected calls: %#v", runner.calls)
+	}
+	for i := range want {
+		if runner.calls[i] != want[i] {
+			t.Fatalf("call %d: expected %q, got %q", i, want[i], runner.calls[i])
+		}
+	}
+	if err := backend.MoveToWorkspace(ctx, 0, "code"); err == nil {
+		t.Fatal("expected invalid move request error")
+	}
+}
+
+func TestNiriBackendEvents(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{stream: `{"WorkspacesChanged":{"workspaces":[]}}
+{"WindowOpenedOrChanged":{"window":{"id":101,"app_id":"kitty"}}}
+not json
+{"WindowClosed":{"id":101}}
+`}
+	events, err := (&NiriBackend{Runner: runner}).Events(context.Background())
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	got := collectEvents(t, events)
+	if len(got) != 3 || got[0].Kind != "WorkspacesChanged" || got[1].Kind != "WindowOpenedOrChanged" || got[2].Kind != "WindowClosed" {
+		t.Fatalf("unexpected events: %#v", got)
+	}
+	if runner.calls[0] != "niri msg -j event-stream" {
+		t.Fatalf("unexpected stream command: %q", runner.calls[0])
+	}
+}
@@ -0,0 +1,189 @@
+package compositor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type SwayBackend struct {
+	Runner Runner
+}
+
+type swayNode struct {
+	ID               int64            `json:"id"`
+	Type             string           `json:"type"`
+	Name             string           `json:"name"`
+	Num              int              `json:"num"`
+	PID              int              `json:"pid"`
+	AppID            *string          `json:"app_id"`
+	WindowProperties *swayWindowProps `json:"window_properties"`
+	Nodes            []swayNode       `json:"nodes"`
+	FloatingNodes    []swayNode       `json:"floating_nodes"`
+}
+
+type swayWindowProps struct {
+	Class    string `json:"class"`
+	Instance string `json:"instance"`
+}
+
+func (b *SwayBackend) Name() string {
+	return Sway
+}
+
+func (b *SwayBackend) Snapshot(ctx context.Context) ([]byte, error) {
+	out, err := b.runner().Run(ctx, "swaymsg", "-r", "-t", "get_tree")
+	if err != nil {
+		return nil, fmt.Errorf("run sway snapshot command: %w", err)
+	}
+	return out, nil
+}
+
+func (b *SwayBackend) Parse(raw []byte) (model.State, error) {
+	var root swayNode
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return model.State{}, fmt.Errorf("decode sway tree: %w", err)
+	}
+
+	state := model.State{}
+	position := 0
+	var walk func(node swayNode, workspaceID string)
+	walk = func(node swayNode, workspaceID string) {
+		switch node.Type {
+		case "output":
+			if strings.HasPrefix(node.Name, "__") {
+				return
+			}
+		case "workspace":
+			position++
+			workspaceID = strconv.FormatInt(node.ID, 10)
+			index := node.Num
+			if index <= 0 {
+				index = position
+			}
+			state.Workspaces = append(state.Workspaces, model.Workspace{
+				ID:    workspaceID,
+				Index: index,
+				Name:  node.Name,
+			})
+		case "con", "floating_con":
+			if workspaceID != "" && node.PID > 0 && len(node.Nodes) == 0 {
+				appID := swayAppID(node)
+				state.Windows = append(state.Windows, model.Window{
+					Key:         fmt.Sprintf("w:%s:%d", appID, node.ID),
+					AppID:       appID,
+					WorkspaceID: workspaceID,
+					PID:         node.PID,
+					Title:       node.Name,
+				})
+				return
+			}
+		}
+		for _, child := range node.Nodes {
+			walk(child, workspaceID)
+		}
+		for _, child := range node.FloatingNodes {
+			walk(child, workspaceID)
+		}
+	}
+	walk(root, "")
+
+	return model.Normalize(state), nil
+}
+
+func swayAppID(node swayNode) string {
+	if node.AppID != nil && *node.AppID != "" {
+		return *node.AppID
+	}
+	if node.WindowProperties != nil {
+		if node.WindowProperties.Class != "" {
+			return node.WindowProperties.Class
+		}
+		return node.WindowProperties.Instance
+	}
+	return ""
+}
+
+func (b *SwayBackend) MoveToWorkspace(ctx context.Context, windowID int, workspaceRef string) error {
+	workspaceRef = strings.TrimSpace(workspaceRef)
+	if windowID <= 0 || workspaceRef == "" {
+		return fmt.Errorf("invalid move request")
+	}
+	return b.command(ctx, fmt.Sprintf("[con_id=%d] move container to workspace %s", windowID, swayQuote(workspaceRef)))
+}
+
+func (b *SwayBackend) FocusWorkspace(ctx context.Context, workspaceRef string) error {
+	workspaceRef = strings.TrimSpace(workspaceRef)
+	if workspaceRef == "" {
+		return fmt.Errorf("invalid focus request")
+	}
+	return b.command(ctx, "workspace "+swayQuote(workspaceRef))
+}
+
+func (b *SwayBackend) CloseWindow(ctx context.Context, windowID int) error {
+	if windowID <= 0 {
+		return fmt.Errorf("invalid close request")
+	}
+	return b.command(ctx, fmt.Sprintf("[con_id=%d] kill", windowID))
+}
+
+func (b *SwayBackend) Events(ctx context.Context) (<-chan Event, error) {
+	stream, err := b.runner().Stream(ctx, "swaymsg", "-r", "-m", "-t", "subscribe", `["window","workspace"]`)
+	if err != nil {
+		return nil, fmt.Errorf("start sway event stream: %w", err)
+	}
+	return streamJSON(ctx, stream, parseSwayEvent), nil
+}
+
+func (b *SwayBackend) command(ctx context.Context, command string) error {
+	out, err := b.runner().Run(ctx, "swaymsg", "-r", command)
+	if err != nil {
+		return fmt.Errorf("sway command %q failed: %w", command, err)
+	}
+	var results []struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal(out, &results) != nil {
+		return nil
+	}
+	for _, result := range results {
+		if !result.Success {
+			return fmt.Errorf("sway command %q failed: %s", command, result.Error)
+		}
+	}
+	return nil
+}
+
+func (b *SwayBackend) runner() Runner {
+	if b.Runner == nil {
+		return ExecRunner{}
+	}
+	return b.Runner
+}
+
+func swayQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+func parseSwayEvent(raw json.RawMessage) (Event, bool) {
+	var payload struct {
+		Change    string          `json:"change"`
+		Container json.RawMessage `json:"container"`
+		Current   json.RawMessage `json:"current"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Change == "" {
+		return Event{}, false
+	}
+	kind := "workspace"
+	if len(payload.Container) > 0 {
+		kind = "window"
+	}
+	return Event{Kind: kind + ":" + payload.Change, Raw: string(raw)}, true
+}
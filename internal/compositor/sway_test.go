@@ -0,0 +1,86 @@
+package compositor
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSwayParseTreeFixture(t *testing.T) {
+	t.Parallel()
+
+	state, err := (&SwayBackend{}).Parse(readFixture(t, "sway_tree.json"))
+	if err != nil {
+		t.Fatalf("parse tree: %v", err)
+	}
+	if len(state.Workspaces) != 2 {
+		t.Fatalf("expected scratchpad excluded, got workspaces %#v", state.Workspaces)
+	}
+	if state.Workspaces[0].ID != "4" || state.Workspaces[0].Name != "1" || state.Workspaces[0].Index != 1 {
+		t.Fatalf("unexpected first workspace: %#v", state.Workspaces[0])
+	}
+	if state.Workspaces[1].Name != "mail" || state.Workspaces[1].Index != 2 {
+		t.Fatalf("expected named workspace indexed by position, got %#v", state.Workspaces[1])
+	}
+
+	if len(state.Windows) != 3 {
+		t.Fatalf("expected 3 windows, got %#v", state.Windows)
+	}
+	byKey := map[string]string{}
+	for _, window := range state.Windows {
+		byKey[window.Key] = window.WorkspaceID
+	}
+	if byKey["w:kitty:11"] != "4" || byKey["w:firefox:12"] != "4" || byKey["w:thunderbird:20"] != "5" {
+		t.Fatalf("unexpected windows: %#v", state.Windows)
+	}
+}
+
+func TestSwayBackendSnapshotAndActions(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{outputs: map[string]string{
+		"swaymsg -r -t get_tree":                                    `{"type":"root"}`,
+		`swaymsg -r [con_id=11] move container to workspace "mail"`: `[{"success":true}]`,
+		`swaymsg -r workspace "my \"ws\""`:                          `[{"success":false,"error":"denied"}]`,
+	}}
+	backend := &SwayBackend{Runner: runner}
+	ctx := context.Background()
+
+	raw, err := backend.Snapshot(ctx)
+	if err != nil || string(raw) != `{"type":"root"}` {
+		t.Fatalf("unexpected snapshot %q, err %v", raw, err)
+	}
+	if err := backend.MoveToWorkspace(ctx, 11, "mail"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if err := backend.FocusWorkspace(ctx, `my "ws"`); err == nil {
+		t.Fatal("expected failed sway command to be reported")
+	}
+	if err := backend.CloseWindow(ctx, 11); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if runner.calls[len(runner.calls)-1] != "swaymsg -r [con_id=11] kill" {
+		t.Fatalf("unexpected close call: %q", runner.calls[len(runner.calls)-1])
+	}
+}
+
+func TestSwayBackendEvents(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{stream: `{
+  "change": "new",
+  "container": {"id": 11, "app_id": "kitty"}
+}
+{
+  "change": "focus",
+  "current": {"id": 4, "name": "1"}
+}
+`}
+	events, err := (&SwayBackend{Runner: runner}).Events(context.Background())
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	got := collectEvents(t, events)
+	if len(got) != 2 || got[0].Kind != "window:new" || got[1].Kind != "workspace:focus" {
+		t.Fatalf("unexpected events: %#v", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -16,22 +17,81 @@ type Config struct {
 	StateDir        string                `yaml:"stateDir"`
 	Host            string                `yaml:"host"`
 	Profile         string                `yaml:"profile"`
+	Compositor      string                `yaml:"compositor"`
 	Capture         CaptureConfig         `yaml:"capture"`
 	ProcessMetadata ProcessMetadataConfig `yaml:"processMetadata"`
 	Retention       RetentionConfig       `yaml:"retention"`
 	Restore         RestoreConfig         `yaml:"restore"`
+	Terminals       []TerminalRule        `yaml:"terminals"`
+	Daemon          DaemonConfig          `yaml:"daemon"`
+	Metrics         MetricsConfig         `yaml:"metrics"`
+	Log             LogConfig             `yaml:"log"`
+	Hooks           []HookRule            `yaml:"hooks"`
+	Redact          RedactConfig          `yaml:"redact"`
+}
+
+type RedactConfig struct {
+	Titles        []RedactTitleRule `yaml:"titles"`
+	CWDPrefixes   []RedactCWDRule   `yaml:"cwdPrefixes"`
+	ExcludeAppIDs []string          `yaml:"excludeAppIds"`
+}
+
+type RedactTitleRule struct {
+	AppID       string `yaml:"appId"`
+	Mode        string `yaml:"mode"`
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+type RedactCWDRule struct {
+	Prefix      string `yaml:"prefix"`
+	Replacement string `yaml:"replacement"`
+}
+
+type HookRule struct {
+	Event   string        `yaml:"event"`
+	Command string        `yaml:"command"`
+	AppID   string        `yaml:"appId"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+type MetricsConfig struct {
+	Listen   string `yaml:"listen"`
+	Textfile string `yaml:"textfile"`
+}
+
+type DaemonConfig struct {
+	Socket  string `yaml:"socket"`
+	Connect bool   `yaml:"connect"`
+}
+
+type TerminalRule struct {
+	AppID string `yaml:"appId"`
+	Kind  string `yaml:"kind"`
 }
 
 type CaptureConfig struct {
 	Interval      time.Duration `yaml:"interval"`
 	SnapshotEvery int           `yaml:"snapshotEvery"`
 	NiriCommand   string        `yaml:"niriCommand"`
+	OnEvents      bool          `yaml:"onEvents"`
+	EventDebounce time.Duration `yaml:"eventDebounce"`
 }
 
 type ProcessMetadataConfig struct {
 	Whitelist         []string `yaml:"whitelist"`
 	WhitelistExtra    []string `yaml:"whitelistExtra"`
 	IncludeSessionTag bool     `yaml:"includeSessionTag"`
+	KittyTabs         bool     `yaml:"kittyTabs"`
+	WezTermPanes      bool     `yaml:"weztermPanes"`
+	ZellijLayouts     bool     `yaml:"zellijLayouts"`
+	Nvim              bool     `yaml:"nvim"`
+	Git               bool     `yaml:"git"`
 }
 
 type RetentionConfig struct {
@@ -39,16 +99,51 @@ type RetentionConfig struct {
 }
 
 type RestoreConfig struct {
-	AppAllowlist             map[string]string `yaml:"appAllowlist"`
-	AppMode                  map[string]string `yaml:"appMode"`
-	ReconcileWorkspaceMoves  bool              `yaml:"reconcileWorkspaceMoves"`
-	WorkspaceReconcileDelay  time.Duration     `yaml:"workspaceReconcileDelay"`
-	Terminal                 TerminalConfig    `yaml:"terminal"`
+	AppAllowlist            map[string]string `yaml:"appAllowlist"`
+	AppMode                 map[string]string `yaml:"appMode"`
+	AppTitlePatterns        map[string]string `yaml:"appTitlePatterns"`
+	Relaunch                map[string]string `yaml:"relaunch"`
+	ReconcileWorkspaceMoves bool              `yaml:"reconcileWorkspaceMoves"`
+	WorkspaceReconcileDelay time.Duration     `yaml:"workspaceReconcileDelay"`
+	ReconcileStrategy       string            `yaml:"reconcileStrategy"`
+	SpawnWindowTimeout      time.Duration     `yaml:"spawnWindowTimeout"`
+	Readiness               ReadinessConfig   `yaml:"readiness"`
+	Execution               ExecutionConfig   `yaml:"execution"`
+	Terminal                TerminalConfig    `yaml:"terminal"`
+	Remap                   RemapConfig       `yaml:"remap"`
+}
+
+type RemapConfig struct {
+	CWDPrefixes []RemapCWDRule    `yaml:"cwdPrefixes"`
+	Workspaces  map[string]string `yaml:"workspaces"`
+	AppIDs      map[string]string `yaml:"appIds"`
+}
+
+type RemapCWDRule struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+type ExecutionConfig struct {
+	MaxInFlight int            `yaml:"maxInFlight"`
+	LaunchDelay time.Duration  `yaml:"launchDelay"`
+	AppPhases   map[string]int `yaml:"appPhases"`
+}
+
+type ReadinessConfig struct {
+	Enabled     bool                     `yaml:"enabled"`
+	Timeout     time.Duration            `yaml:"timeout"`
+	AppTimeouts map[string]time.Duration `yaml:"appTimeouts"`
 }
 
 type TerminalConfig struct {
 	Command              string `yaml:"command"`
 	ZellijAttachOrCreate bool   `yaml:"zellijAttachOrCreate"`
+	TmuxAttachOrCreate   bool   `yaml:"tmuxAttachOrCreate"`
+	RestoreNvim          bool   `yaml:"restoreNvim"`
+	MissingCWD           string `yaml:"missingCwd"`
+	VerifyGitBranch      bool   `yaml:"verifyGitBranch"`
+	RestoreGitWorktree   bool   `yaml:"restoreGitWorktree"`
 }
 
 func DefaultStateDir() string {
@@ -75,29 +170,63 @@ func DefaultConfigPath() string {
 
 func Defaults() Config {
 	return Config{
-		StateDir: DefaultStateDir(),
-		Host:     "local",
-		Profile:  "default",
+		StateDir:   DefaultStateDir(),
+		Host:       "local",
+		Profile:    "default",
+		Compositor: "niri",
 		Capture: CaptureConfig{
 			Interval:      60 * time.Second,
 			SnapshotEvery: 100,
 			NiriCommand:   "niri msg -j windows",
+			EventDebounce: 2 * time.Second,
 		},
 		ProcessMetadata: ProcessMetadataConfig{
 			Whitelist:         []string{},
 			WhitelistExtra:    []string{},
 			IncludeSessionTag: true,
+			ZellijLayouts:     true,
 		},
 		Retention: RetentionConfig{Days: 30},
 		Restore: RestoreConfig{
 			AppAllowlist:            map[string]string{},
 			AppMode:                 map[string]string{},
+			AppTitlePatterns:        map[string]string{},
+			Relaunch:                map[string]string{},
 			ReconcileWorkspaceMoves: true,
 			WorkspaceReconcileDelay: 1200 * time.Millisecond,
+			ReconcileStrategy:       "move",
+			SpawnWindowTimeout:      5 * time.Second,
+			Readiness: ReadinessConfig{
+				Enabled:     false,
+				Timeout:     10 * time.Second,
+				AppTimeouts: map[string]time.Duration{},
+			},
+			Execution: ExecutionConfig{
+				MaxInFlight: 1,
+				LaunchDelay: 0,
+				AppPhases:   map[string]int{},
+			},
 			Terminal: TerminalConfig{
 				Command:              "kitty",
 				ZellijAttachOrCreate: true,
+				TmuxAttachOrCreate:   true,
+				RestoreNvim:          true,
+				MissingCWD:           "parent",
 			},
+			Remap: RemapConfig{
+				CWDPrefixes: []RemapCWDRule{},
+				Workspaces:  map[string]string{},
+				AppIDs:      map[string]string{},
+			},
+		},
+		Terminals: []TerminalRule{},
+		Daemon:    DaemonConfig{Connect: true},
+		Log:       LogConfig{Level: "info", Format: "text"},
+		Hooks:     []HookRule{},
+		Redact: RedactConfig{
+			Titles:        []RedactTitleRule{},
+			CWDPrefixes:   []RedactCWDRule{},
+			ExcludeAppIDs: []string{},
 		},
 	}
 }
@@ -125,12 +254,121 @@ func Load(path string, explicitPath bool) (Config, error) {
 		return Config{}, fmt.Errorf("parse config file: %w", err)
 	}
 
+	switch strings.ToLower(strings.TrimSpace(cfg.Compositor)) {
+	case "niri", "sway", "hyprland":
+		cfg.Compositor = strings.ToLower(strings.TrimSpace(cfg.Compositor))
+	case "":
+		cfg.Compositor = "niri"
+	default:
+		return Config{}, fmt.Errorf("unsupported compositor %q (want niri, sway or hyprland)", cfg.Compositor)
+	}
+	if err := ValidateLogLevel(cfg.Log.Level); err != nil {
+		return Config{}, err
+	}
+	if err := ValidateLogFormat(cfg.Log.Format); err != nil {
+		return Config{}, err
+	}
+
 	if cfg.Restore.AppAllowlist == nil {
 		cfg.Restore.AppAllowlist = map[string]string{}
 	}
 	if cfg.Restore.AppMode == nil {
 		cfg.Restore.AppMode = map[string]string{}
 	}
+	if cfg.Restore.AppTitlePatterns == nil {
+		cfg.Restore.AppTitlePatterns = map[string]string{}
+	}
+	if cfg.Restore.Relaunch == nil {
+		cfg.Restore.Relaunch = map[string]string{}
+	}
+	if cfg.Restore.Readiness.AppTimeouts == nil {
+		cfg.Restore.Readiness.AppTimeouts = map[string]time.Duration{}
+	}
+	if cfg.Restore.Execution.AppPhases == nil {
+		cfg.Restore.Execution.AppPhases = map[string]int{}
+	}
+	if cfg.Restore.Remap.CWDPrefixes == nil {
+		cfg.Restore.Remap.CWDPrefixes = []RemapCWDRule{}
+	}
+	if cfg.Restore.Remap.Workspaces == nil {
+		cfg.Restore.Remap.Workspaces = map[string]string{}
+	}
+	if cfg.Restore.Remap.AppIDs == nil {
+		cfg.Restore.Remap.AppIDs = map[string]string{}
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Restore.Terminal.MissingCWD)) {
+	case "parent", "skip":
+		cfg.Restore.Terminal.MissingCWD = strings.ToLower(strings.TrimSpace(cfg.Restore.Terminal.MissingCWD))
+	case "":
+		cfg.Restore.Terminal.MissingCWD = "parent"
+	default:
+		return Config{}, fmt.Errorf("unsupported restore.terminal.missingCwd %q (want parent or skip)", cfg.Restore.Terminal.MissingCWD)
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Restore.ReconcileStrategy)) {
+	case "move", "spawn":
+		cfg.Restore.ReconcileStrategy = strings.ToLower(strings.TrimSpace(cfg.Restore.ReconcileStrategy))
+	case "":
+		cfg.Restore.ReconcileStrategy = "move"
+	default:
+		return Config{}, fmt.Errorf("unsupported restore.reconcileStrategy %q (want move or spawn)", cfg.Restore.ReconcileStrategy)
+	}
+	for i, rule := range cfg.Restore.Remap.CWDPrefixes {
+		if strings.TrimSpace(rule.From) == "" || strings.TrimSpace(rule.To) == "" {
+			return Config{}, fmt.Errorf("restore.remap.cwdPrefixes[%d]: from and to are required", i)
+		}
+	}
+	if cfg.Terminals == nil {
+		cfg.Terminals = []TerminalRule{}
+	}
+	for i, rule := range cfg.Terminals {
+		if strings.TrimSpace(rule.AppID) == "" || strings.TrimSpace(rule.Kind) == "" {
+			return Config{}, fmt.Errorf("terminals[%d]: appId and kind are required", i)
+		}
+		cfg.Terminals[i].Kind = strings.ToLower(strings.TrimSpace(rule.Kind))
+	}
+	if cfg.Hooks == nil {
+		cfg.Hooks = []HookRule{}
+	}
+	for i, hook := range cfg.Hooks {
+		switch hook.Event {
+		case "capture.state_changed", "capture.snapshot_written", "capture.window_appeared",
+			"restore.item_before", "restore.item_after", "restore.run_after",
+			"prune.before", "prune.after":
+		default:
+			return Config{}, fmt.Errorf("hooks[%d]: unsupported event %q", i, hook.Event)
+		}
+		if strings.TrimSpace(hook.Command) == "" {
+			return Config{}, fmt.Errorf("hooks[%d]: command is required", i)
+		}
+	}
+	if cfg.Redact.Titles == nil {
+		cfg.Redact.Titles = []RedactTitleRule{}
+	}
+	if cfg.Redact.CWDPrefixes == nil {
+		cfg.Redact.CWDPrefixes = []RedactCWDRule{}
+	}
+	if cfg.Redact.ExcludeAppIDs == nil {
+		cfg.Redact.ExcludeAppIDs = []string{}
+	}
+	for i, rule := range cfg.Redact.Titles {
+		switch strings.ToLower(strings.TrimSpace(rule.Mode)) {
+		case "drop", "hash":
+		case "replace":
+			if rule.Pattern == "" {
+				return Config{}, fmt.Errorf("redact.titles[%d]: pattern is required for mode replace", i)
+			}
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return Config{}, fmt.Errorf("redact.titles[%d]: invalid pattern: %w", i, err)
+			}
+		default:
+			return Config{}, fmt.Errorf("redact.titles[%d]: unsupported mode %q (want drop, hash or replace)", i, rule.Mode)
+		}
+	}
+	for i, rule := range cfg.Redact.CWDPrefixes {
+		if strings.TrimSpace(rule.Prefix) == "" {
+			return Config{}, fmt.Errorf("redact.cwdPrefixes[%d]: prefix is required", i)
+		}
+	}
 	if cfg.ProcessMetadata.Whitelist == nil {
 		cfg.ProcessMetadata.Whitelist = []string{}
 	}
@@ -140,3 +378,21 @@ func Load(path string, explicitPath bool) (Config, error) {
 
 	return cfg, nil
 }
+
+func ValidateLogLevel(level string) error {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug", "info", "warn", "error":
+		return nil
+	default:
+		return fmt.Errorf("unsupported log level %q (want debug, info, warn or error)", level)
+	}
+}
+
+func ValidateLogFormat(format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "text", "json":
+		return nil
+	default:
+		return fmt.Errorf("unsupported log format %q (want text or json)", format)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -37,6 +38,18 @@ func TestLoadMissingDefaultPathUsesDefaults(t *testing.T) {
 	if cfg.Capture.Interval != 60*time.Second {
 		t.Fatalf("expected default interval 60s, got %s", cfg.Capture.Interval)
 	}
+	if cfg.Compositor != "niri" {
+		t.Fatalf("expected default compositor niri, got %q", cfg.Compositor)
+	}
+	if !cfg.Daemon.Connect || cfg.Daemon.Socket != "" {
+		t.Fatalf("unexpected default daemon config: %+v", cfg.Daemon)
+	}
+	if cfg.Metrics.Listen != "" || cfg.Metrics.Textfile != "" {
+		t.Fatalf("expected metrics disabled by default, got %+v", cfg.Metrics)
+	}
+	if cfg.Log.Level != "info" || cfg.Log.Format != "text" {
+		t.Fatalf("unexpected default log config: %+v", cfg.Log)
+	}
 	if !cfg.Restore.ReconcileWorkspaceMoves {
 		t.Fatalf("expected reconcile workspace moves default true")
 	}
@@ -52,6 +65,95 @@ func TestLoadMissingExplicitPathReturnsError(t *testing.T) {
 	}
 }
 
+func TestLoadRejectsUnknownCompositor(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("compositor: river\n"), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	if _, err := Load(configPath, true); err == nil {
+		t.Fatal("expected error for unknown compositor")
+	}
+}
+
+func TestLoadNormalizesCompositorCase(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("compositor: \" Niri \"\n"), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	cfg, err := Load(configPath, true)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Compositor != "niri" {
+		t.Fatalf("expected compositor niri, got %q", cfg.Compositor)
+	}
+}
+
+func TestLoadRejectsUnknownLogSettings(t *testing.T) {
+	for _, payload := range []string{"log:\n  level: verbose\n", "log:\n  format: logfmt\n"} {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte(payload), 0o600); err != nil {
+			t.Fatalf("write config file: %v", err)
+		}
+		if _, err := Load(configPath, true); err == nil {
+			t.Fatalf("expected error for %q", payload)
+		}
+	}
+}
+
+func TestLoadRejectsInvalidHooks(t *testing.T) {
+	for _, payload := range []string{
+		"hooks:\n  - event: capture.exploded\n    command: true\n",
+		"hooks:\n  - event: prune.before\n",
+	} {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte(payload), 0o600); err != nil {
+			t.Fatalf("write config file: %v", err)
+		}
+		if _, err := Load(configPath, true); err == nil {
+			t.Fatalf("expected error for %q", payload)
+		}
+	}
+}
+
+func TestLoadValidatesRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{name: "unknown title mode", payload: "redact:\n  titles:\n    - appId: firefox\n      mode: blur\n", wantErr: "redact.titles[0]"},
+		{name: "invalid replace pattern", payload: "redact:\n  titles:\n    - appId: kitty\n      mode: replace\n      pattern: \"(\"\n", wantErr: "redact.titles[0]"},
+		{name: "cwd prefix without prefix", payload: "redact:\n  cwdPrefixes:\n    - replacement: /x\n", wantErr: "redact.cwdPrefixes[0]"},
+		{name: "remap without to", payload: "restore:\n  remap:\n    cwdPrefixes:\n      - from: /home/desk\n", wantErr: "restore.remap.cwdPrefixes[0]"},
+		{name: "unknown missing cwd mode", payload: "restore:\n  terminal:\n    missingCwd: home\n", wantErr: "restore.terminal.missingCwd"},
+		{name: "unknown reconcile strategy", payload: "restore:\n  reconcileStrategy: spwan\n", wantErr: "restore.reconcileStrategy"},
+		{name: "terminal without kind", payload: "terminals:\n  - appId: org.kde.konsole\n", wantErr: "terminals[0]"},
+		{name: "capture-only terminal kind", payload: "terminals:\n  - appId: org.kde.konsole\n    kind: Konsole\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.payload), 0o600); err != nil {
+				t.Fatalf("write config file: %v", err)
+			}
+			cfg, err := Load(configPath, true)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected config to load, got %v", err)
+				}
+				if len(cfg.Terminals) != 1 || cfg.Terminals[0].Kind != "konsole" {
+					t.Fatalf("expected terminal kind normalized, got %+v", cfg.Terminals)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestLoadYAMLMergesOverDefaults(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
@@ -59,15 +161,22 @@ func TestLoadYAMLMergesOverDefaults(t *testing.T) {
 	configPath := filepath.Join(t.TempDir(), "config.yaml")
 	err := os.WriteFile(configPath, []byte(`stateDir: /tmp/redeem
 host: host-a
+compositor: sway
 capture:
   interval: 15s
   snapshotEvery: 5
+  onEvents: true
+  eventDebounce: 500ms
 processMetadata:
   whitelist:
     - zellij
   whitelistExtra:
     - tmux
   includeSessionTag: false
+  kittyTabs: true
+  weztermPanes: true
+  nvim: true
+  git: true
 retention:
   days: 14
 restore:
@@ -75,11 +184,73 @@ restore:
     firefox: firefox --new-window
   appMode:
     firefox: oneshot
+  appTitlePatterns:
+    code: "— (?P<project>\\S+)$"
+  relaunch:
+    claude: ""
+    opencode: "opencode --continue"
   reconcileWorkspaceMoves: false
   workspaceReconcileDelay: 3s
+  reconcileStrategy: spawn
+  spawnWindowTimeout: 8s
+  readiness:
+    enabled: true
+    timeout: 4s
+    appTimeouts:
+      firefox: 20s
+  execution:
+    maxInFlight: 4
+    launchDelay: 150ms
+    appPhases:
+      firefox: 0
+      kitty: 1
   terminal:
     command: foot
     zellijAttachOrCreate: false
+    tmuxAttachOrCreate: false
+    missingCwd: skip
+    verifyGitBranch: true
+    restoreGitWorktree: true
+  remap:
+    cwdPrefixes:
+      - from: /home/desk
+        to: /home/laptop
+    workspaces:
+      dev: "2"
+    appIds:
+      org.gnome.Nautilus: thunar
+terminals:
+  - appId: "kitty-*"
+    kind: kitty
+daemon:
+  socket: /run/user/1000/redeem.sock
+  connect: false
+metrics:
+  listen: 127.0.0.1:9464
+  textfile: /var/lib/node-exporter/redeem.prom
+log:
+  level: debug
+  format: json
+hooks:
+  - event: capture.window_appeared
+    appId: firefox
+    command: notify-send firefox
+    timeout: 3s
+  - event: restore.run_after
+    command: ~/bin/after-restore
+redact:
+  titles:
+    - appId: firefox
+      mode: drop
+    - appId: kitty
+      mode: replace
+      pattern: 'ssh \S+'
+      replacement: ssh <host>
+  cwdPrefixes:
+    - prefix: /home/me/clients
+      replacement: ~clients
+  excludeAppIds:
+    - org.keepassxc.KeePassXC
 `), 0o600)
 	if err != nil {
 		t.Fatalf("write config file: %v", err)
@@ -105,6 +276,12 @@ restore:
 	if cfg.Capture.SnapshotEvery != 5 {
 		t.Fatalf("expected snapshotEvery 5, got %d", cfg.Capture.SnapshotEvery)
 	}
+	if cfg.Compositor != "sway" {
+		t.Fatalf("expected compositor sway, got %q", cfg.Compositor)
+	}
+	if !cfg.Capture.OnEvents || cfg.Capture.EventDebounce != 500*time.Millisecond {
+		t.Fatalf("unexpected capture event config: %+v", cfg.Capture)
+	}
 	if len(cfg.ProcessMetadata.Whitelist) != 1 || cfg.ProcessMetadata.Whitelist[0] != "zellij" {
 		t.Fatalf("unexpected whitelist: %#v", cfg.ProcessMetadata.Whitelist)
 	}
@@ -114,12 +291,27 @@ restore:
 	if cfg.ProcessMetadata.IncludeSessionTag != false {
 		t.Fatalf("expected includeSessionTag false, got %v", cfg.ProcessMetadata.IncludeSessionTag)
 	}
+	if !cfg.ProcessMetadata.KittyTabs {
+		t.Fatal("expected kittyTabs true")
+	}
+	if !cfg.ProcessMetadata.WezTermPanes {
+		t.Fatal("expected weztermPanes true")
+	}
+	if !cfg.ProcessMetadata.Nvim {
+		t.Fatal("expected nvim true")
+	}
+	if !cfg.ProcessMetadata.Git {
+		t.Fatal("expected git true")
+	}
 	if cfg.Retention.Days != 14 {
 		t.Fatalf("expected retention days 14, got %d", cfg.Retention.Days)
 	}
 	if cfg.Restore.Terminal.Command != "foot" {
 		t.Fatalf("expected terminal command foot, got %q", cfg.Restore.Terminal.Command)
 	}
+	if cfg.Restore.Terminal.TmuxAttachOrCreate != false {
+		t.Fatalf("expected tmuxAttachOrCreate false, got %v", cfg.Restore.Terminal.TmuxAttachOrCreate)
+	}
 	if cfg.Restore.Terminal.ZellijAttachOrCreate != false {
 		t.Fatalf("expected zellijAttachOrCreate false, got %v", cfg.Restore.Terminal.ZellijAttachOrCreate)
 	}
@@ -129,10 +321,65 @@ restore:
 	if cfg.Restore.AppMode["firefox"] != "oneshot" {
 		t.Fatalf("unexpected app mode: %#v", cfg.Restore.AppMode)
 	}
+	if cfg.Restore.AppTitlePatterns["code"] != `— (?P<project>\S+)$` {
+		t.Fatalf("unexpected app title patterns: %#v", cfg.Restore.AppTitlePatterns)
+	}
+	if command, ok := cfg.Restore.Relaunch["claude"]; !ok || command != "" || cfg.Restore.Relaunch["opencode"] != "opencode --continue" {
+		t.Fatalf("unexpected relaunch config: %#v", cfg.Restore.Relaunch)
+	}
 	if cfg.Restore.ReconcileWorkspaceMoves {
 		t.Fatalf("expected reconcileWorkspaceMoves false, got true")
 	}
 	if cfg.Restore.WorkspaceReconcileDelay != 3*time.Second {
 		t.Fatalf("expected workspaceReconcileDelay 3s, got %s", cfg.Restore.WorkspaceReconcileDelay)
 	}
+	if cfg.Restore.ReconcileStrategy != "spawn" {
+		t.Fatalf("expected reconcileStrategy spawn, got %q", cfg.Restore.ReconcileStrategy)
+	}
+	if cfg.Restore.SpawnWindowTimeout != 8*time.Second {
+		t.Fatalf("expected spawnWindowTimeout 8s, got %s", cfg.Restore.SpawnWindowTimeout)
+	}
+	if !cfg.Restore.Readiness.Enabled || cfg.Restore.Readiness.Timeout != 4*time.Second {
+		t.Fatalf("unexpected readiness config: %+v", cfg.Restore.Readiness)
+	}
+	if cfg.Restore.Readiness.AppTimeouts["firefox"] != 20*time.Second {
+		t.Fatalf("unexpected readiness app timeouts: %#v", cfg.Restore.Readiness.AppTimeouts)
+	}
+	if cfg.Restore.Execution.MaxInFlight != 4 || cfg.Restore.Execution.LaunchDelay != 150*time.Millisecond {
+		t.Fatalf("unexpected execution config: %+v", cfg.Restore.Execution)
+	}
+	if cfg.Restore.Execution.AppPhases["kitty"] != 1 {
+		t.Fatalf("unexpected execution app phases: %#v", cfg.Restore.Execution.AppPhases)
+	}
+	if cfg.Daemon.Socket != "/run/user/1000/redeem.sock" || cfg.Daemon.Connect {
+		t.Fatalf("unexpected daemon config: %+v", cfg.Daemon)
+	}
+	if cfg.Metrics.Listen != "127.0.0.1:9464" || cfg.Metrics.Textfile != "/var/lib/node-exporter/redeem.prom" {
+		t.Fatalf("unexpected metrics config: %+v", cfg.Metrics)
+	}
+	if cfg.Log.Level != "debug" || cfg.Log.Format != "json" {
+		t.Fatalf("unexpected log config: %+v", cfg.Log)
+	}
+	if len(cfg.Hooks) != 2 || cfg.Hooks[0].AppID != "firefox" || cfg.Hooks[0].Timeout != 3*time.Second || cfg.Hooks[1].Event != "restore.run_after" {
+		t.Fatalf("unexpected hooks: %+v", cfg.Hooks)
+	}
+	if len(cfg.Redact.Titles) != 2 || cfg.Redact.Titles[1].Pattern != `ssh \S+` || cfg.Redact.Titles[1].Replacement != "ssh <host>" {
+		t.Fatalf("unexpected redact titles: %+v", cfg.Redact.Titles)
+	}
+	if len(cfg.Redact.CWDPrefixes) != 1 || cfg.Redact.CWDPrefixes[0].Replacement != "~clients" || len(cfg.Redact.ExcludeAppIDs) != 1 {
+		t.Fatalf("unexpected redact config: %+v", cfg.Redact)
+	}
+	if cfg.Restore.Terminal.MissingCWD != "skip" {
+		t.Fatalf("expected missingCwd skip, got %q", cfg.Restore.Terminal.MissingCWD)
+	}
+	if !cfg.Restore.Terminal.VerifyGitBranch || !cfg.Restore.Terminal.RestoreGitWorktree {
+		t.Fatalf("expected git restore options enabled, got %+v", cfg.Restore.Terminal)
+	}
+	remap := cfg.Restore.Remap
+	if len(remap.CWDPrefixes) != 1 || remap.CWDPrefixes[0].To != "/home/laptop" || remap.Workspaces["dev"] != "2" || remap.AppIDs["org.gnome.Nautilus"] != "thunar" {
+		t.Fatalf("unexpected restore remap: %+v", remap)
+	}
+	if len(cfg.Terminals) != 1 || cfg.Terminals[0].AppID != "kitty-*" || cfg.Terminals[0].Kind != "kitty" {
+		t.Fatalf("unexpected terminals: %#v", cfg.Terminals)
+	}
 }
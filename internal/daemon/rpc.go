@@ -0,0 +1,328 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	CodeParseError     = -32700
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeServerError    = -32000
+)
+
+const MethodCancel = "cancel"
+
+var ErrAlreadyRunning = errors.New("daemon already running")
+
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type CancelParams struct {
+	ID int64 `json:"id"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("daemon error %d: %s", e.Code, e.Message)
+}
+
+type Handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+type Server struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewServer() *Server {
+	return &Server{handlers: make(map[string]Handler)}
+}
+
+func (s *Server) Handle(method string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = handler
+}
+
+func SocketPath(stateDir string) string {
+	return filepath.Join(stateDir, "daemon.sock")
+}
+
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create socket dir: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		conn, dialErr := net.DialTimeout("unix", path, 500*time.Millisecond)
+		if dialErr == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%w: %s", ErrAlreadyRunning, path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("restrict socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	var mu sync.Mutex
+	cancels := make(map[int64]context.CancelFunc)
+	queue := make(chan queuedRequest)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(queue)
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			var request Request
+			_ = json.Unmarshal(line, &request)
+			if request.Method == MethodCancel {
+				var params CancelParams
+				if decodeParams(request.Params, &params) == nil {
+					mu.Lock()
+					if cancel, ok := cancels[params.ID]; ok {
+						cancel()
+					}
+					mu.Unlock()
+				}
+				continue
+			}
+			requestCtx, cancel := context.WithCancel(ctx)
+			mu.Lock()
+			cancels[request.ID] = cancel
+			mu.Unlock()
+			select {
+			case queue <- queuedRequest{ctx: requestCtx, cancel: cancel, id: request.ID, line: line}:
+			case <-done:
+				cancel()
+				return
+			}
+		}
+	}()
+
+	encoder := json.NewEncoder(conn)
+	for request := range queue {
+		response := s.dispatch(request.ctx, request.line)
+		mu.Lock()
+		delete(cancels, request.id)
+		mu.Unlock()
+		request.cancel()
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+	}
+}
+
+type queuedRequest struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	id     int64
+	line   []byte
+}
+
+func (s *Server) dispatch(ctx context.Context, line []byte) Response {
+	var request Request
+	if err := json.Unmarshal(line, &request); err != nil {
+		return Response{JSONRPC: "2.0", Error: &Error{Code: CodeParseError, Message: err.Error()}}
+	}
+	response := Response{JSONRPC: "2.0", ID: request.ID}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[request.Method]
+	s.mu.RUnlock()
+	if !ok {
+		response.Error = &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %q", request.Method)}
+		return response
+	}
+
+	result, err := handler(ctx, request.Params)
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			response.Error = rpcErr
+		} else {
+			response.Error = &Error{Code: CodeServerError, Message: err.Error()}
+		}
+		return response
+	}
+	payload, err := json.Marshal(result)
+	if err != nil {
+		response.Error = &Error{Code: CodeServerError, Message: fmt.Sprintf("encode result: %v", err)}
+		return response
+	}
+	response.Result = payload
+	return response
+}
+
+func decodeParams(raw json.RawMessage, out any) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+type Client struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	scanner *bufio.Scanner
+	nextID  int64
+}
+
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	return &Client{conn: conn, scanner: scanner}, nil
+}
+
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	request := Request{JSONRPC: "2.0", ID: c.nextID, Method: method}
+	if params != nil {
+		payload, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("encode params: %w", err)
+		}
+		request.Params = payload
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("send %s: %w", method, err)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		if errors.Is(ctx.Err(), context.Canceled) && c.cancel(id) == nil {
+			return
+		}
+		_ = c.conn.SetDeadline(time.Now())
+	})
+	defer stop()
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return fmt.Errorf("read %s response: %w", method, err)
+		}
+		return fmt.Errorf("read %s response: connection closed", method)
+	}
+
+	var response Response
+	if err := json.Unmarshal(c.scanner.Bytes(), &response); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+func (c *Client) cancel(id int64) error {
+	params, err := json.Marshal(CancelParams{ID: id})
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(Request{JSONRPC: "2.0", Method: MethodCancel, Params: params})
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.Write(append(payload, '\n'))
+	return err
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServerAndClientRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	server.Handle("echo", func(_ context.Context, raw json.RawMessage) (any, error) {
+		var params map[string]string
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		return params, nil
+	})
+	server.Handle("fail", func(context.Context, json.RawMessage) (any, error) {
+		return nil, errors.New("boom")
+	})
+	path := startServer(t, server)
+
+	client, err := Dial(path)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	var echoed map[string]string
+	if err := client.Call(context.Background(), "echo", map[string]string{"hello": "world"}, &echoed); err != nil {
+		t.Fatalf("call echo: %v", err)
+	}
+	if echoed["hello"] != "world" {
+		t.Fatalf("unexpected echo result: %#v", echoed)
+	}
+
+	var rpcErr *Error
+	err = client.Call(context.Background(), "fail", nil, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeServerError || rpcErr.Message != "boom" {
+		t.Fatalf("expected server error, got %v", err)
+	}
+	err = client.Call(context.Background(), "missing", nil, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Fatalf("expected method not found, got %v", err)
+	}
+	err = client.Call(context.Background(), "echo", []string{"not", "an", "object"}, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Fatalf("expected invalid params, got %v", err)
+	}
+}
+
+func TestClientCancelStopsHandlerAndKeepsResult(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer()
+	server.Handle("wait", func(ctx context.Context, _ json.RawMessage) (any, error) {
+		select {
+		case <-ctx.Done():
+			return "cancelled", nil
+		case <-time.After(10 * time.Second):
+			return "finished", nil
+		}
+	})
+	client, err := Dial(startServer(t, server))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	var result string
+	if err := client.Call(ctx, "wait", nil, &result); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if result != "cancelled" {
+		t.Fatalf("expected handler to observe the cancel, got %q", result)
+	}
+	if err := client.Call(ctx, "wait", nil, &result); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled context to fail before sending, got %v", err)
+	}
+}
+
+func TestListenRefusesLiveSocketAndReplacesStaleOne(t *testing.T) {
+	t.Parallel()
+
+	path := startServer(t, NewServer())
+	if _, err := Listen(path); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("expected already running error, got %v", err)
+	}
+
+	stale := filepath.Join(t.TempDir(), "daemon.sock")
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatalf("write stale socket: %v", err)
+	}
+	listener, err := Listen(stale)
+	if err != nil {
+		t.Fatalf("listen over stale socket: %v", err)
+	}
+	_ = listener.Close()
+}
+
+func startServer(t *testing.T, server *Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "daemon.sock")
+	listener, err := Listen(path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected private socket, got %v %v", info, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	})
+	return path
+}
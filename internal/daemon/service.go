@@ -0,0 +1,302 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/capture"
+	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/replay"
+	"github.com/jmo/terminal-redeemer/internal/restore"
+)
+
+const (
+	MethodStatus       = "status"
+	MethodState        = "state"
+	MethodCapture      = "capture"
+	MethodHistoryList  = "history.list"
+	MethodRestorePlan  = "restore.plan"
+	MethodRestoreApply = "restore.apply"
+)
+
+type Capturer interface {
+	Capture(ctx context.Context) (capture.Result, error)
+	CaptureOnce(ctx context.Context) (capture.Result, error)
+	State() (model.State, bool)
+}
+
+type Planner interface {
+	Build(state model.State) restore.Plan
+}
+
+type ApplyFunc func(ctx context.Context, restoredAt time.Time, plan restore.Plan) (string, error)
+
+type Config struct {
+	StateDir   string
+	Host       string
+	Profile    string
+	CaptureKey string
+	RestoreKey string
+	Capturer   Capturer
+	Planner    Planner
+	Apply      ApplyFunc
+	Now        func() time.Time
+	Logger     *slog.Logger
+}
+
+type Service struct {
+	config Config
+
+	captureMu sync.Mutex
+
+	mu            sync.Mutex
+	state         model.State
+	hasState      bool
+	startedAt     time.Time
+	lastCaptureAt time.Time
+	lastError     string
+	captures      int
+}
+
+type Status struct {
+	PID           int        `json:"pid"`
+	StateDir      string     `json:"state_dir"`
+	Host          string     `json:"host"`
+	Profile       string     `json:"profile"`
+	StartedAt     time.Time  `json:"started_at"`
+	LastCaptureAt *time.Time `json:"last_capture_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Captures      int        `json:"captures"`
+	Windows       int        `json:"windows"`
+	StateHash     string     `json:"state_hash,omitempty"`
+	CaptureKey    string     `json:"capture_key,omitempty"`
+	RestoreKey    string     `json:"restore_key,omitempty"`
+}
+
+type CaptureResult struct {
+	EventsWritten int    `json:"events_written"`
+	SnapshotPath  string `json:"snapshot_path,omitempty"`
+	StateHash     string `json:"state_hash"`
+}
+
+type CaptureParams struct {
+	Full bool `json:"full,omitempty"`
+}
+
+type AtParams struct {
+	At *time.Time `json:"at,omitempty"`
+}
+
+type HistoryParams struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+type ApplyParams struct {
+	At *time.Time `json:"at,omitempty"`
+}
+
+type ApplyResult struct {
+	Output string `json:"output"`
+}
+
+func NewService(config Config) *Service {
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if config.Logger == nil {
+		config.Logger = slog.New(slog.DiscardHandler)
+	}
+	return &Service{config: config, startedAt: config.Now().UTC()}
+}
+
+func (s *Service) Register(server *Server) {
+	server.Handle(MethodStatus, func(_ context.Context, _ json.RawMessage) (any, error) {
+		return s.Status(), nil
+	})
+	server.Handle(MethodState, func(_ context.Context, raw json.RawMessage) (any, error) {
+		var params AtParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		return s.State(params.At)
+	})
+	server.Handle(MethodCapture, func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var params CaptureParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		if params.Full {
+			return s.CaptureOnce(ctx)
+		}
+		return s.Capture(ctx)
+	})
+	server.Handle(MethodHistoryList, func(_ context.Context, raw json.RawMessage) (any, error) {
+		var params HistoryParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		list, err := replay.ListEvents(s.config.StateDir, params.From, params.To)
+		if err != nil {
+			return nil, err
+		}
+		if list == nil {
+			list = []events.Event{}
+		}
+		return list, nil
+	})
+	server.Handle(MethodRestorePlan, func(_ context.Context, raw json.RawMessage) (any, error) {
+		var params AtParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		return s.Plan(params.At)
+	})
+	server.Handle(MethodRestoreApply, func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var params ApplyParams
+		if err := decodeParams(raw, &params); err != nil {
+			return nil, err
+		}
+		return s.Apply(ctx, params)
+	})
+}
+
+func (s *Service) Run(ctx context.Context, ticks <-chan time.Time) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ticks:
+			if !ok {
+				return
+			}
+			if _, err := s.Capture(ctx); err != nil {
+				s.config.Logger.Error("capture_once_error", "err", err)
+			}
+		}
+	}
+}
+
+func (s *Service) Capture(ctx context.Context) (CaptureResult, error) {
+	return s.capture(ctx, s.config.Capturer.Capture)
+}
+
+func (s *Service) CaptureOnce(ctx context.Context) (CaptureResult, error) {
+	return s.capture(ctx, s.config.Capturer.CaptureOnce)
+}
+
+func (s *Service) capture(ctx context.Context, run func(context.Context) (capture.Result, error)) (CaptureResult, error) {
+	s.captureMu.Lock()
+	result, err := run(ctx)
+	state, hasState := s.config.Capturer.State()
+	s.captureMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.lastError = err.Error()
+		return CaptureResult{}, err
+	}
+	s.state, s.hasState = state, hasState
+	s.lastError = ""
+	s.lastCaptureAt = s.config.Now().UTC()
+	s.captures++
+	return CaptureResult{EventsWritten: result.EventsWritten, SnapshotPath: result.SnapshotPath, StateHash: result.StateHash}, nil
+}
+
+func (s *Service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		PID:        os.Getpid(),
+		StateDir:   s.config.StateDir,
+		Host:       s.config.Host,
+		Profile:    s.config.Profile,
+		StartedAt:  s.startedAt,
+		LastError:  s.lastError,
+		Captures:   s.captures,
+		CaptureKey: s.config.CaptureKey,
+		RestoreKey: s.config.RestoreKey,
+	}
+	if !s.lastCaptureAt.IsZero() {
+		at := s.lastCaptureAt
+		status.LastCaptureAt = &at
+	}
+	if s.hasState {
+		status.Windows = len(s.state.Windows)
+		if hash, err := s.state.Hash(); err == nil {
+			status.StateHash = hash
+		}
+	}
+	return status
+}
+
+func (s *Service) State(at *time.Time) (model.State, error) {
+	if at == nil {
+		s.mu.Lock()
+		state, hasState := s.state, s.hasState
+		s.mu.Unlock()
+		if hasState {
+			return state, nil
+		}
+	}
+	engine, err := replay.NewEngine(s.config.StateDir)
+	if err != nil {
+		return model.State{}, err
+	}
+	engine = engine.Scoped(s.config.Host, s.config.Profile)
+	if at == nil {
+		list, err := replay.ListEvents(s.config.StateDir, nil, nil)
+		if err != nil {
+			return model.State{}, err
+		}
+		var last time.Time
+		for _, event := range list {
+			if engine.Matches(event.Host, event.Profile) && event.TS.After(last) {
+				last = event.TS
+			}
+		}
+		if last.IsZero() {
+			return model.State{}, fmt.Errorf("no events recorded in %s", s.config.StateDir)
+		}
+		at = &last
+	}
+	return engine.At(*at)
+}
+
+func (s *Service) Plan(at *time.Time) (restore.Plan, error) {
+	if s.config.Planner == nil {
+		return restore.Plan{}, fmt.Errorf("restore planning is not configured")
+	}
+	state, err := s.State(at)
+	if err != nil {
+		return restore.Plan{}, err
+	}
+	return s.config.Planner.Build(state), nil
+}
+
+func (s *Service) Apply(ctx context.Context, params ApplyParams) (ApplyResult, error) {
+	if s.config.Apply == nil {
+		return ApplyResult{}, fmt.Errorf("restore apply is not configured")
+	}
+	restoredAt := s.config.Now().UTC()
+	if params.At != nil {
+		restoredAt = *params.At
+	}
+	plan, err := s.Plan(params.At)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	output, err := s.config.Apply(ctx, restoredAt, plan)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	return ApplyResult{Output: output}, nil
+}
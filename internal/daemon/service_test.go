@@ -0,0 +1,266 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/capture"
+	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/restore"
+)
+
+func TestServiceServesCapturedStateOverSocket(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	appendWindowEvents(t, root, time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), "persisted")
+	capturer := &stubCapturer{state: model.State{Windows: []model.Window{{Key: "w-live", AppID: "kitty", WorkspaceID: "ws-1"}}}}
+	var applied restore.Plan
+	service := NewService(Config{
+		StateDir:   root,
+		CaptureKey: "capture-key",
+		RestoreKey: "restore-key",
+		Capturer:   capturer,
+		Planner:    stubPlanner{},
+		Apply: func(_ context.Context, _ time.Time, plan restore.Plan) (string, error) {
+			applied = plan
+			return "restore_summary restored=1 skipped=0 failed=0\n", nil
+		},
+	})
+	server := NewServer()
+	service.Register(server)
+	client, err := Dial(startServer(t, server))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	ctx := context.Background()
+
+	var status Status
+	if err := client.Call(ctx, MethodStatus, nil, &status); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status.StateDir != root || status.Captures != 0 || status.LastCaptureAt != nil || status.CaptureKey != "capture-key" || status.RestoreKey != "restore-key" {
+		t.Fatalf("unexpected initial status: %+v", status)
+	}
+
+	var captured CaptureResult
+	if err := client.Call(ctx, MethodCapture, nil, &captured); err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if captured.EventsWritten != 1 || captured.StateHash != "sha256:test" || capturer.full {
+		t.Fatalf("unexpected capture result: %+v", captured)
+	}
+	if err := client.Call(ctx, MethodCapture, CaptureParams{Full: true}, &captured); err != nil {
+		t.Fatalf("capture full: %v", err)
+	}
+	if !capturer.full {
+		t.Fatal("expected full capture to write state_full")
+	}
+
+	var state model.State
+	if err := client.Call(ctx, MethodState, AtParams{}, &state); err != nil {
+		t.Fatalf("state: %v", err)
+	}
+	if len(state.Windows) != 1 || state.Windows[0].Key != "w-live" {
+		t.Fatalf("expected the in-memory state, got %#v", state)
+	}
+	persistedAt := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	if err := client.Call(ctx, MethodState, AtParams{At: &persistedAt}, &state); err != nil {
+		t.Fatalf("state at: %v", err)
+	}
+	if len(state.Windows) != 1 || state.Windows[0].Key != "w-1" || state.Windows[0].Title != "persisted" {
+		t.Fatalf("expected historical state replayed from the store, got %#v", state)
+	}
+
+	var plan restore.Plan
+	if err := client.Call(ctx, MethodRestorePlan, AtParams{}, &plan); err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Items) != 1 || plan.Items[0].WindowKey != "w-live" {
+		t.Fatalf("unexpected plan: %#v", plan)
+	}
+	var result ApplyResult
+	if err := client.Call(ctx, MethodRestoreApply, ApplyParams{}, &result); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if result.Output == "" || len(applied.Items) != 1 || applied.Items[0].WindowKey != "w-live" {
+		t.Fatalf("expected plan applied, got %+v / %#v", result, applied)
+	}
+
+	capturer.err = errors.New("niri unavailable")
+	if err := client.Call(ctx, MethodCapture, nil, nil); err == nil {
+		t.Fatal("expected capture error to be returned")
+	}
+	if err := client.Call(ctx, MethodStatus, nil, &status); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if status.Captures != 2 || status.Windows != 1 || status.LastError != "niri unavailable" || status.LastCaptureAt == nil {
+		t.Fatalf("unexpected status after failure: %+v", status)
+	}
+}
+
+func TestServiceHistoryAndReplayFromStore(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	appendWindowEvents(t, root, t0, "old", "new")
+
+	server := NewServer()
+	NewService(Config{StateDir: root, Capturer: &stubCapturer{}}).Register(server)
+	client, err := Dial(startServer(t, server))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	var list []events.Event
+	from := t0.Add(30 * time.Second)
+	if err := client.Call(context.Background(), MethodHistoryList, HistoryParams{From: &from}, &list); err != nil {
+		t.Fatalf("history list: %v", err)
+	}
+	if len(list) != 1 || list[0].Patch["title"] != "new" {
+		t.Fatalf("unexpected history: %#v", list)
+	}
+
+	var state model.State
+	if err := client.Call(context.Background(), MethodState, AtParams{At: &t0}, &state); err != nil {
+		t.Fatalf("state at: %v", err)
+	}
+	if len(state.Windows) != 1 || state.Windows[0].Title != "old" {
+		t.Fatalf("unexpected replayed state: %#v", state)
+	}
+	if err := client.Call(context.Background(), MethodState, AtParams{}, &state); err != nil {
+		t.Fatalf("state latest: %v", err)
+	}
+	if len(state.Windows) != 1 || state.Windows[0].Title != "new" {
+		t.Fatalf("expected last persisted state before any capture, got %#v", state)
+	}
+	if err := client.Call(context.Background(), MethodRestorePlan, nil, nil); err == nil {
+		t.Fatal("expected plan without planner to fail")
+	}
+}
+
+func TestServiceReplaysOnlyItsOwnIdentity(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	appendWindowEvents(t, root, t0, "local")
+	service := NewService(Config{StateDir: root, Host: "desk", Profile: "default", Capturer: &stubCapturer{}})
+
+	if _, err := service.State(nil); err == nil {
+		t.Fatal("expected no events for another host")
+	}
+	state, err := service.State(&t0)
+	if err != nil {
+		t.Fatalf("state at: %v", err)
+	}
+	if len(state.Windows) != 0 {
+		t.Fatalf("expected events of host-a to be ignored, got %#v", state)
+	}
+	if status := service.Status(); status.Host != "desk" || status.Profile != "default" {
+		t.Fatalf("unexpected status identity: %+v", status)
+	}
+}
+
+func TestServiceApplyIgnoresClientPlan(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	appendWindowEvents(t, root, t0, "stored")
+	var applied restore.Plan
+	server := NewServer()
+	NewService(Config{
+		StateDir: root,
+		Capturer: &stubCapturer{},
+		Planner:  stubPlanner{},
+		Apply: func(_ context.Context, _ time.Time, plan restore.Plan) (string, error) {
+			applied = plan
+			return "ok\n", nil
+		},
+	}).Register(server)
+	client, err := Dial(startServer(t, server))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	params := map[string]any{"at": t0, "plan": restore.Plan{Items: []restore.Item{{WindowKey: "w-evil", AppID: "kitty", Status: restore.StatusReady, Command: "sh -c true"}}}}
+	if err := client.Call(context.Background(), MethodRestoreApply, params, nil); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(applied.Items) != 1 || applied.Items[0].WindowKey != "w-1" {
+		t.Fatalf("expected plan rebuilt from the store, got %#v", applied)
+	}
+}
+
+func appendWindowEvents(t *testing.T, root string, start time.Time, titles ...string) {
+	t.Helper()
+
+	store, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+	for i, title := range titles {
+		event := events.Event{V: 1, TS: start.Add(time.Duration(i) * time.Minute), Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": title}, StateHash: "sha256:a"}
+		if _, err := writer.Append(event); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+}
+
+type stubCapturer struct {
+	state    model.State
+	captured bool
+	full     bool
+	err      error
+}
+
+func (s *stubCapturer) Capture(context.Context) (capture.Result, error) {
+	if s.err != nil {
+		return capture.Result{}, s.err
+	}
+	s.captured = true
+	return capture.Result{EventsWritten: 1, StateHash: "sha256:test"}, nil
+}
+
+func (s *stubCapturer) CaptureOnce(ctx context.Context) (capture.Result, error) {
+	result, err := s.Capture(ctx)
+	if err == nil {
+		s.full = true
+	}
+	return result, err
+}
+
+func (s *stubCapturer) State() (model.State, bool) {
+	return s.state, s.captured
+}
+
+type stubPlanner struct{}
+
+func (stubPlanner) Build(state model.State) restore.Plan {
+	plan := restore.Plan{}
+	for _, window := range state.Windows {
+		plan.Items = append(plan.Items, restore.Item{WindowKey: window.Key, AppID: window.AppID, Status: restore.StatusReady})
+	}
+	return plan
+}
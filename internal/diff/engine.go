@@ -1,6 +1,7 @@
 package diff
 
 import (
+	"slices"
 	"sort"
 
 	"github.com/jmo/terminal-redeemer/internal/model"
@@ -120,7 +121,7 @@ func terminalEqual(a, b *model.Terminal) bool {
 	if a == nil || b == nil {
 		return a == b
 	}
-	if a.CWD != b.CWD || a.SessionTag != b.SessionTag {
+	if a.CWD != b.CWD || a.SessionTag != b.SessionTag || a.Multiplexer != b.Multiplexer || a.LayoutRef != b.LayoutRef {
 		return false
 	}
 	if len(a.ProcessTags) != len(b.ProcessTags) {
@@ -131,5 +132,54 @@ func terminalEqual(a, b *model.Terminal) bool {
 			return false
 		}
 	}
+	if !tabsEqual(a.Tabs, b.Tabs) || !nvimEqual(a.Nvim, b.Nvim) || !gitEqual(a.Git, b.Git) {
+		return false
+	}
+	if len(a.ProcessArgs) != len(b.ProcessArgs) {
+		return false
+	}
+	for tag, argsA := range a.ProcessArgs {
+		argsB, ok := b.ProcessArgs[tag]
+		if !ok || len(argsA) != len(argsB) {
+			return false
+		}
+		for i := range argsA {
+			if argsA[i] != argsB[i] {
+				return false
+			}
+		}
+	}
 	return true
 }
+
+func tabsEqual(a, b []model.Tab) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Title != b[i].Title || a[i].Layout != b[i].Layout || a[i].Active != b[i].Active || len(a[i].Panes) != len(b[i].Panes) {
+			return false
+		}
+		for j := range a[i].Panes {
+			paneA, paneB := a[i].Panes[j], b[i].Panes[j]
+			if paneA.Title != paneB.Title || paneA.CWD != paneB.CWD || paneA.Active != paneB.Active || paneA.Split != paneB.Split || !slices.Equal(paneA.Command, paneB.Command) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func nvimEqual(a, b *model.Nvim) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.CWD == b.CWD && a.Session == b.Session && slices.Equal(a.Buffers, b.Buffers)
+}
+
+func gitEqual(a, b *model.Git) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -100,3 +100,73 @@ func TestOptionalFieldAddRemoveBehavior(t *testing.T) {
 		t.Fatalf("expected terminal nil tombstone, got %#v", removePatches[0].Fields)
 	}
 }
+
+func TestProcessArgsChangeEmitsTerminalPatch(t *testing.T) {
+	t.Parallel()
+
+	terminal := func(args ...string) *model.Terminal {
+		return &model.Terminal{CWD: "/tmp", ProcessTags: []string{"claude"}, ProcessArgs: map[string][]string{"claude": args}}
+	}
+	before := model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty", Terminal: terminal("claude")}}}
+	after := model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty", Terminal: terminal("claude", "--resume")}}}
+
+	patches, changed, err := NewEngine().Diff(before, after)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if !changed || len(patches) != 1 {
+		t.Fatalf("expected one patch for argv change, got changed=%v patches=%d", changed, len(patches))
+	}
+
+	_, changed, err = NewEngine().Diff(after, after)
+	if err != nil {
+		t.Fatalf("diff unchanged: %v", err)
+	}
+	if changed {
+		t.Fatal("expected identical argv to produce no change")
+	}
+}
+
+func TestPaneCommandChangeEmitsTerminalPatch(t *testing.T) {
+	t.Parallel()
+
+	terminal := func(command ...string) *model.Terminal {
+		return &model.Terminal{CWD: "/tmp", Tabs: []model.Tab{{Layout: "splits", Panes: []model.Pane{{CWD: "/tmp"}, {CWD: "/tmp", Command: command}}}}}
+	}
+	before := model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty", Terminal: terminal("nvim")}}}
+	after := model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty", Terminal: terminal("nvim", "main.go")}}}
+
+	patches, changed, err := NewEngine().Diff(before, after)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if !changed || len(patches) != 1 {
+		t.Fatalf("expected one patch for pane change, got changed=%v patches=%d", changed, len(patches))
+	}
+}
+
+func TestGitMetadataChangeEmitsTerminalPatch(t *testing.T) {
+	t.Parallel()
+
+	terminal := func(dirty bool) *model.Terminal {
+		return &model.Terminal{CWD: "/src/app", Git: &model.Git{Root: "/src/app", Branch: "main", Dirty: dirty}}
+	}
+	before := model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty", Terminal: terminal(false)}}}
+	after := model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty", Terminal: terminal(true)}}}
+
+	patches, changed, err := NewEngine().Diff(before, after)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if !changed || len(patches) != 1 {
+		t.Fatalf("expected one patch for dirty flag change, got changed=%v patches=%d", changed, len(patches))
+	}
+
+	_, changed, err = NewEngine().Diff(after, model.State{Windows: []model.Window{{Key: "w-1", AppID: "kitty", Terminal: terminal(true)}}})
+	if err != nil {
+		t.Fatalf("diff unchanged: %v", err)
+	}
+	if changed {
+		t.Fatal("expected identical git metadata to produce no change")
+	}
+}
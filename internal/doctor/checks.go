@@ -1,19 +1,22 @@
 package doctor
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/jmo/terminal-redeemer/internal/config"
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/model"
 	"github.com/jmo/terminal-redeemer/internal/niri"
+	"github.com/jmo/terminal-redeemer/internal/seal"
 	"github.com/jmo/terminal-redeemer/internal/snapshots"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
 )
 
 type StateDirWritableCheck struct {
@@ -77,6 +80,7 @@ func (c ConfigLoadCheck) Run(_ context.Context) Result {
 }
 
 type NiriSourceCheck struct {
+	Compositor  string
 	FixturePath string
 	Command     string
 	ReadFile    func(name string) ([]byte, error)
@@ -85,6 +89,9 @@ type NiriSourceCheck struct {
 }
 
 func (c NiriSourceCheck) Name() string {
+	if compositor := strings.TrimSpace(c.Compositor); compositor != "" {
+		return compositor + "_source"
+	}
 	return "niri_source"
 }
 
@@ -154,6 +161,63 @@ func (c CommandAvailableCheck) Run(_ context.Context) Result {
 	return Result{Name: c.Name(), Status: StatusPass, Detail: fmt.Sprintf("available: %s", binary)}
 }
 
+type TerminalDetectionCheck struct {
+	FixturePath string
+	Command     string
+	Terminals   *terminals.Matcher
+	Snapshot    func(ctx context.Context) ([]byte, error)
+	Parse       func(raw []byte) (model.State, error)
+}
+
+func (c TerminalDetectionCheck) Name() string {
+	return "terminal_detection"
+}
+
+func (c TerminalDetectionCheck) Run(ctx context.Context) Result {
+	snapshot := c.Snapshot
+	if snapshot == nil {
+		if fixture := strings.TrimSpace(c.FixturePath); fixture != "" {
+			snapshot = niri.FileSnapshotter{Path: fixture}.Snapshot
+		} else {
+			snapshot = niri.CommandSnapshotter{Command: c.Command}.Snapshot
+		}
+	}
+	parse := c.Parse
+	if parse == nil {
+		parse = niri.ParseSnapshot
+	}
+	matcher := c.Terminals
+	if matcher == nil {
+		matcher = terminals.Default()
+	}
+
+	raw, err := snapshot(ctx)
+	if err != nil {
+		return Result{Name: c.Name(), Status: StatusPass, Detail: fmt.Sprintf("skipped: snapshot unavailable: %v", err)}
+	}
+	state, err := parse(raw)
+	if err != nil {
+		return Result{Name: c.Name(), Status: StatusPass, Detail: fmt.Sprintf("skipped: snapshot invalid: %v", err)}
+	}
+
+	unmatched := make(map[string]struct{})
+	for _, window := range state.Windows {
+		if terminals.LooksLikeTerminal(window.AppID) && !matcher.IsTerminal(window.AppID) {
+			unmatched[window.AppID] = struct{}{}
+		}
+	}
+	if len(unmatched) == 0 {
+		return Result{Name: c.Name(), Status: StatusPass, Detail: fmt.Sprintf("all terminal-like app ids configured (%d windows)", len(state.Windows))}
+	}
+
+	appIDs := make([]string, 0, len(unmatched))
+	for appID := range unmatched {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Strings(appIDs)
+	return Result{Name: c.Name(), Status: StatusWarn, Detail: fmt.Sprintf("terminal-like app ids not in terminals config: %s", strings.Join(appIDs, ","))}
+}
+
 type EventsIntegrityCheck struct {
 	StateDir string
 	OpenFile func(name string) (*os.File, error)
@@ -169,6 +233,11 @@ func (c EventsIntegrityCheck) Run(_ context.Context) Result {
 		openFile = os.Open
 	}
 
+	codec, err := seal.Load(c.StateDir)
+	if err != nil {
+		return Result{Name: c.Name(), Status: StatusFail, Detail: fmt.Sprintf("encryption: %v", err)}
+	}
+
 	path := filepath.Join(c.StateDir, "events.jsonl")
 	f, err := openFile(path)
 	if err != nil {
@@ -181,12 +250,16 @@ func (c EventsIntegrityCheck) Run(_ context.Context) Result {
 		_ = f.Close()
 	}()
 
-	scanner := bufio.NewScanner(f)
+	scanner := seal.NewScanner(f)
 	line := 0
 	for scanner.Scan() {
 		line++
+		payload, err := codec.Open(scanner.Bytes())
+		if err != nil {
+			return Result{Name: c.Name(), Status: StatusFail, Detail: fmt.Sprintf("line %d open failed: %v", line, err)}
+		}
 		var event events.Event
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+		if err := json.Unmarshal(payload, &event); err != nil {
 			return Result{Name: c.Name(), Status: StatusFail, Detail: fmt.Sprintf("line %d decode failed: %v", line, err)}
 		}
 		if err := event.Validate(); err != nil {
@@ -220,6 +293,11 @@ func (c SnapshotsIntegrityCheck) Run(_ context.Context) Result {
 		readFile = os.ReadFile
 	}
 
+	codec, err := seal.Load(c.StateDir)
+	if err != nil {
+		return Result{Name: c.Name(), Status: StatusFail, Detail: fmt.Sprintf("encryption: %v", err)}
+	}
+
 	dir := filepath.Join(c.StateDir, "snapshots")
 	entries, err := readDir(dir)
 	if err != nil {
@@ -240,6 +318,10 @@ func (c SnapshotsIntegrityCheck) Run(_ context.Context) Result {
 		if err != nil {
 			return Result{Name: c.Name(), Status: StatusFail, Detail: fmt.Sprintf("read %s failed: %v", entry.Name(), err)}
 		}
+		payload, err = codec.Open(payload)
+		if err != nil {
+			return Result{Name: c.Name(), Status: StatusFail, Detail: fmt.Sprintf("open %s failed: %v", entry.Name(), err)}
+		}
 		var snapshot snapshots.Snapshot
 		if err := json.Unmarshal(payload, &snapshot); err != nil {
 			return Result{Name: c.Name(), Status: StatusFail, Detail: fmt.Sprintf("decode %s failed: %v", entry.Name(), err)}
@@ -252,6 +334,30 @@ func (c SnapshotsIntegrityCheck) Run(_ context.Context) Result {
 	return Result{Name: c.Name(), Status: StatusPass, Detail: fmt.Sprintf("readable and valid (%d snapshots)", checked)}
 }
 
+type EncryptionCheck struct {
+	StateDir string
+	Keyring  seal.KeyringReader
+}
+
+func (c EncryptionCheck) Name() string {
+	return "store_encryption"
+}
+
+func (c EncryptionCheck) Run(_ context.Context) Result {
+	keyring := c.Keyring
+	if keyring == nil {
+		keyring = seal.Keyctl{}
+	}
+	codec, err := seal.LoadWithKeyring(c.StateDir, keyring)
+	if err != nil {
+		return Result{Name: c.Name(), Status: StatusFail, Detail: err.Error()}
+	}
+	if codec == nil {
+		return Result{Name: c.Name(), Status: StatusPass, Detail: "disabled (plaintext store)"}
+	}
+	return Result{Name: c.Name(), Status: StatusPass, Detail: fmt.Sprintf("%s key=%s", seal.Cipher, codec.Source())}
+}
+
 type LocalInstallCheck struct {
 	Path string
 	Stat func(name string) (os.FileInfo, error)
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/config"
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/seal"
 	"github.com/jmo/terminal-redeemer/internal/snapshots"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
 )
 
 type staticCheck struct {
@@ -40,6 +43,64 @@ func TestRunSummaryAndFailureDetection(t *testing.T) {
 	}
 }
 
+func TestWarningsAreNotFailures(t *testing.T) {
+	t.Parallel()
+
+	results := Run(context.Background(), []Check{
+		staticCheck{name: "a", result: Result{Name: "a", Status: StatusPass}},
+		staticCheck{name: "b", result: Result{Name: "b", Status: StatusWarn}},
+	})
+
+	summary := Summarize(results)
+	if summary.Total != 2 || summary.Passed != 1 || summary.Warned != 1 || summary.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if HasFailures(results) {
+		t.Fatal("expected warnings not to count as failures")
+	}
+}
+
+func TestTerminalDetectionCheckWarnsOnUnconfiguredTerminals(t *testing.T) {
+	t.Parallel()
+
+	snapshot := func(context.Context) ([]byte, error) {
+		return []byte(`[
+			{"id": 1, "app_id": "kitty", "title": "a", "pid": 10},
+			{"id": 2, "app_id": "kitty-scratch", "title": "b", "pid": 11},
+			{"id": 3, "app_id": "org.kde.konsole", "title": "c", "pid": 12},
+			{"id": 4, "app_id": "firefox", "title": "d", "pid": 13}
+		]`), nil
+	}
+
+	result := TerminalDetectionCheck{Snapshot: snapshot}.Run(context.Background())
+	if result.Status != StatusWarn {
+		t.Fatalf("expected warn, got %+v", result)
+	}
+	if result.Detail != "terminal-like app ids not in terminals config: kitty-scratch,org.kde.konsole" {
+		t.Fatalf("unexpected detail: %q", result.Detail)
+	}
+
+	matcher, err := terminals.NewMatcher([]terminals.Rule{{Pattern: "kitty-*", Kind: "kitty"}, {Pattern: "*konsole", Kind: "konsole"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	result = TerminalDetectionCheck{Snapshot: snapshot, Terminals: matcher}.Run(context.Background())
+	if result.Status != StatusPass {
+		t.Fatalf("expected pass with configured terminals, got %+v", result)
+	}
+}
+
+func TestTerminalDetectionCheckSkipsWithoutSnapshot(t *testing.T) {
+	t.Parallel()
+
+	result := TerminalDetectionCheck{Snapshot: func(context.Context) ([]byte, error) {
+		return nil, errors.New("niri not running")
+	}}.Run(context.Background())
+	if result.Status != StatusPass {
+		t.Fatalf("expected pass when snapshot unavailable, got %+v", result)
+	}
+}
+
 func TestStateDirWritableCheck(t *testing.T) {
 	t.Parallel()
 
@@ -204,6 +265,58 @@ func TestEventsIntegrityCheck(t *testing.T) {
 	}
 }
 
+func TestEncryptionCheckAndIntegrityChecksOnEncryptedStore(t *testing.T) {
+	t.Parallel()
+
+	stateDir := t.TempDir()
+	plain := EncryptionCheck{StateDir: stateDir}.Run(context.Background())
+	if plain.Status != StatusPass || !strings.Contains(plain.Detail, "disabled") {
+		t.Fatalf("expected plaintext store to pass, got %+v", plain)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "redeem.key")
+	if err := seal.GenerateKeyFile(keyFile); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if _, err := seal.Encrypt(stateDir, seal.KeySource{File: keyFile}, nil); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	store, err := events.NewStore(stateDir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	if _, err := writer.Append(events.Event{V: 1, TS: time.Now().UTC(), Host: "h", Profile: "p", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "x"}, StateHash: "sha256:x"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	_ = writer.Close()
+	snapStore, err := snapshots.NewStore(stateDir)
+	if err != nil {
+		t.Fatalf("new snapshots store: %v", err)
+	}
+	if _, err := snapStore.Write(snapshots.Snapshot{V: 1, CreatedAt: time.Now().UTC(), Host: "h", Profile: "p", State: map[string]any{"windows": []any{}}, StateHash: "sha256:x"}); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+
+	for _, check := range []Check{EncryptionCheck{StateDir: stateDir}, EventsIntegrityCheck{StateDir: stateDir}, SnapshotsIntegrityCheck{StateDir: stateDir}} {
+		if result := check.Run(context.Background()); result.Status != StatusPass {
+			t.Fatalf("expected %s to pass on encrypted store, got %+v", check.Name(), result)
+		}
+	}
+
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatalf("remove key: %v", err)
+	}
+	for _, check := range []Check{EncryptionCheck{StateDir: stateDir}, EventsIntegrityCheck{StateDir: stateDir}, SnapshotsIntegrityCheck{StateDir: stateDir}} {
+		if result := check.Run(context.Background()); result.Status != StatusFail {
+			t.Fatalf("expected %s to fail without key, got %+v", check.Name(), result)
+		}
+	}
+}
+
 func TestLocalInstallCheckPassesWhenAbsent(t *testing.T) {
 	t.Parallel()
 
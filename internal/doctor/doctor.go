@@ -7,6 +7,7 @@ type Status string
 const (
 	StatusPass Status = "pass"
 	StatusFail Status = "fail"
+	StatusWarn Status = "warn"
 )
 
 type Result struct {
@@ -24,6 +25,7 @@ type Summary struct {
 	Total  int
 	Passed int
 	Failed int
+	Warned int
 }
 
 func Run(ctx context.Context, checks []Check) []Result {
@@ -44,18 +46,21 @@ func Run(ctx context.Context, checks []Check) []Result {
 func Summarize(results []Result) Summary {
 	summary := Summary{Total: len(results)}
 	for _, result := range results {
-		if result.Status == StatusPass {
+		switch result.Status {
+		case StatusPass:
 			summary.Passed++
-			continue
+		case StatusWarn:
+			summary.Warned++
+		default:
+			summary.Failed++
 		}
-		summary.Failed++
 	}
 	return summary
 }
 
 func HasFailures(results []Result) bool {
 	for _, result := range results {
-		if result.Status != StatusPass {
+		if result.Status != StatusPass && result.Status != StatusWarn {
 			return true
 		}
 	}
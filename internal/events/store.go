@@ -1,7 +1,6 @@
 package events
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +9,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/seal"
 )
 
 var ErrLocked = errors.New("event store is locked")
@@ -65,11 +66,21 @@ func (e Event) Validate() error {
 }
 
 type Store struct {
+	root       string
 	eventsPath string
 	lockPath   string
+	codec      *seal.Codec
 }
 
 func NewStore(root string) (*Store, error) {
+	codec, err := seal.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	return NewStoreWithCodec(root, codec)
+}
+
+func NewStoreWithCodec(root string, codec *seal.Codec) (*Store, error) {
 	if err := os.MkdirAll(filepath.Join(root, "meta"), 0o755); err != nil {
 		return nil, fmt.Errorf("create meta dir: %w", err)
 	}
@@ -84,14 +95,17 @@ func NewStore(root string) (*Store, error) {
 	}
 
 	return &Store{
+		root:       root,
 		eventsPath: eventsPath,
 		lockPath:   filepath.Join(root, "meta", "lock"),
+		codec:      codec,
 	}, nil
 }
 
 type Writer struct {
 	lockPath string
 	file     *os.File
+	codec    *seal.Codec
 }
 
 func (s *Store) AcquireWriter() (*Writer, error) {
@@ -109,13 +123,18 @@ func (s *Store) AcquireWriter() (*Writer, error) {
 	}
 	_ = lockFile.Close()
 
+	if err := seal.Current(s.root, s.codec); err != nil {
+		_ = os.Remove(s.lockPath)
+		return nil, err
+	}
+
 	eventsFile, err := os.OpenFile(s.eventsPath, os.O_APPEND|os.O_WRONLY, 0o600)
 	if err != nil {
 		_ = os.Remove(s.lockPath)
 		return nil, fmt.Errorf("open events file: %w", err)
 	}
 
-	return &Writer{lockPath: s.lockPath, file: eventsFile}, nil
+	return &Writer{lockPath: s.lockPath, file: eventsFile, codec: s.codec}, nil
 }
 
 func (w *Writer) Append(event Event) (int64, error) {
@@ -127,6 +146,10 @@ func (w *Writer) Append(event Event) (int64, error) {
 	if err != nil {
 		return 0, fmt.Errorf("marshal event: %w", err)
 	}
+	payload, err = w.codec.Seal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("seal event: %w", err)
+	}
 	payload = append(payload, '\n')
 
 	if _, err := w.file.Write(payload); err != nil {
@@ -167,9 +190,12 @@ func (s *Store) ReadSince(cursor int64) ([]Event, int64, error) {
 	}
 
 	var out []Event
-	scanner := bufio.NewScanner(f)
+	scanner := seal.NewScanner(f)
 	for scanner.Scan() {
-		line := scanner.Bytes()
+		line, err := s.codec.Open(scanner.Bytes())
+		if err != nil {
+			return nil, cursor, fmt.Errorf("open event: %w", err)
+		}
 		var event Event
 		if err := json.Unmarshal(line, &event); err != nil {
 			return nil, cursor, fmt.Errorf("decode event: %w", err)
@@ -2,8 +2,13 @@ package events
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/seal"
 )
 
 func TestStoreAppendAndReadInOrder(t *testing.T) {
@@ -152,3 +157,63 @@ func TestLockPreventsConcurrentWriters(t *testing.T) {
 		t.Fatalf("expected ErrLocked, got %v", err)
 	}
 }
+
+func TestEncryptedStoreSealsLinesTransparently(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "redeem.key")
+	if err := seal.GenerateKeyFile(keyFile); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if _, err := seal.Encrypt(root, seal.KeySource{File: keyFile}, nil); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	store, err := NewStore(root)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	offset, err := writer.Append(Event{V: 1, TS: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "secret"}, StateHash: "sha256:a"})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	_ = writer.Close()
+
+	payload, err := os.ReadFile(filepath.Join(root, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	if int64(len(payload)) != offset || !seal.IsSealed(payload) || strings.Contains(string(payload), "secret") {
+		t.Fatalf("expected one sealed line ending at offset %d, got %q", offset, payload)
+	}
+
+	got, _, err := store.ReadSince(0)
+	if err != nil {
+		t.Fatalf("read since: %v", err)
+	}
+	if len(got) != 1 || got[0].Patch["title"] != "secret" {
+		t.Fatalf("expected decrypted event, got %#v", got)
+	}
+
+	if err := os.Remove(seal.MetaPath(root)); err != nil {
+		t.Fatalf("remove meta: %v", err)
+	}
+	plain, err := NewStore(root)
+	if err != nil {
+		t.Fatalf("new store without meta: %v", err)
+	}
+	if _, _, err := plain.ReadSince(0); !errors.Is(err, seal.ErrKeyRequired) {
+		t.Fatalf("expected ErrKeyRequired, got %v", err)
+	}
+	if _, err := store.AcquireWriter(); !errors.Is(err, seal.ErrStaleCodec) {
+		t.Fatalf("expected writer from a store opened before decryption to be refused, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "meta", "lock")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected lock released after refusing the writer, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package gitmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type Inspector interface {
+	Inspect(dir string) (model.Git, bool, error)
+}
+
+type Command struct {
+	Binary  string
+	Timeout time.Duration
+}
+
+func (c Command) Inspect(dir string) (model.Git, bool, error) {
+	out, code, err := c.run(dir, "rev-parse", "--path-format=absolute", "--show-toplevel", "--git-common-dir")
+	if err != nil || code != 0 {
+		return model.Git{}, false, err
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		return model.Git{}, false, fmt.Errorf("unexpected rev-parse output %q", out)
+	}
+	toplevel, common := filepath.Clean(lines[0]), filepath.Clean(lines[1])
+	git := model.Git{Root: toplevel}
+	if filepath.Base(common) == ".git" {
+		git.Root = filepath.Dir(common)
+	}
+	if git.Root != toplevel {
+		git.Worktree = toplevel
+	}
+
+	branch, code, err := c.run(dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err == nil && code > 1 {
+		err = fmt.Errorf("git symbolic-ref exited with status %d", code)
+	}
+	if err != nil {
+		return model.Git{}, false, err
+	}
+	if code == 0 {
+		git.Branch = strings.TrimSpace(branch)
+	}
+
+	status, code, err := c.run(dir, "status", "--porcelain", "--untracked-files=no", "--ignore-submodules=dirty")
+	if err == nil && code != 0 {
+		err = fmt.Errorf("git status exited with status %d", code)
+	}
+	if err != nil {
+		return model.Git{}, false, err
+	}
+	git.Dirty = strings.TrimSpace(status) != ""
+	return git, true, nil
+}
+
+func (c Command) BranchExists(root string, branch string) (bool, error) {
+	_, code, err := c.run(root, "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
+	switch {
+	case err != nil:
+		return false, err
+	case code == 0:
+		return true, nil
+	case code == 1:
+		return false, nil
+	default:
+		return false, fmt.Errorf("git show-ref exited with status %d", code)
+	}
+}
+
+func (c Command) run(dir string, args ...string) (string, int, error) {
+	binary := strings.TrimSpace(c.Binary)
+	if binary == "" {
+		binary = "git"
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, binary, append([]string{"--no-optional-locks", "-C", dir}, args...)...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return "", exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return string(out), 0, nil
+}
+
+type Enricher struct {
+	inspector Inspector
+}
+
+func NewEnricher(inspector Inspector) *Enricher {
+	if inspector == nil {
+		inspector = Command{}
+	}
+	return &Enricher{inspector: inspector}
+}
+
+func (e *Enricher) EnrichWindow(window model.Window) (model.Window, error) {
+	if window.Terminal == nil || strings.TrimSpace(window.Terminal.CWD) == "" {
+		return window, nil
+	}
+	git, ok, err := e.inspector.Inspect(window.Terminal.CWD)
+	if err != nil {
+		return model.Window{}, err
+	}
+	if !ok {
+		return window, nil
+	}
+
+	out := window
+	terminal := *window.Terminal
+	terminal.Git = &git
+	out.Terminal = &terminal
+	return out, nil
+}
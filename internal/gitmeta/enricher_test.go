@@ -0,0 +1,208 @@
+package gitmeta
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestCommandInspectsRepositoryBranchAndDirtyFlag(t *testing.T) {
+	t.Parallel()
+
+	repo := newRepo(t)
+	sub := filepath.Join(repo, "cmd")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	got, ok, err := Command{}.Inspect(sub)
+	if err != nil || !ok {
+		t.Fatalf("inspect: ok=%v err=%v", ok, err)
+	}
+	if got != (model.Git{Root: repo, Branch: "main"}) {
+		t.Fatalf("unexpected clean metadata %#v", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("changed\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, _, err = Command{}.Inspect(repo)
+	if err != nil || !got.Dirty {
+		t.Fatalf("expected dirty repository, got %#v err=%v", got, err)
+	}
+}
+
+func TestCommandLeavesIndexAndUntrackedFilesAlone(t *testing.T) {
+	t.Parallel()
+
+	repo := newRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "notes.txt"), []byte("scratch\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	stale := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(repo, "README.md"), stale, stale); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+	index := filepath.Join(repo, ".git", "index")
+	before, err := os.Stat(index)
+	if err != nil {
+		t.Fatalf("stat index: %v", err)
+	}
+
+	got, ok, err := Command{}.Inspect(repo)
+	if err != nil || !ok {
+		t.Fatalf("inspect: ok=%v err=%v", ok, err)
+	}
+	if got.Dirty {
+		t.Fatalf("expected untracked files and stale stat info not to mark the repo dirty, got %#v", got)
+	}
+	after, err := os.Stat(index)
+	if err != nil {
+		t.Fatalf("stat index: %v", err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		t.Fatalf("expected inspect not to refresh the index, mtime %v -> %v", before.ModTime(), after.ModTime())
+	}
+}
+
+func TestCommandInspectsLinkedWorktree(t *testing.T) {
+	t.Parallel()
+
+	repo := newRepo(t)
+	worktree := filepath.Join(filepath.Dir(repo), "feature")
+	git(t, repo, "worktree", "add", "-q", "-b", "feature", worktree)
+
+	got, ok, err := Command{}.Inspect(worktree)
+	if err != nil || !ok {
+		t.Fatalf("inspect: ok=%v err=%v", ok, err)
+	}
+	if got != (model.Git{Root: repo, Branch: "feature", Worktree: worktree}) {
+		t.Fatalf("unexpected worktree metadata %#v", got)
+	}
+
+	exists, err := Command{}.BranchExists(repo, "feature")
+	if err != nil || !exists {
+		t.Fatalf("expected feature branch to exist, got %v err=%v", exists, err)
+	}
+	exists, err = Command{}.BranchExists(repo, "gone")
+	if err != nil || exists {
+		t.Fatalf("expected gone branch to be missing, got %v err=%v", exists, err)
+	}
+	if _, err := (Command{}).BranchExists(filepath.Join(repo, "missing"), "feature"); err == nil {
+		t.Fatal("expected an error for a root that is not a repository")
+	}
+}
+
+func TestCommandReportsDetachedHeadAndNonRepositories(t *testing.T) {
+	t.Parallel()
+
+	repo := newRepo(t)
+	git(t, repo, "checkout", "-q", "--detach")
+	got, ok, err := Command{}.Inspect(repo)
+	if err != nil || !ok || got.Branch != "" || got.Root != repo {
+		t.Fatalf("unexpected detached metadata %#v ok=%v err=%v", got, ok, err)
+	}
+
+	if _, ok, err := (Command{}).Inspect(t.TempDir()); err != nil || ok {
+		t.Fatalf("expected plain directory to be ignored, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := (Command{}).Inspect(filepath.Join(repo, "missing")); err != nil || ok {
+		t.Fatalf("expected missing directory to be ignored, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestEnricherRecordsGitMetadataWithoutMutatingInput(t *testing.T) {
+	t.Parallel()
+
+	inspector := &stubInspector{git: model.Git{Root: "/src/app", Branch: "main", Dirty: true}, ok: true}
+	window := model.Window{Key: "w-1", AppID: "kitty", Terminal: &model.Terminal{CWD: "/src/app/cmd"}}
+
+	got, err := NewEnricher(inspector).EnrichWindow(window)
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if inspector.dir != "/src/app/cmd" {
+		t.Fatalf("expected cwd inspected, got %q", inspector.dir)
+	}
+	if got.Terminal.Git == nil || *got.Terminal.Git != inspector.git {
+		t.Fatalf("unexpected git metadata %#v", got.Terminal.Git)
+	}
+	if window.Terminal.Git != nil {
+		t.Fatal("expected input terminal to be left untouched")
+	}
+}
+
+func TestEnricherSkipsWindowsOutsideRepositories(t *testing.T) {
+	t.Parallel()
+
+	inspector := &stubInspector{}
+	enricher := NewEnricher(inspector)
+	for _, window := range []model.Window{
+		{Key: "w-1", AppID: "firefox"},
+		{Key: "w-2", AppID: "kitty", Terminal: &model.Terminal{SessionTag: "work"}},
+		{Key: "w-3", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp"}},
+	} {
+		got, err := enricher.EnrichWindow(window)
+		if err != nil {
+			t.Fatalf("enrich %s: %v", window.Key, err)
+		}
+		if got.Terminal != nil && got.Terminal.Git != nil {
+			t.Fatalf("expected no git metadata for %s, got %#v", window.Key, got.Terminal.Git)
+		}
+	}
+	if inspector.dir != "/tmp" {
+		t.Fatalf("expected only the cwd window inspected, got %q", inspector.dir)
+	}
+
+	failing := NewEnricher(&stubInspector{err: errors.New("git timed out")})
+	if _, err := failing.EnrichWindow(model.Window{Key: "w-4", Terminal: &model.Terminal{CWD: "/src"}}); err == nil {
+		t.Fatal("expected inspect error to be returned")
+	}
+}
+
+type stubInspector struct {
+	git model.Git
+	ok  bool
+	err error
+	dir string
+}
+
+func (s *stubInspector) Inspect(dir string) (model.Git, bool, error) {
+	s.dir = dir
+	return s.git, s.ok, s.err
+}
+
+func newRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("resolve temp dir: %v", err)
+	}
+	repo := filepath.Join(base, "repo")
+	git(t, base, "init", "-q", "-b", "main", repo)
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("hello\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	git(t, repo, "add", "README.md")
+	git(t, repo, "commit", "-q", "-m", "init")
+	return repo
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
@@ -0,0 +1,162 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	CaptureStateChanged    = "capture.state_changed"
+	CaptureSnapshotWritten = "capture.snapshot_written"
+	CaptureWindowAppeared  = "capture.window_appeared"
+	RestoreItemBefore      = "restore.item_before"
+	RestoreItemAfter       = "restore.item_after"
+	RestoreRunAfter        = "restore.run_after"
+	PruneBefore            = "prune.before"
+	PruneAfter             = "prune.after"
+)
+
+const DefaultTimeout = 10 * time.Second
+
+type Event struct {
+	Name  string
+	AppID string
+	Data  any
+}
+
+type Hook struct {
+	Event   string
+	Command string
+	AppID   string
+	Timeout time.Duration
+}
+
+type Runner interface {
+	Run(ctx context.Context, command string, env []string, stdin []byte) error
+}
+
+type ShellRunner struct{}
+
+func (ShellRunner) Run(ctx context.Context, command string, env []string, stdin []byte) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return fmt.Errorf("%w: %s", err, detail)
+		}
+		return err
+	}
+	return nil
+}
+
+type Config struct {
+	Hooks  []Hook
+	Runner Runner
+	Now    func() time.Time
+	Logger *slog.Logger
+}
+
+type Dispatcher struct {
+	hooks  []Hook
+	runner Runner
+	now    func() time.Time
+	logger *slog.Logger
+}
+
+type payload struct {
+	Event string    `json:"event"`
+	At    time.Time `json:"at"`
+	Data  any       `json:"data"`
+}
+
+func New(config Config) *Dispatcher {
+	runner := config.Runner
+	if runner == nil {
+		runner = ShellRunner{}
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	hooks := make([]Hook, 0, len(config.Hooks))
+	for _, hook := range config.Hooks {
+		if strings.TrimSpace(hook.Command) == "" {
+			continue
+		}
+		hook.AppID = strings.ToLower(strings.TrimSpace(hook.AppID))
+		if hook.Timeout <= 0 {
+			hook.Timeout = DefaultTimeout
+		}
+		hooks = append(hooks, hook)
+	}
+	return &Dispatcher{hooks: hooks, runner: runner, now: now, logger: logger}
+}
+
+func (d *Dispatcher) Fire(ctx context.Context, event Event) error {
+	var matched []Hook
+	for _, hook := range d.hooks {
+		if hook.Event == event.Name && matchAppID(hook.AppID, event.AppID) {
+			matched = append(matched, hook)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	stdin, err := json.Marshal(payload{Event: event.Name, At: d.now().UTC(), Data: event.Data})
+	if err != nil {
+		d.logger.Warn("hook_failed", "event", event.Name, "err", err)
+		return fmt.Errorf("encode %s payload: %w", event.Name, err)
+	}
+	env := []string{"REDEEM_HOOK_EVENT=" + event.Name}
+	if event.AppID != "" {
+		env = append(env, "REDEEM_HOOK_APP_ID="+event.AppID)
+	}
+
+	var errs []error
+	for _, hook := range matched {
+		hookCtx, cancel := context.WithTimeout(ctx, hook.Timeout)
+		err := d.runner.Run(hookCtx, hook.Command, env, stdin)
+		cancel()
+		if err != nil {
+			d.logger.Warn("hook_failed", "event", event.Name, "command", hook.Command, "err", err)
+			errs = append(errs, fmt.Errorf("hook %s %q: %w", event.Name, hook.Command, err))
+			continue
+		}
+		d.logger.Debug("hook_ran", "event", event.Name, "command", hook.Command)
+	}
+	return errors.Join(errs...)
+}
+
+func matchAppID(pattern string, appID string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, strings.ToLower(appID))
+	return ok
+}
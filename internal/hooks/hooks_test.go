@@ -0,0 +1,138 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFireRunsMatchingHooksWithPayload(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{}
+	dispatcher := New(Config{
+		Hooks: []Hook{
+			{Event: RestoreRunAfter, Command: "notify-send restored"},
+			{Event: CaptureWindowAppeared, Command: "firefox-hook", AppID: "Firefox*"},
+			{Event: CaptureWindowAppeared, Command: "any-window"},
+			{Event: PruneBefore, Command: "  "},
+		},
+		Runner: runner,
+		Now:    func() time.Time { return time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC) },
+	})
+
+	if err := dispatcher.Fire(context.Background(), Event{Name: CaptureWindowAppeared, AppID: "kitty", Data: map[string]string{"key": "w-1"}}); err != nil {
+		t.Fatalf("fire kitty: %v", err)
+	}
+	if err := dispatcher.Fire(context.Background(), Event{Name: CaptureWindowAppeared, AppID: "firefox-nightly", Data: map[string]string{"key": "w-2"}}); err != nil {
+		t.Fatalf("fire firefox: %v", err)
+	}
+	if err := dispatcher.Fire(context.Background(), Event{Name: PruneBefore}); err != nil {
+		t.Fatalf("fire prune: %v", err)
+	}
+
+	want := []string{"any-window", "firefox-hook", "any-window"}
+	if len(runner.calls) != len(want) {
+		t.Fatalf("unexpected calls: %+v", runner.calls)
+	}
+	for i, command := range want {
+		if runner.calls[i].command != command {
+			t.Fatalf("call %d = %q, want %q", i, runner.calls[i].command, command)
+		}
+	}
+
+	var got struct {
+		Event string            `json:"event"`
+		At    time.Time         `json:"at"`
+		Data  map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(runner.calls[1].stdin, &got); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if got.Event != CaptureWindowAppeared || got.Data["key"] != "w-2" || !got.At.Equal(time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+	env := strings.Join(runner.calls[1].env, " ")
+	if !strings.Contains(env, "REDEEM_HOOK_EVENT=capture.window_appeared") || !strings.Contains(env, "REDEEM_HOOK_APP_ID=firefox-nightly") {
+		t.Fatalf("unexpected env: %v", runner.calls[1].env)
+	}
+}
+
+func TestFireReportsFailuresAndKeepsGoing(t *testing.T) {
+	t.Parallel()
+
+	runner := &stubRunner{errs: map[string]error{"broken": errors.New("exit status 3")}}
+	var logs bytes.Buffer
+	dispatcher := New(Config{
+		Hooks: []Hook{
+			{Event: PruneAfter, Command: "broken"},
+			{Event: PruneAfter, Command: "works"},
+		},
+		Runner: runner,
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+
+	err := dispatcher.Fire(context.Background(), Event{Name: PruneAfter})
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Fatalf("expected hook failure, got %v", err)
+	}
+	if len(runner.calls) != 2 {
+		t.Fatalf("expected later hooks to run, got %+v", runner.calls)
+	}
+	if !strings.Contains(logs.String(), "level=WARN msg=hook_failed event=prune.after command=broken") {
+		t.Fatalf("expected failure log, got %q", logs.String())
+	}
+}
+
+func TestShellRunnerPassesStdinAndEnforcesTimeout(t *testing.T) {
+	t.Parallel()
+
+	out := filepath.Join(t.TempDir(), "payload.json")
+	dispatcher := New(Config{Hooks: []Hook{
+		{Event: RestoreRunAfter, Command: "cat > '" + out + "'; echo \"$REDEEM_HOOK_EVENT\" >> '" + out + "'"},
+		{Event: PruneBefore, Command: "sleep 5", Timeout: 50 * time.Millisecond},
+	}})
+
+	if err := dispatcher.Fire(context.Background(), Event{Name: RestoreRunAfter, Data: RestoreRun{Restored: 2}}); err != nil {
+		t.Fatalf("fire restore: %v", err)
+	}
+	payload, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	if !strings.Contains(string(payload), `"restored":2`) || !strings.HasSuffix(string(payload), "restore.run_after\n") {
+		t.Fatalf("unexpected hook output: %q", payload)
+	}
+
+	started := time.Now()
+	err = dispatcher.Fire(context.Background(), Event{Name: PruneBefore})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Fatalf("hook was not stopped at its timeout, took %s", elapsed)
+	}
+}
+
+type hookCall struct {
+	command string
+	env     []string
+	stdin   []byte
+}
+
+type stubRunner struct {
+	calls []hookCall
+	errs  map[string]error
+}
+
+func (r *stubRunner) Run(_ context.Context, command string, env []string, stdin []byte) error {
+	r.calls = append(r.calls, hookCall{command: command, env: env, stdin: stdin})
+	return r.errs[command]
+}
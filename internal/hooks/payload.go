@@ -0,0 +1,60 @@
+package hooks
+
+import (
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type StateChanged struct {
+	Host              string `json:"host"`
+	Profile           string `json:"profile"`
+	Source            string `json:"source"`
+	StateHash         string `json:"state_hash"`
+	PreviousStateHash string `json:"previous_state_hash,omitempty"`
+	EventsWritten     int    `json:"events_written"`
+	Windows           int    `json:"windows"`
+	Workspaces        int    `json:"workspaces"`
+}
+
+type SnapshotWritten struct {
+	Host      string `json:"host"`
+	Profile   string `json:"profile"`
+	Path      string `json:"path"`
+	StateHash string `json:"state_hash"`
+}
+
+type WindowAppeared struct {
+	Host    string       `json:"host"`
+	Profile string       `json:"profile"`
+	Window  model.Window `json:"window"`
+}
+
+type RestoreItem struct {
+	WindowKey   string `json:"window_key"`
+	AppID       string `json:"app_id"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	Command     string `json:"command,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Error       string `json:"error,omitempty"`
+	PID         int    `json:"pid,omitempty"`
+}
+
+type RestoreRun struct {
+	Restored int           `json:"restored"`
+	Skipped  int           `json:"skipped"`
+	Failed   int           `json:"failed"`
+	TimedOut int           `json:"timed_out"`
+	Items    []RestoreItem `json:"items"`
+}
+
+type Prune struct {
+	Root            string    `json:"root"`
+	Days            int       `json:"days"`
+	Cutoff          time.Time `json:"cutoff"`
+	EventsPruned    int       `json:"events_pruned"`
+	SnapshotsPruned int       `json:"snapshots_pruned"`
+	LayoutsPruned   int       `json:"layouts_pruned"`
+	Error           string    `json:"error,omitempty"`
+}
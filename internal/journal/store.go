@@ -0,0 +1,183 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/seal"
+)
+
+var ErrNoRun = errors.New("no restore run recorded")
+
+type Run struct {
+	V          int        `json:"v"`
+	ID         string     `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RestoredAt time.Time  `json:"restored_at,omitempty"`
+	Windows    []Window   `json:"windows"`
+	UndoneAt   *time.Time `json:"undone_at,omitempty"`
+}
+
+type Window struct {
+	Key      string `json:"key"`
+	WindowID int    `json:"window_id"`
+	AppID    string `json:"app_id,omitempty"`
+}
+
+func (r Run) Validate() error {
+	if r.V != 1 {
+		return fmt.Errorf("invalid version: %d", r.V)
+	}
+	if strings.TrimSpace(r.ID) == "" {
+		return errors.New("id is required")
+	}
+	if r.CreatedAt.IsZero() {
+		return errors.New("created_at is required")
+	}
+	for _, window := range r.Windows {
+		if window.WindowID <= 0 {
+			return fmt.Errorf("invalid window id for %s", window.Key)
+		}
+	}
+	return nil
+}
+
+type Store struct {
+	dir   string
+	codec *seal.Codec
+}
+
+func NewStore(root string) (*Store, error) {
+	codec, err := seal.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	return NewStoreWithCodec(root, codec)
+}
+
+func NewStoreWithCodec(root string, codec *seal.Codec) (*Store, error) {
+	dir := filepath.Join(root, "restores")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create restores dir: %w", err)
+	}
+	return &Store{dir: dir, codec: codec}, nil
+}
+
+func NewRunID(at time.Time) string {
+	return at.UTC().Format("20060102T150405.000Z")
+}
+
+func (s *Store) Write(run Run) (string, error) {
+	if err := run.Validate(); err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(run.ID, `/\`) {
+		return "", fmt.Errorf("invalid run id: %s", run.ID)
+	}
+
+	payload, err := json.Marshal(run)
+	if err != nil {
+		return "", fmt.Errorf("marshal restore run: %w", err)
+	}
+	payload, err = s.codec.Seal(payload)
+	if err != nil {
+		return "", fmt.Errorf("seal restore run: %w", err)
+	}
+
+	path := s.path(run.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return "", fmt.Errorf("write restore run: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("write restore run: %w", err)
+	}
+	return path, nil
+}
+
+func (s *Store) Read(id string) (Run, error) {
+	id = strings.TrimSpace(id)
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return Run{}, fmt.Errorf("invalid run id: %q", id)
+	}
+	payload, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Run{}, fmt.Errorf("%w: %s", ErrNoRun, id)
+	}
+	if err != nil {
+		return Run{}, fmt.Errorf("read restore run: %w", err)
+	}
+	payload, err = s.codec.Open(payload)
+	if err != nil {
+		return Run{}, fmt.Errorf("open restore run: %w", err)
+	}
+
+	var run Run
+	if err := json.Unmarshal(payload, &run); err != nil {
+		return Run{}, fmt.Errorf("decode restore run: %w", err)
+	}
+	if err := run.Validate(); err != nil {
+		return Run{}, err
+	}
+	return run, nil
+}
+
+func (s *Store) List() ([]Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read restores dir: %w", err)
+	}
+
+	runs := make([]Run, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		run, err := s.Read(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].CreatedAt.Equal(runs[j].CreatedAt) {
+			return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+		}
+		return runs[i].ID < runs[j].ID
+	})
+	return runs, nil
+}
+
+func (s *Store) LatestActive() (Run, error) {
+	runs, err := s.List()
+	if err != nil {
+		return Run{}, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].UndoneAt == nil {
+			return runs[i], nil
+		}
+	}
+	return Run{}, ErrNoRun
+}
+
+func (s *Store) MarkUndone(id string, at time.Time) error {
+	run, err := s.Read(id)
+	if err != nil {
+		return err
+	}
+	undoneAt := at.UTC()
+	run.UndoneAt = &undoneAt
+	_, err = s.Write(run)
+	return err
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,145 @@
+package journal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/seal"
+)
+
+func TestRunWriteReadRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new journal store: %v", err)
+	}
+
+	createdAt := time.Date(2026, 2, 15, 10, 20, 0, 0, time.UTC)
+	want := Run{
+		V:         1,
+		ID:        NewRunID(createdAt),
+		CreatedAt: createdAt,
+		Windows:   []Window{{Key: "w:kitty:30", WindowID: 30, AppID: "kitty"}},
+	}
+	if _, err := store.Write(want); err != nil {
+		t.Fatalf("write run: %v", err)
+	}
+
+	got, err := store.Read(want.ID)
+	if err != nil {
+		t.Fatalf("read run: %v", err)
+	}
+	if got.ID != "20260215T102000.000Z" {
+		t.Fatalf("unexpected run id: %q", got.ID)
+	}
+	if len(got.Windows) != 1 || got.Windows[0].WindowID != 30 {
+		t.Fatalf("unexpected windows: %#v", got.Windows)
+	}
+}
+
+func TestLatestActiveSkipsUndoneRuns(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new journal store: %v", err)
+	}
+
+	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	for i, ts := range []time.Time{t0, t0.Add(time.Minute)} {
+		run := Run{V: 1, ID: NewRunID(ts), CreatedAt: ts, Windows: []Window{{Key: "w:kitty:1", WindowID: i + 1}}}
+		if _, err := store.Write(run); err != nil {
+			t.Fatalf("write run: %v", err)
+		}
+	}
+
+	latest, err := store.LatestActive()
+	if err != nil {
+		t.Fatalf("latest active: %v", err)
+	}
+	if latest.ID != NewRunID(t0.Add(time.Minute)) {
+		t.Fatalf("expected newest run, got %q", latest.ID)
+	}
+
+	if err := store.MarkUndone(latest.ID, t0.Add(time.Hour)); err != nil {
+		t.Fatalf("mark undone: %v", err)
+	}
+	latest, err = store.LatestActive()
+	if err != nil {
+		t.Fatalf("latest active after undo: %v", err)
+	}
+	if latest.ID != NewRunID(t0) {
+		t.Fatalf("expected older run after undo, got %q", latest.ID)
+	}
+
+	if err := store.MarkUndone(latest.ID, t0.Add(time.Hour)); err != nil {
+		t.Fatalf("mark undone: %v", err)
+	}
+	if _, err := store.LatestActive(); !errors.Is(err, ErrNoRun) {
+		t.Fatalf("expected ErrNoRun, got %v", err)
+	}
+}
+
+func TestReadRejectsPathLikeIDs(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new journal store: %v", err)
+	}
+	if _, err := store.Read("../events"); err == nil {
+		t.Fatal("expected invalid run id error")
+	}
+	if _, err := store.Read("missing"); !errors.Is(err, ErrNoRun) {
+		t.Fatalf("expected ErrNoRun for missing run, got %v", err)
+	}
+}
+
+func TestRunsAreSealedWithStoreCodec(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	codec, err := seal.New(bytes.Repeat([]byte{5}, seal.KeySize), seal.KeySource{File: "/keys/redeem"})
+	if err != nil {
+		t.Fatalf("new codec: %v", err)
+	}
+	store, err := NewStoreWithCodec(root, codec)
+	if err != nil {
+		t.Fatalf("new journal store: %v", err)
+	}
+
+	createdAt := time.Date(2026, 2, 15, 10, 20, 0, 0, time.UTC)
+	run := Run{V: 1, ID: NewRunID(createdAt), CreatedAt: createdAt, Windows: []Window{{Key: "w:kitty:30", WindowID: 30, AppID: "kitty"}}}
+	path, err := store.Write(run)
+	if err != nil {
+		t.Fatalf("write run: %v", err)
+	}
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read run file: %v", err)
+	}
+	if !seal.IsSealed(payload) || strings.Contains(string(payload), "w:kitty:30") {
+		t.Fatalf("expected sealed run file, got %q", payload)
+	}
+	got, err := store.Read(run.ID)
+	if err != nil || len(got.Windows) != 1 || got.Windows[0].WindowID != 30 {
+		t.Fatalf("unexpected run %#v err=%v", got, err)
+	}
+
+	plain, err := NewStoreWithCodec(root, nil)
+	if err != nil {
+		t.Fatalf("new plaintext store: %v", err)
+	}
+	if _, err := plain.Read(run.ID); !errors.Is(err, seal.ErrKeyRequired) {
+		t.Fatalf("expected ErrKeyRequired without the key, got %v", err)
+	}
+	if filepath.Dir(path) != filepath.Join(root, "restores") {
+		t.Fatalf("unexpected run path %s", path)
+	}
+}
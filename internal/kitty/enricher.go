@@ -0,0 +1,124 @@
+package kitty
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
+)
+
+const listenOnEnv = "KITTY_LISTEN_ON"
+
+type Lister interface {
+	List(socket string) ([]byte, error)
+}
+
+type EnvLookup interface {
+	LookupEnv(pid int, name string) (string, bool)
+}
+
+type CommandLister struct {
+	Binary  string
+	Timeout time.Duration
+}
+
+func (l CommandLister) List(socket string) ([]byte, error) {
+	binary := strings.TrimSpace(l.Binary)
+	if binary == "" {
+		binary = "kitty"
+	}
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return exec.CommandContext(ctx, binary, "@", "--to", socket, "ls").Output()
+}
+
+type Config struct {
+	Terminals *terminals.Matcher
+}
+
+type Enricher struct {
+	lister Lister
+	env    EnvLookup
+	config Config
+}
+
+func NewEnricher(lister Lister, env EnvLookup, config Config) *Enricher {
+	if lister == nil {
+		lister = CommandLister{}
+	}
+	if config.Terminals == nil {
+		config.Terminals = terminals.Default()
+	}
+	return &Enricher{lister: lister, env: env, config: config}
+}
+
+func (e *Enricher) EnrichWindow(window model.Window) (model.Window, error) {
+	if kind, ok := e.config.Terminals.Match(window.AppID); !ok || kind != "kitty" {
+		return window, nil
+	}
+	if window.PID <= 0 || e.env == nil {
+		return window, nil
+	}
+	socket, ok := e.env.LookupEnv(window.PID, listenOnEnv)
+	if !ok || strings.TrimSpace(socket) == "" {
+		return window, nil
+	}
+
+	raw, err := e.lister.List(strings.TrimSpace(socket))
+	if err != nil {
+		return model.Window{}, err
+	}
+	osWindows, err := ParseLS(raw)
+	if err != nil {
+		return model.Window{}, err
+	}
+	osWindow, ok := matchOSWindow(osWindows, window.Title)
+	if !ok {
+		return window, nil
+	}
+	tabs := osWindow.ModelTabs()
+	if paneCount(tabs) < 2 {
+		return window, nil
+	}
+
+	out := window
+	terminal := model.Terminal{}
+	if window.Terminal != nil {
+		terminal = *window.Terminal
+	}
+	terminal.Tabs = tabs
+	if terminal.CWD == "" {
+		if active, ok := osWindow.ActiveWindow(); ok {
+			terminal.CWD = active.pane().CWD
+		}
+	}
+	out.Terminal = &terminal
+	return out, nil
+}
+
+func matchOSWindow(windows []OSWindow, title string) (OSWindow, bool) {
+	if len(windows) == 1 {
+		return windows[0], true
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return OSWindow{}, false
+	}
+	var matched []OSWindow
+	for _, window := range windows {
+		if active, ok := window.ActiveWindow(); ok && strings.TrimSpace(active.Title) == title {
+			matched = append(matched, window)
+		}
+	}
+	if len(matched) != 1 {
+		return OSWindow{}, false
+	}
+	return matched[0], true
+}
@@ -0,0 +1,131 @@
+package kitty
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestParseLSFixture(t *testing.T) {
+	t.Parallel()
+
+	osWindows, err := ParseLS(readFixture(t))
+	if err != nil {
+		t.Fatalf("parse ls: %v", err)
+	}
+	if len(osWindows) != 2 {
+		t.Fatalf("expected 2 os windows, got %d", len(osWindows))
+	}
+
+	tabs := osWindows[0].ModelTabs()
+	if len(tabs) != 2 || tabs[0].Layout != "splits" || !tabs[0].Active || tabs[1].Active {
+		t.Fatalf("unexpected tabs: %#v", tabs)
+	}
+	editor := tabs[0].Panes[0]
+	if editor.CWD != "/home/jmo/src/redeemer/cmd" || len(editor.Command) != 2 || editor.Command[0] != "nvim" || !editor.Active {
+		t.Fatalf("unexpected editor pane: %#v", editor)
+	}
+	shell := tabs[0].Panes[1]
+	if shell.CWD != "/home/jmo/src/redeemer" || shell.Command != nil {
+		t.Fatalf("expected plain shell pane without command, got %#v", shell)
+	}
+}
+
+func TestEnricherCapturesTabsForMatchingOSWindow(t *testing.T) {
+	t.Parallel()
+
+	lister := &stubLister{payload: readFixture(t)}
+	enricher := NewEnricher(lister, stubEnv{"KITTY_LISTEN_ON": "unix:/tmp/kitty-5100"}, Config{})
+
+	window := model.Window{Key: "w-1", AppID: "kitty", PID: 5100, Title: "nvim main.go", Terminal: &model.Terminal{CWD: "/home/jmo/src/redeemer", ProcessTags: []string{"claude"}}}
+	got, err := enricher.EnrichWindow(window)
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if lister.socket != "unix:/tmp/kitty-5100" {
+		t.Fatalf("expected socket from window env, got %q", lister.socket)
+	}
+	if got.Terminal == nil || len(got.Terminal.Tabs) != 2 {
+		t.Fatalf("expected captured tabs, got %#v", got.Terminal)
+	}
+	if got.Terminal.CWD != "/home/jmo/src/redeemer" || len(got.Terminal.ProcessTags) != 1 {
+		t.Fatalf("expected existing terminal metadata preserved, got %#v", got.Terminal)
+	}
+	if window.Terminal.Tabs != nil {
+		t.Fatal("expected input window to be left untouched")
+	}
+}
+
+func TestEnricherSkipsSinglePaneWindows(t *testing.T) {
+	t.Parallel()
+
+	enricher := NewEnricher(&stubLister{payload: readFixture(t)}, stubEnv{"KITTY_LISTEN_ON": "unix:/tmp/kitty"}, Config{})
+	got, err := enricher.EnrichWindow(model.Window{Key: "w-2", AppID: "kitty", PID: 5100, Title: "scratch"})
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal != nil {
+		t.Fatalf("expected single pane window to be left alone, got %#v", got.Terminal)
+	}
+}
+
+func TestEnricherWithoutSocketLeavesWindowUnchanged(t *testing.T) {
+	t.Parallel()
+
+	lister := &stubLister{err: errors.New("should not be called")}
+	enricher := NewEnricher(lister, stubEnv{}, Config{})
+	got, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "kitty", PID: 5100})
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal != nil || lister.socket != "" {
+		t.Fatalf("expected untouched window, got %#v", got)
+	}
+
+	got, err = enricher.EnrichWindow(model.Window{Key: "w-2", AppID: "foot", PID: 5100})
+	if err != nil || got.Terminal != nil {
+		t.Fatalf("expected non-kitty window to be ignored, got %#v err=%v", got, err)
+	}
+}
+
+func TestEnricherListFailureReturnsError(t *testing.T) {
+	t.Parallel()
+
+	enricher := NewEnricher(&stubLister{err: errors.New("connection refused")}, stubEnv{"KITTY_LISTEN_ON": "unix:/tmp/kitty"}, Config{})
+	if _, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "kitty", PID: 5100}); err == nil {
+		t.Fatal("expected list error")
+	}
+}
+
+func readFixture(t *testing.T) []byte {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "ls.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return payload
+}
+
+type stubLister struct {
+	payload []byte
+	err     error
+	socket  string
+}
+
+func (s *stubLister) List(socket string) ([]byte, error) {
+	s.socket = socket
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.payload, nil
+}
+
+type stubEnv map[string]string
+
+func (s stubEnv) LookupEnv(_ int, name string) (string, bool) {
+	value, ok := s[name]
+	return value, ok
+}
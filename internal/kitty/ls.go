@@ -0,0 +1,124 @@
+package kitty
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type OSWindow struct {
+	ID       int   `json:"id"`
+	IsActive bool  `json:"is_active"`
+	Tabs     []Tab `json:"tabs"`
+}
+
+type Tab struct {
+	ID       int      `json:"id"`
+	Title    string   `json:"title"`
+	Layout   string   `json:"layout"`
+	IsActive bool     `json:"is_active"`
+	Windows  []Window `json:"windows"`
+}
+
+type Window struct {
+	ID                  int       `json:"id"`
+	Title               string    `json:"title"`
+	CWD                 string    `json:"cwd"`
+	PID                 int       `json:"pid"`
+	IsActive            bool      `json:"is_active"`
+	Cmdline             []string  `json:"cmdline"`
+	ForegroundProcesses []Process `json:"foreground_processes"`
+}
+
+type Process struct {
+	PID     int      `json:"pid"`
+	CWD     string   `json:"cwd"`
+	Cmdline []string `json:"cmdline"`
+}
+
+var shells = map[string]struct{}{
+	"sh":   {},
+	"bash": {},
+	"zsh":  {},
+	"fish": {},
+	"nu":   {},
+	"dash": {},
+}
+
+func ParseLS(raw []byte) ([]OSWindow, error) {
+	var windows []OSWindow
+	if err := json.Unmarshal(raw, &windows); err != nil {
+		return nil, fmt.Errorf("decode kitty ls: %w", err)
+	}
+	return windows, nil
+}
+
+func (w OSWindow) ActiveWindow() (Window, bool) {
+	for _, tab := range w.Tabs {
+		if !tab.IsActive {
+			continue
+		}
+		for _, window := range tab.Windows {
+			if window.IsActive {
+				return window, true
+			}
+		}
+	}
+	return Window{}, false
+}
+
+func (w OSWindow) ModelTabs() []model.Tab {
+	out := make([]model.Tab, 0, len(w.Tabs))
+	for _, tab := range w.Tabs {
+		converted := model.Tab{
+			Title:  strings.TrimSpace(tab.Title),
+			Layout: strings.TrimSpace(tab.Layout),
+			Active: tab.IsActive,
+			Panes:  make([]model.Pane, 0, len(tab.Windows)),
+		}
+		for _, window := range tab.Windows {
+			converted.Panes = append(converted.Panes, window.pane())
+		}
+		out = append(out, converted)
+	}
+	return out
+}
+
+func (w Window) pane() model.Pane {
+	pane := model.Pane{
+		Title:  strings.TrimSpace(w.Title),
+		CWD:    strings.TrimSpace(w.CWD),
+		Active: w.IsActive,
+	}
+	for _, process := range w.ForegroundProcesses {
+		if len(process.Cmdline) == 0 {
+			continue
+		}
+		if cwd := strings.TrimSpace(process.CWD); cwd != "" {
+			pane.CWD = cwd
+		}
+		if isShell(process.Cmdline[0]) {
+			continue
+		}
+		pane.Command = append([]string(nil), process.Cmdline...)
+		break
+	}
+	return pane
+}
+
+func isShell(arg string) bool {
+	name := strings.TrimPrefix(filepath.Base(strings.TrimSpace(arg)), "-")
+	_, ok := shells[strings.ToLower(name)]
+	return ok
+}
+
+func paneCount(tabs []model.Tab) int {
+	count := 0
+	for _, tab := range tabs {
+		count += len(tab.Panes)
+	}
+	return count
+}
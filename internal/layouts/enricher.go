@@ -0,0 +1,78 @@
+package layouts
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type Dumper interface {
+	DumpLayout(session string) (string, error)
+}
+
+type Putter interface {
+	Put(content string) (string, error)
+}
+
+type ZellijDumper struct {
+	Binary  string
+	Timeout time.Duration
+}
+
+func (d ZellijDumper) DumpLayout(session string) (string, error) {
+	binary := strings.TrimSpace(d.Binary)
+	if binary == "" {
+		binary = "zellij"
+	}
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, binary, "--session", session, "action", "dump-layout").Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+type Enricher struct {
+	dumper Dumper
+	store  Putter
+}
+
+func NewEnricher(dumper Dumper, store Putter) *Enricher {
+	if dumper == nil {
+		dumper = ZellijDumper{}
+	}
+	return &Enricher{dumper: dumper, store: store}
+}
+
+func (e *Enricher) EnrichWindow(window model.Window) (model.Window, error) {
+	if window.Terminal == nil {
+		return window, nil
+	}
+	session := strings.TrimSpace(window.Terminal.SessionTag)
+	if session == "" || (window.Terminal.Multiplexer != "" && window.Terminal.Multiplexer != "zellij") {
+		return window, nil
+	}
+
+	layout, err := e.dumper.DumpLayout(session)
+	if err != nil {
+		return model.Window{}, err
+	}
+	ref, err := e.store.Put(layout)
+	if err != nil {
+		return model.Window{}, err
+	}
+
+	out := window
+	terminal := *window.Terminal
+	terminal.LayoutRef = ref
+	out.Terminal = &terminal
+	return out, nil
+}
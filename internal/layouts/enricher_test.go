@@ -0,0 +1,82 @@
+package layouts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestEnricherStoresZellijLayoutRef(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	dumper := &stubDumper{layout: "layout {\n    pane\n}\n"}
+	enricher := NewEnricher(dumper, store)
+
+	window := model.Window{Key: "w-1", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp", SessionTag: "work"}}
+	got, err := enricher.EnrichWindow(window)
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if dumper.session != "work" {
+		t.Fatalf("expected layout dumped for session work, got %q", dumper.session)
+	}
+	if got.Terminal == nil || got.Terminal.LayoutRef != Ref(dumper.layout) {
+		t.Fatalf("expected layout ref, got %#v", got.Terminal)
+	}
+	if window.Terminal.LayoutRef != "" {
+		t.Fatal("expected input terminal to be left untouched")
+	}
+}
+
+func TestEnricherSkipsNonZellijWindows(t *testing.T) {
+	t.Parallel()
+
+	dumper := &stubDumper{err: errors.New("should not be called")}
+	enricher := NewEnricher(dumper, nil)
+
+	for _, window := range []model.Window{
+		{Key: "w-1", AppID: "firefox"},
+		{Key: "w-2", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp"}},
+		{Key: "w-3", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp", SessionTag: "work", Multiplexer: "tmux"}},
+	} {
+		got, err := enricher.EnrichWindow(window)
+		if err != nil {
+			t.Fatalf("enrich %s: %v", window.Key, err)
+		}
+		if got.Terminal != nil && got.Terminal.LayoutRef != "" {
+			t.Fatalf("expected no layout for %s, got %#v", window.Key, got.Terminal)
+		}
+	}
+	if dumper.session != "" {
+		t.Fatalf("expected no dump, got session %q", dumper.session)
+	}
+}
+
+func TestEnricherDumpFailureReturnsError(t *testing.T) {
+	t.Parallel()
+
+	enricher := NewEnricher(&stubDumper{err: errors.New("session not running")}, nil)
+	window := model.Window{Key: "w-1", AppID: "kitty", Terminal: &model.Terminal{SessionTag: "gone", Multiplexer: "zellij"}}
+	if _, err := enricher.EnrichWindow(window); err == nil {
+		t.Fatal("expected dump error")
+	}
+}
+
+type stubDumper struct {
+	layout  string
+	err     error
+	session string
+}
+
+func (s *stubDumper) DumpLayout(session string) (string, error) {
+	s.session = session
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.layout, nil
+}
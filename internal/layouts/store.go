@@ -0,0 +1,105 @@
+package layouts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/seal"
+)
+
+var ErrNotFound = errors.New("layout not found")
+
+type Store struct {
+	dir   string
+	codec *seal.Codec
+}
+
+func NewStore(root string) (*Store, error) {
+	codec, err := seal.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	return NewStoreWithCodec(root, codec)
+}
+
+func NewStoreWithCodec(root string, codec *seal.Codec) (*Store, error) {
+	dir := filepath.Join(root, "layouts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create layouts dir: %w", err)
+	}
+	return &Store{dir: dir, codec: codec}, nil
+}
+
+func Ref(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Store) Put(content string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", errors.New("layout is empty")
+	}
+	ref := Ref(content)
+	path := s.path(ref)
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err == nil {
+		return ref, nil
+	}
+
+	payload, err := s.codec.Seal([]byte(content))
+	if err != nil {
+		return "", fmt.Errorf("seal layout: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return "", fmt.Errorf("write layout: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("write layout: %w", err)
+	}
+	return ref, nil
+}
+
+func (s *Store) Layout(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.ContainsAny(ref, `/\.`) {
+		return "", fmt.Errorf("invalid layout ref: %q", ref)
+	}
+	payload, err := os.ReadFile(s.path(ref))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read layout: %w", err)
+	}
+	payload, err = s.codec.Open(payload)
+	if err != nil {
+		return "", fmt.Errorf("open layout: %w", err)
+	}
+	return string(payload), nil
+}
+
+func (s *Store) Remove(ref string) (bool, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.ContainsAny(ref, `/\.`) {
+		return false, fmt.Errorf("invalid layout ref: %q", ref)
+	}
+	err := os.Remove(s.path(ref))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("remove layout: %w", err)
+	}
+	return true, nil
+}
+
+func (s *Store) path(ref string) string {
+	return filepath.Join(s.dir, ref+".kdl")
+}
@@ -0,0 +1,130 @@
+package layouts
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/seal"
+)
+
+func TestPutIsContentAddressedAndTouchesExisting(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	store, err := NewStore(root)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	layout := "layout {\n    tab name=\"code\" {\n        pane cwd=\"/tmp\"\n    }\n}\n"
+	ref, err := store.Put(layout)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if ref != Ref(layout) {
+		t.Fatalf("expected content hash ref, got %s", ref)
+	}
+
+	path := filepath.Join(root, "layouts", ref+".kdl")
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	again, err := store.Put(layout)
+	if err != nil || again != ref {
+		t.Fatalf("expected same ref on re-put, got %s err=%v", again, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if !info.ModTime().After(old) {
+		t.Fatal("expected re-put to refresh layout mtime")
+	}
+
+	got, err := store.Layout(ref)
+	if err != nil || got != layout {
+		t.Fatalf("unexpected layout %q err=%v", got, err)
+	}
+}
+
+func TestLayoutMissingAndInvalidRefs(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	if _, err := store.Layout("deadbeef"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := store.Layout("../events"); err == nil {
+		t.Fatal("expected invalid ref error")
+	}
+	if _, err := store.Put("  \n"); err == nil {
+		t.Fatal("expected empty layout error")
+	}
+}
+
+func TestRemoveDeletesLayoutOnce(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	ref, err := store.Put("layout { pane; }")
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if removed, err := store.Remove(ref); err != nil || !removed {
+		t.Fatalf("expected layout removed, got %v err=%v", removed, err)
+	}
+	if _, err := store.Layout(ref); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected removed layout to be gone, got %v", err)
+	}
+	if removed, err := store.Remove(ref); err != nil || removed {
+		t.Fatalf("expected second remove to be a no-op, got %v err=%v", removed, err)
+	}
+	if _, err := store.Remove("../events"); err == nil {
+		t.Fatal("expected invalid ref error")
+	}
+}
+
+func TestPutSealsLayoutsWithStoreCodec(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	codec, err := seal.New(bytes.Repeat([]byte{3}, seal.KeySize), seal.KeySource{File: "/keys/redeem"})
+	if err != nil {
+		t.Fatalf("new codec: %v", err)
+	}
+	store, err := NewStoreWithCodec(root, codec)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	layout := "layout {\n    pane cwd=\"/home/me/secret\"\n}\n"
+	ref, err := store.Put(layout)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	payload, err := os.ReadFile(filepath.Join(root, "layouts", ref+".kdl"))
+	if err != nil {
+		t.Fatalf("read layout file: %v", err)
+	}
+	if !seal.IsSealed(payload) || strings.Contains(string(payload), "secret") {
+		t.Fatalf("expected sealed layout file, got %q", payload)
+	}
+	if got, err := store.Layout(ref); err != nil || got != layout {
+		t.Fatalf("unexpected layout %q err=%v", got, err)
+	}
+	if ref != Ref(layout) {
+		t.Fatalf("expected ref of the plaintext layout, got %s", ref)
+	}
+}
@@ -0,0 +1,272 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/capture"
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+const (
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+var collectBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var storeKinds = []struct {
+	kind string
+	path string
+}{
+	{kind: "events", path: "events.jsonl"},
+	{kind: "snapshots", path: "snapshots"},
+	{kind: "layouts", path: "layouts"},
+}
+
+type Config struct {
+	StateDir string
+	Textfile string
+	Now      func() time.Time
+	Logger   *slog.Logger
+}
+
+type Registry struct {
+	stateDir string
+	textfile string
+	now      func() time.Time
+	logger   *slog.Logger
+
+	mu                 sync.Mutex
+	capturesAttempted  uint64
+	capturesSucceeded  uint64
+	capturesFailed     uint64
+	eventsWritten      uint64
+	snapshotWrites     uint64
+	lastCaptureSuccess time.Time
+	collectCounts      []uint64
+	collectCount       uint64
+	collectSum         float64
+	enrichmentFailures map[string]uint64
+	windows            int
+	workspaces         int
+	hasState           bool
+}
+
+func NewRegistry(config Config) *Registry {
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Registry{
+		stateDir:           config.StateDir,
+		textfile:           config.Textfile,
+		now:                now,
+		logger:             logger,
+		collectCounts:      make([]uint64, len(collectBuckets)),
+		enrichmentFailures: map[string]uint64{},
+	}
+}
+
+func (r *Registry) ObserveCapture(result capture.Result, err error) {
+	r.mu.Lock()
+	r.capturesAttempted++
+	if err != nil {
+		r.capturesFailed++
+	} else {
+		r.capturesSucceeded++
+		r.eventsWritten += uint64(result.EventsWritten)
+		if result.SnapshotPath != "" {
+			r.snapshotWrites++
+		}
+		r.lastCaptureSuccess = r.now()
+	}
+	r.mu.Unlock()
+
+	if r.textfile == "" {
+		return
+	}
+	if err := r.WriteTextfile(r.textfile); err != nil {
+		r.logger.Warn("metrics_textfile_error", "path", r.textfile, "err", err)
+	}
+}
+
+func (r *Registry) CollectDone(duration time.Duration, state model.State, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seconds := duration.Seconds()
+	for i, bound := range collectBuckets {
+		if seconds <= bound {
+			r.collectCounts[i]++
+		}
+	}
+	r.collectCount++
+	r.collectSum += seconds
+	if err != nil {
+		return
+	}
+	r.windows = len(state.Windows)
+	r.workspaces = len(state.Workspaces)
+	r.hasState = true
+}
+
+func (r *Registry) EnrichmentFailed(_ model.Window, reason string, _ error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enrichmentFailures[reason]++
+}
+
+func (r *Registry) WriteText(w io.Writer, openMetrics bool) error {
+	r.mu.Lock()
+	var buf bytes.Buffer
+	counter(&buf, openMetrics, "redeem_captures_attempted", "Capture attempts.", r.capturesAttempted)
+	counter(&buf, openMetrics, "redeem_captures_succeeded", "Captures that were written to the store.", r.capturesSucceeded)
+	counter(&buf, openMetrics, "redeem_captures_failed", "Captures that failed.", r.capturesFailed)
+	counter(&buf, openMetrics, "redeem_events_written", "Events appended to events.jsonl.", r.eventsWritten)
+	counter(&buf, openMetrics, "redeem_snapshot_writes", "Snapshots written.", r.snapshotWrites)
+
+	header(&buf, counterFamily("redeem_enrichment_failures", openMetrics), "counter", "Per-window enrichment failures by reason.")
+	reasons := make([]string, 0, len(r.enrichmentFailures))
+	for reason := range r.enrichmentFailures {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		_, _ = fmt.Fprintf(&buf, "redeem_enrichment_failures_total{reason=%q} %d\n", reason, r.enrichmentFailures[reason])
+	}
+
+	header(&buf, "redeem_collect_duration_seconds", "histogram", "Time spent collecting compositor state.")
+	for i, bound := range collectBuckets {
+		_, _ = fmt.Fprintf(&buf, "redeem_collect_duration_seconds_bucket{le=%q} %d\n", formatFloat(bound), r.collectCounts[i])
+	}
+	_, _ = fmt.Fprintf(&buf, "redeem_collect_duration_seconds_bucket{le=\"+Inf\"} %d\n", r.collectCount)
+	_, _ = fmt.Fprintf(&buf, "redeem_collect_duration_seconds_sum %s\n", formatFloat(r.collectSum))
+	_, _ = fmt.Fprintf(&buf, "redeem_collect_duration_seconds_count %d\n", r.collectCount)
+
+	if r.hasState {
+		gauge(&buf, "redeem_windows", "Windows in the last collected state.", float64(r.windows))
+		gauge(&buf, "redeem_workspaces", "Workspaces in the last collected state.", float64(r.workspaces))
+	}
+	if !r.lastCaptureSuccess.IsZero() {
+		gauge(&buf, "redeem_last_capture_success_timestamp_seconds", "Unix time of the last successful capture.", float64(r.lastCaptureSuccess.UnixNano())/1e9)
+	}
+	r.mu.Unlock()
+
+	if r.stateDir != "" {
+		header(&buf, "redeem_store_bytes", "gauge", "Bytes on disk in the state directory.")
+		for _, entry := range storeKinds {
+			_, _ = fmt.Fprintf(&buf, "redeem_store_bytes{kind=%q} %d\n", entry.kind, pathSize(filepath.Join(r.stateDir, entry.path)))
+		}
+	}
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (r *Registry) WriteTextfile(path string) error {
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf, false); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create textfile dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create textfile temp: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write textfile temp: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("chmod textfile temp: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("close textfile temp: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename textfile: %w", err)
+	}
+	return nil
+}
+
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+		if openMetrics {
+			w.Header().Set("Content-Type", contentTypeOpenMetrics)
+		} else {
+			w.Header().Set("Content-Type", contentTypeText)
+		}
+		_ = r.WriteText(w, openMetrics)
+	})
+	return mux
+}
+
+func header(buf *bytes.Buffer, name string, kind string, help string) {
+	_, _ = fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	_, _ = fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+}
+
+func counter(buf *bytes.Buffer, openMetrics bool, name string, help string, value uint64) {
+	header(buf, counterFamily(name, openMetrics), "counter", help)
+	_, _ = fmt.Fprintf(buf, "%s_total %d\n", name, value)
+}
+
+func counterFamily(name string, openMetrics bool) string {
+	if openMetrics {
+		return name
+	}
+	return name + "_total"
+}
+
+func gauge(buf *bytes.Buffer, name string, help string, value float64) {
+	header(buf, name, "gauge", help)
+	_, _ = fmt.Fprintf(buf, "%s %s\n", name, formatFloat(value))
+}
+
+func formatFloat(value float64) string {
+	return fmt.Sprintf("%g", value)
+}
+
+func pathSize(path string) int64 {
+	var total int64
+	_ = filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/capture"
+	"github.com/jmo/terminal-redeemer/internal/collector"
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestWriteTextReportsCaptureAndCollectMetrics(t *testing.T) {
+	t.Parallel()
+
+	stateDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(stateDir, "events.jsonl"), []byte("0123456789"), 0o600); err != nil {
+		t.Fatalf("write events: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(stateDir, "snapshots"), 0o755); err != nil {
+		t.Fatalf("mkdir snapshots: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, "snapshots", "a.json"), []byte("abcd"), 0o600); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+
+	registry := NewRegistry(Config{
+		StateDir: stateDir,
+		Now:      func() time.Time { return time.Unix(1700000000, 0) },
+	})
+	registry.CollectDone(30*time.Millisecond, model.State{
+		Workspaces: []model.Workspace{{ID: "ws-1"}},
+		Windows:    []model.Window{{Key: "w-1"}, {Key: "w-2"}},
+	}, nil)
+	registry.EnrichmentFailed(model.Window{Key: "w-1"}, collector.ReasonNotFound, os.ErrNotExist)
+	registry.EnrichmentFailed(model.Window{Key: "w-2"}, collector.ReasonNotFound, os.ErrNotExist)
+	registry.ObserveCapture(capture.Result{EventsWritten: 3, SnapshotPath: "snap.json"}, nil)
+	registry.ObserveCapture(capture.Result{}, errors.New("niri unavailable"))
+
+	var out bytes.Buffer
+	if err := registry.WriteText(&out, false); err != nil {
+		t.Fatalf("write text: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		"# TYPE redeem_captures_attempted_total counter\n",
+		"redeem_captures_attempted_total 2\n",
+		"redeem_captures_succeeded_total 1\n",
+		"redeem_captures_failed_total 1\n",
+		"redeem_events_written_total 3\n",
+		"redeem_snapshot_writes_total 1\n",
+		"redeem_enrichment_failures_total{reason=\"not_found\"} 2\n",
+		"redeem_collect_duration_seconds_bucket{le=\"0.025\"} 0\n",
+		"redeem_collect_duration_seconds_bucket{le=\"0.05\"} 1\n",
+		"redeem_collect_duration_seconds_bucket{le=\"+Inf\"} 1\n",
+		"redeem_collect_duration_seconds_count 1\n",
+		"redeem_windows 2\n",
+		"redeem_workspaces 1\n",
+		"redeem_last_capture_success_timestamp_seconds 1.7e+09\n",
+		"redeem_store_bytes{kind=\"events\"} 10\n",
+		"redeem_store_bytes{kind=\"snapshots\"} 4\n",
+		"redeem_store_bytes{kind=\"layouts\"} 0\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in output:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "# EOF") {
+		t.Fatalf("text format must not end with EOF marker:\n%s", text)
+	}
+}
+
+func TestHandlerNegotiatesOpenMetrics(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry(Config{})
+	registry.ObserveCapture(capture.Result{EventsWritten: 1}, nil)
+
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, request)
+
+	if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/openmetrics-text") {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, "# TYPE redeem_captures_attempted counter\n") || !strings.Contains(body, "redeem_captures_attempted_total 1\n") {
+		t.Fatalf("unexpected openmetrics body:\n%s", body)
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Fatalf("expected EOF marker:\n%s", body)
+	}
+	if strings.Contains(body, "redeem_windows") {
+		t.Fatalf("window gauge must be absent before a collect:\n%s", body)
+	}
+}
+
+func TestObserveCaptureWritesTextfile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "collector", "redeem.prom")
+	registry := NewRegistry(Config{Textfile: path})
+	registry.ObserveCapture(capture.Result{EventsWritten: 1}, nil)
+
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read textfile: %v", err)
+	}
+	if !strings.Contains(string(payload), "redeem_captures_succeeded_total 1\n") {
+		t.Fatalf("unexpected textfile:\n%s", payload)
+	}
+	leftovers, err := filepath.Glob(filepath.Join(filepath.Dir(path), ".redeem.prom.tmp-*"))
+	if err != nil || len(leftovers) != 0 {
+		t.Fatalf("expected no temp files, got %v (%v)", leftovers, err)
+	}
+}
+
+func TestObserveCaptureLogsTextfileErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatalf("write blocker: %v", err)
+	}
+	var logs bytes.Buffer
+	registry := NewRegistry(Config{Textfile: filepath.Join(blocker, "redeem.prom"), Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+	registry.ObserveCapture(capture.Result{}, nil)
+
+	if !strings.Contains(logs.String(), "metrics_textfile_error") {
+		t.Fatalf("expected textfile error log, got %q", logs.String())
+	}
+}
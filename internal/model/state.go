@@ -28,11 +28,50 @@ type Window struct {
 }
 
 type Terminal struct {
-	CWD         string   `json:"cwd,omitempty"`
-	ProcessTags []string `json:"process_tags,omitempty"`
-	SessionTag  string   `json:"session_tag,omitempty"`
+	CWD         string              `json:"cwd,omitempty"`
+	ProcessTags []string            `json:"process_tags,omitempty"`
+	ProcessArgs map[string][]string `json:"process_args,omitempty"`
+	SessionTag  string              `json:"session_tag,omitempty"`
+	Multiplexer string              `json:"multiplexer,omitempty"`
+	LayoutRef   string              `json:"layout_ref,omitempty"`
+	Tabs        []Tab               `json:"tabs,omitempty"`
+	Nvim        *Nvim               `json:"nvim,omitempty"`
+	Git         *Git                `json:"git,omitempty"`
 }
 
+type Nvim struct {
+	CWD     string   `json:"cwd,omitempty"`
+	Session string   `json:"session,omitempty"`
+	Buffers []string `json:"buffers,omitempty"`
+}
+
+type Git struct {
+	Root     string `json:"root"`
+	Branch   string `json:"branch,omitempty"`
+	Worktree string `json:"worktree,omitempty"`
+	Dirty    bool   `json:"dirty,omitempty"`
+}
+
+type Tab struct {
+	Title  string `json:"title,omitempty"`
+	Layout string `json:"layout,omitempty"`
+	Active bool   `json:"active,omitempty"`
+	Panes  []Pane `json:"panes"`
+}
+
+type Pane struct {
+	Title   string   `json:"title,omitempty"`
+	CWD     string   `json:"cwd,omitempty"`
+	Command []string `json:"command,omitempty"`
+	Active  bool     `json:"active,omitempty"`
+	Split   string   `json:"split,omitempty"`
+}
+
+const (
+	SplitRight  = "right"
+	SplitBottom = "bottom"
+)
+
 func Normalize(s State) State {
 	out := State{
 		Workspaces: append([]Workspace(nil), s.Workspaces...),
@@ -0,0 +1,76 @@
+package nvim
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int64
+}
+
+func Dial(address string, timeout time.Duration) (*Client, error) {
+	network := "unix"
+	if !strings.Contains(address, "/") && strings.Contains(address, ":") {
+		network = "tcp"
+	}
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+	}
+	return &Client{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) Call(method string, args ...any) (any, error) {
+	c.nextID++
+	id := c.nextID
+	if args == nil {
+		args = []any{}
+	}
+	if err := encode(c.conn, []any{int64(0), id, method, args}); err != nil {
+		return nil, fmt.Errorf("send %s: %w", method, err)
+	}
+
+	for {
+		raw, err := decode(c.reader)
+		if err != nil {
+			return nil, fmt.Errorf("read %s response: %w", method, err)
+		}
+		message, ok := raw.([]any)
+		if !ok || len(message) == 0 {
+			return nil, fmt.Errorf("unexpected rpc message %v", raw)
+		}
+		if kind, _ := message[0].(int64); kind != 1 {
+			continue
+		}
+		if len(message) != 4 {
+			return nil, fmt.Errorf("malformed rpc response %v", message)
+		}
+		if msgID, _ := message[1].(int64); msgID != id {
+			continue
+		}
+		if message[2] != nil {
+			return nil, fmt.Errorf("%s: %v", method, rpcErrorMessage(message[2]))
+		}
+		return message[3], nil
+	}
+}
+
+func rpcErrorMessage(raw any) string {
+	if values, ok := raw.([]any); ok && len(values) == 2 {
+		return fmt.Sprint(values[1])
+	}
+	return fmt.Sprint(raw)
+}
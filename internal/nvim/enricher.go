@@ -0,0 +1,181 @@
+package nvim
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/procmeta"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
+)
+
+const stateLua = `local buffers = {}
+for _, buf in ipairs(vim.api.nvim_list_bufs()) do
+  if vim.bo[buf].buflisted and vim.bo[buf].buftype == '' then
+    local name = vim.api.nvim_buf_get_name(buf)
+    if name ~= '' then
+      table.insert(buffers, name)
+    end
+  end
+end
+return { cwd = vim.fn.getcwd(), session = vim.v.this_session, buffers = buffers }`
+
+type Querier interface {
+	Query(address string) (model.Nvim, error)
+}
+
+type EnvLookup interface {
+	LookupEnv(pid int, name string) (string, bool)
+}
+
+type RPCQuerier struct {
+	Timeout time.Duration
+}
+
+func (q RPCQuerier) Query(address string) (model.Nvim, error) {
+	timeout := q.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	client, err := Dial(address, timeout)
+	if err != nil {
+		return model.Nvim{}, err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	result, err := client.Call("nvim_exec_lua", stateLua, []any{})
+	if err != nil {
+		return model.Nvim{}, err
+	}
+	return parseState(result)
+}
+
+func parseState(raw any) (model.Nvim, error) {
+	values, ok := raw.(map[string]any)
+	if !ok {
+		return model.Nvim{}, fmt.Errorf("unexpected nvim state %T", raw)
+	}
+	state := model.Nvim{}
+	state.CWD, _ = values["cwd"].(string)
+	state.Session, _ = values["session"].(string)
+	buffers, _ := values["buffers"].([]any)
+	for _, buffer := range buffers {
+		if name, ok := buffer.(string); ok && name != "" {
+			state.Buffers = append(state.Buffers, name)
+		}
+	}
+	return state, nil
+}
+
+type Config struct {
+	Terminals  *terminals.Matcher
+	RuntimeDir string
+}
+
+type Enricher struct {
+	reader  procmeta.Reader
+	env     EnvLookup
+	querier Querier
+	config  Config
+}
+
+func NewEnricher(reader procmeta.Reader, env EnvLookup, querier Querier, config Config) *Enricher {
+	if querier == nil {
+		querier = RPCQuerier{}
+	}
+	if config.Terminals == nil {
+		config.Terminals = terminals.Default()
+	}
+	if strings.TrimSpace(config.RuntimeDir) == "" {
+		config.RuntimeDir = os.Getenv("XDG_RUNTIME_DIR")
+	}
+	return &Enricher{reader: reader, env: env, querier: querier, config: config}
+}
+
+func (e *Enricher) EnrichWindow(window model.Window) (model.Window, error) {
+	if !e.config.Terminals.IsTerminal(window.AppID) || window.PID <= 0 {
+		return window, nil
+	}
+	info, err := e.reader.Inspect(window.PID)
+	if err != nil {
+		return model.Window{}, err
+	}
+	addresses := e.serverAddresses(info.Descendants)
+	if len(addresses) == 0 {
+		return window, nil
+	}
+
+	var lastErr error
+	for _, address := range addresses {
+		state, err := e.querier.Query(address)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		out := window
+		terminal := model.Terminal{}
+		if window.Terminal != nil {
+			terminal = *window.Terminal
+		}
+		terminal.Nvim = &state
+		if terminal.CWD == "" {
+			terminal.CWD = state.CWD
+		}
+		out.Terminal = &terminal
+		return out, nil
+	}
+	return model.Window{}, fmt.Errorf("query nvim: %w", lastErr)
+}
+
+func (e *Enricher) serverAddresses(processes []procmeta.Process) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	add := func(address string) {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			return
+		}
+		if _, ok := seen[address]; ok {
+			return
+		}
+		seen[address] = struct{}{}
+		out = append(out, address)
+	}
+
+	for _, process := range processes {
+		if strings.ToLower(process.Name) != "nvim" {
+			continue
+		}
+		add(listenAddress(process.Args))
+		if e.config.RuntimeDir != "" {
+			socket := filepath.Join(e.config.RuntimeDir, "nvim."+strconv.Itoa(process.PID)+".0")
+			if _, err := os.Stat(socket); err == nil {
+				add(socket)
+			}
+		}
+		if e.env != nil {
+			if address, ok := e.env.LookupEnv(process.PID, "NVIM"); ok {
+				add(address)
+			}
+		}
+	}
+	return out
+}
+
+func listenAddress(args []string) string {
+	for i, arg := range args {
+		if arg == "--listen" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, "--listen="); ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package nvim
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/procmeta"
+)
+
+func TestRPCQuerierReadsStateFromServer(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(shortTempDir(t), "nvim.sock")
+	calls := serveFakeNvim(t, socket, func(method string, args []any) (any, any) {
+		if method != "nvim_exec_lua" || len(args) != 2 {
+			return nil, []any{int64(0), "unexpected call"}
+		}
+		return map[string]any{
+			"cwd":     "/home/jmo/src/redeemer",
+			"session": "",
+			"buffers": []any{"/home/jmo/src/redeemer/main.go", "/home/jmo/src/redeemer/README.md"},
+		}, nil
+	})
+
+	state, err := RPCQuerier{}.Query(socket)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if state.CWD != "/home/jmo/src/redeemer" || state.Session != "" || len(state.Buffers) != 2 || state.Buffers[1] != "/home/jmo/src/redeemer/README.md" {
+		t.Fatalf("unexpected state: %#v", state)
+	}
+	if got := <-calls; got != "nvim_exec_lua" {
+		t.Fatalf("unexpected method %q", got)
+	}
+}
+
+func TestRPCQuerierSurfacesServerErrors(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(shortTempDir(t), "nvim.sock")
+	serveFakeNvim(t, socket, func(string, []any) (any, any) {
+		return nil, []any{int64(1), "Vim:E5108: lua error"}
+	})
+
+	if _, err := (RPCQuerier{}).Query(socket); err == nil || err.Error() != "nvim_exec_lua: Vim:E5108: lua error" {
+		t.Fatalf("expected rpc error, got %v", err)
+	}
+}
+
+func TestEnricherFindsDefaultServerSocketForNvimDescendant(t *testing.T) {
+	t.Parallel()
+
+	runtimeDir := shortTempDir(t)
+	serveFakeNvim(t, filepath.Join(runtimeDir, "nvim.5180.0"), func(string, []any) (any, any) {
+		return map[string]any{"cwd": "/srv/app", "session": "/srv/app/Session.vim", "buffers": map[string]any{}}, nil
+	})
+	reader := stubReader{info: procmeta.ProcessInfo{Descendants: []procmeta.Process{
+		{PID: 5101, Name: "zsh"},
+		{PID: 5179, Name: "nvim", Args: []string{"nvim"}},
+		{PID: 5180, Name: "nvim", Args: []string{"/usr/bin/nvim", "--embed"}},
+	}}}
+	enricher := NewEnricher(reader, nil, nil, Config{RuntimeDir: runtimeDir})
+
+	got, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "kitty", PID: 5100, Terminal: &model.Terminal{CWD: "/srv"}})
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal == nil || got.Terminal.Nvim == nil || got.Terminal.Nvim.Session != "/srv/app/Session.vim" || len(got.Terminal.Nvim.Buffers) != 0 {
+		t.Fatalf("unexpected nvim metadata: %#v", got.Terminal)
+	}
+	if got.Terminal.CWD != "/srv" {
+		t.Fatalf("expected terminal cwd preserved, got %q", got.Terminal.CWD)
+	}
+}
+
+func TestEnricherPrefersListenAddressAndReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	querier := &stubQuerier{err: errors.New("connection refused")}
+	reader := stubReader{info: procmeta.ProcessInfo{Descendants: []procmeta.Process{
+		{PID: 7, Name: "nvim", Args: []string{"nvim", "--listen", "/tmp/custom.sock", "notes.md"}},
+	}}}
+	enricher := NewEnricher(reader, stubEnv{"NVIM": "/tmp/parent.sock"}, querier, Config{RuntimeDir: shortTempDir(t)})
+
+	if _, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "kitty", PID: 5}); err == nil {
+		t.Fatal("expected query failure")
+	}
+	if len(querier.addresses) != 2 || querier.addresses[0] != "/tmp/custom.sock" || querier.addresses[1] != "/tmp/parent.sock" {
+		t.Fatalf("unexpected query order: %#v", querier.addresses)
+	}
+}
+
+func TestEnricherWithoutNvimLeavesWindowUnchanged(t *testing.T) {
+	t.Parallel()
+
+	querier := &stubQuerier{}
+	reader := stubReader{info: procmeta.ProcessInfo{Descendants: []procmeta.Process{{PID: 1, Name: "zsh"}}}}
+	got, err := NewEnricher(reader, nil, querier, Config{}).EnrichWindow(model.Window{Key: "w-1", AppID: "kitty", PID: 5})
+	if err != nil || got.Terminal != nil || len(querier.addresses) != 0 {
+		t.Fatalf("expected untouched window, got %#v err=%v queries=%v", got.Terminal, err, querier.addresses)
+	}
+}
+
+func serveFakeNvim(t *testing.T, socket string, handle func(method string, args []any) (any, any)) <-chan string {
+	t.Helper()
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	calls := make(chan string, 8)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			raw, err := decode(reader)
+			if err != nil {
+				_ = conn.Close()
+				continue
+			}
+			request := raw.([]any)
+			method := request[2].(string)
+			calls <- method
+			result, rpcErr := handle(method, request[3].([]any))
+			_ = encode(conn, []any{int64(2), "nvim_buf_lines_event", []any{}})
+			_ = encode(conn, []any{int64(1), request[1], rpcErr, result})
+			_ = conn.Close()
+		}
+	}()
+	return calls
+}
+
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "nvim")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	return dir
+}
+
+type stubReader struct {
+	info procmeta.ProcessInfo
+}
+
+func (s stubReader) Inspect(int) (procmeta.ProcessInfo, error) {
+	return s.info, nil
+}
+
+type stubEnv map[string]string
+
+func (s stubEnv) LookupEnv(_ int, name string) (string, bool) {
+	value, ok := s[name]
+	return value, ok
+}
+
+type stubQuerier struct {
+	state     model.Nvim
+	err       error
+	addresses []string
+}
+
+func (s *stubQuerier) Query(address string) (model.Nvim, error) {
+	s.addresses = append(s.addresses, address)
+	return s.state, s.err
+}
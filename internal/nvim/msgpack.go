@@ -0,0 +1,291 @@
+package nvim
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+func encode(w io.Writer, value any) error {
+	var buf []byte
+	buf, err := appendValue(buf, value)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func appendValue(buf []byte, value any) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if v {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case int:
+		return appendInt(buf, int64(v)), nil
+	case int64:
+		return appendInt(buf, v), nil
+	case uint64:
+		if v <= math.MaxInt64 {
+			return appendInt(buf, int64(v)), nil
+		}
+		buf = append(buf, 0xcf)
+		return binary.BigEndian.AppendUint64(buf, v), nil
+	case string:
+		return appendString(buf, v), nil
+	case []string:
+		buf = appendArrayHeader(buf, len(v))
+		for _, item := range v {
+			buf = appendString(buf, item)
+		}
+		return buf, nil
+	case []any:
+		buf = appendArrayHeader(buf, len(v))
+		for _, item := range v {
+			var err error
+			if buf, err = appendValue(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf = appendMapHeader(buf, len(v))
+		for _, key := range keys {
+			buf = appendString(buf, key)
+			var err error
+			if buf, err = appendValue(buf, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type %T", value)
+	}
+}
+
+func appendInt(buf []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= 0x7f:
+		return append(buf, byte(v))
+	case v < 0 && v >= -32:
+		return append(buf, byte(int8(v)))
+	case v >= 0 && v <= math.MaxUint32:
+		buf = append(buf, 0xce)
+		return binary.BigEndian.AppendUint32(buf, uint32(v))
+	default:
+		buf = append(buf, 0xd3)
+		return binary.BigEndian.AppendUint64(buf, uint64(v))
+	}
+}
+
+func appendString(buf []byte, v string) []byte {
+	n := len(v)
+	switch {
+	case n <= 31:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = append(buf, 0xda)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 0xdb)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(n))
+	}
+	return append(buf, v...)
+}
+
+func appendArrayHeader(buf []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		buf = append(buf, 0xdc)
+		return binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 0xdd)
+		return binary.BigEndian.AppendUint32(buf, uint32(n))
+	}
+}
+
+func appendMapHeader(buf []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		buf = append(buf, 0xde)
+		return binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 0xdf)
+		return binary.BigEndian.AppendUint32(buf, uint32(n))
+	}
+}
+
+var errMsgpackTooLarge = errors.New("msgpack: value too large")
+
+const maxMsgpackLength = 16 << 20
+
+func decode(r *bufio.Reader) (any, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xe0 == 0xa0:
+		return readString(r, int(b&0x1f))
+	case b&0xf0 == 0x90:
+		return readArray(r, int(b&0x0f))
+	case b&0xf0 == 0x80:
+		return readMap(r, int(b&0x0f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readUint(r, 1<<(b-0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		n, err := readUint(r, size)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, nil
+	case 0xca:
+		n, err := readUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 0xcb:
+		n, err := readUint(r, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(n), nil
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		width := map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4, 0xc4: 1, 0xc5: 2, 0xc6: 4}[b]
+		n, err := readUint(r, width)
+		if err != nil {
+			return nil, err
+		}
+		return readString(r, int(n))
+	case 0xdc, 0xdd:
+		n, err := readUint(r, 2<<(b-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readArray(r, int(n))
+	case 0xde, 0xdf:
+		n, err := readUint(r, 2<<(b-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMap(r, int(n))
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readExt(r, 1<<(b-0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readUint(r, 1<<(b-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return readExt(r, int(n))
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type byte 0x%x", b)
+}
+
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, b := range raw {
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
+
+func readString(r *bufio.Reader, n int) (string, error) {
+	if n > maxMsgpackLength {
+		return "", errMsgpackTooLarge
+	}
+	raw := make([]byte, n)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func readArray(r *bufio.Reader, n int) ([]any, error) {
+	if n > maxMsgpackLength {
+		return nil, errMsgpackTooLarge
+	}
+	out := make([]any, 0, min(n, 1024))
+	for range n {
+		value, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, value)
+	}
+	return out, nil
+}
+
+func readMap(r *bufio.Reader, n int) (map[string]any, error) {
+	if n > maxMsgpackLength {
+		return nil, errMsgpackTooLarge
+	}
+	out := make(map[string]any, min(n, 1024))
+	for range n {
+		key, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		out[fmt.Sprint(key)] = value
+	}
+	return out, nil
+}
+
+func readExt(r *bufio.Reader, n int) (any, error) {
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+	if _, err := readString(r, n); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
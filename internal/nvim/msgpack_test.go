@@ -0,0 +1,43 @@
+package nvim
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	value := []any{
+		int64(0), int64(-5), int64(300), int64(-70000), int64(1 << 40),
+		nil, true, false,
+		"short", strings.Repeat("x", 300),
+		map[string]any{"buffers": []any{"/tmp/a", "/tmp/b"}, "cwd": "/tmp"},
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, value); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	got, err := decode(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, value) {
+		t.Fatalf("round trip mismatch:\n got %#v\nwant %#v", got, value)
+	}
+}
+
+func TestMsgpackDecodesExtAsNil(t *testing.T) {
+	t.Parallel()
+
+	got, err := decode(bufio.NewReader(bytes.NewReader([]byte{0x92, 0xd4, 0x00, 0x01, 0xa1, 'a'})))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, []any{nil, "a"}) {
+		t.Fatalf("unexpected value %#v", got)
+	}
+}
@@ -1,11 +1,13 @@
 package procmeta
 
 import (
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 
 	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
 )
 
 type Reader interface {
@@ -15,26 +17,33 @@ type Reader interface {
 type ProcessInfo struct {
 	CWD          string
 	ProcessChain []string
+	Descendants  []Process
 	Args         []string
 	Env          map[string]string
 }
 
+type Process struct {
+	PID  int
+	Name string
+	Args []string
+}
+
 type Config struct {
 	Whitelist         []string
 	WhitelistExtra    []string
 	IncludeSessionTag bool
+	Terminals         *terminals.Matcher
 }
 
 type Enricher struct {
-	reader    Reader
-	whitelist map[string]struct{}
-	config    Config
-	verifier  SessionVerifier
-	resolver  SessionCWDResolver
+	reader       Reader
+	whitelist    map[string]struct{}
+	config       Config
+	multiplexers []Multiplexer
 }
 
 func NewEnricher(reader Reader, config Config) *Enricher {
-	return NewEnricherWithDependencies(reader, config, NewZellijSessionVerifier(nil), NewZellijSessionCWDResolver(""))
+	return NewEnricherWithMultiplexers(reader, config, DefaultMultiplexers())
 }
 
 func NewEnricherWithVerifier(reader Reader, config Config, verifier SessionVerifier) *Enricher {
@@ -42,6 +51,10 @@ func NewEnricherWithVerifier(reader Reader, config Config, verifier SessionVerif
 }
 
 func NewEnricherWithDependencies(reader Reader, config Config, verifier SessionVerifier, resolver SessionCWDResolver) *Enricher {
+	return NewEnricherWithMultiplexers(reader, config, []Multiplexer{Zellij{Verifier: verifier, Resolver: resolver}})
+}
+
+func NewEnricherWithMultiplexers(reader Reader, config Config, multiplexers []Multiplexer) *Enricher {
 	whitelist := map[string]struct{}{
 		"opencode": {},
 		"claude":   {},
@@ -53,11 +66,15 @@ func NewEnricherWithDependencies(reader Reader, config Config, verifier SessionV
 		whitelist[strings.ToLower(strings.TrimSpace(p))] = struct{}{}
 	}
 
-	return &Enricher{reader: reader, whitelist: whitelist, config: config, verifier: verifier, resolver: resolver}
+	if config.Terminals == nil {
+		config.Terminals = terminals.Default()
+	}
+
+	return &Enricher{reader: reader, whitelist: whitelist, config: config, multiplexers: multiplexers}
 }
 
 func (e *Enricher) EnrichWindow(window model.Window) (model.Window, error) {
-	if !isTerminal(window.AppID) {
+	if !e.config.Terminals.IsTerminal(window.AppID) {
 		return window, nil
 	}
 	if window.PID <= 0 {
@@ -74,14 +91,23 @@ func (e *Enricher) EnrichWindow(window model.Window) (model.Window, error) {
 	if strings.TrimSpace(info.CWD) != "" {
 		terminal.CWD = info.CWD
 	}
-	tags := e.filterTags(info.ProcessChain)
+	names := append([]string(nil), info.ProcessChain...)
+	for _, process := range info.Descendants {
+		names = append(names, process.Name)
+	}
+	tags := e.filterTags(names)
 	if len(tags) > 0 {
 		terminal.ProcessTags = tags
 	}
+	if args := e.taggedProcessArgs(info.Descendants); len(args) > 0 {
+		terminal.ProcessArgs = args
+	}
 	if e.config.IncludeSessionTag {
-		terminal.SessionTag = e.extractSessionTag(window.Title, info)
-		if session := strings.TrimSpace(terminal.SessionTag); session != "" && e.resolver != nil {
-			if upgraded, err := e.resolver.Resolve(session); err == nil {
+		session, multiplexer := e.extractSessionTag(window.Title, info)
+		if session = strings.TrimSpace(session); session != "" {
+			terminal.SessionTag = session
+			terminal.Multiplexer = multiplexer.Name()
+			if upgraded, err := multiplexer.ResolveCWD(session); err == nil {
 				upgraded = strings.TrimSpace(upgraded)
 				if upgraded != "" {
 					terminal.CWD = upgraded
@@ -97,21 +123,38 @@ func (e *Enricher) EnrichWindow(window model.Window) (model.Window, error) {
 	return out, nil
 }
 
-func (e *Enricher) extractSessionTag(windowTitle string, info ProcessInfo) string {
-	if session := extractSessionTagFromProcess(info); session != "" {
-		return session
+func (e *Enricher) taggedProcessArgs(processes []Process) map[string][]string {
+	out := make(map[string][]string)
+	for _, process := range processes {
+		name := strings.ToLower(strings.TrimSpace(process.Name))
+		if _, ok := e.whitelist[name]; !ok || len(process.Args) == 0 {
+			continue
+		}
+		if _, seen := out[name]; seen {
+			continue
+		}
+		out[name] = append([]string(nil), process.Args...)
 	}
+	return out
+}
 
-	candidate := extractSessionTagFromTitle(windowTitle)
-	if candidate == "" || e.verifier == nil {
-		return ""
+func (e *Enricher) extractSessionTag(windowTitle string, info ProcessInfo) (string, Multiplexer) {
+	for _, multiplexer := range e.multiplexers {
+		if session := multiplexer.Detect(info); session != "" {
+			return session, multiplexer
+		}
 	}
 
-	ok, err := e.verifier.Exists(candidate)
-	if err != nil || !ok {
-		return ""
+	candidate := extractSessionTagFromTitle(windowTitle)
+	if candidate == "" {
+		return "", nil
+	}
+	for _, multiplexer := range e.multiplexers {
+		if ok, err := multiplexer.Exists(candidate); err == nil && ok {
+			return candidate, multiplexer
+		}
 	}
-	return candidate
+	return "", nil
 }
 
 func (e *Enricher) filterTags(chain []string) []string {
@@ -130,15 +173,6 @@ func (e *Enricher) filterTags(chain []string) []string {
 	return out
 }
 
-func isTerminal(appID string) bool {
-	switch strings.ToLower(strings.TrimSpace(appID)) {
-	case "kitty", "alacritty", "foot", "wezterm":
-		return true
-	default:
-		return false
-	}
-}
-
 var titleSessionPattern = regexp.MustCompile(`\[session:([^\]]+)\]`)
 var titlePrefixPattern = regexp.MustCompile(`^([^|]+)\s+\|`)
 
@@ -147,7 +181,18 @@ func extractSessionTagFromProcess(info ProcessInfo) string {
 		return session
 	}
 
-	for i := range info.Args {
+	start := -1
+	for i, arg := range info.Args {
+		if filepath.Base(arg) == "zellij" {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+
+	for i := start; i < len(info.Args); i++ {
 		arg := info.Args[i]
 		if (arg == "--session" || arg == "-s" || arg == "attach") && i+1 < len(info.Args) {
 			next := strings.TrimSpace(info.Args[i+1])
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
 )
 
 func TestCWDExtractionBehavior(t *testing.T) {
@@ -24,6 +25,67 @@ func TestCWDExtractionBehavior(t *testing.T) {
 	}
 }
 
+func TestConfiguredTerminalPatternsAreEnriched(t *testing.T) {
+	t.Parallel()
+
+	matcher, err := terminals.NewMatcher([]terminals.Rule{{Pattern: "kitty-*", Kind: "kitty"}, {Pattern: "org.kde.konsole", Kind: "konsole"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	reader := stubReader{byPID: map[int]ProcessInfo{4242: {CWD: "/tmp/scratch"}, 4343: {CWD: "/tmp/konsole"}}}
+
+	window := model.Window{Key: "w-1", AppID: "kitty-scratch", PID: 4242}
+	got, err := NewEnricher(reader, Config{}).EnrichWindow(window)
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal != nil {
+		t.Fatalf("expected unconfigured app id to be left alone, got %#v", got.Terminal)
+	}
+
+	got, err = NewEnricher(reader, Config{Terminals: matcher}).EnrichWindow(window)
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal == nil || got.Terminal.CWD != "/tmp/scratch" {
+		t.Fatalf("expected configured terminal to be enriched, got %#v", got.Terminal)
+	}
+
+	got, err = NewEnricher(reader, Config{Terminals: matcher}).EnrichWindow(model.Window{Key: "w-2", AppID: "org.kde.konsole", PID: 4343})
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal == nil || got.Terminal.CWD != "/tmp/konsole" {
+		t.Fatalf("expected capture-only terminal kind to be enriched, got %#v", got.Terminal)
+	}
+}
+
+func TestTaggedDescendantArgvIsCaptured(t *testing.T) {
+	t.Parallel()
+
+	reader := stubReader{byPID: map[int]ProcessInfo{4242: {
+		CWD: "/tmp/project",
+		Descendants: []Process{
+			{PID: 1, Name: "zsh", Args: []string{"-zsh"}},
+			{PID: 2, Name: "claude", Args: []string{"claude", "--resume", "abc"}},
+			{PID: 3, Name: "opencode"},
+		},
+	}}}
+	enricher := NewEnricher(reader, Config{})
+
+	got, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "kitty", PID: 4242})
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal == nil || len(got.Terminal.ProcessTags) != 2 {
+		t.Fatalf("expected descendant tags, got %#v", got.Terminal)
+	}
+	args := got.Terminal.ProcessArgs
+	if len(args) != 1 || len(args["claude"]) != 3 || args["claude"][2] != "abc" {
+		t.Fatalf("unexpected process args: %#v", args)
+	}
+}
+
 func TestWhitelistProcessTagsDefaultAndExtras(t *testing.T) {
 	t.Parallel()
 
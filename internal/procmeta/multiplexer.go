@@ -0,0 +1,202 @@
+package procmeta
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	MultiplexerZellij = "zellij"
+	MultiplexerTmux   = "tmux"
+)
+
+type Multiplexer interface {
+	Name() string
+	Detect(info ProcessInfo) string
+	Exists(session string) (bool, error)
+	ResolveCWD(session string) (string, error)
+	AttachOrCreateCommand(session string) string
+}
+
+type LayoutCreator interface {
+	CreateWithLayoutCommand(session string, layoutPath string) string
+}
+
+func DefaultMultiplexers() []Multiplexer {
+	return []Multiplexer{NewZellij(), NewTmux(nil)}
+}
+
+type Zellij struct {
+	Verifier SessionVerifier
+	Resolver SessionCWDResolver
+}
+
+func NewZellij() Zellij {
+	return Zellij{Verifier: NewZellijSessionVerifier(nil), Resolver: NewZellijSessionCWDResolver("")}
+}
+
+func (Zellij) Name() string {
+	return MultiplexerZellij
+}
+
+func (Zellij) Detect(info ProcessInfo) string {
+	return extractSessionTagFromProcess(info)
+}
+
+func (z Zellij) Exists(session string) (bool, error) {
+	if z.Verifier == nil {
+		return false, nil
+	}
+	return z.Verifier.Exists(session)
+}
+
+func (z Zellij) ResolveCWD(session string) (string, error) {
+	if z.Resolver == nil {
+		return "", nil
+	}
+	return z.Resolver.Resolve(session)
+}
+
+const zellijEnv = "env -u ZELLIJ -u ZELLIJ_SESSION_NAME -u ZELLIJ_PANE_ID -u ZELLIJ_TAB_INDEX -u ZELLIJ_TAB_NAME"
+
+func (Zellij) AttachOrCreateCommand(session string) string {
+	return fmt.Sprintf("%s zellij attach --create %s || %s", zellijEnv, shellQuote(session), fallbackShell("zellij attach failed", session))
+}
+
+func (z Zellij) CreateWithLayoutCommand(session string, layoutPath string) string {
+	return fmt.Sprintf("if zellij list-sessions --short 2>/dev/null | grep -Fxq -- %s; then %s; else %s zellij --session %s --layout %s || %s; fi",
+		shellQuote(session), z.AttachOrCreateCommand(session), zellijEnv, shellQuote(session), shellQuote(layoutPath), fallbackShell("zellij layout restore failed", session))
+}
+
+type Tmux struct {
+	exec commandExecutor
+}
+
+func NewTmux(exec commandExecutor) Tmux {
+	if exec == nil {
+		exec = osCommandExecutor{}
+	}
+	return Tmux{exec: exec}
+}
+
+func (Tmux) Name() string {
+	return MultiplexerTmux
+}
+
+func (t Tmux) Detect(info ProcessInfo) string {
+	if session := tmuxSessionFromArgs(info.Args); session != "" {
+		return session
+	}
+	var clients []int
+	for _, process := range info.Descendants {
+		if session := tmuxSessionFromArgs(process.Args); session != "" {
+			return session
+		}
+		if process.PID > 0 && isTmuxClient(process) {
+			clients = append(clients, process.PID)
+		}
+	}
+	return t.clientSession(clients)
+}
+
+func (t Tmux) clientSession(pids []int) string {
+	if len(pids) == 0 || t.exec == nil {
+		return ""
+	}
+	out, err := t.exec.Output("tmux", "list-clients", "-F", "#{client_pid}\t#{session_name}")
+	if err != nil {
+		return ""
+	}
+	sessions := make(map[int]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		pid, session, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		if id, err := strconv.Atoi(pid); err == nil {
+			sessions[id] = strings.TrimSpace(session)
+		}
+	}
+	for _, pid := range pids {
+		if session := sessions[pid]; session != "" {
+			return session
+		}
+	}
+	return ""
+}
+
+func isTmuxClient(process Process) bool {
+	if strings.HasPrefix(process.Name, "tmux") {
+		return true
+	}
+	return len(process.Args) > 0 && filepath.Base(process.Args[0]) == "tmux"
+}
+
+func (t Tmux) Exists(session string) (bool, error) {
+	session = strings.TrimSpace(session)
+	if session == "" {
+		return false, nil
+	}
+
+	out, err := t.exec.Output("tmux", "list-sessions", "-F", "#{session_name}")
+	if err != nil {
+		return false, err
+	}
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if strings.TrimSpace(string(line)) == session {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (t Tmux) ResolveCWD(session string) (string, error) {
+	session = strings.TrimSpace(session)
+	if session == "" {
+		return "", nil
+	}
+
+	out, err := t.exec.Output("tmux", "display-message", "-p", "-t", "="+session+":", "#{pane_current_path}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (Tmux) AttachOrCreateCommand(session string) string {
+	return fmt.Sprintf("env -u TMUX -u TMUX_PANE tmux new-session -A -s %s || %s", shellQuote(session), fallbackShell("tmux attach failed", session))
+}
+
+func fallbackShell(message string, session string) string {
+	return fmt.Sprintf("{ printf 'terminal-redeemer: %s for session %%s\\n' %s; exec ${SHELL:-sh} -l; }", message, shellQuote(session))
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func tmuxSessionFromArgs(args []string) string {
+	start := -1
+	for i, arg := range args {
+		if filepath.Base(arg) == "tmux" {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+
+	for i := start; i < len(args); i++ {
+		if (args[i] == "-t" || args[i] == "-s") && i+1 < len(args) {
+			next := strings.TrimSpace(strings.TrimPrefix(args[i+1], "="))
+			if next != "" && !strings.HasPrefix(next, "-") {
+				return next
+			}
+		}
+	}
+	return ""
+}
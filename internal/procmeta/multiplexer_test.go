@@ -0,0 +1,159 @@
+package procmeta
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestTmuxDetectFromArgsAndDescendants(t *testing.T) {
+	t.Parallel()
+
+	tmux := NewTmux(stubCommandExec{})
+	if got := tmux.Detect(ProcessInfo{Args: []string{"kitty", "-e", "/usr/bin/tmux", "new-session", "-A", "-s", "work"}}); got != "work" {
+		t.Fatalf("expected session from terminal args, got %q", got)
+	}
+	info := ProcessInfo{Descendants: []Process{
+		{Name: "zsh", Args: []string{"-zsh"}},
+		{Name: "tmux: client", Args: []string{"tmux", "attach", "-t", "=notes"}},
+	}}
+	if got := tmux.Detect(info); got != "notes" {
+		t.Fatalf("expected session from tmux client, got %q", got)
+	}
+	if got := tmux.Detect(ProcessInfo{Args: []string{"kitty", "--session", "x"}}); got != "" {
+		t.Fatalf("expected no session without tmux invocation, got %q", got)
+	}
+}
+
+func TestTmuxDetectPlainClientFromListClients(t *testing.T) {
+	t.Parallel()
+
+	tmux := NewTmux(routedCommandExec{"list-clients": []byte("4100\tother\n5151\tscratch\n")})
+	info := ProcessInfo{Args: []string{"kitty"}, Descendants: []Process{
+		{PID: 5150, Name: "zsh", Args: []string{"-zsh"}},
+		{PID: 5151, Name: "tmux: client", Args: []string{"tmux"}},
+	}}
+	if got := tmux.Detect(info); got != "scratch" {
+		t.Fatalf("expected session of the plain tmux client, got %q", got)
+	}
+	if got := tmux.Detect(ProcessInfo{Descendants: []Process{{PID: 7000, Name: "tmux: client", Args: []string{"tmux"}}}}); got != "" {
+		t.Fatalf("expected no session for an unknown client pid, got %q", got)
+	}
+	if got := NewTmux(stubCommandExec{err: errors.New("no server running")}).Detect(info); got != "" {
+		t.Fatalf("expected no session without a tmux server, got %q", got)
+	}
+}
+
+func TestTmuxExistsAndResolveCWD(t *testing.T) {
+	t.Parallel()
+
+	tmux := NewTmux(stubCommandExec{out: []byte("work\nnotes\n")})
+	if ok, err := tmux.Exists("notes"); err != nil || !ok {
+		t.Fatalf("expected notes to exist, got %v %v", ok, err)
+	}
+	if ok, err := tmux.Exists("missing"); err != nil || ok {
+		t.Fatalf("expected missing session, got %v %v", ok, err)
+	}
+
+	cwd, err := NewTmux(stubCommandExec{out: []byte("/home/user/project\n")}).ResolveCWD("work")
+	if err != nil || cwd != "/home/user/project" {
+		t.Fatalf("unexpected resolved cwd %q err=%v", cwd, err)
+	}
+}
+
+func TestZellijDetectRequiresZellijInvocation(t *testing.T) {
+	t.Parallel()
+
+	zellij := Zellij{}
+	if got := zellij.Detect(ProcessInfo{Args: []string{"kitty", "-e", "zellij", "attach", "proj"}}); got != "proj" {
+		t.Fatalf("expected zellij session, got %q", got)
+	}
+	if got := zellij.Detect(ProcessInfo{Args: []string{"kitty", "-e", "tmux", "new", "-s", "proj"}}); got != "" {
+		t.Fatalf("expected tmux args to be ignored, got %q", got)
+	}
+}
+
+func TestAttachOrCreateCommands(t *testing.T) {
+	t.Parallel()
+
+	if got := (Tmux{}).AttachOrCreateCommand("work"); !strings.Contains(got, `tmux new-session -A -s 'work'`) || !strings.Contains(got, "env -u TMUX") {
+		t.Fatalf("unexpected tmux command %q", got)
+	}
+	if got := (Zellij{}).AttachOrCreateCommand("work"); !strings.Contains(got, `zellij attach --create 'work'`) {
+		t.Fatalf("unexpected zellij command %q", got)
+	}
+}
+
+func TestZellijCreateWithLayoutQuotesSessionAndPath(t *testing.T) {
+	t.Parallel()
+
+	got := (Zellij{}).CreateWithLayoutCommand("$(id)", "/run/my layouts/`x`.kdl")
+	if !strings.HasPrefix(got, `if zellij list-sessions --short 2>/dev/null | grep -Fxq -- '$(id)'; then `) {
+		t.Fatalf("expected running session check first, got %q", got)
+	}
+	if !strings.Contains(got, "zellij attach --create '$(id)'") || !strings.Contains(got, "zellij --session '$(id)' --layout '/run/my layouts/`x`.kdl'") {
+		t.Fatalf("expected quoted session and layout path, got %q", got)
+	}
+	if strings.Contains(got, `"`) {
+		t.Fatalf("expected no double-quoted arguments, got %q", got)
+	}
+}
+
+func TestEnricherRecordsPlainTmuxClientSession(t *testing.T) {
+	t.Parallel()
+
+	reader := stubReader{byPID: map[int]ProcessInfo{4242: {
+		CWD:         "/home/user",
+		Descendants: []Process{{PID: 4243, Name: "zsh", Args: []string{"-zsh"}}, {PID: 4244, Name: "tmux: client", Args: []string{"tmux"}}},
+	}}}
+	tmux := NewTmux(routedCommandExec{
+		"list-clients":    []byte("4244\t0\n"),
+		"display-message": []byte("/home/user/src\n"),
+	})
+	enricher := NewEnricherWithMultiplexers(reader, Config{IncludeSessionTag: true}, []Multiplexer{Zellij{}, tmux})
+
+	got, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "kitty", PID: 4242})
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal == nil || got.Terminal.SessionTag != "0" || got.Terminal.Multiplexer != MultiplexerTmux || got.Terminal.CWD != "/home/user/src" {
+		t.Fatalf("expected the plain client's tmux session, got %#v", got.Terminal)
+	}
+}
+
+type routedCommandExec map[string][]byte
+
+func (r routedCommandExec) Output(_ string, args ...string) ([]byte, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no subcommand")
+	}
+	out, ok := r[args[0]]
+	if !ok {
+		return nil, errors.New("unexpected subcommand " + args[0])
+	}
+	return out, nil
+}
+
+func TestEnricherRecordsSessionMultiplexer(t *testing.T) {
+	t.Parallel()
+
+	reader := stubReader{byPID: map[int]ProcessInfo{4242: {
+		CWD:         "/home/user",
+		Descendants: []Process{{Name: "tmux: client", Args: []string{"tmux", "attach", "-t", "work"}}},
+	}}}
+	tmux := NewTmux(stubCommandExec{out: []byte("/home/user/work\n")})
+	enricher := NewEnricherWithMultiplexers(reader, Config{IncludeSessionTag: true}, []Multiplexer{Zellij{}, tmux})
+
+	got, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "kitty", PID: 4242})
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if got.Terminal == nil || got.Terminal.SessionTag != "work" || got.Terminal.Multiplexer != MultiplexerTmux {
+		t.Fatalf("expected tmux session metadata, got %#v", got.Terminal)
+	}
+	if got.Terminal.CWD != "/home/user/work" {
+		t.Fatalf("expected tmux pane cwd, got %q", got.Terminal.CWD)
+	}
+}
@@ -51,17 +51,62 @@ func (r ProcReader) Inspect(pid int) (ProcessInfo, error) {
 		info.Env = parseEnv(payload)
 	}
 
-	if preferred, ok := r.detectPreferredCWD(root, pid, windowCWD); ok {
+	descendants := collectDescendants(root, pid, maxDescendantDepth)
+	if preferred, ok := r.detectPreferredCWD(root, descendants, windowCWD); ok {
 		info.CWD = preferred
 	}
+	for _, candidate := range descendants {
+		process := Process{PID: candidate.pid, Name: candidate.comm}
+		if payload, err := os.ReadFile(filepath.Join(root, strconv.Itoa(candidate.pid), "cmdline")); err == nil {
+			process.Args = parseNullSeparated(payload)
+		}
+		info.Descendants = append(info.Descendants, process)
+	}
 
 	info.ProcessChain = r.readProcessChain(root, pid)
 
 	return info, nil
 }
 
-func (r ProcReader) detectPreferredCWD(root string, rootPID int, windowCWD string) (string, bool) {
-	descendants := collectDescendants(root, rootPID, maxDescendantDepth)
+func (r ProcReader) ParentPID(pid int) (int, error) {
+	root := r.ProcRoot
+	if strings.TrimSpace(root) == "" {
+		root = "/proc"
+	}
+
+	payload, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	return parseParentPIDFromStat(string(payload))
+}
+
+func (r ProcReader) LookupEnv(pid int, name string) (string, bool) {
+	if pid <= 0 {
+		return "", false
+	}
+	root := r.ProcRoot
+	if strings.TrimSpace(root) == "" {
+		root = "/proc"
+	}
+
+	pids := []int{pid}
+	for _, candidate := range collectDescendants(root, pid, maxDescendantDepth) {
+		pids = append(pids, candidate.pid)
+	}
+	for _, candidate := range pids {
+		payload, err := os.ReadFile(filepath.Join(root, strconv.Itoa(candidate), "environ"))
+		if err != nil {
+			continue
+		}
+		if value, ok := parseEnv(payload)[name]; ok && strings.TrimSpace(value) != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (r ProcReader) detectPreferredCWD(root string, descendants []descendantCandidate, windowCWD string) (string, bool) {
 	if len(descendants) == 0 {
 		return "", false
 	}
@@ -72,6 +72,69 @@ func TestInspectFallsBackToWindowPIDCWD(t *testing.T) {
 	}
 }
 
+func TestParentPIDReadsStat(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeProcEntry(t, root, 300, 200, "kitty", "/tmp")
+
+	ppid, err := ProcReader{ProcRoot: root}.ParentPID(300)
+	if err != nil {
+		t.Fatalf("parent pid: %v", err)
+	}
+	if ppid != 200 {
+		t.Fatalf("expected ppid 200, got %d", ppid)
+	}
+	if _, err := (ProcReader{ProcRoot: root}).ParentPID(999); err == nil {
+		t.Fatal("expected error for missing process")
+	}
+}
+
+func TestInspectRecordsDescendantArgv(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeProcEntry(t, root, 100, 1, "kitty", "/home/user")
+	writeProcEntry(t, root, 101, 100, "zsh", "/home/user/project")
+	writeProcEntry(t, root, 102, 101, "claude", "/home/user/project")
+	cmdline := []byte("claude\x00--resume\x00abc 123\x00")
+	if err := os.WriteFile(filepath.Join(root, "102", "cmdline"), cmdline, 0o600); err != nil {
+		t.Fatalf("write cmdline: %v", err)
+	}
+
+	info, err := ProcReader{ProcRoot: root}.Inspect(100)
+	if err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	if len(info.Descendants) != 2 || info.Descendants[1].Name != "claude" {
+		t.Fatalf("unexpected descendants: %#v", info.Descendants)
+	}
+	args := info.Descendants[1].Args
+	if len(args) != 3 || args[0] != "claude" || args[2] != "abc 123" {
+		t.Fatalf("unexpected argv: %#v", args)
+	}
+}
+
+func TestLookupEnvSearchesDescendants(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeProcEntry(t, root, 100, 1, "kitty", "/home/user")
+	writeProcEntry(t, root, 101, 100, "zsh", "/home/user")
+	environ := []byte("HOME=/home/user\x00KITTY_LISTEN_ON=unix:/tmp/kitty-100\x00")
+	if err := os.WriteFile(filepath.Join(root, "101", "environ"), environ, 0o600); err != nil {
+		t.Fatalf("write environ: %v", err)
+	}
+
+	value, ok := ProcReader{ProcRoot: root}.LookupEnv(100, "KITTY_LISTEN_ON")
+	if !ok || value != "unix:/tmp/kitty-100" {
+		t.Fatalf("expected socket from child environ, got %q %v", value, ok)
+	}
+	if _, ok := (ProcReader{ProcRoot: root}).LookupEnv(100, "MISSING"); ok {
+		t.Fatal("expected missing variable")
+	}
+}
+
 func writeProcEntry(t *testing.T, root string, pid int, ppid int, comm string, cwd string) {
 	t.Helper()
 
@@ -1,10 +1,11 @@
 package prune
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,35 +13,87 @@ import (
 	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/hooks"
+	"github.com/jmo/terminal-redeemer/internal/seal"
 )
 
 var ErrActiveWriter = errors.New("active writer lock present")
 
+type Hooks interface {
+	Fire(ctx context.Context, event hooks.Event) error
+}
+
+type Config struct {
+	Root   string
+	Days   int
+	Now    func() time.Time
+	Logger *slog.Logger
+	Hooks  Hooks
+}
+
 type Runner struct {
-	root string
-	days int
-	now  func() time.Time
+	root   string
+	days   int
+	now    func() time.Time
+	logger *slog.Logger
+	hooks  Hooks
 }
 
 type Summary struct {
 	EventsPruned    int
 	SnapshotsPruned int
+	LayoutsPruned   int
 }
 
 func NewRunner(root string, days int, now func() time.Time) *Runner {
+	return NewRunnerWithConfig(Config{Root: root, Days: days, Now: now})
+}
+
+func NewRunnerWithConfig(config Config) *Runner {
+	now := config.Now
 	if now == nil {
 		now = time.Now
 	}
-	return &Runner{root: root, days: days, now: now}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Runner{root: config.Root, days: config.Days, now: now, logger: logger, hooks: config.Hooks}
 }
 
 func (r *Runner) Run() (Summary, error) {
+	cutoff := r.now().UTC().AddDate(0, 0, -r.days)
+	r.logger.Debug("prune_started", "root", r.root, "cutoff", cutoff)
+	r.fire(hooks.PruneBefore, hooks.Prune{Root: r.root, Days: r.days, Cutoff: cutoff})
+	summary, err := r.prune(cutoff)
+	after := hooks.Prune{
+		Root:            r.root,
+		Days:            r.days,
+		Cutoff:          cutoff,
+		EventsPruned:    summary.EventsPruned,
+		SnapshotsPruned: summary.SnapshotsPruned,
+		LayoutsPruned:   summary.LayoutsPruned,
+	}
+	if err != nil {
+		after.Error = err.Error()
+		r.fire(hooks.PruneAfter, after)
+		return Summary{}, err
+	}
+	r.logger.Info("prune_done", "events_pruned", summary.EventsPruned, "snapshots_pruned", summary.SnapshotsPruned, "layouts_pruned", summary.LayoutsPruned)
+	r.fire(hooks.PruneAfter, after)
+	return summary, nil
+}
+
+func (r *Runner) prune(cutoff time.Time) (Summary, error) {
 	if _, err := os.Stat(filepath.Join(r.root, "meta", "lock")); err == nil {
+		r.logger.Warn("prune_writer_locked", "root", r.root)
 		return Summary{}, ErrActiveWriter
 	}
-
-	cutoff := r.now().UTC().AddDate(0, 0, -r.days)
-	eventsPruned, err := r.pruneEvents(cutoff)
+	codec, err := seal.Load(r.root)
+	if err != nil {
+		return Summary{}, err
+	}
+	eventsPruned, err := r.pruneEvents(cutoff, codec)
 	if err != nil {
 		return Summary{}, err
 	}
@@ -48,11 +101,21 @@ func (r *Runner) Run() (Summary, error) {
 	if err != nil {
 		return Summary{}, err
 	}
+	layoutsPruned, err := r.pruneLayouts(cutoff)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summary{EventsPruned: eventsPruned, SnapshotsPruned: snapshotsPruned, LayoutsPruned: layoutsPruned}, nil
+}
 
-	return Summary{EventsPruned: eventsPruned, SnapshotsPruned: snapshotsPruned}, nil
+func (r *Runner) fire(name string, data hooks.Prune) {
+	if r.hooks == nil {
+		return
+	}
+	_ = r.hooks.Fire(context.Background(), hooks.Event{Name: name, Data: data})
 }
 
-func (r *Runner) pruneEvents(cutoff time.Time) (int, error) {
+func (r *Runner) pruneEvents(cutoff time.Time, codec *seal.Codec) (int, error) {
 	eventsPath := filepath.Join(r.root, "events.jsonl")
 	f, err := os.Open(eventsPath)
 	if errors.Is(err, os.ErrNotExist) {
@@ -67,10 +130,17 @@ func (r *Runner) pruneEvents(cutoff time.Time) (int, error) {
 
 	kept := make([]events.Event, 0)
 	var anchor *events.Event
-	scanner := bufio.NewScanner(f)
+	scanner := seal.NewScanner(f)
 	for scanner.Scan() {
+		line, err := codec.Open(scanner.Bytes())
+		if errors.Is(err, seal.ErrKeyRequired) {
+			return 0, err
+		}
+		if err != nil {
+			continue
+		}
 		var event events.Event
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+		if err := json.Unmarshal(line, &event); err != nil {
 			continue
 		}
 		if err := event.Validate(); err != nil {
@@ -91,8 +161,8 @@ func (r *Runner) pruneEvents(cutoff time.Time) (int, error) {
 		kept = append([]events.Event{*anchor}, kept...)
 	}
 
-	originalCount := countValidEvents(filepath.Join(r.root, "events.jsonl"))
-	if err := rewriteEvents(eventsPath, kept); err != nil {
+	originalCount := countValidEvents(filepath.Join(r.root, "events.jsonl"), codec)
+	if err := rewriteEvents(eventsPath, kept, codec); err != nil {
 		return 0, err
 	}
 
@@ -148,13 +218,45 @@ func (r *Runner) pruneSnapshots(cutoff time.Time) (int, error) {
 		if err := os.Remove(snap.path); err != nil {
 			return 0, err
 		}
+		r.logger.Debug("prune_snapshot_removed", "path", snap.path)
 		pruned++
 	}
 
 	return pruned, nil
 }
 
-func rewriteEvents(path string, kept []events.Event) error {
+func (r *Runner) pruneLayouts(cutoff time.Time) (int, error) {
+	dir := filepath.Join(r.root, "layouts")
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read layouts dir: %w", err)
+	}
+
+	pruned := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".kdl" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return 0, err
+		}
+		r.logger.Debug("prune_layout_removed", "path", filepath.Join(dir, entry.Name()))
+		pruned++
+	}
+	return pruned, nil
+}
+
+func rewriteEvents(path string, kept []events.Event, codec *seal.Codec) error {
 	tmp := path + ".tmp"
 	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
 	if err != nil {
@@ -166,6 +268,11 @@ func rewriteEvents(path string, kept []events.Event) error {
 			_ = f.Close()
 			return err
 		}
+		payload, err = codec.Seal(payload)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
 		if _, err := f.Write(append(payload, '\n')); err != nil {
 			_ = f.Close()
 			return err
@@ -177,7 +284,7 @@ func rewriteEvents(path string, kept []events.Event) error {
 	return os.Rename(tmp, path)
 }
 
-func countValidEvents(path string) int {
+func countValidEvents(path string, codec *seal.Codec) int {
 	f, err := os.Open(path)
 	if err != nil {
 		return 0
@@ -187,10 +294,14 @@ func countValidEvents(path string) int {
 	}()
 
 	count := 0
-	scanner := bufio.NewScanner(f)
+	scanner := seal.NewScanner(f)
 	for scanner.Scan() {
+		line, err := codec.Open(scanner.Bytes())
+		if err != nil {
+			continue
+		}
 		var event events.Event
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+		if err := json.Unmarshal(line, &event); err != nil {
 			continue
 		}
 		if err := event.Validate(); err != nil {
@@ -1,12 +1,19 @@
 package prune
 
 import (
+	"bytes"
+	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/hooks"
+	"github.com/jmo/terminal-redeemer/internal/seal"
 	"github.com/jmo/terminal-redeemer/internal/snapshots"
 )
 
@@ -132,3 +139,115 @@ func TestNoDataLossForCurrentReplayWindow(t *testing.T) {
 		t.Fatalf("expected latest event preserved, got %#v", remaining[len(remaining)-1].Patch)
 	}
 }
+
+func TestPruneKeepsEncryptedEventsSealed(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "redeem.key")
+	if err := seal.GenerateKeyFile(keyFile); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if _, err := seal.Encrypt(root, seal.KeySource{File: keyFile}, nil); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	writer, err := eventStore.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+
+	now := time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC)
+	for i, ts := range []time.Time{now.AddDate(0, 0, -50), now.AddDate(0, 0, -40), now.AddDate(0, 0, -5)} {
+		if _, err := writer.Append(events.Event{V: 1, TS: ts, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "secret"}, StateHash: "sha256:" + strconv.Itoa(i)}); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+	}
+	_ = writer.Close()
+
+	summary, err := NewRunner(root, 30, func() time.Time { return now }).Run()
+	if err != nil {
+		t.Fatalf("prune run: %v", err)
+	}
+	if summary.EventsPruned != 1 {
+		t.Fatalf("expected 1 event pruned, got %+v", summary)
+	}
+
+	payload, err := os.ReadFile(filepath.Join(root, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	if strings.Contains(string(payload), "secret") {
+		t.Fatalf("expected pruned log to stay encrypted, got %q", payload)
+	}
+	remaining, _, err := eventStore.ReadSince(0)
+	if err != nil {
+		t.Fatalf("read remaining: %v", err)
+	}
+	if len(remaining) != 2 || remaining[1].StateHash != "sha256:2" {
+		t.Fatalf("expected anchor and recent event, got %#v", remaining)
+	}
+}
+
+func TestPruneRemovesLayoutsNotRefreshedSinceCutoff(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "layouts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir layouts: %v", err)
+	}
+	now := time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC)
+	for name, mtime := range map[string]time.Time{
+		"stale.kdl": now.AddDate(0, 0, -40),
+		"fresh.kdl": now.AddDate(0, 0, -1),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("layout {}\n"), 0o600); err != nil {
+			t.Fatalf("write layout: %v", err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	var logs bytes.Buffer
+	recorder := &recordingHooks{}
+	summary, err := NewRunnerWithConfig(Config{
+		Root:   root,
+		Days:   30,
+		Now:    func() time.Time { return now },
+		Logger: slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Hooks:  recorder,
+	}).Run()
+	if err != nil {
+		t.Fatalf("prune run: %v", err)
+	}
+	if !strings.Contains(logs.String(), "msg=prune_layout_removed") || !strings.Contains(logs.String(), "msg=prune_done events_pruned=0 snapshots_pruned=0 layouts_pruned=1") {
+		t.Fatalf("unexpected prune logs: %q", logs.String())
+	}
+	if summary.LayoutsPruned != 1 {
+		t.Fatalf("expected one layout pruned, got %+v", summary)
+	}
+	if len(recorder.events) != 2 || recorder.events[0].Name != hooks.PruneBefore || recorder.events[1].Name != hooks.PruneAfter {
+		t.Fatalf("unexpected prune hooks: %+v", recorder.events)
+	}
+	if after := recorder.events[1].Data.(hooks.Prune); after.LayoutsPruned != 1 || after.Days != 30 || after.Error != "" {
+		t.Fatalf("unexpected prune.after payload: %+v", after)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fresh.kdl")); err != nil {
+		t.Fatalf("expected fresh layout kept: %v", err)
+	}
+}
+
+type recordingHooks struct {
+	events []hooks.Event
+}
+
+func (h *recordingHooks) Fire(_ context.Context, event hooks.Event) error {
+	h.events = append(h.events, event)
+	return nil
+}
@@ -0,0 +1,345 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/layouts"
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+const (
+	TitleDrop    = "drop"
+	TitleHash    = "hash"
+	TitleReplace = "replace"
+)
+
+const DefaultCWDReplacement = "/redacted"
+
+type TitleRule struct {
+	AppID       string
+	Mode        string
+	Pattern     string
+	Replacement string
+}
+
+type CWDRule struct {
+	Prefix      string
+	Replacement string
+}
+
+type LayoutStore interface {
+	Layout(ref string) (string, error)
+	Put(content string) (string, error)
+}
+
+type Config struct {
+	Titles        []TitleRule
+	CWDPrefixes   []CWDRule
+	ExcludeAppIDs []string
+	TitleKey      []byte
+	Layouts       LayoutStore
+}
+
+type Redactor struct {
+	titles  []titleRule
+	cwds    []CWDRule
+	exclude []string
+	layouts LayoutStore
+}
+
+type titleRule struct {
+	appID       string
+	mode        string
+	pattern     *regexp.Regexp
+	replacement string
+	key         []byte
+}
+
+var quotedString = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+
+func New(config Config) (*Redactor, error) {
+	r := &Redactor{layouts: config.Layouts}
+	for i, rule := range config.Titles {
+		compiled := titleRule{
+			appID:       strings.ToLower(strings.TrimSpace(rule.AppID)),
+			mode:        strings.ToLower(strings.TrimSpace(rule.Mode)),
+			replacement: rule.Replacement,
+		}
+		if _, err := path.Match(compiled.appID, ""); err != nil {
+			return nil, fmt.Errorf("titles[%d]: invalid appId pattern %q: %w", i, rule.AppID, err)
+		}
+		switch compiled.mode {
+		case TitleDrop:
+		case TitleHash:
+			if len(config.TitleKey) == 0 {
+				return nil, fmt.Errorf("titles[%d]: mode hash needs a title key", i)
+			}
+			compiled.key = config.TitleKey
+		case TitleReplace:
+			if rule.Pattern == "" {
+				return nil, fmt.Errorf("titles[%d]: pattern is required for mode replace", i)
+			}
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("titles[%d]: invalid pattern: %w", i, err)
+			}
+			compiled.pattern = pattern
+		default:
+			return nil, fmt.Errorf("titles[%d]: unsupported mode %q (want drop, hash or replace)", i, rule.Mode)
+		}
+		r.titles = append(r.titles, compiled)
+	}
+	for i, rule := range config.CWDPrefixes {
+		prefix := strings.TrimSpace(rule.Prefix)
+		if prefix == "" {
+			return nil, fmt.Errorf("cwdPrefixes[%d]: prefix is required", i)
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = DefaultCWDReplacement
+		}
+		rule.Prefix = filepath.Clean(prefix)
+		r.cwds = append(r.cwds, rule)
+	}
+	sort.SliceStable(r.cwds, func(i, j int) bool {
+		return len(r.cwds[i].Prefix) > len(r.cwds[j].Prefix)
+	})
+	for i, appID := range config.ExcludeAppIDs {
+		pattern := strings.ToLower(strings.TrimSpace(appID))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("excludeAppIds[%d]: invalid pattern %q: %w", i, appID, err)
+		}
+		r.exclude = append(r.exclude, pattern)
+	}
+	return r, nil
+}
+
+func (r *Redactor) Enabled() bool {
+	return len(r.titles) > 0 || len(r.cwds) > 0 || len(r.exclude) > 0
+}
+
+func (r *Redactor) Excluded(appID string) bool {
+	for _, pattern := range r.exclude {
+		if matchAppID(pattern, appID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) Apply(state model.State) model.State {
+	out := model.State{Workspaces: append([]model.Workspace(nil), state.Workspaces...)}
+	for _, window := range state.Windows {
+		if r.Excluded(window.AppID) {
+			continue
+		}
+		out.Windows = append(out.Windows, r.RedactWindow(window))
+	}
+	return model.Normalize(out)
+}
+
+func (r *Redactor) RedactWindow(window model.Window) model.Window {
+	rule, hasRule := r.titleRule(window.AppID)
+	if hasRule {
+		window.Title = rule.apply(window.Title)
+	}
+	if window.Terminal == nil {
+		return window
+	}
+
+	terminal := *window.Terminal
+	terminal.CWD = r.maskCWD(terminal.CWD)
+	if len(terminal.ProcessArgs) > 0 {
+		args := make(map[string][]string, len(terminal.ProcessArgs))
+		for tag, argv := range terminal.ProcessArgs {
+			args[tag] = r.maskPaths(argv)
+		}
+		terminal.ProcessArgs = args
+	}
+	if terminal.LayoutRef != "" {
+		terminal.LayoutRef = r.maskLayoutRef(terminal.LayoutRef)
+	}
+	if len(terminal.Tabs) > 0 {
+		tabs := make([]model.Tab, len(terminal.Tabs))
+		for i, tab := range terminal.Tabs {
+			if hasRule {
+				tab.Title = rule.apply(tab.Title)
+			}
+			if len(tab.Panes) > 0 {
+				panes := make([]model.Pane, len(tab.Panes))
+				for j, pane := range tab.Panes {
+					if hasRule {
+						pane.Title = rule.apply(pane.Title)
+					}
+					pane.CWD = r.maskCWD(pane.CWD)
+					pane.Command = r.maskPaths(pane.Command)
+					panes[j] = pane
+				}
+				tab.Panes = panes
+			}
+			tabs[i] = tab
+		}
+		terminal.Tabs = tabs
+	}
+	if terminal.Nvim != nil {
+		nvim := *terminal.Nvim
+		nvim.CWD = r.maskCWD(nvim.CWD)
+		nvim.Session = r.maskCWD(nvim.Session)
+		nvim.Buffers = r.maskPaths(nvim.Buffers)
+		terminal.Nvim = &nvim
+	}
+	if terminal.Git != nil {
+		git := *terminal.Git
+		git.Root = r.maskCWD(git.Root)
+		git.Worktree = r.maskCWD(git.Worktree)
+		terminal.Git = &git
+	}
+	window.Terminal = &terminal
+	return window
+}
+
+func (r *Redactor) titleRule(appID string) (titleRule, bool) {
+	for _, rule := range r.titles {
+		if matchAppID(rule.appID, appID) {
+			return rule, true
+		}
+	}
+	return titleRule{}, false
+}
+
+func (r *Redactor) MaskLayout(layout string) string {
+	if len(r.cwds) == 0 {
+		return layout
+	}
+	return quotedString.ReplaceAllStringFunc(layout, func(quoted string) string {
+		return `"` + r.maskCWD(quoted[1:len(quoted)-1]) + `"`
+	})
+}
+
+func (r *Redactor) MaskedLayouts(store LayoutStore) LayoutStore {
+	return maskedLayouts{redactor: r, store: store}
+}
+
+func (r *Redactor) maskLayoutRef(ref string) string {
+	if r.layouts == nil || len(r.cwds) == 0 {
+		return ref
+	}
+	layout, err := r.layouts.Layout(ref)
+	if err != nil {
+		return ref
+	}
+	masked := r.MaskLayout(layout)
+	if masked == layout {
+		return ref
+	}
+	maskedRef, err := r.layouts.Put(masked)
+	if err != nil {
+		return ""
+	}
+	return maskedRef
+}
+
+func (r *Redactor) maskPaths(values []string) []string {
+	if len(values) == 0 {
+		return values
+	}
+	out := make([]string, len(values))
+	for i, value := range values {
+		out[i] = r.maskCWD(value)
+	}
+	return out
+}
+
+func (r *Redactor) maskCWD(value string) string {
+	if value == "" {
+		return value
+	}
+	for _, rule := range r.cwds {
+		if value == rule.Prefix {
+			return rule.Replacement
+		}
+		if rule.Prefix == "/" {
+			return strings.TrimSuffix(rule.Replacement, "/") + value
+		}
+		if strings.HasPrefix(value, rule.Prefix+"/") {
+			return strings.TrimSuffix(rule.Replacement, "/") + value[len(rule.Prefix):]
+		}
+	}
+	return value
+}
+
+func (rule titleRule) apply(title string) string {
+	if title == "" {
+		return title
+	}
+	switch rule.mode {
+	case TitleDrop:
+		return ""
+	case TitleHash:
+		if isHashedTitle(title) {
+			return title
+		}
+		return HashTitle(rule.key, title)
+	case TitleReplace:
+		return rule.pattern.ReplaceAllString(title, rule.replacement)
+	}
+	return title
+}
+
+func HashTitle(key []byte, title string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(title))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func isHashedTitle(title string) bool {
+	digest, ok := strings.CutPrefix(title, "hmac:")
+	if !ok || len(digest) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
+
+func matchAppID(pattern string, appID string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, strings.ToLower(appID))
+	return ok
+}
+
+type maskedLayouts struct {
+	redactor *Redactor
+	store    LayoutStore
+}
+
+func (m maskedLayouts) Layout(ref string) (string, error) {
+	return m.store.Layout(ref)
+}
+
+func (m maskedLayouts) Put(content string) (string, error) {
+	return m.store.Put(m.redactor.MaskLayout(content))
+}
+
+type layoutPreview struct {
+	store LayoutStore
+}
+
+func (p layoutPreview) Layout(ref string) (string, error) {
+	return p.store.Layout(ref)
+}
+
+func (p layoutPreview) Put(content string) (string, error) {
+	return layouts.Ref(content), nil
+}
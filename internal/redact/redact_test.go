@@ -0,0 +1,206 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/layouts"
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestRedactWindowAppliesFirstMatchingTitleRule(t *testing.T) {
+	t.Parallel()
+
+	redactor, err := New(Config{TitleKey: testKey, Titles: []TitleRule{
+		{AppID: "firefox", Mode: TitleDrop},
+		{AppID: "org.gnome.*", Mode: TitleHash},
+		{AppID: "kitty", Mode: TitleReplace, Pattern: `ssh \S+`, Replacement: "ssh <host>"},
+		{AppID: "*", Mode: TitleDrop},
+	}})
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+
+	cases := []struct {
+		appID string
+		title string
+		want  string
+	}{
+		{appID: "firefox", title: "Bank - Mozilla Firefox", want: ""},
+		{appID: "org.gnome.Nautilus", title: "secret", want: HashTitle(testKey, "secret")},
+		{appID: "Kitty", title: "ssh prod-db-1: ~", want: "ssh <host> ~"},
+		{appID: "foot", title: "anything", want: ""},
+	}
+	for _, tc := range cases {
+		got := redactor.RedactWindow(model.Window{AppID: tc.appID, Title: tc.title})
+		if got.Title != tc.want {
+			t.Fatalf("%s: expected title %q, got %q", tc.appID, tc.want, got.Title)
+		}
+	}
+}
+
+func TestHashTitleIsStableAndIdempotent(t *testing.T) {
+	t.Parallel()
+
+	redactor, err := New(Config{TitleKey: testKey, Titles: []TitleRule{{AppID: "*", Mode: TitleHash}}})
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+
+	once := redactor.RedactWindow(model.Window{AppID: "kitty", Title: "private"})
+	twice := redactor.RedactWindow(once)
+	if !strings.HasPrefix(once.Title, "hmac:") || once.Title != HashTitle(testKey, "private") {
+		t.Fatalf("unexpected hashed title %q", once.Title)
+	}
+	if twice.Title != once.Title {
+		t.Fatalf("expected hashing to be idempotent, got %q then %q", once.Title, twice.Title)
+	}
+	if HashTitle([]byte("another store"), "private") == once.Title {
+		t.Fatal("expected the hash to depend on the store key")
+	}
+	if _, err := New(Config{Titles: []TitleRule{{AppID: "*", Mode: TitleHash}}}); err == nil {
+		t.Fatal("expected hash mode without a key to be rejected")
+	}
+}
+
+func TestRedactWindowMasksCWDPrefixesWithoutMutatingInput(t *testing.T) {
+	t.Parallel()
+
+	redactor, err := New(Config{
+		Titles: []TitleRule{{AppID: "kitty", Mode: TitleDrop}},
+		CWDPrefixes: []CWDRule{
+			{Prefix: "/home/me/clients"},
+			{Prefix: "/home/me/clients/acme/", Replacement: "~acme"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+
+	input := model.Window{AppID: "kitty", Title: "vim", Terminal: &model.Terminal{
+		CWD: "/home/me/clients/globex/api",
+		Tabs: []model.Tab{{Title: "tab", Panes: []model.Pane{
+			{Title: "pane", CWD: "/home/me/clients/acme/web", Command: []string{"tail", "-f", "/home/me/clients/acme/web/log"}},
+			{CWD: "/home/me/clientsbackup"},
+		}}},
+		ProcessArgs: map[string][]string{"nvim": {"nvim", "/home/me/clients/globex/notes.md"}},
+		Nvim:        &model.Nvim{CWD: "/home/me/clients", Session: "/home/me/clients/acme/Session.vim", Buffers: []string{"/home/me/clients/acme/README.md", "/etc/hosts"}},
+		Git:         &model.Git{Root: "/home/me/clients/globex", Branch: "main", Worktree: "/home/me/clients/acme/web"},
+	}}
+	got := redactor.RedactWindow(input)
+
+	if got.Terminal.CWD != "/redacted/globex/api" {
+		t.Fatalf("unexpected cwd %q", got.Terminal.CWD)
+	}
+	panes := got.Terminal.Tabs[0].Panes
+	if got.Terminal.Tabs[0].Title != "" || panes[0].Title != "" {
+		t.Fatalf("expected tab and pane titles dropped, got %#v", got.Terminal.Tabs[0])
+	}
+	if panes[0].CWD != "~acme/web" || panes[1].CWD != "/home/me/clientsbackup" {
+		t.Fatalf("unexpected pane cwds %q %q", panes[0].CWD, panes[1].CWD)
+	}
+	if panes[0].Command[2] != "~acme/web/log" || got.Terminal.ProcessArgs["nvim"][1] != "/redacted/globex/notes.md" {
+		t.Fatalf("unexpected pane command %q or process args %q", panes[0].Command, got.Terminal.ProcessArgs)
+	}
+	if got.Terminal.Nvim.CWD != "/redacted" || got.Terminal.Nvim.Session != "~acme/Session.vim" || got.Terminal.Nvim.Buffers[0] != "~acme/README.md" || got.Terminal.Nvim.Buffers[1] != "/etc/hosts" {
+		t.Fatalf("unexpected nvim metadata %#v", got.Terminal.Nvim)
+	}
+	if got.Terminal.Git.Root != "/redacted/globex" || got.Terminal.Git.Worktree != "~acme/web" || got.Terminal.Git.Branch != "main" {
+		t.Fatalf("unexpected git metadata %#v", got.Terminal.Git)
+	}
+	if input.Title != "vim" || input.Terminal.CWD != "/home/me/clients/globex/api" || input.Terminal.Tabs[0].Panes[0].CWD != "/home/me/clients/acme/web" || input.Terminal.Nvim.CWD != "/home/me/clients" || input.Terminal.Git.Root != "/home/me/clients/globex" || input.Terminal.ProcessArgs["nvim"][1] != "/home/me/clients/globex/notes.md" || input.Terminal.Tabs[0].Panes[0].Command[2] != "/home/me/clients/acme/web/log" {
+		t.Fatalf("expected input window to be left untouched, got %#v", input.Terminal)
+	}
+}
+
+func TestRedactWindowMasksLayoutPaths(t *testing.T) {
+	t.Parallel()
+
+	store := stubLayoutStore{}
+	ref, _ := store.Put("layout {\n    pane cwd=\"/home/me/clients/acme\" command=\"tail\" {\n        args \"-f\" \"/home/me/clients/acme/log\"\n    }\n}\n")
+	redactor, err := New(Config{CWDPrefixes: []CWDRule{{Prefix: "/home/me/clients"}}, Layouts: store})
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+
+	got := redactor.RedactWindow(model.Window{AppID: "kitty", Terminal: &model.Terminal{SessionTag: "work", LayoutRef: ref}})
+	if got.Terminal.LayoutRef == ref {
+		t.Fatal("expected layout ref to point at a masked copy")
+	}
+	masked := store[got.Terminal.LayoutRef]
+	if strings.Contains(masked, "/home/me/clients") || !strings.Contains(masked, `cwd="/redacted/acme"`) || !strings.Contains(masked, `"/redacted/acme/log"`) || !strings.Contains(masked, `command="tail"`) {
+		t.Fatalf("unexpected masked layout %q", masked)
+	}
+	if again := redactor.RedactWindow(got); again.Terminal.LayoutRef != got.Terminal.LayoutRef {
+		t.Fatalf("expected masking an already masked layout to keep its ref, got %q", again.Terminal.LayoutRef)
+	}
+
+	putRef, err := redactor.MaskedLayouts(store).Put("layout { pane cwd=\"/home/me/clients/globex\"; }")
+	if err != nil || store[putRef] != "layout { pane cwd=\"/redacted/globex\"; }" {
+		t.Fatalf("expected layouts masked before they are stored, got %q err=%v", store[putRef], err)
+	}
+}
+
+func TestApplyDropsExcludedAppIDs(t *testing.T) {
+	t.Parallel()
+
+	redactor, err := New(Config{ExcludeAppIDs: []string{"org.keepassxc.*", "signal"}})
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+
+	state := redactor.Apply(model.State{
+		Workspaces: []model.Workspace{{ID: "ws-1", Index: 1}},
+		Windows: []model.Window{
+			{Key: "w-1", AppID: "org.keepassxc.KeePassXC"},
+			{Key: "w-2", AppID: "Signal"},
+			{Key: "w-3", AppID: "kitty"},
+		},
+	})
+	if len(state.Windows) != 1 || state.Windows[0].Key != "w-3" {
+		t.Fatalf("expected only kitty to remain, got %#v", state.Windows)
+	}
+	if len(state.Workspaces) != 1 {
+		t.Fatalf("expected workspaces to be kept, got %#v", state.Workspaces)
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{name: "mode", config: Config{Titles: []TitleRule{{AppID: "kitty", Mode: "blur"}}}, want: "unsupported mode"},
+		{name: "pattern required", config: Config{Titles: []TitleRule{{AppID: "kitty", Mode: TitleReplace}}}, want: "pattern is required"},
+		{name: "pattern", config: Config{Titles: []TitleRule{{AppID: "kitty", Mode: TitleReplace, Pattern: "("}}}, want: "invalid pattern"},
+		{name: "glob", config: Config{ExcludeAppIDs: []string{"["}}, want: "invalid pattern"},
+		{name: "prefix", config: Config{CWDPrefixes: []CWDRule{{Replacement: "x"}}}, want: "prefix is required"},
+	}
+	for _, tc := range cases {
+		_, err := New(tc.config)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+type stubLayoutStore map[string]string
+
+func (s stubLayoutStore) Layout(ref string) (string, error) {
+	layout, ok := s[ref]
+	if !ok {
+		return "", layouts.ErrNotFound
+	}
+	return layout, nil
+}
+
+func (s stubLayoutStore) Put(content string) (string, error) {
+	ref := layouts.Ref(content)
+	s[ref] = content
+	return ref, nil
+}
@@ -0,0 +1,495 @@
+package redact
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/layouts"
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/replay"
+	"github.com/jmo/terminal-redeemer/internal/seal"
+	"github.com/jmo/terminal-redeemer/internal/snapshots"
+)
+
+const TitleKeySize = 32
+
+type Summary struct {
+	EventsRewritten    int
+	EventsDropped      int
+	SnapshotsRewritten int
+	LayoutsRewritten   int
+}
+
+type eventLine struct {
+	raw       []byte
+	canonical []byte
+	event     *events.Event
+	dropped   bool
+}
+
+type offsetPair struct {
+	old int64
+	new int64
+}
+
+func Rewrite(root string, redactor *Redactor, dryRun bool) (Summary, error) {
+	codec, err := seal.Load(root)
+	if err != nil {
+		return Summary{}, err
+	}
+	if !dryRun {
+		store, err := events.NewStoreWithCodec(root, codec)
+		if err != nil {
+			return Summary{}, err
+		}
+		writer, err := store.AcquireWriter()
+		if err != nil {
+			return Summary{}, err
+		}
+		defer func() {
+			_ = writer.Close()
+		}()
+	}
+
+	if dryRun && redactor.layouts != nil {
+		preview := *redactor
+		preview.layouts = layoutPreview{store: redactor.layouts}
+		redactor = &preview
+	}
+
+	referenced := map[string]bool{}
+	eventsPath := filepath.Join(root, "events.jsonl")
+	lines, err := redactEvents(eventsPath, redactor, codec, referenced)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	summary := Summary{}
+	kept := map[string]bool{}
+	var out bytes.Buffer
+	offsets := make([]offsetPair, 0, len(lines))
+	var oldOffset int64
+	for _, line := range lines {
+		oldOffset += int64(len(line.raw)) + 1
+		switch {
+		case line.dropped:
+			summary.EventsDropped++
+		case line.event == nil:
+			out.Write(line.raw)
+			out.WriteByte('\n')
+		default:
+			collectLayoutRefs(kept, *line.event)
+			payload, err := json.Marshal(line.event)
+			if err != nil {
+				return Summary{}, fmt.Errorf("marshal event: %w", err)
+			}
+			if bytes.Equal(payload, line.canonical) {
+				payload = line.raw
+			} else {
+				summary.EventsRewritten++
+				payload, err = codec.Seal(payload)
+				if err != nil {
+					return Summary{}, fmt.Errorf("seal event: %w", err)
+				}
+			}
+			out.Write(payload)
+			out.WriteByte('\n')
+		}
+		offsets = append(offsets, offsetPair{old: oldOffset, new: int64(out.Len())})
+	}
+
+	if !dryRun && (summary.EventsRewritten > 0 || summary.EventsDropped > 0) {
+		if err := writeFileAtomic(eventsPath, out.Bytes()); err != nil {
+			return Summary{}, fmt.Errorf("rewrite events: %w", err)
+		}
+	}
+
+	rewritten, err := redactSnapshots(filepath.Join(root, "snapshots"), redactor, codec, offsets, referenced, kept, dryRun)
+	if err != nil {
+		return Summary{}, err
+	}
+	summary.SnapshotsRewritten = rewritten
+
+	removed, err := removeStaleLayouts(root, codec, referenced, kept, dryRun)
+	if err != nil {
+		return Summary{}, err
+	}
+	summary.LayoutsRewritten = removed
+	return summary, nil
+}
+
+func removeStaleLayouts(root string, codec *seal.Codec, referenced map[string]bool, kept map[string]bool, dryRun bool) (int, error) {
+	stale := make([]string, 0, len(referenced))
+	for ref := range referenced {
+		if !kept[ref] {
+			stale = append(stale, ref)
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+	sort.Strings(stale)
+	store, err := layouts.NewStoreWithCodec(root, codec)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, ref := range stale {
+		if dryRun {
+			if _, err := store.Layout(ref); err == nil {
+				removed++
+			}
+			continue
+		}
+		ok, err := store.Remove(ref)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func collectLayoutRefs(refs map[string]bool, event events.Event) {
+	switch event.EventType {
+	case "state_full":
+		collectStateLayoutRefs(refs, replay.DecodeState(event.State))
+	case "window_patch":
+		terminal, ok := event.Patch["terminal"].(map[string]any)
+		if !ok {
+			return
+		}
+		if ref, ok := terminal["layout_ref"].(string); ok && ref != "" {
+			refs[ref] = true
+		}
+	}
+}
+
+func collectStateLayoutRefs(refs map[string]bool, state model.State) {
+	for _, window := range state.Windows {
+		if window.Terminal != nil && window.Terminal.LayoutRef != "" {
+			refs[window.Terminal.LayoutRef] = true
+		}
+	}
+}
+
+func redactEvents(path string, redactor *Redactor, codec *seal.Codec, referenced map[string]bool) ([]eventLine, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open events file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var (
+		lines      []eventLine
+		workspaces []model.Workspace
+		batch      []*events.Event
+		batchTS    time.Time
+		batchHash  string
+	)
+	windows := map[string]model.Window{}
+	hidden := map[string]bool{}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		state := model.State{Workspaces: workspaces}
+		for _, window := range windows {
+			state.Windows = append(state.Windows, window)
+		}
+		if hash, changed := redactedHash(redactor, state); changed {
+			for _, event := range batch {
+				event.StateHash = hash
+			}
+		}
+		batch = nil
+	}
+
+	scanner := seal.NewScanner(f)
+	for scanner.Scan() {
+		raw := append([]byte(nil), scanner.Bytes()...)
+		plain, err := codec.Open(raw)
+		if err != nil {
+			return nil, fmt.Errorf("open event: %w", err)
+		}
+		var event events.Event
+		if err := json.Unmarshal(plain, &event); err != nil {
+			lines = append(lines, eventLine{raw: raw})
+			continue
+		}
+		if err := event.Validate(); err != nil {
+			lines = append(lines, eventLine{raw: raw})
+			continue
+		}
+		canonical, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("marshal event: %w", err)
+		}
+		collectLayoutRefs(referenced, event)
+		if event.EventType != "window_patch" || !event.TS.Equal(batchTS) || event.StateHash != batchHash {
+			flush()
+		}
+
+		switch event.EventType {
+		case "state_full":
+			state := replay.DecodeState(event.State)
+			workspaces = state.Workspaces
+			windows = make(map[string]model.Window, len(state.Windows))
+			for _, window := range state.Windows {
+				windows[window.Key] = window
+			}
+			hidden = map[string]bool{}
+			for _, window := range state.Windows {
+				if redactor.Excluded(window.AppID) {
+					hidden[window.Key] = true
+				}
+			}
+			if hash, changed := redactedHash(redactor, state); changed {
+				event.State = stateAsMap(redactor.Apply(state))
+				event.StateHash = hash
+			}
+			lines = append(lines, eventLine{raw: raw, canonical: canonical, event: &event})
+		case "window_patch":
+			patch, keep := redactPatch(redactor, windows, hidden, event.WindowKey, event.Patch)
+			if !keep {
+				lines = append(lines, eventLine{raw: raw, dropped: true})
+				continue
+			}
+			event.Patch = patch
+			lines = append(lines, eventLine{raw: raw, canonical: canonical, event: &event})
+			batch = append(batch, &event)
+			batchTS, batchHash = event.TS, event.StateHash
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan events: %w", err)
+	}
+	flush()
+	return lines, nil
+}
+
+func redactPatch(redactor *Redactor, windows map[string]model.Window, hidden map[string]bool, key string, patch map[string]any) (map[string]any, bool) {
+	_, existed := windows[key]
+	replay.ApplyWindowPatch(windows, key, patch)
+	if deleted, ok := patch["deleted"].(bool); ok && deleted {
+		if hidden[key] {
+			delete(hidden, key)
+			return nil, false
+		}
+		return patch, true
+	}
+
+	window := windows[key]
+	if redactor.Excluded(window.AppID) {
+		if hidden[key] {
+			return nil, false
+		}
+		hidden[key] = true
+		if !existed {
+			return nil, false
+		}
+		return map[string]any{"deleted": true}, true
+	}
+
+	redacted := redactor.RedactWindow(window)
+	if hidden[key] {
+		delete(hidden, key)
+		return normalizePatch(map[string]any{
+			"app_id":       redacted.AppID,
+			"workspace_id": redacted.WorkspaceID,
+			"title":        redacted.Title,
+			"terminal":     redacted.Terminal,
+		}), true
+	}
+
+	out := make(map[string]any, len(patch))
+	for field, value := range patch {
+		out[field] = value
+	}
+	if _, ok := patch["title"]; ok {
+		out["title"] = redacted.Title
+	}
+	if _, ok := patch["terminal"]; ok {
+		out["terminal"] = redacted.Terminal
+	}
+	return normalizePatch(out), true
+}
+
+func normalizePatch(patch map[string]any) map[string]any {
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		return patch
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(payload, &out); err != nil {
+		return patch
+	}
+	return out
+}
+
+func redactSnapshots(dir string, redactor *Redactor, codec *seal.Codec, offsets []offsetPair, referenced map[string]bool, kept map[string]bool, dryRun bool) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read snapshots dir: %w", err)
+	}
+	store, err := snapshots.NewStoreWithCodec(filepath.Dir(dir), codec)
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		snapshot, err := store.Read(path)
+		if err != nil {
+			continue
+		}
+		original, err := json.Marshal(snapshot)
+		if err != nil {
+			return 0, fmt.Errorf("marshal snapshot: %w", err)
+		}
+
+		state := replay.DecodeState(snapshot.State)
+		collectStateLayoutRefs(referenced, state)
+		if hash, changed := redactedHash(redactor, state); changed {
+			state = redactor.Apply(state)
+			snapshot.State = stateAsMap(state)
+			snapshot.StateHash = hash
+		}
+		collectStateLayoutRefs(kept, state)
+		snapshot.LastEventOffset = remapOffset(offsets, snapshot.LastEventOffset)
+
+		payload, err := json.Marshal(snapshot)
+		if err != nil {
+			return 0, fmt.Errorf("marshal snapshot: %w", err)
+		}
+		if bytes.Equal(payload, original) {
+			continue
+		}
+		rewritten++
+		if dryRun {
+			continue
+		}
+		payload, err = codec.Seal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("seal snapshot: %w", err)
+		}
+		if err := writeFileAtomic(path, payload); err != nil {
+			return 0, fmt.Errorf("rewrite snapshot: %w", err)
+		}
+	}
+	return rewritten, nil
+}
+
+func redactedHash(redactor *Redactor, state model.State) (string, bool) {
+	original, err := state.Hash()
+	if err != nil {
+		return "", false
+	}
+	redacted, err := redactor.Apply(state).Hash()
+	if err != nil {
+		return "", false
+	}
+	return redacted, redacted != original
+}
+
+func remapOffset(offsets []offsetPair, offset int64) int64 {
+	if len(offsets) == 0 {
+		return offset
+	}
+	i := sort.Search(len(offsets), func(i int) bool { return offsets[i].old > offset })
+	if i == 0 {
+		return 0
+	}
+	return offsets[i-1].new
+}
+
+func stateAsMap(state model.State) map[string]any {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return map[string]any{}
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(payload, &out); err != nil {
+		return map[string]any{}
+	}
+	return out
+}
+
+func TitleKeyPath(root string) string {
+	return filepath.Join(root, "meta", "redact.key")
+}
+
+func LoadTitleKey(root string) ([]byte, error) {
+	path := TitleKeyPath(root)
+	payload, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(payload)))
+		if err != nil || len(key) != TitleKeySize {
+			return nil, fmt.Errorf("title key %s must be %d hex-encoded bytes", path, TitleKeySize)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read title key: %w", err)
+	}
+
+	key := make([]byte, TitleKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate title key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create meta dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return LoadTitleKey(root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create title key: %w", err)
+	}
+	if _, err := f.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("write title key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("write title key: %w", err)
+	}
+	return key, nil
+}
+
+func writeFileAtomic(path string, payload []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
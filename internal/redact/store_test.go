@@ -0,0 +1,317 @@
+package redact
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/layouts"
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/replay"
+	"github.com/jmo/terminal-redeemer/internal/snapshots"
+)
+
+func TestRewriteRedactsEventsAndSnapshots(t *testing.T) {
+	t.Parallel()
+
+	root, base := writeSensitiveStore(t)
+	redactor := newStoreRedactor(t)
+
+	summary, err := Rewrite(root, redactor, false)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if summary.EventsRewritten != 2 || summary.EventsDropped != 2 || summary.SnapshotsRewritten != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	payload, err := os.ReadFile(filepath.Join(root, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	for _, secret := range []string{"vault", "ssh", "/home/me/clients", "keepassxc"} {
+		if strings.Contains(string(payload), secret) {
+			t.Fatalf("expected %q to be redacted from events:\n%s", secret, payload)
+		}
+	}
+
+	engine, err := replay.NewEngine(root)
+	if err != nil {
+		t.Fatalf("new replay engine: %v", err)
+	}
+	for _, at := range []time.Time{base.Add(90 * time.Second), base.Add(time.Hour)} {
+		state, err := engine.At(at)
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		if len(state.Windows) != 1 || state.Windows[0].Key != "w-1" {
+			t.Fatalf("expected only w-1 after redaction at %s, got %#v", at, state.Windows)
+		}
+		window := state.Windows[0]
+		if window.Title != "" || window.Terminal == nil || window.Terminal.CWD != "/redacted/acme" {
+			t.Fatalf("unexpected redacted window %#v (terminal %#v)", window, window.Terminal)
+		}
+	}
+
+	store, err := snapshots.NewStore(root)
+	if err != nil {
+		t.Fatalf("new snapshot store: %v", err)
+	}
+	snapshot, _, err := store.LoadNearest(base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("load snapshot: %v", err)
+	}
+	if snapshot.LastEventOffset != int64(len(payload)) {
+		t.Fatalf("expected snapshot offset remapped to %d, got %d", len(payload), snapshot.LastEventOffset)
+	}
+	hash, err := replay.DecodeState(snapshot.State).Hash()
+	if err != nil {
+		t.Fatalf("hash snapshot state: %v", err)
+	}
+	if snapshot.StateHash != hash {
+		t.Fatalf("expected snapshot hash %s, got %s", hash, snapshot.StateHash)
+	}
+
+	again, err := Rewrite(root, redactor, false)
+	if err != nil {
+		t.Fatalf("second rewrite: %v", err)
+	}
+	if again != (Summary{}) {
+		t.Fatalf("expected second rewrite to be a no-op, got %+v", again)
+	}
+}
+
+func TestRewriteDryRunLeavesStoreUntouched(t *testing.T) {
+	t.Parallel()
+
+	root, _ := writeSensitiveStore(t)
+	before, err := os.ReadFile(filepath.Join(root, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+
+	summary, err := Rewrite(root, newStoreRedactor(t), true)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if summary.EventsRewritten != 2 || summary.EventsDropped != 2 || summary.SnapshotsRewritten != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	after, err := os.ReadFile(filepath.Join(root, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	if string(after) != string(before) {
+		t.Fatalf("expected dry run to leave events untouched")
+	}
+}
+
+func TestRewriteReplacesReferencedLayoutDumps(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	layoutStore, err := layouts.NewStore(root)
+	if err != nil {
+		t.Fatalf("new layout store: %v", err)
+	}
+	original, err := layoutStore.Put("layout {\n    pane cwd=\"/home/me/clients/acme\"\n}\n")
+	if err != nil {
+		t.Fatalf("put layout: %v", err)
+	}
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	writer, err := eventStore.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	state := model.State{Workspaces: []model.Workspace{{ID: "ws-1", Index: 1}}, Windows: []model.Window{{Key: "w-1", AppID: "kitty", WorkspaceID: "ws-1", Terminal: &model.Terminal{SessionTag: "work", LayoutRef: original}}}}
+	offset, err := writer.Append(events.Event{V: 1, TS: base, Host: "host-a", Profile: "default", EventType: "state_full", State: stateAsMap(state), StateHash: mustHash(t, state)})
+	if err != nil {
+		t.Fatalf("append event: %v", err)
+	}
+	_ = writer.Close()
+	snapStore, err := snapshots.NewStore(root)
+	if err != nil {
+		t.Fatalf("new snapshot store: %v", err)
+	}
+	if _, err := snapStore.Write(snapshots.Snapshot{V: 1, CreatedAt: base, Host: "host-a", Profile: "default", LastEventOffset: offset, StateHash: mustHash(t, state), State: stateAsMap(state)}); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	newRedactor := func() *Redactor {
+		redactor, err := New(Config{CWDPrefixes: []CWDRule{{Prefix: "/home/me/clients"}}, Layouts: layoutStore})
+		if err != nil {
+			t.Fatalf("new redactor: %v", err)
+		}
+		return redactor
+	}
+
+	preview, err := Rewrite(root, newRedactor(), true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if preview.LayoutsRewritten != 1 {
+		t.Fatalf("expected dry run to count the layout dump, got %+v", preview)
+	}
+	if _, err := layoutStore.Layout(original); err != nil {
+		t.Fatalf("expected dry run to keep the unmasked dump: %v", err)
+	}
+
+	summary, err := Rewrite(root, newRedactor(), false)
+	if err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if summary.EventsRewritten != 1 || summary.SnapshotsRewritten != 1 || summary.LayoutsRewritten != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if _, err := layoutStore.Layout(original); !errors.Is(err, layouts.ErrNotFound) {
+		t.Fatalf("expected the unmasked dump to be removed, got %v", err)
+	}
+	replayed, err := replay.NewEngine(root)
+	if err != nil {
+		t.Fatalf("new replay engine: %v", err)
+	}
+	got, err := replayed.At(base)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	masked, err := layoutStore.Layout(got.Windows[0].Terminal.LayoutRef)
+	if err != nil || !strings.Contains(masked, `cwd="/redacted/acme"`) {
+		t.Fatalf("expected the masked dump to stay referenced, got %q err=%v", masked, err)
+	}
+
+	again, err := Rewrite(root, newRedactor(), false)
+	if err != nil {
+		t.Fatalf("second rewrite: %v", err)
+	}
+	if again != (Summary{}) {
+		t.Fatalf("expected second rewrite to be a no-op, got %+v", again)
+	}
+}
+
+func TestRewriteRefusesWhileWriterLocked(t *testing.T) {
+	t.Parallel()
+
+	root, _ := writeSensitiveStore(t)
+	store, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	writer, err := store.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+
+	if _, err := Rewrite(root, newStoreRedactor(t), false); !errors.Is(err, events.ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+}
+
+func TestLoadTitleKeyCreatesPrivateKeyOnce(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	key, err := LoadTitleKey(root)
+	if err != nil || len(key) != TitleKeySize {
+		t.Fatalf("expected a %d byte key, got %d err=%v", TitleKeySize, len(key), err)
+	}
+	info, err := os.Stat(TitleKeyPath(root))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected private key file, got %v err=%v", info, err)
+	}
+	again, err := LoadTitleKey(root)
+	if err != nil || string(again) != string(key) {
+		t.Fatalf("expected the stored key to be reused, err=%v", err)
+	}
+
+	if err := os.WriteFile(TitleKeyPath(root), []byte("short\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	if _, err := LoadTitleKey(root); err == nil {
+		t.Fatal("expected an invalid key file to be rejected")
+	}
+}
+
+func newStoreRedactor(t *testing.T) *Redactor {
+	t.Helper()
+
+	redactor, err := New(Config{
+		Titles:        []TitleRule{{AppID: "kitty", Mode: TitleDrop}},
+		CWDPrefixes:   []CWDRule{{Prefix: "/home/me/clients"}},
+		ExcludeAppIDs: []string{"org.keepassxc.*"},
+	})
+	if err != nil {
+		t.Fatalf("new redactor: %v", err)
+	}
+	return redactor
+}
+
+func writeSensitiveStore(t *testing.T) (string, time.Time) {
+	t.Helper()
+
+	root := t.TempDir()
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	writer, err := eventStore.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	workspaces := []model.Workspace{{ID: "ws-1", Index: 1}}
+	kitty := model.Window{Key: "w-1", AppID: "kitty", WorkspaceID: "ws-1", Title: "ssh prod", Terminal: &model.Terminal{CWD: "/home/me/clients/acme"}}
+	vault := model.Window{Key: "w-2", AppID: "org.keepassxc.KeePassXC", WorkspaceID: "ws-1", Title: "vault"}
+	initial := model.State{Workspaces: workspaces, Windows: []model.Window{kitty, vault}}
+	kitty.Title = "ssh staging"
+	second := model.Window{Key: "w-3", AppID: "org.keepassxc.KeePassXC", WorkspaceID: "ws-1", Title: "vault 2"}
+	updated := model.State{Workspaces: workspaces, Windows: []model.Window{kitty, vault, second}}
+	final := model.State{Workspaces: workspaces, Windows: []model.Window{kitty, second}}
+
+	appendEvent := func(event events.Event) int64 {
+		t.Helper()
+		event.V, event.Host, event.Profile = 1, "host-a", "default"
+		offset, err := writer.Append(event)
+		if err != nil {
+			t.Fatalf("append event: %v", err)
+		}
+		return offset
+	}
+	appendEvent(events.Event{TS: base, EventType: "state_full", State: stateAsMap(initial), StateHash: mustHash(t, initial)})
+	appendEvent(events.Event{TS: base.Add(time.Minute), EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "ssh staging"}, StateHash: mustHash(t, updated)})
+	offset := appendEvent(events.Event{TS: base.Add(time.Minute), EventType: "window_patch", WindowKey: "w-3", Patch: map[string]any{"app_id": second.AppID, "workspace_id": "ws-1", "title": second.Title, "terminal": nil}, StateHash: mustHash(t, updated)})
+	appendEvent(events.Event{TS: base.Add(2 * time.Minute), EventType: "window_patch", WindowKey: "w-2", Patch: map[string]any{"deleted": true}, StateHash: mustHash(t, final)})
+
+	snapStore, err := snapshots.NewStore(root)
+	if err != nil {
+		t.Fatalf("new snapshot store: %v", err)
+	}
+	if _, err := snapStore.Write(snapshots.Snapshot{V: 1, CreatedAt: base.Add(time.Minute), Host: "host-a", Profile: "default", LastEventOffset: offset, StateHash: mustHash(t, updated), State: stateAsMap(updated)}); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	return root, base
+}
+
+func mustHash(t *testing.T, state model.State) string {
+	t.Helper()
+
+	hash, err := state.Hash()
+	if err != nil {
+		t.Fatalf("hash state: %v", err)
+	}
+	return hash
+}
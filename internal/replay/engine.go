@@ -1,7 +1,6 @@
 package replay
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,34 +8,61 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/events"
 	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/seal"
 	"github.com/jmo/terminal-redeemer/internal/snapshots"
 )
 
 type Engine struct {
 	eventsPath string
 	snapshots  *snapshots.Store
+	codec      *seal.Codec
+	host       string
+	profile    string
 }
 
 func NewEngine(root string) (*Engine, error) {
-	snapshotStore, err := snapshots.NewStore(root)
+	codec, err := seal.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngineWithCodec(root, codec)
+}
+
+func NewEngineWithCodec(root string, codec *seal.Codec) (*Engine, error) {
+	snapshotStore, err := snapshots.NewStoreWithCodec(root, codec)
 	if err != nil {
 		return nil, err
 	}
 	return &Engine{
 		eventsPath: filepath.Join(root, "events.jsonl"),
 		snapshots:  snapshotStore,
+		codec:      codec,
 	}, nil
 }
 
+func (e *Engine) Scoped(host string, profile string) *Engine {
+	scoped := *e
+	scoped.host = strings.TrimSpace(host)
+	scoped.profile = strings.TrimSpace(profile)
+	return &scoped
+}
+
+func (e *Engine) Matches(host string, profile string) bool {
+	return (e.host == "" || host == e.host) && (e.profile == "" || profile == e.profile)
+}
+
 func (e *Engine) At(at time.Time) (model.State, error) {
 	state := model.State{}
 	cursor := int64(0)
 
-	snapshot, _, err := e.snapshots.LoadNearest(at)
+	snapshot, _, err := e.snapshots.LoadNearestMatching(at, func(snapshot snapshots.Snapshot) bool {
+		return e.Matches(snapshot.Host, snapshot.Profile)
+	})
 	if err == nil {
 		state = decodeSnapshotState(snapshot)
 		cursor = snapshot.LastEventOffset
@@ -64,23 +90,30 @@ func (e *Engine) At(at time.Time) (model.State, error) {
 		windowsByKey[window.Key] = window
 	}
 
-	scanner := bufio.NewScanner(f)
+	scanner := seal.NewScanner(f)
 	for scanner.Scan() {
+		line, err := e.codec.Open(scanner.Bytes())
+		if errors.Is(err, seal.ErrKeyRequired) {
+			return model.State{}, err
+		}
+		if err != nil {
+			continue
+		}
 		var event events.Event
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+		if err := json.Unmarshal(line, &event); err != nil {
 			continue
 		}
 		if err := event.Validate(); err != nil {
 			continue
 		}
-		if event.TS.After(at) {
+		if event.TS.After(at) || !e.Matches(event.Host, event.Profile) {
 			continue
 		}
 		switch event.EventType {
 		case "window_patch":
-			applyWindowPatch(windowsByKey, event.WindowKey, event.Patch)
+			ApplyWindowPatch(windowsByKey, event.WindowKey, event.Patch)
 		case "state_full":
-			state = decodeEventState(event.State)
+			state = DecodeState(event.State)
 			windowsByKey = make(map[string]model.Window, len(state.Windows))
 			for _, window := range state.Windows {
 				windowsByKey[window.Key] = window
@@ -99,7 +132,7 @@ func (e *Engine) At(at time.Time) (model.State, error) {
 	return model.Normalize(state), nil
 }
 
-func decodeEventState(raw map[string]any) model.State {
+func DecodeState(raw map[string]any) model.State {
 	if raw == nil {
 		return model.State{}
 	}
@@ -126,7 +159,7 @@ func decodeSnapshotState(snapshot snapshots.Snapshot) model.State {
 	return state
 }
 
-func applyWindowPatch(windows map[string]model.Window, key string, patch map[string]any) {
+func ApplyWindowPatch(windows map[string]model.Window, key string, patch map[string]any) {
 	if patch == nil {
 		return
 	}
@@ -170,7 +203,7 @@ func decodeTerminal(raw any) *model.Terminal {
 	if err := json.Unmarshal(payload, &terminal); err != nil {
 		return nil
 	}
-	if terminal.CWD == "" && terminal.SessionTag == "" && len(terminal.ProcessTags) == 0 {
+	if terminal.CWD == "" && terminal.SessionTag == "" && len(terminal.ProcessTags) == 0 && len(terminal.Tabs) == 0 && terminal.Nvim == nil && terminal.Git == nil {
 		return nil
 	}
 	sort.Strings(terminal.ProcessTags)
@@ -1,12 +1,15 @@
 package replay
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/seal"
 	"github.com/jmo/terminal-redeemer/internal/snapshots"
 )
 
@@ -210,3 +213,119 @@ func TestReplayStateFullReplacesPriorWindows(t *testing.T) {
 		t.Fatalf("expected live window to remain, got %#v", state.Windows[0])
 	}
 }
+
+func TestReplayReadsEncryptedStore(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "redeem.key")
+	if err := seal.GenerateKeyFile(keyFile); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if _, err := seal.Encrypt(root, seal.KeySource{File: keyFile}, nil); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	snapStore, err := snapshots.NewStore(root)
+	if err != nil {
+		t.Fatalf("new snapshot store: %v", err)
+	}
+	writer, err := eventStore.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+
+	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	offsetA, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"app_id": "kitty", "workspace_id": "ws-1", "title": "a"}, StateHash: "sha256:a"})
+	if err != nil {
+		t.Fatalf("append A: %v", err)
+	}
+	if _, err := writer.Append(events.Event{V: 1, TS: t0.Add(2 * time.Second), Host: "host-a", Profile: "default", EventType: "window_patch", WindowKey: "w-1", Patch: map[string]any{"title": "b"}, StateHash: "sha256:b"}); err != nil {
+		t.Fatalf("append B: %v", err)
+	}
+	if _, err := snapStore.Write(snapshots.Snapshot{V: 1, CreatedAt: t0.Add(time.Second), Host: "host-a", Profile: "default", LastEventOffset: offsetA, StateHash: "sha256:a", State: map[string]any{"windows": []any{map[string]any{"key": "w-1", "app_id": "kitty", "workspace_id": "ws-1", "title": "a"}}}}); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+
+	engine, err := NewEngine(root)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	state, err := engine.At(t0.Add(2 * time.Second))
+	if err != nil {
+		t.Fatalf("replay at: %v", err)
+	}
+	if len(state.Windows) != 1 || state.Windows[0].Title != "b" {
+		t.Fatalf("expected title b from encrypted replay, got %#v", state.Windows)
+	}
+	listed, err := ListEvents(root, nil, nil)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("expected 2 decrypted events, got %d", len(listed))
+	}
+
+	if err := os.Remove(seal.MetaPath(root)); err != nil {
+		t.Fatalf("remove meta: %v", err)
+	}
+	if _, err := ListEvents(root, nil, nil); !errors.Is(err, seal.ErrKeyRequired) {
+		t.Fatalf("expected ErrKeyRequired without meta, got %v", err)
+	}
+}
+
+func TestReplayReadsLargeSealedEvents(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "redeem.key")
+	if err := seal.GenerateKeyFile(keyFile); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if _, err := seal.Encrypt(root, seal.KeySource{File: keyFile}, nil); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	eventStore, err := events.NewStore(root)
+	if err != nil {
+		t.Fatalf("new event store: %v", err)
+	}
+	writer, err := eventStore.AcquireWriter()
+	if err != nil {
+		t.Fatalf("acquire writer: %v", err)
+	}
+	t0 := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	title := strings.Repeat("x", 512*1024)
+	state := map[string]any{"windows": []any{map[string]any{"key": "w-1", "app_id": "kitty", "workspace_id": "ws-1", "title": title}}}
+	if _, err := writer.Append(events.Event{V: 1, TS: t0, Host: "host-a", Profile: "default", EventType: "state_full", State: state, StateHash: "sha256:a"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	_ = writer.Close()
+
+	listed, _, err := eventStore.ReadSince(0)
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("expected the large event to be read back, got %d events err=%v", len(listed), err)
+	}
+	engine, err := NewEngine(root)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	replayed, err := engine.At(t0)
+	if err != nil {
+		t.Fatalf("replay at: %v", err)
+	}
+	if len(replayed.Windows) != 1 || replayed.Windows[0].Title != title {
+		t.Fatalf("expected the large window to replay, got %d windows", len(replayed.Windows))
+	}
+	if _, err := seal.Decrypt(root, nil); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if _, err := ListEvents(root, nil, nil); err != nil {
+		t.Fatalf("list events after decrypt: %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package replay
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,9 +9,18 @@ import (
 	"time"
 
 	"github.com/jmo/terminal-redeemer/internal/events"
+	"github.com/jmo/terminal-redeemer/internal/seal"
 )
 
 func ListEvents(root string, from *time.Time, to *time.Time) ([]events.Event, error) {
+	codec, err := seal.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	return ListEventsWithCodec(root, codec, from, to)
+}
+
+func ListEventsWithCodec(root string, codec *seal.Codec, from *time.Time, to *time.Time) ([]events.Event, error) {
 	path := filepath.Join(root, "events.jsonl")
 	f, err := os.Open(path)
 	if errors.Is(err, os.ErrNotExist) {
@@ -26,10 +34,17 @@ func ListEvents(root string, from *time.Time, to *time.Time) ([]events.Event, er
 	}()
 
 	out := make([]events.Event, 0)
-	scanner := bufio.NewScanner(f)
+	scanner := seal.NewScanner(f)
 	for scanner.Scan() {
+		line, err := codec.Open(scanner.Bytes())
+		if errors.Is(err, seal.ErrKeyRequired) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
 		var event events.Event
-		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+		if err := json.Unmarshal(line, &event); err != nil {
 			continue
 		}
 		if err := event.Validate(); err != nil {
@@ -0,0 +1,118 @@
+package restore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type shellArg string
+
+func (a shellArg) String() string {
+	return shellQuote(string(a))
+}
+
+type shellArgs []shellArg
+
+func (a shellArgs) String() string {
+	parts := make([]string, 0, len(a))
+	for _, arg := range a {
+		parts = append(parts, arg.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func shellArgsOf(values []string) shellArgs {
+	out := make(shellArgs, 0, len(values))
+	for _, value := range values {
+		out = append(out, shellArg(value))
+	}
+	return out
+}
+
+type commandData struct {
+	AppID        shellArg
+	Title        shellArg
+	CWD          shellArg
+	WorkspaceRef shellArg
+	SessionTag   shellArg
+	ProcessTags  shellArgs
+	Argv         shellArgs
+	Matches      shellArgs
+	Match        map[string]shellArg
+}
+
+func isCommandTemplate(command string) bool {
+	return strings.Contains(command, "{{")
+}
+
+func renderCommand(command string, window model.Window, titlePattern string) (string, error) {
+	if !isCommandTemplate(command) {
+		return command, nil
+	}
+
+	data, err := newCommandData(window, titlePattern)
+	if err != nil {
+		return "", err
+	}
+	return executeCommandTemplate(command, data)
+}
+
+func executeCommandTemplate(command string, data commandData) (string, error) {
+	tmpl, err := template.New("command").Option("missingkey=error").Parse(command)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	rendered := strings.TrimSpace(out.String())
+	if rendered == "" {
+		return "", fmt.Errorf("rendered command is empty")
+	}
+	return rendered, nil
+}
+
+func newCommandData(window model.Window, titlePattern string) (commandData, error) {
+	data := commandData{
+		AppID:        shellArg(window.AppID),
+		Title:        shellArg(window.Title),
+		WorkspaceRef: shellArg(window.WorkspaceID),
+		Match:        map[string]shellArg{},
+	}
+	if window.Terminal != nil {
+		data.CWD = shellArg(window.Terminal.CWD)
+		data.SessionTag = shellArg(window.Terminal.SessionTag)
+		for _, tag := range window.Terminal.ProcessTags {
+			data.ProcessTags = append(data.ProcessTags, shellArg(tag))
+		}
+	}
+
+	if strings.TrimSpace(titlePattern) == "" {
+		return data, nil
+	}
+	pattern, err := regexp.Compile(titlePattern)
+	if err != nil {
+		return commandData{}, fmt.Errorf("invalid title pattern: %w", err)
+	}
+	match := pattern.FindStringSubmatch(window.Title)
+	if match == nil {
+		return commandData{}, fmt.Errorf("title does not match pattern %q", titlePattern)
+	}
+	for i, name := range pattern.SubexpNames() {
+		data.Matches = append(data.Matches, shellArg(match[i]))
+		if name != "" {
+			data.Match[name] = shellArg(match[i])
+		}
+	}
+	return data, nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,71 @@
+package restore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestPlannerRendersAllowlistTemplates(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{
+		Workspaces: []model.Workspace{{ID: "ws-1", Index: 1, Name: "code"}},
+		Windows: []model.Window{
+			{Key: "w-1", AppID: "code", WorkspaceID: "ws-1", Title: "main.go — myproj"},
+			{Key: "w-2", AppID: "firefox", WorkspaceID: "ws-1", Title: "it's a page", Terminal: &model.Terminal{ProcessTags: []string{"a", "b"}}},
+		},
+	}
+	planner := NewPlanner(PlannerConfig{
+		AppAllowlist: map[string]string{
+			"code":    "code ~/src/{{.Match.project}} --title {{.Title}}",
+			"firefox": "firefox --new-window {{.Title}} {{if .ProcessTags}}{{.ProcessTags}}{{end}} {{.WorkspaceRef}}",
+		},
+		AppTitlePatterns: map[string]string{"Code": `— (?P<project>\S+)$`},
+	})
+	plan := planner.Build(state)
+
+	if got := commandOf(plan, "w-1"); got != `code ~/src/'myproj' --title 'main.go — myproj'` {
+		t.Fatalf("unexpected code command %q", got)
+	}
+	if got := commandOf(plan, "w-2"); got != `firefox --new-window 'it'\''s a page' 'a' 'b' 'code'` {
+		t.Fatalf("unexpected firefox command %q", got)
+	}
+}
+
+func TestPlannerDegradesOnTemplateErrors(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-parse", AppID: "broken", Title: "x"},
+		{Key: "w-missing", AppID: "code", Title: "no project here"},
+		{Key: "w-field", AppID: "typo", Title: "x"},
+		{Key: "w-plain", AppID: "plain", Title: "x"},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		AppAllowlist: map[string]string{
+			"broken": "app {{.Title",
+			"code":   "code {{.Match.project}}",
+			"typo":   "app {{.Nope}}",
+			"plain":  "plain --flag",
+		},
+		AppTitlePatterns: map[string]string{"code": `— (?P<project>\S+)$`},
+	})
+	plan := planner.Build(state)
+
+	for _, key := range []string{"w-parse", "w-missing", "w-field"} {
+		if statusOf(plan, key) != StatusDegraded {
+			t.Fatalf("expected %s degraded, got %s", key, statusOf(plan, key))
+		}
+		if !strings.HasPrefix(reasonOf(plan, key), "command template error: ") {
+			t.Fatalf("unexpected reason for %s: %q", key, reasonOf(plan, key))
+		}
+	}
+	if !strings.Contains(reasonOf(plan, "w-missing"), "title does not match pattern") {
+		t.Fatalf("unexpected reason for w-missing: %q", reasonOf(plan, "w-missing"))
+	}
+	if statusOf(plan, "w-plain") != StatusReady || commandOf(plan, "w-plain") != "plain --flag" {
+		t.Fatalf("expected plain command untouched, got %s %q", statusOf(plan, "w-plain"), commandOf(plan, "w-plain"))
+	}
+}
@@ -0,0 +1,67 @@
+package restore
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+)
+
+type MissingCWD string
+
+const (
+	MissingCWDParent MissingCWD = "parent"
+	MissingCWDSkip   MissingCWD = "skip"
+)
+
+type FileSystem interface {
+	Stat(name string) (fs.FileInfo, error)
+}
+
+type OSFileSystem struct{}
+
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (i Item) Launchable() bool {
+	return i.Status == StatusReady || (i.Status == StatusDegraded && i.CWDFallback != "")
+}
+
+func (p *Planner) dirExists(dir string) bool {
+	info, err := p.config.FS.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+func (p *Planner) pathExists(name string) bool {
+	_, err := p.config.FS.Stat(name)
+	return err == nil
+}
+
+func (p *Planner) resolveCWD(cwd string) (string, bool) {
+	if cwd == "" || p.dirExists(cwd) {
+		return cwd, true
+	}
+	if p.config.Terminal.MissingCWD == MissingCWDSkip || !path.IsAbs(cwd) {
+		return "", false
+	}
+	for dir := path.Dir(path.Clean(cwd)); ; dir = path.Dir(dir) {
+		if p.dirExists(dir) {
+			return dir, true
+		}
+		if dir == "/" {
+			return "", false
+		}
+	}
+}
+
+func markReady(item Item, usedParent bool) Item {
+	if !usedParent {
+		item.Status = StatusReady
+		return item
+	}
+	item.Status = StatusDegraded
+	item.Reason = fmt.Sprintf("cwd missing, using parent %s", item.CWD)
+	item.CWDFallback = item.CWD
+	return item
+}
@@ -0,0 +1,101 @@
+package restore
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type stubFS []string
+
+func (s stubFS) Stat(name string) (fs.FileInfo, error) {
+	if name == "/" || slices.Contains(s, name) {
+		return stubDirInfo(name), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+type existingDirs struct{}
+
+func (existingDirs) Stat(name string) (fs.FileInfo, error) {
+	return stubDirInfo(name), nil
+}
+
+type stubDirInfo string
+
+func (i stubDirInfo) Name() string       { return string(i) }
+func (i stubDirInfo) Size() int64        { return 0 }
+func (i stubDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o755 }
+func (i stubDirInfo) ModTime() time.Time { return time.Time{} }
+func (i stubDirInfo) IsDir() bool        { return true }
+func (i stubDirInfo) Sys() any           { return nil }
+
+func TestPlannerUsesNearestExistingParentForMissingCWD(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-present", AppID: "kitty", Terminal: &model.Terminal{CWD: "/home/me/src", SessionTag: "a"}},
+		{Key: "w-deleted", AppID: "kitty", Terminal: &model.Terminal{CWD: "/home/me/src/old/branch", SessionTag: "b"}},
+		{Key: "w-unmounted", AppID: "kitty", Terminal: &model.Terminal{CWD: "/mnt/usb/photos", SessionTag: "c"}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true},
+		FS:       stubFS{"/home/me/src", "/mnt"},
+	})
+
+	plan := planner.Build(state)
+	if item := itemByKey(t, plan, "w-present"); item.Status != StatusReady || item.CWDFallback != "" {
+		t.Fatalf("expected existing cwd to stay ready, got %+v", item)
+	}
+	deleted := itemByKey(t, plan, "w-deleted")
+	if deleted.Status != StatusDegraded || deleted.Reason != "cwd missing, using parent /home/me/src" || deleted.CWD != "/home/me/src" {
+		t.Fatalf("unexpected fallback item %+v", deleted)
+	}
+	if !strings.Contains(deleted.Command, `--directory '/home/me/src'`) || !deleted.Launchable() {
+		t.Fatalf("expected launchable command in the parent, got %+v", deleted)
+	}
+	if unmounted := itemByKey(t, plan, "w-unmounted"); unmounted.CWDFallback != "/mnt" {
+		t.Fatalf("expected /mnt fallback, got %+v", unmounted)
+	}
+}
+
+func TestPlannerSkipsMissingCWDWhenConfigured(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-1", AppID: "kitty", Terminal: &model.Terminal{CWD: "/home/me/gone", SessionTag: "a"}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true, MissingCWD: MissingCWDSkip},
+		FS:       stubFS{"/home/me"},
+	})
+
+	item := itemByKey(t, planner.Build(state), "w-1")
+	if item.Status != StatusSkipped || item.Reason != "cwd /home/me/gone missing" || item.Launchable() {
+		t.Fatalf("expected skipped item, got %+v", item)
+	}
+}
+
+func TestExecutorLaunchesParentFallbackItems(t *testing.T) {
+	t.Parallel()
+
+	runner := &concurrencyRunner{}
+	executor := NewExecutor(runner)
+	result := executor.Execute(context.Background(), Plan{Items: []Item{
+		{WindowKey: "w-parent", Status: StatusDegraded, Reason: "cwd missing, using parent /home/me", CWDFallback: "/home/me", Command: "kitty --directory /home/me"},
+		{WindowKey: "w-degraded", Status: StatusDegraded, Reason: "missing terminal session tag", Command: "kitty"},
+	}})
+
+	if len(runner.started) != 1 || runner.started[0] != "kitty --directory /home/me" {
+		t.Fatalf("expected only the fallback item to launch, got %q", runner.started)
+	}
+	if result.Summary.Restored != 1 || result.Summary.Skipped != 1 {
+		t.Fatalf("unexpected summary %+v", result.Summary)
+	}
+}
@@ -1,30 +1,97 @@
 package restore
 
-import "context"
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/hooks"
+)
 
 type CommandRunner interface {
 	Run(ctx context.Context, command string) error
 }
 
+type PIDRunner interface {
+	RunWithPID(ctx context.Context, command string) (int, error)
+}
+
+type Hooks interface {
+	Fire(ctx context.Context, event hooks.Event) error
+}
+
+type ExecutorConfig struct {
+	Readiness   ReadinessChecker
+	MaxInFlight int
+	LaunchDelay time.Duration
+	AppPhases   map[string]int
+	Logger      *slog.Logger
+	Hooks       Hooks
+}
+
 type Executor struct {
-	runner CommandRunner
+	runner      CommandRunner
+	readiness   ReadinessChecker
+	maxInFlight int
+	launchDelay time.Duration
+	appPhases   map[string]int
+	logger      *slog.Logger
+	hooks       Hooks
 }
 
 func NewExecutor(runner CommandRunner) *Executor {
-	return &Executor{runner: runner}
+	return NewExecutorWithConfig(runner, ExecutorConfig{})
+}
+
+func NewExecutorWithReadiness(runner CommandRunner, readiness ReadinessChecker) *Executor {
+	return NewExecutorWithConfig(runner, ExecutorConfig{Readiness: readiness})
+}
+
+func NewExecutorWithConfig(runner CommandRunner, config ExecutorConfig) *Executor {
+	maxInFlight := config.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+	launchDelay := config.LaunchDelay
+	if launchDelay < 0 {
+		launchDelay = 0
+	}
+	appPhases := make(map[string]int, len(config.AppPhases))
+	for appID, phase := range config.AppPhases {
+		appPhases[normalizeAppID(appID)] = phase
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Executor{
+		runner:      runner,
+		readiness:   config.Readiness,
+		maxInFlight: maxInFlight,
+		launchDelay: launchDelay,
+		appPhases:   appPhases,
+		logger:      logger,
+		hooks:       config.Hooks,
+	}
 }
 
 type Summary struct {
 	Restored int
 	Skipped  int
 	Failed   int
+	TimedOut int
 }
 
 type ItemResult struct {
-	WindowKey string
-	Status    Status
-	Reason    string
-	Error     string
+	WindowKey       string
+	Status          Status
+	Reason          string
+	Error           string
+	PID             int
+	OpenedWindowKey string
 }
 
 type Result struct {
@@ -33,21 +100,156 @@ type Result struct {
 }
 
 func (e *Executor) Execute(ctx context.Context, plan Plan) Result {
-	summary := Summary{}
-	results := make([]ItemResult, 0, len(plan.Items))
-	for _, item := range plan.Items {
-		if item.Status != StatusReady {
-			summary.Skipped++
-			results = append(results, ItemResult{WindowKey: item.WindowKey, Status: item.Status, Reason: item.Reason})
+	results := make([]ItemResult, len(plan.Items))
+	if e.readiness != nil {
+		e.readiness.Prepare(ctx)
+	}
+
+	phases := make(map[int][]int)
+	for i, item := range plan.Items {
+		if !item.Launchable() {
+			results[i] = ItemResult{WindowKey: item.WindowKey, Status: item.Status, Reason: item.Reason}
 			continue
 		}
-		if err := e.runner.Run(ctx, item.Command); err != nil {
+		phase := e.appPhases[normalizeAppID(item.AppID)]
+		phases[phase] = append(phases[phase], i)
+	}
+	phaseOrder := make([]int, 0, len(phases))
+	for phase := range phases {
+		phaseOrder = append(phaseOrder, phase)
+	}
+	sort.Ints(phaseOrder)
+
+	launched := 0
+	slots := make(chan struct{}, e.maxInFlight)
+	for _, phase := range phaseOrder {
+		var wg sync.WaitGroup
+		for _, i := range phases[phase] {
+			if ctx.Err() != nil || !e.acquireSlot(ctx, slots, launched > 0) {
+				results[i] = ItemResult{WindowKey: plan.Items[i].WindowKey, Status: StatusSkipped, Reason: "restore cancelled"}
+				continue
+			}
+			launched++
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				results[i] = e.executeItem(ctx, plan.Items[i])
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	return Result{Summary: summarizeResults(results), Items: results}
+}
+
+func (e *Executor) acquireSlot(ctx context.Context, slots chan struct{}, delay bool) bool {
+	if delay && e.launchDelay > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(e.launchDelay):
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case slots <- struct{}{}:
+		return true
+	}
+}
+
+func (e *Executor) executeItem(ctx context.Context, item Item) ItemResult {
+	e.fire(ctx, hooks.RestoreItemBefore, item.AppID, hookItem(item, ItemResult{}))
+	result := e.launchItem(ctx, item)
+	e.fire(ctx, hooks.RestoreItemAfter, item.AppID, hookItem(item, result))
+	return result
+}
+
+func (e *Executor) launchItem(ctx context.Context, item Item) ItemResult {
+	if err := writeLaunchFiles(item.Files); err != nil {
+		e.logger.Warn("restore_launch_failed", "window", item.WindowKey, "app_id", item.AppID, "err", err)
+		return ItemResult{WindowKey: item.WindowKey, Status: StatusFailed, Error: err.Error()}
+	}
+	pid, err := e.launch(ctx, item.Command)
+	if err != nil {
+		e.logger.Warn("restore_launch_failed", "window", item.WindowKey, "app_id", item.AppID, "err", err)
+		return ItemResult{WindowKey: item.WindowKey, Status: StatusFailed, Error: err.Error()}
+	}
+	e.logger.Debug("restore_launched", "window", item.WindowKey, "app_id", item.AppID, "pid", pid)
+	var opened string
+	if e.readiness != nil {
+		opened, err = e.readiness.WaitForWindow(ctx, item, pid)
+		if errors.Is(err, ErrWindowTimeout) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			e.logger.Warn("restore_window_timeout", "window", item.WindowKey, "app_id", item.AppID, "pid", pid, "err", err)
+			return ItemResult{WindowKey: item.WindowKey, Status: StatusTimeout, Error: err.Error(), PID: pid}
+		}
+	}
+	return ItemResult{WindowKey: item.WindowKey, Status: StatusReady, PID: pid, OpenedWindowKey: opened}
+}
+
+func (e *Executor) launch(ctx context.Context, command string) (int, error) {
+	if runner, ok := e.runner.(PIDRunner); ok {
+		return runner.RunWithPID(ctx, command)
+	}
+	return 0, e.runner.Run(ctx, command)
+}
+
+func (e *Executor) FireRunAfter(ctx context.Context, result Result) {
+	if e.hooks == nil {
+		return
+	}
+	run := hooks.RestoreRun{
+		Restored: result.Summary.Restored,
+		Skipped:  result.Summary.Skipped,
+		Failed:   result.Summary.Failed,
+		TimedOut: result.Summary.TimedOut,
+		Items:    make([]hooks.RestoreItem, 0, len(result.Items)),
+	}
+	for _, item := range result.Items {
+		run.Items = append(run.Items, hooks.RestoreItem{
+			WindowKey: item.WindowKey,
+			Status:    string(item.Status),
+			Reason:    item.Reason,
+			Error:     item.Error,
+			PID:       item.PID,
+		})
+	}
+	e.fire(ctx, hooks.RestoreRunAfter, "", run)
+}
+
+func (e *Executor) fire(ctx context.Context, name string, appID string, data any) {
+	if e.hooks == nil {
+		return
+	}
+	_ = e.hooks.Fire(ctx, hooks.Event{Name: name, AppID: appID, Data: data})
+}
+
+func hookItem(item Item, result ItemResult) hooks.RestoreItem {
+	return hooks.RestoreItem{
+		WindowKey:   item.WindowKey,
+		AppID:       item.AppID,
+		WorkspaceID: item.WorkspaceID,
+		Command:     item.Command,
+		Status:      string(result.Status),
+		Error:       result.Error,
+		PID:         result.PID,
+	}
+}
+
+func summarizeResults(results []ItemResult) Summary {
+	summary := Summary{}
+	for _, result := range results {
+		switch result.Status {
+		case StatusReady:
+			summary.Restored++
+		case StatusFailed:
 			summary.Failed++
-			results = append(results, ItemResult{WindowKey: item.WindowKey, Status: StatusFailed, Error: err.Error()})
-			continue
+		case StatusTimeout:
+			summary.TimedOut++
+		default:
+			summary.Skipped++
 		}
-		summary.Restored++
-		results = append(results, ItemResult{WindowKey: item.WindowKey, Status: StatusReady})
 	}
-	return Result{Summary: summary, Items: results}
+	return summary
 }
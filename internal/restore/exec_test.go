@@ -0,0 +1,258 @@
+package restore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/hooks"
+)
+
+func TestExecutorLimitsInFlightLaunchesAndKeepsPlanOrder(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: make([]Item, 0, 8)}
+	for i := 0; i < 8; i++ {
+		plan.Items = append(plan.Items, Item{WindowKey: fmt.Sprintf("w-%d", i), AppID: "kitty", Status: StatusReady, Command: fmt.Sprintf("cmd-%d", i)})
+	}
+	plan.Items = append(plan.Items, Item{WindowKey: "w-skip", Status: StatusSkipped, Reason: "app not allowlisted"})
+
+	runner := &concurrencyRunner{hold: 10 * time.Millisecond}
+	executor := NewExecutorWithConfig(runner, ExecutorConfig{MaxInFlight: 3})
+	result := executor.Execute(context.Background(), plan)
+
+	if runner.maxSeen > 3 {
+		t.Fatalf("expected at most 3 launches in flight, saw %d", runner.maxSeen)
+	}
+	if runner.maxSeen < 2 {
+		t.Fatalf("expected launches to overlap, saw max %d in flight", runner.maxSeen)
+	}
+	if result.Summary.Restored != 8 || result.Summary.Skipped != 1 {
+		t.Fatalf("unexpected summary: %+v", result.Summary)
+	}
+	for i, item := range plan.Items {
+		if result.Items[i].WindowKey != item.WindowKey {
+			t.Fatalf("expected result %d to be %s, got %s", i, item.WindowKey, result.Items[i].WindowKey)
+		}
+	}
+}
+
+func TestExecutorRunsAppPhasesInOrder(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", Status: StatusReady, Command: "kitty-1"},
+		{WindowKey: "w-2", AppID: "firefox", Status: StatusReady, Command: "firefox"},
+		{WindowKey: "w-3", AppID: "code", Status: StatusReady, Command: "code"},
+		{WindowKey: "w-4", AppID: "kitty", Status: StatusReady, Command: "kitty-2"},
+	}}
+
+	runner := &concurrencyRunner{}
+	executor := NewExecutorWithConfig(runner, ExecutorConfig{AppPhases: map[string]int{"Firefox": -1, "kitty": 5}})
+	executor.Execute(context.Background(), plan)
+
+	want := []string{"firefox", "code", "kitty-1", "kitty-2"}
+	if !reflect.DeepEqual(runner.started, want) {
+		t.Fatalf("unexpected launch order: %#v", runner.started)
+	}
+}
+
+func TestExecutorSpacesLaunchesByDelay(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", Status: StatusReady, Command: "a"},
+		{WindowKey: "w-2", AppID: "kitty", Status: StatusReady, Command: "b"},
+		{WindowKey: "w-3", AppID: "kitty", Status: StatusReady, Command: "c"},
+	}}
+
+	runner := &concurrencyRunner{}
+	executor := NewExecutorWithConfig(runner, ExecutorConfig{MaxInFlight: 3, LaunchDelay: 15 * time.Millisecond})
+	start := time.Now()
+	executor.Execute(context.Background(), plan)
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected launches spaced by delay, elapsed=%s", elapsed)
+	}
+}
+
+func TestExecutorStopsLaunchingWhenCancelled(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", Status: StatusReady, Command: "a"},
+		{WindowKey: "w-2", AppID: "kitty", Status: StatusReady, Command: "b"},
+		{WindowKey: "w-3", AppID: "firefox", Status: StatusReady, Command: "c"},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runner := &cancellingRunner{cancel: cancel}
+	executor := NewExecutorWithConfig(runner, ExecutorConfig{LaunchDelay: time.Hour, AppPhases: map[string]int{"firefox": 1}})
+	result := executor.Execute(ctx, plan)
+
+	if !reflect.DeepEqual(runner.started, []string{"a"}) {
+		t.Fatalf("expected launches to stop after cancellation, got %#v", runner.started)
+	}
+	if result.Summary.Restored != 1 || result.Summary.Skipped != 2 {
+		t.Fatalf("unexpected summary: %+v", result.Summary)
+	}
+	for _, item := range result.Items[1:] {
+		if item.Status != StatusSkipped || item.Reason != "restore cancelled" {
+			t.Fatalf("expected %s skipped as cancelled, got %+v", item.WindowKey, item)
+		}
+	}
+}
+
+func TestExecutorLogsLaunchFailures(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{{WindowKey: "w-1", AppID: "firefox", Status: StatusReady, Command: "firefox"}}}
+	var logs bytes.Buffer
+	executor := NewExecutorWithConfig(failingRunner{err: errors.New("exec: firefox: not found")}, ExecutorConfig{
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	result := executor.Execute(context.Background(), plan)
+
+	if result.Summary.Failed != 1 {
+		t.Fatalf("unexpected summary: %+v", result.Summary)
+	}
+	if !strings.Contains(logs.String(), "level=WARN msg=restore_launch_failed window=w-1 app_id=firefox") {
+		t.Fatalf("expected launch failure log, got %q", logs.String())
+	}
+}
+
+func TestExecutorWritesLaunchFilesBeforeLaunch(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "launch", "kitty-1.session")
+	plan := Plan{Items: []Item{{WindowKey: "w-1", AppID: "kitty", Status: StatusReady, Command: "kitty --session " + path, Files: []LaunchFile{{Path: path, Content: "new_tab it's\n"}}}}}
+	var seen string
+	runner := runnerFunc(func(command string) error {
+		payload, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		seen = string(payload)
+		return nil
+	})
+	result := NewExecutor(runner).Execute(context.Background(), plan)
+
+	if result.Summary.Restored != 1 || seen != "new_tab it's\n" {
+		t.Fatalf("expected the session file written before launch, got %+v content %q", result.Summary, seen)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected a private launch file, got %v err=%v", info, err)
+	}
+}
+
+func TestExecutorFiresItemAndRunHooks(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", WorkspaceID: "ws-1", Status: StatusReady, Command: "kitty"},
+		{WindowKey: "w-2", AppID: "slack", Status: StatusSkipped, Reason: "app not allowlisted"},
+	}}
+	recorder := &recordingHooks{}
+	executor := NewExecutorWithConfig(&concurrencyRunner{}, ExecutorConfig{Hooks: recorder})
+	result := executor.Execute(context.Background(), plan)
+	if names := recorder.names(); !reflect.DeepEqual(names, []string{hooks.RestoreItemBefore, hooks.RestoreItemAfter}) {
+		t.Fatalf("expected run_after left to the caller, got %#v", names)
+	}
+	executor.FireRunAfter(context.Background(), result)
+
+	names := recorder.names()
+	want := []string{hooks.RestoreItemBefore, hooks.RestoreItemAfter, hooks.RestoreRunAfter}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("unexpected hook order: %#v", names)
+	}
+	after := recorder.events[1].Data.(hooks.RestoreItem)
+	if recorder.events[1].AppID != "kitty" || after.Status != string(StatusReady) || after.Command != "kitty" {
+		t.Fatalf("unexpected item_after payload: %+v", recorder.events[1])
+	}
+	run := recorder.events[2].Data.(hooks.RestoreRun)
+	if run.Restored != 1 || run.Skipped != 1 || len(run.Items) != 2 || run.Items[1].Reason != "app not allowlisted" {
+		t.Fatalf("unexpected run_after payload: %+v", run)
+	}
+}
+
+type recordingHooks struct {
+	mu     sync.Mutex
+	events []hooks.Event
+}
+
+func (h *recordingHooks) Fire(_ context.Context, event hooks.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *recordingHooks) names() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := make([]string, 0, len(h.events))
+	for _, event := range h.events {
+		names = append(names, event.Name)
+	}
+	return names
+}
+
+type runnerFunc func(command string) error
+
+func (f runnerFunc) Run(_ context.Context, command string) error {
+	return f(command)
+}
+
+type failingRunner struct {
+	err error
+}
+
+func (r failingRunner) Run(_ context.Context, _ string) error {
+	return r.err
+}
+
+type cancellingRunner struct {
+	cancel  context.CancelFunc
+	started []string
+}
+
+func (r *cancellingRunner) Run(_ context.Context, command string) error {
+	r.started = append(r.started, command)
+	r.cancel()
+	return nil
+}
+
+type concurrencyRunner struct {
+	mu       sync.Mutex
+	hold     time.Duration
+	inFlight int
+	maxSeen  int
+	started  []string
+}
+
+func (r *concurrencyRunner) Run(_ context.Context, command string) error {
+	r.mu.Lock()
+	r.inFlight++
+	if r.inFlight > r.maxSeen {
+		r.maxSeen = r.inFlight
+	}
+	r.started = append(r.started, command)
+	r.mu.Unlock()
+
+	time.Sleep(r.hold)
+
+	r.mu.Lock()
+	r.inFlight--
+	r.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,61 @@
+package restore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type GitChecker interface {
+	BranchExists(root string, branch string) (bool, error)
+}
+
+func (p *Planner) planGit(item *Item, git *model.Git) (string, bool) {
+	if git == nil || strings.TrimSpace(git.Root) == "" || !p.dirExists(git.Root) {
+		return "", true
+	}
+	branch := strings.TrimSpace(git.Branch)
+	if branch == "" {
+		return "", true
+	}
+	if p.config.Terminal.VerifyGitBranch && p.config.Git != nil {
+		exists, err := p.config.Git.BranchExists(git.Root, branch)
+		if err != nil {
+			item.Status = StatusDegraded
+			item.Reason = fmt.Sprintf("git branch check for %s in %s failed: %v", branch, git.Root, err)
+			return "", false
+		}
+		if !exists {
+			item.Status = StatusDegraded
+			item.Reason = fmt.Sprintf("git branch %s no longer exists in %s", branch, git.Root)
+			return "", false
+		}
+	}
+	worktree := strings.TrimSpace(git.Worktree)
+	if !p.config.Terminal.RestoreGitWorktree || worktree == "" || p.dirExists(worktree) {
+		return "", true
+	}
+	return "git -C " + shellQuote(git.Root) + " worktree add " + shellQuote(worktree) + " " + shellQuote(branch), true
+}
+
+func withinDir(name string, dir string) bool {
+	name, dir = path.Clean(name), path.Clean(dir)
+	return name == dir || strings.HasPrefix(name, strings.TrimSuffix(dir, "/")+"/")
+}
+
+func (p *Planner) planTerminalWorktree(item Item, window model.Window, kind TerminalKind, worktreeAdd string, fallbackCWD string, hasFallback bool) Item {
+	planned := p.planTerminalIn(item, window, kind, item.CWD)
+	if !planned.Launchable() {
+		return planned
+	}
+	if hasFallback {
+		if fallback := p.planTerminalIn(item, window, kind, fallbackCWD); fallback.Launchable() {
+			planned.Command = fmt.Sprintf("if %s; then %s; else %s; fi", worktreeAdd, planned.Command, fallback.Command)
+			return planned
+		}
+	}
+	planned.Command = worktreeAdd + " && " + planned.Command
+	return planned
+}
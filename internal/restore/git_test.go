@@ -0,0 +1,118 @@
+package restore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type stubGitChecker map[string]bool
+
+func (s stubGitChecker) BranchExists(root string, branch string) (bool, error) {
+	exists, ok := s[root+"@"+branch]
+	if !ok {
+		return false, errors.New("unknown repository")
+	}
+	return exists, nil
+}
+
+func TestPlannerVerifiesRecordedGitBranch(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-kept", AppID: "kitty", Terminal: &model.Terminal{CWD: "/src/app", Git: &model.Git{Root: "/src/app", Branch: "main"}}},
+		{Key: "w-gone", AppID: "kitty", Terminal: &model.Terminal{CWD: "/src/app", Git: &model.Git{Root: "/src/app", Branch: "spike", Dirty: true}}},
+		{Key: "w-unknown", AppID: "kitty", Terminal: &model.Terminal{CWD: "/src/lib", Git: &model.Git{Root: "/src/lib", Branch: "main"}}},
+		{Key: "w-detached", AppID: "kitty", Terminal: &model.Terminal{CWD: "/src/app", Git: &model.Git{Root: "/src/app"}}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", VerifyGitBranch: true},
+		FS:       existingDirs{},
+		Git:      stubGitChecker{"/src/app@main": true, "/src/app@spike": false},
+	})
+
+	plan := planner.Build(state)
+	for _, key := range []string{"w-kept", "w-detached"} {
+		if item := itemByKey(t, plan, key); item.Status != StatusReady || item.Git == nil {
+			t.Fatalf("expected %s ready with git metadata, got %+v", key, item)
+		}
+	}
+	gone := itemByKey(t, plan, "w-gone")
+	if gone.Status != StatusDegraded || gone.Reason != "git branch spike no longer exists in /src/app" || gone.Launchable() {
+		t.Fatalf("expected unlaunchable degraded item for deleted branch, got %+v", gone)
+	}
+
+	unknown := itemByKey(t, plan, "w-unknown")
+	if unknown.Status != StatusDegraded || unknown.Reason != "git branch check for main in /src/lib failed: unknown repository" {
+		t.Fatalf("expected failed branch check to be reported, got %+v", unknown)
+	}
+
+	unchecked := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "kitty"}, FS: existingDirs{}, Git: stubGitChecker{}})
+	if item := itemByKey(t, unchecked.Build(state), "w-gone"); item.Status != StatusReady {
+		t.Fatalf("expected branch check to be opt-in, got %+v", item)
+	}
+}
+
+func TestPlannerRecreatesMissingGitWorktree(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-1", AppID: "kitty", Terminal: &model.Terminal{
+			CWD: "/src/app-feature/cmd",
+			Git: &model.Git{Root: "/src/app", Branch: "feature", Worktree: "/src/app-feature"},
+		}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", VerifyGitBranch: true, RestoreGitWorktree: true},
+		FS:       stubFS{"/src/app", "/src"},
+		Git:      stubGitChecker{"/src/app@feature": true},
+	})
+
+	item := itemByKey(t, planner.Build(state), "w-1")
+	if item.Status != StatusReady || item.CWD != "/src/app-feature/cmd" {
+		t.Fatalf("expected ready item in the recorded cwd, got %+v", item)
+	}
+	want := "if git -C '/src/app' worktree add '/src/app-feature' 'feature'; then kitty --directory '/src/app-feature/cmd'; else kitty --directory '/src'; fi"
+	if item.Command != want {
+		t.Fatalf("expected worktree added before launch with a parent fallback, got %q", item.Command)
+	}
+
+	skip := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", RestoreGitWorktree: true, MissingCWD: MissingCWDSkip},
+		FS:       stubFS{"/src/app", "/src"},
+	})
+	if item := itemByKey(t, skip.Build(state), "w-1"); item.Command != "git -C '/src/app' worktree add '/src/app-feature' 'feature' && kitty --directory '/src/app-feature/cmd'" {
+		t.Fatalf("expected no fallback launch with missingCwd skip, got %q", item.Command)
+	}
+
+	fallback := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "kitty"}, FS: stubFS{"/src/app", "/src"}})
+	if item := itemByKey(t, fallback.Build(state), "w-1"); item.CWDFallback != "/src" || strings.Contains(item.Command, "worktree add") {
+		t.Fatalf("expected parent fallback without worktree restore, got %+v", item)
+	}
+}
+
+func TestPlannerRemapsGitPaths(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-1", AppID: "kitty", Terminal: &model.Terminal{
+			CWD: "/home/desk/src/app-feature",
+			Git: &model.Git{Root: "/home/desk/src/app", Branch: "feature", Worktree: "/home/desk/src/app-feature"},
+		}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", RestoreGitWorktree: true},
+		Remap:    Remap{CWDPrefixes: []CWDRemap{{From: "/home/desk", To: "/home/laptop"}}},
+		FS:       stubFS{"/home/laptop/src/app"},
+	})
+
+	item := itemByKey(t, planner.Build(state), "w-1")
+	if item.Git == nil || item.Git.Root != "/home/laptop/src/app" || item.Git.Worktree != "/home/laptop/src/app-feature" {
+		t.Fatalf("expected remapped git paths, got %+v", item.Git)
+	}
+	if !strings.HasPrefix(item.Command, "if git -C '/home/laptop/src/app' worktree add '/home/laptop/src/app-feature' 'feature'; then ") {
+		t.Fatalf("expected worktree added at the remapped path, got %q", item.Command)
+	}
+}
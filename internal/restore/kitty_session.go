@@ -0,0 +1,108 @@
+package restore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func (p *Planner) kittySession(window model.Window) (string, error) {
+	var b strings.Builder
+	for _, tab := range window.Terminal.Tabs {
+		if len(tab.Panes) == 0 {
+			continue
+		}
+		b.WriteString(sessionLine("new_tab", tab.Title))
+		if layout := strings.TrimSpace(tab.Layout); layout != "" {
+			b.WriteString(sessionLine("layout", layout))
+		}
+		for _, pane := range tab.Panes {
+			parts := []string{"launch"}
+			if cwd := strings.TrimSpace(pane.CWD); cwd != "" {
+				parts = append(parts, shellQuote("--cwd="+cwd))
+			}
+			exec, ok, err := p.paneExec(window, pane)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				parts = append(parts, "sh", "-lc", shellQuote(exec))
+			}
+			b.WriteString(strings.Join(parts, " ") + "\n")
+			if tab.Active && pane.Active {
+				b.WriteString("focus\n")
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("no panes recorded")
+	}
+	return b.String(), nil
+}
+
+func (p *Planner) paneExec(window model.Window, pane model.Pane) (string, bool, error) {
+	if len(pane.Command) == 0 {
+		return "", false, nil
+	}
+	template, ok := p.config.Relaunch[strings.ToLower(filepath.Base(pane.Command[0]))]
+	if !ok {
+		return "", false, nil
+	}
+	if template == "" {
+		template = "{{.Argv}}"
+	}
+	data, err := newCommandData(window, "")
+	if err != nil {
+		return "", false, err
+	}
+	data.Argv = shellArgsOf(pane.Command)
+	rendered, err := executeCommandTemplate(template, data)
+	if err != nil {
+		return "", false, err
+	}
+	return rendered + "; exec ${SHELL:-sh} -l", true, nil
+}
+
+func sessionLine(keyword string, value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return keyword + "\n"
+	}
+	return keyword + " " + value + "\n"
+}
+
+func (p *Planner) launchFile(kind string, windowKey string, ext string) string {
+	sum := sha256.Sum256([]byte(windowKey))
+	return filepath.Join(p.config.FilesDir, kind+"-"+hex.EncodeToString(sum[:8])+ext)
+}
+
+type LaunchFile struct {
+	Path    string
+	Content string
+}
+
+func writeLaunchFiles(files []LaunchFile) error {
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0o700); err != nil {
+			return fmt.Errorf("create launch dir: %w", err)
+		}
+		tmp := file.Path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(file.Content), 0o600); err != nil {
+			return fmt.Errorf("write launch file: %w", err)
+		}
+		if err := os.Chmod(tmp, 0o600); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("write launch file: %w", err)
+		}
+		if err := os.Rename(tmp, file.Path); err != nil {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("write launch file: %w", err)
+		}
+	}
+	return nil
+}
@@ -2,11 +2,15 @@ package restore
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/procmeta"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
 )
 
 type Status string
@@ -16,12 +20,26 @@ const (
 	StatusSkipped  Status = "skipped"
 	StatusDegraded Status = "degraded"
 	StatusFailed   Status = "failed"
+	StatusTimeout  Status = "timeout"
 )
 
 type PlannerConfig struct {
-	AppAllowlist map[string]string
-	AppMode      map[string]AppMode
-	Terminal     TerminalConfig
+	AppAllowlist     map[string]string
+	AppMode          map[string]AppMode
+	AppTitlePatterns map[string]string
+	Relaunch         map[string]string
+	Terminal         TerminalConfig
+	Terminals        *terminals.Matcher
+	Multiplexers     []procmeta.Multiplexer
+	Layouts          LayoutSource
+	Remap            Remap
+	FS               FileSystem
+	Git              GitChecker
+	FilesDir         string
+}
+
+type LayoutSource interface {
+	Layout(ref string) (string, error)
 }
 
 type AppMode string
@@ -34,6 +52,11 @@ const (
 type TerminalConfig struct {
 	Command              string
 	ZellijAttachOrCreate bool
+	TmuxAttachOrCreate   bool
+	RestoreNvim          bool
+	MissingCWD           MissingCWD
+	VerifyGitBranch      bool
+	RestoreGitWorktree   bool
 }
 
 type Planner struct {
@@ -59,6 +82,29 @@ func NewPlanner(config PlannerConfig) *Planner {
 		normalizedAppModes[normalized] = AppModePerWindow
 	}
 	config.AppMode = normalizedAppModes
+	normalizedTitlePatterns := make(map[string]string, len(config.AppTitlePatterns))
+	for appID, pattern := range config.AppTitlePatterns {
+		normalizedTitlePatterns[normalizeAppID(appID)] = pattern
+	}
+	config.AppTitlePatterns = normalizedTitlePatterns
+	normalizedRelaunch := make(map[string]string, len(config.Relaunch))
+	for tag, command := range config.Relaunch {
+		normalizedRelaunch[strings.ToLower(strings.TrimSpace(tag))] = strings.TrimSpace(command)
+	}
+	config.Relaunch = normalizedRelaunch
+	if config.Terminals == nil {
+		config.Terminals = terminals.Default()
+	}
+	if config.Multiplexers == nil {
+		config.Multiplexers = procmeta.DefaultMultiplexers()
+	}
+	config.Remap = normalizeRemap(config.Remap)
+	if config.FS == nil {
+		config.FS = OSFileSystem{}
+	}
+	if strings.TrimSpace(config.FilesDir) == "" {
+		config.FilesDir = filepath.Join(os.TempDir(), "terminal-redeemer")
+	}
 	return &Planner{config: config}
 }
 
@@ -73,8 +119,22 @@ type Item struct {
 	Status      Status
 	Reason      string
 	Command     string
+	Title       string
+	CWD         string
+	Session     SessionMode
+	Remapped    []string
+	CWDFallback string
+	Git         *model.Git
+	Files       []LaunchFile
 }
 
+type SessionMode string
+
+const (
+	SessionAttached SessionMode = "attached"
+	SessionLayout   SessionMode = "layout"
+)
+
 func (p *Planner) Build(state model.State) Plan {
 	plan := Plan{Items: make([]Item, 0, len(state.Windows))}
 	workspaceRefs := workspaceRefsByID(state)
@@ -87,10 +147,11 @@ func (p *Planner) Build(state model.State) Plan {
 		if strings.TrimSpace(resolvedWindow.WorkspaceID) == "" {
 			resolvedWindow.WorkspaceID = window.WorkspaceID
 		}
+		resolvedWindow, remapped := p.remapWindow(resolvedWindow)
 
 		var item Item
-		if isTerminal(resolvedWindow.AppID) {
-			item = p.planTerminal(resolvedWindow)
+		if kind, ok := p.config.Terminals.Match(resolvedWindow.AppID); ok {
+			item = p.planTerminal(resolvedWindow, TerminalKind(kind), cwdRemapped(window, resolvedWindow))
 		} else {
 			item = p.planApp(resolvedWindow)
 			mode := p.appMode(resolvedWindow.AppID)
@@ -105,6 +166,7 @@ func (p *Planner) Build(state model.State) Plan {
 				}
 			}
 		}
+		item.Remapped = remapped
 		plan.Items = append(plan.Items, item)
 	}
 	return plan
@@ -190,8 +252,17 @@ func (p *Planner) appMode(appID string) AppMode {
 	return AppModePerWindow
 }
 
-func (p *Planner) planTerminal(window model.Window) Item {
-	item := Item{WindowKey: window.Key, WorkspaceID: window.WorkspaceID, AppID: window.AppID}
+func cwdRemapped(original model.Window, resolved model.Window) bool {
+	return original.Terminal != nil && resolved.Terminal != nil && original.Terminal.CWD != resolved.Terminal.CWD
+}
+
+func (p *Planner) planTerminal(window model.Window, kind TerminalKind, remapped bool) Item {
+	item := Item{WindowKey: window.Key, WorkspaceID: window.WorkspaceID, AppID: window.AppID, Title: window.Title}
+	if _, ok := LauncherForKind(kind); !ok {
+		item.Status = StatusDegraded
+		item.Reason = fmt.Sprintf("no launcher for kind %s", kind)
+		return item
+	}
 	if window.Terminal == nil {
 		item.Status = StatusSkipped
 		item.Reason = "missing terminal metadata"
@@ -200,19 +271,106 @@ func (p *Planner) planTerminal(window model.Window) Item {
 
 	cwd := strings.TrimSpace(window.Terminal.CWD)
 	sessionTag := strings.TrimSpace(window.Terminal.SessionTag)
+	item.CWD = cwd
 	if cwd == "" && sessionTag == "" {
 		item.Status = StatusSkipped
 		item.Reason = "missing terminal metadata"
 		return item
 	}
+	item.Git = window.Terminal.Git
+	worktreeAdd, ok := p.planGit(&item, window.Terminal.Git)
+	if !ok {
+		return item
+	}
+
+	resolvedCWD, ok := p.resolveCWD(cwd)
+	if worktreeAdd != "" && withinDir(cwd, window.Terminal.Git.Worktree) {
+		return p.planTerminalWorktree(item, window, kind, worktreeAdd, resolvedCWD, ok)
+	}
+	if !ok && remapped {
+		item.Status = StatusDegraded
+		item.Reason = fmt.Sprintf("remapped cwd %s does not exist", cwd)
+		return item
+	}
+	if !ok {
+		item.Status = StatusSkipped
+		item.Reason = fmt.Sprintf("cwd %s missing", cwd)
+		return item
+	}
+	item = p.planTerminalIn(item, window, kind, resolvedCWD)
+	if remapped && item.CWDFallback != "" {
+		item.Reason = fmt.Sprintf("remapped cwd %s does not exist, using parent %s", cwd, item.CWDFallback)
+	}
+	return item
+}
+
+func (p *Planner) planTerminalIn(item Item, window model.Window, kind TerminalKind, cwd string) Item {
+	sessionTag := strings.TrimSpace(window.Terminal.SessionTag)
+	usedParent := cwd != item.CWD
+	item.CWD = cwd
+
+	launch := TerminalLaunch{CWD: cwd}
+	sessionAttach := false
+	savedLayout, layoutPath := "", ""
+	if sessionTag != "" {
+		multiplexer, ok := p.multiplexer(window.Terminal.Multiplexer)
+		if !ok {
+			item.Status = StatusDegraded
+			item.Reason = fmt.Sprintf("unsupported multiplexer %s", window.Terminal.Multiplexer)
+			item.Command = p.terminalCommand(kind, window.AppID, launch)
+			return item
+		}
+		if p.attachOrCreate(multiplexer.Name()) {
+			sessionAttach = true
+			launch.Exec = multiplexer.AttachOrCreateCommand(sessionTag)
+			item.Session = SessionAttached
+			if creator, layout, ok := p.savedLayout(multiplexer, window.Terminal); ok {
+				savedLayout = layout
+				layoutPath = p.launchFile("zellij", window.Key, ".kdl")
+				launch.Exec = creator.CreateWithLayoutCommand(sessionTag, layoutPath)
+				item.Session = SessionLayout
+			}
+		}
+	}
 
-	command := strings.TrimSpace(p.config.Terminal.Command)
-	if cwd != "" {
-		command = fmt.Sprintf("%s --directory %q", command, cwd)
+	if !sessionAttach && len(window.Terminal.Tabs) > 0 && (kind == TerminalKitty || kind == TerminalWezTerm) {
+		return p.planTerminalTabs(item, window, kind, launch, usedParent)
 	}
-	if p.config.Terminal.ZellijAttachOrCreate && sessionTag != "" {
-		zellijScript := fmt.Sprintf("env -u ZELLIJ -u ZELLIJ_SESSION_NAME -u ZELLIJ_PANE_ID -u ZELLIJ_TAB_INDEX -u ZELLIJ_TAB_NAME zellij attach --create %q || { echo \"terminal-redeemer: zellij attach failed for session %s\"; exec ${SHELL:-sh} -l; }", sessionTag, sessionTag)
-		command = fmt.Sprintf("%s -e sh -lc %q", strings.TrimSpace(p.config.Terminal.Command), zellijScript)
+
+	nvimExec, restoreNvim := "", false
+	if !sessionAttach && p.config.Terminal.RestoreNvim && window.Terminal.Nvim != nil {
+		nvimExec, restoreNvim = p.nvimCommand(cwd, window.Terminal.Nvim)
+	}
+
+	relaunchTag, relaunchTemplate, relaunch := p.relaunchFor(window.Terminal)
+	if restoreNvim {
+		launch.Exec = nvimExec
+	} else if relaunch && !sessionAttach {
+		argv := window.Terminal.ProcessArgs[relaunchTag]
+		if len(argv) == 0 {
+			item.Status = StatusDegraded
+			item.Reason = fmt.Sprintf("missing argv for process tag %s", relaunchTag)
+			item.Command = p.terminalCommand(kind, window.AppID, launch)
+			return item
+		}
+		data, err := newCommandData(window, "")
+		if err != nil {
+			item.Status = StatusDegraded
+			item.Reason = fmt.Sprintf("relaunch template error: %v", err)
+			return item
+		}
+		data.Argv = shellArgsOf(argv)
+		rendered, err := executeCommandTemplate(relaunchTemplate, data)
+		if err != nil {
+			item.Status = StatusDegraded
+			item.Reason = fmt.Sprintf("relaunch template error: %v", err)
+			return item
+		}
+		launch.Exec = rendered + "; exec ${SHELL:-sh} -l"
+	}
+	command := p.terminalCommand(kind, window.AppID, launch)
+	if savedLayout != "" {
+		item.Files = []LaunchFile{{Path: layoutPath, Content: savedLayout}}
 	}
 
 	if cwd == "" {
@@ -221,20 +379,159 @@ func (p *Planner) planTerminal(window model.Window) Item {
 		item.Command = command
 		return item
 	}
-	if p.config.Terminal.ZellijAttachOrCreate && sessionTag == "" {
+	if p.config.Terminal.ZellijAttachOrCreate && sessionTag == "" && !relaunch && !restoreNvim {
 		item.Status = StatusDegraded
 		item.Reason = "missing terminal session tag"
 		item.Command = command
 		return item
 	}
 
-	item.Status = StatusReady
 	item.Command = command
-	return item
+	return markReady(item, usedParent)
+}
+
+func (p *Planner) planTerminalTabs(item Item, window model.Window, kind TerminalKind, launch TerminalLaunch, usedParent bool) Item {
+	var command string
+	switch kind {
+	case TerminalKitty:
+		session, err := p.kittySession(window)
+		if err != nil {
+			item.Status = StatusDegraded
+			item.Reason = fmt.Sprintf("kitty session error: %v", err)
+			return item
+		}
+		launch.Session = p.launchFile(string(kind), window.Key, ".session")
+		item.Files = []LaunchFile{{Path: launch.Session, Content: session}}
+		command = p.terminalCommand(kind, window.AppID, launch)
+	case TerminalWezTerm:
+		script, firstCWD, err := p.wezTermScript(window)
+		if err != nil {
+			item.Status = StatusDegraded
+			item.Reason = fmt.Sprintf("wezterm pane script error: %v", err)
+			return item
+		}
+		if firstCWD != "" {
+			launch.CWD = firstCWD
+		}
+		scriptPath := p.launchFile(string(kind), window.Key, ".sh")
+		launch.Exec = "sh " + shellQuote(scriptPath)
+		item.Files = []LaunchFile{{Path: scriptPath, Content: script}}
+		command = p.terminalCommand(kind, window.AppID, launch)
+	}
+
+	item.Command = command
+	if item.CWD == "" {
+		item.Status = StatusDegraded
+		item.Reason = "missing terminal cwd"
+		return item
+	}
+	return markReady(item, usedParent)
+}
+
+func (p *Planner) multiplexer(name string) (procmeta.Multiplexer, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = procmeta.MultiplexerZellij
+	}
+	for _, multiplexer := range p.config.Multiplexers {
+		if multiplexer.Name() == name {
+			return multiplexer, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Planner) nvimCommand(cwd string, state *model.Nvim) (string, bool) {
+	var command string
+	base := cwd
+	nvimCWD := strings.TrimSpace(state.CWD)
+	if nvimCWD != "" && nvimCWD != cwd && p.dirExists(nvimCWD) {
+		base = nvimCWD
+	}
+	exists := func(name string) bool {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(base, name)
+		}
+		return p.pathExists(name)
+	}
+	session := strings.TrimSpace(state.Session)
+	buffers := make([]string, 0, len(state.Buffers))
+	for _, buffer := range state.Buffers {
+		if exists(buffer) {
+			buffers = append(buffers, buffer)
+		}
+	}
+	switch {
+	case session != "" && exists(session):
+		command = "nvim -S " + shellQuote(session)
+	case len(buffers) > 0:
+		command = "nvim " + shellArgsOf(buffers).String()
+	default:
+		return "", false
+	}
+	if base != cwd {
+		command = "cd " + shellQuote(base) + " && " + command
+	}
+	return command + "; exec ${SHELL:-sh} -l", true
+}
+
+func (p *Planner) savedLayout(multiplexer procmeta.Multiplexer, terminal *model.Terminal) (procmeta.LayoutCreator, string, bool) {
+	ref := strings.TrimSpace(terminal.LayoutRef)
+	creator, ok := multiplexer.(procmeta.LayoutCreator)
+	if ref == "" || p.config.Layouts == nil || !ok {
+		return nil, "", false
+	}
+	layout, err := p.config.Layouts.Layout(ref)
+	if err != nil || strings.TrimSpace(layout) == "" {
+		return nil, "", false
+	}
+	return creator, layout, true
+}
+
+func (p *Planner) attachOrCreate(multiplexer string) bool {
+	switch multiplexer {
+	case procmeta.MultiplexerZellij:
+		return p.config.Terminal.ZellijAttachOrCreate
+	case procmeta.MultiplexerTmux:
+		return p.config.Terminal.TmuxAttachOrCreate
+	default:
+		return true
+	}
+}
+
+func (p *Planner) relaunchFor(terminal *model.Terminal) (string, string, bool) {
+	for _, tag := range terminal.ProcessTags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		command, ok := p.config.Relaunch[tag]
+		if !ok {
+			continue
+		}
+		if command == "" {
+			command = "{{.Argv}}"
+		}
+		return tag, command, true
+	}
+	return "", "", false
+}
+
+func (p *Planner) terminalCommand(kind TerminalKind, appID string, launch TerminalLaunch) string {
+	configured := strings.TrimSpace(p.config.Terminal.Command)
+	configuredLauncher, configuredKnown := launcherForCommand(configured)
+	launcher, ok := LauncherForKind(kind)
+	if !ok {
+		return ""
+	}
+	if normalized := normalizeAppID(appID); normalized != string(launcher.Kind()) && normalized != launcher.AppID() {
+		launch.Class = appID
+	}
+	if configuredKnown && configuredLauncher.Kind() == launcher.Kind() {
+		return launcher.Command(configured, launch)
+	}
+	return launcher.Command(launcher.Binary(), launch)
 }
 
 func (p *Planner) planApp(window model.Window) Item {
-	item := Item{WindowKey: window.Key, WorkspaceID: window.WorkspaceID, AppID: window.AppID}
+	item := Item{WindowKey: window.Key, WorkspaceID: window.WorkspaceID, AppID: window.AppID, Title: window.Title}
 	command, ok := p.config.AppAllowlist[normalizeAppID(window.AppID)]
 	if !ok {
 		item.Status = StatusSkipped
@@ -246,6 +543,12 @@ func (p *Planner) planApp(window model.Window) Item {
 		item.Reason = "allowlist command is empty"
 		return item
 	}
+	command, err := renderCommand(command, window, p.config.AppTitlePatterns[normalizeAppID(window.AppID)])
+	if err != nil {
+		item.Status = StatusDegraded
+		item.Reason = fmt.Sprintf("command template error: %v", err)
+		return item
+	}
 	item.Status = StatusReady
 	item.Command = command
 	return item
@@ -254,12 +557,3 @@ func (p *Planner) planApp(window model.Window) Item {
 func normalizeAppID(appID string) string {
 	return strings.ToLower(strings.TrimSpace(appID))
 }
-
-func isTerminal(appID string) bool {
-	switch strings.ToLower(strings.TrimSpace(appID)) {
-	case "kitty", "alacritty", "foot", "wezterm":
-		return true
-	default:
-		return false
-	}
-}
@@ -3,10 +3,14 @@ package restore
 import (
 	"context"
 	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/procmeta"
 )
 
 func TestPlanStatusClassification(t *testing.T) {
@@ -130,6 +134,44 @@ func TestTerminalRestoreMarksPartialMetadataAsDegraded(t *testing.T) {
 	}
 }
 
+func TestTerminalRestoreNamesMissingRemappedCWD(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		missingCWD MissingCWD
+		reason     string
+		launchable bool
+	}{
+		{name: "parent", missingCWD: MissingCWDParent, reason: "remapped cwd /home/laptop/gone does not exist, using parent /home/laptop", launchable: true},
+		{name: "skip", missingCWD: MissingCWDSkip, reason: "remapped cwd /home/laptop/gone does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			state := model.State{Windows: []model.Window{
+				{Key: "w-remapped", AppID: "kitty", Terminal: &model.Terminal{CWD: "/home/desk/gone"}},
+				{Key: "w-local", AppID: "kitty", Terminal: &model.Terminal{CWD: "/srv/gone"}},
+			}}
+			planner := NewPlanner(PlannerConfig{
+				Terminal: TerminalConfig{Command: "kitty", MissingCWD: tt.missingCWD},
+				Remap:    Remap{CWDPrefixes: []CWDRemap{{From: "/home/desk", To: "/home/laptop"}}},
+				FS:       stubFS{"/home/laptop"},
+			})
+			plan := planner.Build(state)
+
+			item := itemByKey(t, plan, "w-remapped")
+			if item.Status != StatusDegraded || item.Reason != tt.reason || item.Launchable() != tt.launchable || len(item.Remapped) != 1 {
+				t.Fatalf("unexpected remapped item %+v", item)
+			}
+			if local := itemByKey(t, plan, "w-local"); strings.Contains(local.Reason, "remapped") {
+				t.Fatalf("expected an unmapped missing cwd to keep its own reason, got %+v", local)
+			}
+		})
+	}
+}
+
 func TestPlannerUsesWorkspaceNameThenIndexForWorkspaceReference(t *testing.T) {
 	t.Parallel()
 
@@ -209,6 +251,320 @@ func TestTerminalZellijCommandUsesAttachCreateAndFallbackShell(t *testing.T) {
 	}
 }
 
+func TestTerminalRelaunchesConfiguredProcessTags(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-argv", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/a", ProcessTags: []string{"claude"}, ProcessArgs: map[string][]string{"claude": {"claude", "--resume", "x y"}}}},
+		{Key: "w-template", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/b", ProcessTags: []string{"opencode"}, ProcessArgs: map[string][]string{"opencode": {"opencode"}}}},
+		{Key: "w-missing", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/c", ProcessTags: []string{"claude"}}},
+		{Key: "w-zellij", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/d", SessionTag: "s", ProcessTags: []string{"claude"}}},
+		{Key: "w-untagged", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/e", ProcessTags: []string{"htop"}, ProcessArgs: map[string][]string{"htop": {"htop"}}}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true},
+		Relaunch: map[string]string{"Claude": "", "opencode": "opencode --continue {{.CWD}}"},
+		FS:       existingDirs{},
+	})
+	plan := planner.Build(state)
+
+	if statusOf(plan, "w-argv") != StatusReady {
+		t.Fatalf("expected relaunch item ready, got %s (%s)", statusOf(plan, "w-argv"), reasonOf(plan, "w-argv"))
+	}
+	if got := commandOf(plan, "w-argv"); got != `kitty --directory '/tmp/a' -e sh -lc `+shellQuote(`'claude' '--resume' 'x y'; exec ${SHELL:-sh} -l`) {
+		t.Fatalf("unexpected relaunch command %q", got)
+	}
+	if got := commandOf(plan, "w-template"); !strings.Contains(got, shellQuote(`opencode --continue '/tmp/b'; exec ${SHELL:-sh} -l`)) {
+		t.Fatalf("expected templated relaunch, got %q", got)
+	}
+	if statusOf(plan, "w-missing") != StatusDegraded || reasonOf(plan, "w-missing") != "missing argv for process tag claude" {
+		t.Fatalf("expected missing argv degraded, got %s %q", statusOf(plan, "w-missing"), reasonOf(plan, "w-missing"))
+	}
+	if got := commandOf(plan, "w-zellij"); !strings.Contains(got, "zellij attach --create") || strings.Contains(got, "claude") {
+		t.Fatalf("expected zellij attach to take precedence, got %q", got)
+	}
+	if statusOf(plan, "w-untagged") != StatusDegraded || reasonOf(plan, "w-untagged") != "missing terminal session tag" {
+		t.Fatalf("expected unconfigured tag to keep previous behavior, got %s %q", statusOf(plan, "w-untagged"), reasonOf(plan, "w-untagged"))
+	}
+}
+
+func TestTerminalRelaunchKeepsCapturedArgvLiteral(t *testing.T) {
+	t.Parallel()
+
+	argv := []string{"printf", "%s|", "$(touch pwned)", "`touch pwned`", "$HOME", `a\b`, "it's"}
+	state := model.State{Windows: []model.Window{
+		{Key: "w-1", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/a", ProcessTags: []string{"printf"}, ProcessArgs: map[string][]string{"printf": argv}}},
+	}}
+	planner := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "kitty"}, Relaunch: map[string]string{"printf": ""}, FS: existingDirs{}})
+
+	command, ok := strings.CutPrefix(commandOf(planner.Build(state), "w-1"), "kitty ")
+	if !ok {
+		t.Fatalf("unexpected command %q", commandOf(planner.Build(state), "w-1"))
+	}
+	dir := t.TempDir()
+	split := exec.Command("sh", "-c", `printf '%s\n' `+command)
+	split.Dir = dir
+	args, err := split.Output()
+	if err != nil {
+		t.Fatalf("split command: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(args), "\n"), "\n")
+	inner := lines[len(lines)-1]
+	cmd := exec.Command("sh", "-c", inner)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "SHELL=true")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("run relaunch: %v", err)
+	}
+	if got := string(out); got != strings.Join(argv[2:], "|")+"|" {
+		t.Fatalf("expected argv printed literally, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+		t.Fatal("captured argv was executed by the shell")
+	}
+}
+
+func TestTerminalSessionAttachUsesRecordedMultiplexer(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-tmux", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/a", SessionTag: "work", Multiplexer: "tmux"}},
+		{Key: "w-legacy", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/b", SessionTag: "proj"}},
+		{Key: "w-screen", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/c", SessionTag: "old", Multiplexer: "screen"}},
+	}}
+	planner := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true, TmuxAttachOrCreate: true}, FS: existingDirs{}})
+	plan := planner.Build(state)
+
+	if got := commandOf(plan, "w-tmux"); statusOf(plan, "w-tmux") != StatusReady || !strings.Contains(got, "tmux new-session -A -s") {
+		t.Fatalf("expected tmux attach command, got %s %q", statusOf(plan, "w-tmux"), got)
+	}
+	if got := commandOf(plan, "w-legacy"); !strings.Contains(got, "zellij attach --create") {
+		t.Fatalf("expected legacy session tags to attach with zellij, got %q", got)
+	}
+	if statusOf(plan, "w-screen") != StatusDegraded || reasonOf(plan, "w-screen") != "unsupported multiplexer screen" {
+		t.Fatalf("expected unsupported multiplexer degraded, got %s %q", statusOf(plan, "w-screen"), reasonOf(plan, "w-screen"))
+	}
+
+	plain := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "kitty"}, FS: existingDirs{}}).Build(state)
+	if got := commandOf(plain, "w-tmux"); got != `kitty --directory '/tmp/a'` {
+		t.Fatalf("expected tmux attach to be disabled, got %q", got)
+	}
+}
+
+func TestTerminalKittyTabsRestoreViaSessionFile(t *testing.T) {
+	t.Parallel()
+
+	tabs := []model.Tab{
+		{Title: "edit", Layout: "splits", Active: true, Panes: []model.Pane{
+			{CWD: "/tmp/a", Command: []string{"/usr/bin/nvim", "main.go"}, Active: true},
+			{CWD: "/tmp/a b"},
+		}},
+		{Title: "logs", Layout: "tall", Panes: []model.Pane{{CWD: "/var/log", Command: []string{"journalctl", "-f"}}}},
+	}
+	state := model.State{Windows: []model.Window{
+		{Key: "w-tabs", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/a", Tabs: tabs}},
+		{Key: "w-attach", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/a", SessionTag: "work", Tabs: tabs}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true},
+		Relaunch: map[string]string{"nvim": ""},
+		FS:       existingDirs{},
+		FilesDir: "/run/redeem",
+	})
+	plan := planner.Build(state)
+
+	if statusOf(plan, "w-tabs") != StatusReady {
+		t.Fatalf("expected kitty tabs item ready, got %s (%s)", statusOf(plan, "w-tabs"), reasonOf(plan, "w-tabs"))
+	}
+	got := commandOf(plan, "w-tabs")
+	sessionPath := planner.launchFile("kitty", "w-tabs", ".session")
+	if !strings.HasPrefix(sessionPath, "/run/redeem/kitty-") || got != `kitty --directory '/tmp/a' --session '`+sessionPath+`'` {
+		t.Fatalf("unexpected kitty session command %q", got)
+	}
+	files := itemOf(plan, "w-tabs").Files
+	if len(files) != 1 || files[0].Path != sessionPath || !strings.HasPrefix(files[0].Content, "new_tab edit") {
+		t.Fatalf("expected the session file to be written by the executor, got %#v", files)
+	}
+	if again := commandOf(planner.Build(state), "w-tabs"); again != got {
+		t.Fatalf("expected a fixed session file per window, got %q then %q", got, again)
+	}
+
+	session, err := planner.kittySession(state.Windows[0])
+	if err != nil {
+		t.Fatalf("kitty session: %v", err)
+	}
+	want := `new_tab edit
+layout splits
+launch '--cwd=/tmp/a' sh -lc ''\''/usr/bin/nvim'\'' '\''main.go'\''; exec ${SHELL:-sh} -l'
+focus
+launch '--cwd=/tmp/a b'
+new_tab logs
+layout tall
+launch '--cwd=/var/log'
+`
+	if session != want {
+		t.Fatalf("unexpected kitty session:\n%s", session)
+	}
+	if got := commandOf(plan, "w-attach"); strings.Contains(got, "--session") || !strings.Contains(got, "zellij attach --create") {
+		t.Fatalf("expected session attach to take precedence over kitty tabs, got %q", got)
+	}
+}
+
+func TestTerminalWezTermPanesRestoreViaCLIScript(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{{Key: "w-wez", AppID: "org.wezfurlong.wezterm", Terminal: &model.Terminal{CWD: "/src", Tabs: []model.Tab{
+		{Title: "code", Panes: []model.Pane{
+			{CWD: "/src"},
+			{CWD: "/src/internal", Split: model.SplitRight},
+			{CWD: "/src", Split: model.SplitBottom},
+		}},
+		{Active: true, Panes: []model.Pane{{CWD: "/home/jmo/My Notes", Active: true}}},
+	}}}}}
+	planner := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true}, FS: existingDirs{}, FilesDir: "/run/redeem"})
+	plan := planner.Build(state)
+
+	if statusOf(plan, "w-wez") != StatusReady {
+		t.Fatalf("expected wezterm panes item ready, got %s (%s)", statusOf(plan, "w-wez"), reasonOf(plan, "w-wez"))
+	}
+	got := commandOf(plan, "w-wez")
+	scriptPath := planner.launchFile("wezterm", "w-wez", ".sh")
+	if got != `wezterm start --cwd '/src' -- sh -lc `+shellQuote("sh "+shellQuote(scriptPath)) {
+		t.Fatalf("unexpected wezterm command %q", got)
+	}
+	if files := itemOf(plan, "w-wez").Files; len(files) != 1 || files[0].Path != scriptPath || !strings.HasPrefix(files[0].Content, "p0=$WEZTERM_PANE") {
+		t.Fatalf("expected the pane script to be written by the executor, got %#v", files)
+	}
+
+	script, cwd, err := planner.wezTermScript(state.Windows[0])
+	if err != nil {
+		t.Fatalf("wezterm script: %v", err)
+	}
+	want := `p0=$WEZTERM_PANE
+p1=$(wezterm cli split-pane --pane-id "$p0" --right --cwd '/src/internal')
+p2=$(wezterm cli split-pane --pane-id "$p1" --bottom --cwd '/src')
+wezterm cli set-tab-title --pane-id "$p0" 'code'
+p3=$(wezterm cli spawn --pane-id "$p0" --cwd '/home/jmo/My Notes')
+wezterm cli activate-pane --pane-id "$p3"
+exec ${SHELL:-sh} -l
+`
+	if script != want || cwd != "/src" {
+		t.Fatalf("unexpected wezterm script (cwd %q):\n%s", cwd, script)
+	}
+}
+
+func TestTerminalZellijSessionRecreatedFromSavedLayout(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-saved", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/a", SessionTag: "gone", LayoutRef: "ref-1"}},
+		{Key: "w-unsaved", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/c", SessionTag: "gone", LayoutRef: "missing"}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal:     TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true},
+		Multiplexers: []procmeta.Multiplexer{procmeta.Zellij{Verifier: failingSessionVerifier{t}}},
+		Layouts:      stubLayouts{"ref-1": "layout {\n    pane\n}\n"},
+		FS:           existingDirs{},
+		FilesDir:     "/run/redeem",
+	})
+	plan := planner.Build(state)
+
+	saved := itemOf(plan, "w-saved")
+	if saved.Status != StatusReady || saved.Session != SessionLayout {
+		t.Fatalf("expected ready item backed by the saved layout, got %#v", saved)
+	}
+	layoutPath := planner.launchFile("zellij", "w-saved", ".kdl")
+	if len(saved.Files) != 1 || saved.Files[0].Path != layoutPath || saved.Files[0].Content != "layout {\n    pane\n}\n" {
+		t.Fatalf("expected the saved layout to be written by the executor, got %#v", saved.Files)
+	}
+	if !strings.HasPrefix(saved.Command, "kitty --directory '/tmp/a' -e sh -lc") || strings.Contains(saved.Command, "pane") ||
+		!strings.Contains(saved.Command, `grep -Fxq -- '\''gone'\''; then `) ||
+		!strings.Contains(saved.Command, `zellij --session '\''gone'\'' --layout '\''`+layoutPath+`'\''`) {
+		t.Fatalf("unexpected recreate command %q", saved.Command)
+	}
+
+	unsaved := itemOf(plan, "w-unsaved")
+	if unsaved.Session != SessionAttached || len(unsaved.Files) != 0 || !strings.Contains(unsaved.Command, "zellij attach --create") || strings.Contains(unsaved.Command, "--layout") {
+		t.Fatalf("expected w-unsaved to attach, got %#v", unsaved)
+	}
+}
+
+type failingSessionVerifier struct {
+	t *testing.T
+}
+
+func (v failingSessionVerifier) Exists(session string) (bool, error) {
+	v.t.Errorf("planner probed zellij for session %s", session)
+	return false, nil
+}
+
+func TestTerminalRestoresNvimSessionOrBuffers(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-session", AppID: "kitty", Terminal: &model.Terminal{CWD: "/srv/app", Nvim: &model.Nvim{CWD: "/srv/app", Session: "/srv/app/Session.vim", Buffers: []string{"/srv/app/a.go"}}}},
+		{Key: "w-buffers", AppID: "kitty", Terminal: &model.Terminal{CWD: "/srv", Nvim: &model.Nvim{CWD: "/srv/app", Buffers: []string{"/srv/app/a.go", "/srv/app/b c.go"}}}},
+		{Key: "w-empty", AppID: "kitty", Terminal: &model.Terminal{CWD: "/srv", Nvim: &model.Nvim{CWD: "/srv"}}},
+		{Key: "w-zellij", AppID: "kitty", Terminal: &model.Terminal{CWD: "/srv", SessionTag: "work", Nvim: &model.Nvim{Session: "/srv/Session.vim"}}},
+	}}
+	planner := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true, RestoreNvim: true}, FS: existingDirs{}})
+	plan := planner.Build(state)
+
+	if got := commandOf(plan, "w-session"); statusOf(plan, "w-session") != StatusReady || got != `kitty --directory '/srv/app' -e sh -lc `+shellQuote(`nvim -S '/srv/app/Session.vim'; exec ${SHELL:-sh} -l`) {
+		t.Fatalf("unexpected nvim session restore %s %q", statusOf(plan, "w-session"), got)
+	}
+	if got := commandOf(plan, "w-buffers"); got != `kitty --directory '/srv' -e sh -lc `+shellQuote(`cd '/srv/app' && nvim '/srv/app/a.go' '/srv/app/b c.go'; exec ${SHELL:-sh} -l`) {
+		t.Fatalf("unexpected nvim buffer restore %q", got)
+	}
+	if statusOf(plan, "w-empty") != StatusDegraded || reasonOf(plan, "w-empty") != "missing terminal session tag" {
+		t.Fatalf("expected nvim without buffers to keep previous behavior, got %s %q", statusOf(plan, "w-empty"), reasonOf(plan, "w-empty"))
+	}
+	if got := commandOf(plan, "w-zellij"); strings.Contains(got, "nvim") {
+		t.Fatalf("expected session attach to take precedence, got %q", got)
+	}
+
+	disabled := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "kitty"}, FS: existingDirs{}}).Build(state)
+	if got := commandOf(disabled, "w-session"); got != `kitty --directory '/srv/app'` {
+		t.Fatalf("expected nvim restore to be opt-in, got %q", got)
+	}
+}
+
+func TestTerminalNvimRestoreDropsMissingSessionAndBuffers(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-session-gone", AppID: "kitty", Terminal: &model.Terminal{CWD: "/srv/app", Nvim: &model.Nvim{CWD: "/srv/gone", Session: "/srv/app/Session.vim", Buffers: []string{"/srv/app/a.go", "/srv/app/deleted.go"}}}},
+		{Key: "w-all-gone", AppID: "kitty", Terminal: &model.Terminal{CWD: "/srv/app", Nvim: &model.Nvim{Buffers: []string{"/srv/app/deleted.go"}}}},
+		{Key: "w-relative", AppID: "kitty", Terminal: &model.Terminal{CWD: "/srv", Nvim: &model.Nvim{CWD: "/srv/app", Session: "Session.vim", Buffers: []string{"a.go", "deleted.go"}}}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", RestoreNvim: true},
+		FS:       stubFS{"/srv", "/srv/app", "/srv/app/a.go"},
+	})
+	plan := planner.Build(state)
+
+	if got := commandOf(plan, "w-session-gone"); got != `kitty --directory '/srv/app' -e sh -lc `+shellQuote(`nvim '/srv/app/a.go'; exec ${SHELL:-sh} -l`) {
+		t.Fatalf("expected only the remaining buffer reopened, got %q", got)
+	}
+	if got := commandOf(plan, "w-all-gone"); got != `kitty --directory '/srv/app'` {
+		t.Fatalf("expected plain terminal when no nvim paths remain, got %q", got)
+	}
+	if got := commandOf(plan, "w-relative"); got != `kitty --directory '/srv' -e sh -lc `+shellQuote(`cd '/srv/app' && nvim 'a.go'; exec ${SHELL:-sh} -l`) {
+		t.Fatalf("expected relative paths checked against the nvim cwd, got %q", got)
+	}
+}
+
+type stubLayouts map[string]string
+
+func (s stubLayouts) Layout(ref string) (string, error) {
+	layout, ok := s[ref]
+	if !ok {
+		return "", errors.New("layout not found")
+	}
+	return layout, nil
+}
+
 func TestExecutorContinueOnFailureSummaryAndResults(t *testing.T) {
 	t.Parallel()
 
@@ -255,6 +611,15 @@ func statusOf(plan Plan, key string) Status {
 	return ""
 }
 
+func itemOf(plan Plan, key string) Item {
+	for _, item := range plan.Items {
+		if item.WindowKey == key {
+			return item
+		}
+	}
+	return Item{}
+}
+
 func reasonOf(plan Plan, key string) string {
 	for _, item := range plan.Items {
 		if item.WindowKey == key {
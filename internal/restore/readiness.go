@@ -0,0 +1,135 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+var ErrWindowTimeout = errors.New("window did not appear")
+
+type ReadinessChecker interface {
+	Prepare(ctx context.Context)
+	WaitForWindow(ctx context.Context, item Item, pid int) (string, error)
+}
+
+type ReadinessConfig struct {
+	Windows        WindowStateReader
+	Lineage        ProcessLineage
+	DefaultTimeout time.Duration
+	AppTimeouts    map[string]time.Duration
+	PollInterval   time.Duration
+}
+
+type WindowReadiness struct {
+	windows        WindowStateReader
+	lineage        ProcessLineage
+	defaultTimeout time.Duration
+	appTimeouts    map[string]time.Duration
+	pollInterval   time.Duration
+
+	mu      sync.Mutex
+	known   map[string]struct{}
+	guessed map[string]struct{}
+}
+
+func NewWindowReadiness(config ReadinessConfig) *WindowReadiness {
+	defaultTimeout := config.DefaultTimeout
+	if defaultTimeout <= 0 {
+		defaultTimeout = 10 * time.Second
+	}
+	pollInterval := config.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 100 * time.Millisecond
+	}
+	appTimeouts := make(map[string]time.Duration, len(config.AppTimeouts))
+	for appID, timeout := range config.AppTimeouts {
+		if timeout > 0 {
+			appTimeouts[normalizeAppID(appID)] = timeout
+		}
+	}
+	return &WindowReadiness{
+		windows:        config.Windows,
+		lineage:        config.Lineage,
+		defaultTimeout: defaultTimeout,
+		appTimeouts:    appTimeouts,
+		pollInterval:   pollInterval,
+		known:          make(map[string]struct{}),
+		guessed:        make(map[string]struct{}),
+	}
+}
+
+func (r *WindowReadiness) Prepare(ctx context.Context) {
+	state, err := r.windows.ReadState(ctx)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, window := range state.Windows {
+		r.known[window.Key] = struct{}{}
+	}
+}
+
+func (r *WindowReadiness) Timeout(appID string) time.Duration {
+	if timeout, ok := r.appTimeouts[normalizeAppID(appID)]; ok {
+		return timeout
+	}
+	return r.defaultTimeout
+}
+
+func (r *WindowReadiness) WaitForWindow(ctx context.Context, item Item, pid int) (string, error) {
+	timeout := r.Timeout(item.AppID)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		state, err := r.windows.ReadState(ctx)
+		if err == nil {
+			if key, ok := r.claim(state, item.AppID, pid); ok {
+				return key, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-deadline.C:
+			return "", fmt.Errorf("%w within %s", ErrWindowTimeout, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *WindowReadiness) claim(state model.State, appID string, pid int) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	appID = normalizeAppID(appID)
+	fallback := ""
+	for _, window := range state.Windows {
+		if _, seen := r.known[window.Key]; seen {
+			continue
+		}
+		if normalizeAppID(window.AppID) != appID {
+			continue
+		}
+		if descendsFrom(window.PID, pid, r.lineage) {
+			r.known[window.Key] = struct{}{}
+			return window.Key, true
+		}
+		if _, guessed := r.guessed[window.Key]; !guessed && fallback == "" {
+			fallback = window.Key
+		}
+	}
+	if fallback == "" {
+		return "", false
+	}
+	r.guessed[fallback] = struct{}{}
+	return "", true
+}
@@ -0,0 +1,173 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestWindowReadinessWaitsForMatchingWindow(t *testing.T) {
+	t.Parallel()
+
+	windows := &scriptedWindows{states: []model.State{
+		{Windows: []model.Window{{Key: "w:kitty:1", AppID: "kitty"}}},
+		{Windows: []model.Window{{Key: "w:kitty:1", AppID: "kitty"}}},
+		{Windows: []model.Window{{Key: "w:kitty:1", AppID: "kitty"}, {Key: "w:firefox:2", AppID: "firefox"}}},
+		{Windows: []model.Window{{Key: "w:kitty:1", AppID: "kitty"}, {Key: "w:firefox:2", AppID: "firefox"}, {Key: "w:kitty:3", AppID: "kitty", PID: 501}}},
+	}}
+	readiness := NewWindowReadiness(ReadinessConfig{Windows: windows, Lineage: stubLineage{501: 500}, DefaultTimeout: time.Second, PollInterval: time.Millisecond})
+
+	readiness.Prepare(context.Background())
+	key, err := readiness.WaitForWindow(context.Background(), Item{AppID: "kitty"}, 500)
+	if err != nil || key != "w:kitty:3" {
+		t.Fatalf("expected w:kitty:3 to become ready, got %q err=%v", key, err)
+	}
+}
+
+func TestWindowReadinessTimesOutWithPerAppTimeout(t *testing.T) {
+	t.Parallel()
+
+	windows := &scriptedWindows{states: []model.State{{Windows: []model.Window{{Key: "w:kitty:1", AppID: "kitty"}}}}}
+	readiness := NewWindowReadiness(ReadinessConfig{
+		Windows:        windows,
+		DefaultTimeout: time.Hour,
+		AppTimeouts:    map[string]time.Duration{" Firefox ": 20 * time.Millisecond},
+		PollInterval:   time.Millisecond,
+	})
+
+	readiness.Prepare(context.Background())
+	_, err := readiness.WaitForWindow(context.Background(), Item{AppID: "firefox"}, 0)
+	if !errors.Is(err, ErrWindowTimeout) {
+		t.Fatalf("expected window timeout, got %v", err)
+	}
+	if readiness.Timeout("kitty") != time.Hour {
+		t.Fatalf("expected default timeout for unconfigured app, got %s", readiness.Timeout("kitty"))
+	}
+}
+
+func TestWindowReadinessDoesNotReuseClaimedWindows(t *testing.T) {
+	t.Parallel()
+
+	windows := &scriptedWindows{states: []model.State{
+		{},
+		{Windows: []model.Window{{Key: "w:kitty:1", AppID: "kitty"}}},
+	}}
+	readiness := NewWindowReadiness(ReadinessConfig{Windows: windows, DefaultTimeout: 20 * time.Millisecond, PollInterval: time.Millisecond})
+
+	readiness.Prepare(context.Background())
+	if _, err := readiness.WaitForWindow(context.Background(), Item{AppID: "kitty"}, 0); err != nil {
+		t.Fatalf("expected first wait to claim window, got %v", err)
+	}
+	if _, err := readiness.WaitForWindow(context.Background(), Item{AppID: "kitty"}, 0); !errors.Is(err, ErrWindowTimeout) {
+		t.Fatalf("expected second wait to time out, got %v", err)
+	}
+}
+
+func TestWindowReadinessLeavesWindowsWithoutLineageToReconcile(t *testing.T) {
+	t.Parallel()
+
+	windows := &scriptedWindows{states: []model.State{
+		{},
+		{Windows: []model.Window{{Key: "w:kitty:1", AppID: "kitty", PID: 601}}},
+	}}
+	readiness := NewWindowReadiness(ReadinessConfig{Windows: windows, Lineage: stubLineage{601: 600}, DefaultTimeout: 50 * time.Millisecond, PollInterval: time.Millisecond})
+
+	readiness.Prepare(context.Background())
+	key, err := readiness.WaitForWindow(context.Background(), Item{AppID: "kitty"}, 500)
+	if err != nil || key != "" {
+		t.Fatalf("expected ready without a claimed window for another launch, got %q err=%v", key, err)
+	}
+	key, err = readiness.WaitForWindow(context.Background(), Item{AppID: "kitty"}, 600)
+	if err != nil || key != "w:kitty:1" {
+		t.Fatalf("expected owning launch to claim its window, got %q err=%v", key, err)
+	}
+}
+
+func TestExecutorReportsTimeoutSeparatelyFromFailures(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", Status: StatusReady, Command: "ok"},
+		{WindowKey: "w-2", AppID: "slow", Status: StatusReady, Command: "ok-slow"},
+		{WindowKey: "w-3", AppID: "code", Status: StatusReady, Command: "fail"},
+	}}
+	readiness := &stubReadiness{timeoutApps: map[string]bool{"slow": true}, opened: map[string]string{"w-1": "w:kitty:7"}}
+	executor := NewExecutorWithReadiness(stubRunner{failCommand: "fail"}, readiness)
+	result := executor.Execute(context.Background(), plan)
+
+	if result.Summary.Restored != 1 || result.Summary.TimedOut != 1 || result.Summary.Failed != 1 {
+		t.Fatalf("unexpected summary: %+v", result.Summary)
+	}
+	if statusForResult(result.Items, "w-2") != StatusTimeout {
+		t.Fatalf("expected w-2 timeout, got %s", statusForResult(result.Items, "w-2"))
+	}
+	if statusForResult(result.Items, "w-3") != StatusFailed {
+		t.Fatalf("expected w-3 failed, got %s", statusForResult(result.Items, "w-3"))
+	}
+	if result.Items[0].OpenedWindowKey != "w:kitty:7" {
+		t.Fatalf("expected claimed window recorded on w-1, got %+v", result.Items[0])
+	}
+	if !readiness.prepared {
+		t.Fatal("expected readiness prepared before launches")
+	}
+}
+
+func TestExecutorReportsCancelledReadinessAsTimeout(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", Status: StatusReady, Command: "ok"},
+		{WindowKey: "w-2", AppID: "slow", Status: StatusReady, Command: "ok-slow"},
+		{WindowKey: "w-3", AppID: "code", Status: StatusReady, Command: "ok-code"},
+	}}
+	readiness := &stubReadiness{errApps: map[string]error{"slow": context.Canceled, "code": context.DeadlineExceeded}}
+	result := NewExecutorWithReadiness(stubRunner{}, readiness).Execute(context.Background(), plan)
+
+	if result.Summary.Restored != 1 || result.Summary.TimedOut != 2 {
+		t.Fatalf("expected cancelled waits counted as timeouts, got %+v", result.Summary)
+	}
+	for _, key := range []string{"w-2", "w-3"} {
+		if statusForResult(result.Items, key) != StatusTimeout {
+			t.Fatalf("expected %s timeout, got %s", key, statusForResult(result.Items, key))
+		}
+	}
+}
+
+type scriptedWindows struct {
+	mu     sync.Mutex
+	states []model.State
+	calls  int
+}
+
+func (s *scriptedWindows) ReadState(_ context.Context) (model.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := s.calls
+	if idx >= len(s.states) {
+		idx = len(s.states) - 1
+	}
+	s.calls++
+	return s.states[idx], nil
+}
+
+type stubReadiness struct {
+	prepared    bool
+	timeoutApps map[string]bool
+	errApps     map[string]error
+	opened      map[string]string
+}
+
+func (s *stubReadiness) Prepare(context.Context) {
+	s.prepared = true
+}
+
+func (s *stubReadiness) WaitForWindow(_ context.Context, item Item, _ int) (string, error) {
+	if s.timeoutApps[item.AppID] {
+		return "", ErrWindowTimeout
+	}
+	return s.opened[item.WindowKey], s.errApps[item.AppID]
+}
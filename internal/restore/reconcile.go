@@ -2,6 +2,7 @@ package restore
 
 import (
 	"context"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -9,11 +10,29 @@ import (
 	"github.com/jmo/terminal-redeemer/internal/model"
 )
 
+type MatchConfidence string
+
+const (
+	MatchReadiness MatchConfidence = "readiness"
+	MatchPID       MatchConfidence = "pid"
+	MatchTitle     MatchConfidence = "title"
+	MatchCWD       MatchConfidence = "cwd"
+	MatchOrder     MatchConfidence = "order"
+)
+
+const maxLineageDepth = 16
+
+type ProcessLineage interface {
+	ParentPID(pid int) (int, error)
+}
+
 type MoveRequest struct {
 	WindowKey    string
 	WindowID     int
 	AppID        string
 	WorkspaceRef string
+	ItemKey      string
+	Confidence   MatchConfidence
 }
 
 type WindowMover interface {
@@ -26,28 +45,56 @@ type MoveFailure struct {
 }
 
 type MoveReport struct {
-	Applied  int
+	Applied   int
 	Attempted int
-	Failures []MoveFailure
+	Failures  []MoveFailure
 }
 
-func BuildMoveRequests(plan Plan, before model.State, after model.State) []MoveRequest {
+func BuildMoveRequests(plan Plan, result Result, before model.State, after model.State, lineage ProcessLineage) []MoveRequest {
+	targets := make([]Item, 0, len(plan.Items))
+	for _, item := range plan.Items {
+		if !item.Launchable() {
+			continue
+		}
+		if strings.TrimSpace(item.WorkspaceID) == "" {
+			continue
+		}
+		targets = append(targets, item)
+	}
+
+	requests := matchNewWindows(targets, result, before, after, lineage)
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].WorkspaceRef != requests[j].WorkspaceRef {
+			return requests[i].WorkspaceRef < requests[j].WorkspaceRef
+		}
+		if requests[i].AppID != requests[j].AppID {
+			return requests[i].AppID < requests[j].AppID
+		}
+		return requests[i].WindowID < requests[j].WindowID
+	})
+	return requests
+}
+
+func matchNewWindows(targets []Item, result Result, before model.State, after model.State, lineage ProcessLineage) []MoveRequest {
 	beforeKeys := make(map[string]struct{}, len(before.Windows))
 	for _, window := range before.Windows {
 		beforeKeys[window.Key] = struct{}{}
 	}
 
-	readyTargetsByApp := make(map[string][]string)
-	for _, item := range plan.Items {
-		if item.Status != StatusReady {
-			continue
+	launchedPIDs := make(map[string]int, len(result.Items))
+	openedWindows := make(map[string]string, len(result.Items))
+	for _, item := range result.Items {
+		if item.Status == StatusReady && item.PID > 0 {
+			launchedPIDs[item.WindowKey] = item.PID
 		}
-		workspaceRef := strings.TrimSpace(item.WorkspaceID)
-		if workspaceRef == "" {
-			continue
+		if item.OpenedWindowKey != "" {
+			openedWindows[item.WindowKey] = item.OpenedWindowKey
 		}
-		appID := normalizeAppID(item.AppID)
-		readyTargetsByApp[appID] = append(readyTargetsByApp[appID], workspaceRef)
+	}
+
+	trackedApps := make(map[string]struct{})
+	for _, item := range targets {
+		trackedApps[normalizeAppID(item.AppID)] = struct{}{}
 	}
 
 	newWindowsByApp := make(map[string][]model.Window)
@@ -55,13 +102,15 @@ func BuildMoveRequests(plan Plan, before model.State, after model.State) []MoveR
 		if _, existed := beforeKeys[window.Key]; existed {
 			continue
 		}
+		if windowNumericID(window.Key) <= 0 {
+			continue
+		}
 		appID := normalizeAppID(window.AppID)
-		if _, tracked := readyTargetsByApp[appID]; !tracked {
+		if _, tracked := trackedApps[appID]; !tracked {
 			continue
 		}
 		newWindowsByApp[appID] = append(newWindowsByApp[appID], window)
 	}
-
 	for appID := range newWindowsByApp {
 		sort.Slice(newWindowsByApp[appID], func(i, j int) bool {
 			left := windowNumericID(newWindowsByApp[appID][i].Key)
@@ -73,34 +122,95 @@ func BuildMoveRequests(plan Plan, before model.State, after model.State) []MoveR
 		})
 	}
 
-	requests := make([]MoveRequest, 0)
-	for appID, targets := range readyTargetsByApp {
-		windows := newWindowsByApp[appID]
-		for i := 0; i < len(targets) && i < len(windows); i++ {
-			windowID := windowNumericID(windows[i].Key)
-			if windowID <= 0 {
+	m := windowMatcher{
+		targets:  targets,
+		windows:  newWindowsByApp,
+		matched:  make(map[string]bool, len(targets)),
+		claimed:  make(map[string]bool),
+		requests: make([]MoveRequest, 0, len(targets)),
+	}
+	m.pass(MatchReadiness, func(item Item, window model.Window) bool {
+		return openedWindows[item.WindowKey] == window.Key
+	})
+	m.pass(MatchPID, func(item Item, window model.Window) bool {
+		return descendsFrom(window.PID, launchedPIDs[item.WindowKey], lineage)
+	})
+	m.pass(MatchTitle, titleMatches)
+	m.pass(MatchCWD, cwdMatches)
+	m.pass(MatchOrder, func(Item, model.Window) bool { return true })
+	return m.requests
+}
+
+type windowMatcher struct {
+	targets  []Item
+	windows  map[string][]model.Window
+	matched  map[string]bool
+	claimed  map[string]bool
+	requests []MoveRequest
+}
+
+func (m *windowMatcher) pass(confidence MatchConfidence, matches func(Item, model.Window) bool) {
+	for _, item := range m.targets {
+		if m.matched[item.WindowKey] {
+			continue
+		}
+		appID := normalizeAppID(item.AppID)
+		for _, window := range m.windows[appID] {
+			if m.claimed[window.Key] || !matches(item, window) {
 				continue
 			}
-			requests = append(requests, MoveRequest{
-				WindowKey:    windows[i].Key,
-				WindowID:     windowID,
+			m.matched[item.WindowKey] = true
+			m.claimed[window.Key] = true
+			m.requests = append(m.requests, MoveRequest{
+				WindowKey:    window.Key,
+				WindowID:     windowNumericID(window.Key),
 				AppID:        appID,
-				WorkspaceRef: targets[i],
+				WorkspaceRef: strings.TrimSpace(item.WorkspaceID),
+				ItemKey:      item.WindowKey,
+				Confidence:   confidence,
 			})
+			break
 		}
 	}
+}
 
-	sort.Slice(requests, func(i, j int) bool {
-		if requests[i].WorkspaceRef != requests[j].WorkspaceRef {
-			return requests[i].WorkspaceRef < requests[j].WorkspaceRef
+func descendsFrom(pid int, ancestor int, lineage ProcessLineage) bool {
+	if pid <= 0 || ancestor <= 0 {
+		return false
+	}
+	current := pid
+	for range maxLineageDepth {
+		if current == ancestor {
+			return true
 		}
-		if requests[i].AppID != requests[j].AppID {
-			return requests[i].AppID < requests[j].AppID
+		if lineage == nil {
+			return false
 		}
-		return requests[i].WindowID < requests[j].WindowID
-	})
+		parent, err := lineage.ParentPID(current)
+		if err != nil || parent <= 1 || parent == current {
+			return false
+		}
+		current = parent
+	}
+	return false
+}
 
-	return requests
+func titleMatches(item Item, window model.Window) bool {
+	title := strings.TrimSpace(item.Title)
+	return title != "" && title == strings.TrimSpace(window.Title)
+}
+
+func cwdMatches(item Item, window model.Window) bool {
+	cwd := strings.TrimRight(strings.TrimSpace(item.CWD), "/")
+	title := strings.TrimSpace(window.Title)
+	if cwd == "" || title == "" {
+		return false
+	}
+	if strings.Contains(title, cwd) {
+		return true
+	}
+	base := filepath.Base(cwd)
+	return len(base) > 1 && strings.Contains(title, base)
 }
 
 func ApplyMoveRequests(ctx context.Context, mover WindowMover, requests []MoveRequest) MoveReport {
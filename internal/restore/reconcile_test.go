@@ -31,7 +31,7 @@ func TestBuildMoveRequestsPerWindowAndOneshot(t *testing.T) {
 		{Key: "w:kitty:31", AppID: "kitty"},
 	}}
 
-	requests := BuildMoveRequests(plan, before, after)
+	requests := BuildMoveRequests(plan, Result{}, before, after, nil)
 	if len(requests) != 3 {
 		t.Fatalf("expected 3 move requests, got %d: %#v", len(requests), requests)
 	}
@@ -55,6 +55,81 @@ func TestBuildMoveRequestsPerWindowAndOneshot(t *testing.T) {
 	}
 }
 
+func TestBuildMoveRequestsMatchesByPIDLineageBeforeOrder(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "saved-kitty-1", AppID: "kitty", WorkspaceID: "5", Status: StatusReady},
+		{WindowKey: "saved-kitty-2", AppID: "kitty", WorkspaceID: "11", Status: StatusReady},
+	}}
+	result := Result{Items: []ItemResult{
+		{WindowKey: "saved-kitty-1", Status: StatusReady, PID: 1000},
+		{WindowKey: "saved-kitty-2", Status: StatusReady, PID: 2000},
+	}}
+	after := model.State{Windows: []model.Window{
+		{Key: "w:kitty:30", AppID: "kitty", PID: 2001},
+		{Key: "w:kitty:31", AppID: "kitty", PID: 1000},
+	}}
+	lineage := stubLineage{2001: 2000}
+
+	requests := BuildMoveRequests(plan, result, model.State{}, after, lineage)
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 move requests, got %d: %#v", len(requests), requests)
+	}
+	for _, request := range requests {
+		if request.Confidence != MatchPID {
+			t.Fatalf("expected pid confidence, got %#v", request)
+		}
+		switch request.WindowID {
+		case 30:
+			if request.WorkspaceRef != "11" || request.ItemKey != "saved-kitty-2" {
+				t.Fatalf("expected window 30 matched to saved-kitty-2 on 11, got %#v", request)
+			}
+		case 31:
+			if request.WorkspaceRef != "5" || request.ItemKey != "saved-kitty-1" {
+				t.Fatalf("expected window 31 matched to saved-kitty-1 on 5, got %#v", request)
+			}
+		default:
+			t.Fatalf("unexpected request %#v", request)
+		}
+	}
+}
+
+func TestBuildMoveRequestsFallsBackToTitleAndCWDHeuristics(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "saved-kitty-1", AppID: "kitty", WorkspaceID: "2", Status: StatusReady, CWD: "/home/me/alpha"},
+		{WindowKey: "saved-kitty-2", AppID: "kitty", WorkspaceID: "3", Status: StatusReady, CWD: "/home/me/beta"},
+		{WindowKey: "saved-firefox", AppID: "firefox", WorkspaceID: "4", Status: StatusReady, Title: "Docs"},
+		{WindowKey: "saved-firefox-2", AppID: "firefox", WorkspaceID: "6", Status: StatusReady, Title: "Mail"},
+	}}
+	after := model.State{Windows: []model.Window{
+		{Key: "w:kitty:40", AppID: "kitty", Title: "~/beta"},
+		{Key: "w:kitty:41", AppID: "kitty", Title: "zsh: ~/alpha"},
+		{Key: "w:firefox:50", AppID: "firefox", Title: "New Tab"},
+		{Key: "w:firefox:51", AppID: "firefox", Title: "Docs"},
+	}}
+
+	requests := BuildMoveRequests(plan, Result{}, model.State{}, after, nil)
+	got := make(map[int]MoveRequest, len(requests))
+	for _, request := range requests {
+		got[request.WindowID] = request
+	}
+	if got[40].WorkspaceRef != "3" || got[40].Confidence != MatchCWD {
+		t.Fatalf("expected window 40 -> 3 by cwd, got %#v", got[40])
+	}
+	if got[41].WorkspaceRef != "2" || got[41].Confidence != MatchCWD {
+		t.Fatalf("expected window 41 -> 2 by cwd, got %#v", got[41])
+	}
+	if got[51].WorkspaceRef != "4" || got[51].Confidence != MatchTitle {
+		t.Fatalf("expected window 51 -> 4 by title, got %#v", got[51])
+	}
+	if got[50].WorkspaceRef != "6" || got[50].Confidence != MatchOrder {
+		t.Fatalf("expected window 50 -> 6 by order, got %#v", got[50])
+	}
+}
+
 func TestApplyMoveRequestsContinuesOnFailure(t *testing.T) {
 	t.Parallel()
 
@@ -98,3 +173,13 @@ func (s *stubWindowMover) MoveToWorkspace(_ context.Context, windowID int, _ str
 	}
 	return nil
 }
+
+type stubLineage map[int]int
+
+func (s stubLineage) ParentPID(pid int) (int, error) {
+	parent, ok := s[pid]
+	if !ok {
+		return 0, errors.New("no such process")
+	}
+	return parent, nil
+}
@@ -0,0 +1,156 @@
+package restore
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type Remap struct {
+	CWDPrefixes []CWDRemap
+	Workspaces  map[string]string
+	AppIDs      map[string]string
+}
+
+type CWDRemap struct {
+	From string
+	To   string
+}
+
+func normalizeRemap(remap Remap) Remap {
+	prefixes := make([]CWDRemap, 0, len(remap.CWDPrefixes))
+	for _, rule := range remap.CWDPrefixes {
+		from := cleanPrefix(rule.From)
+		if from == "" {
+			continue
+		}
+		prefixes = append(prefixes, CWDRemap{From: from, To: cleanPrefix(rule.To)})
+	}
+	sort.SliceStable(prefixes, func(i, j int) bool { return len(prefixes[i].From) > len(prefixes[j].From) })
+
+	workspaces := make(map[string]string, len(remap.Workspaces))
+	for from, to := range remap.Workspaces {
+		workspaces[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	appIDs := make(map[string]string, len(remap.AppIDs))
+	for from, to := range remap.AppIDs {
+		appIDs[normalizeAppID(from)] = strings.TrimSpace(to)
+	}
+	return Remap{CWDPrefixes: prefixes, Workspaces: workspaces, AppIDs: appIDs}
+}
+
+func cleanPrefix(prefix string) string {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return ""
+	}
+	return path.Clean(prefix)
+}
+
+func (p *Planner) remapWindow(window model.Window) (model.Window, []string) {
+	var notes []string
+	remap := p.config.Remap
+
+	if to, ok := remap.Workspaces[strings.TrimSpace(window.WorkspaceID)]; ok && to != "" && to != window.WorkspaceID {
+		notes = append(notes, fmt.Sprintf("workspace %s -> %s", window.WorkspaceID, to))
+		window.WorkspaceID = to
+	}
+	if to, ok := remap.AppIDs[normalizeAppID(window.AppID)]; ok && to != "" && to != window.AppID {
+		notes = append(notes, fmt.Sprintf("app_id %s -> %s", window.AppID, to))
+		window.AppID = to
+	}
+	if window.Terminal == nil || len(remap.CWDPrefixes) == 0 {
+		return window, notes
+	}
+
+	terminal := *window.Terminal
+	seen := map[string]bool{}
+	rewrite := func(cwd string) string {
+		mapped, ok := p.remapCWD(cwd)
+		if !ok {
+			return cwd
+		}
+		if !seen[cwd] {
+			seen[cwd] = true
+			notes = append(notes, fmt.Sprintf("cwd %s -> %s", cwd, mapped))
+		}
+		return mapped
+	}
+	rewritePath := func(name string) string {
+		mapped, ok := p.remapCWD(name)
+		if !ok || !path.IsAbs(name) {
+			return name
+		}
+		if !seen[name] {
+			seen[name] = true
+			notes = append(notes, fmt.Sprintf("path %s -> %s", name, mapped))
+		}
+		return mapped
+	}
+	terminal.CWD = rewrite(terminal.CWD)
+	if len(terminal.ProcessArgs) > 0 {
+		terminal.ProcessArgs = make(map[string][]string, len(window.Terminal.ProcessArgs))
+		for tag, argv := range window.Terminal.ProcessArgs {
+			mapped := make([]string, len(argv))
+			for i, arg := range argv {
+				mapped[i] = rewritePath(arg)
+			}
+			terminal.ProcessArgs[tag] = mapped
+		}
+	}
+	if len(terminal.Tabs) > 0 {
+		terminal.Tabs = make([]model.Tab, len(window.Terminal.Tabs))
+		for i, tab := range window.Terminal.Tabs {
+			tab.Panes = append([]model.Pane(nil), tab.Panes...)
+			for j := range tab.Panes {
+				tab.Panes[j].CWD = rewrite(tab.Panes[j].CWD)
+			}
+			terminal.Tabs[i] = tab
+		}
+	}
+	if terminal.Nvim != nil {
+		nvim := *terminal.Nvim
+		nvim.CWD = rewrite(nvim.CWD)
+		nvim.Session = rewritePath(nvim.Session)
+		if len(nvim.Buffers) > 0 {
+			nvim.Buffers = make([]string, len(window.Terminal.Nvim.Buffers))
+			for i, buffer := range window.Terminal.Nvim.Buffers {
+				nvim.Buffers[i] = rewritePath(buffer)
+			}
+		}
+		terminal.Nvim = &nvim
+	}
+	if terminal.Git != nil {
+		git := *terminal.Git
+		if root, ok := p.remapCWD(git.Root); ok {
+			git.Root = root
+		}
+		if worktree, ok := p.remapCWD(git.Worktree); ok {
+			git.Worktree = worktree
+		}
+		terminal.Git = &git
+	}
+	window.Terminal = &terminal
+	return window, notes
+}
+
+func (p *Planner) remapCWD(cwd string) (string, bool) {
+	if strings.TrimSpace(cwd) == "" {
+		return cwd, false
+	}
+	for _, rule := range p.config.Remap.CWDPrefixes {
+		if cwd == rule.From {
+			return rule.To, true
+		}
+		if rule.From == "/" {
+			return path.Join(rule.To, cwd), true
+		}
+		if rest, ok := strings.CutPrefix(cwd, rule.From+"/"); ok {
+			return path.Join(rule.To, rest), true
+		}
+	}
+	return cwd, false
+}
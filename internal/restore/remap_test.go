@@ -0,0 +1,141 @@
+package restore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestPlannerRemapsCWDWorkspaceAndAppID(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{
+		Workspaces: []model.Workspace{{ID: "ws-1", Index: 1, Name: "dev"}, {ID: "ws-2", Index: 4}},
+		Windows: []model.Window{
+			{Key: "w-term", AppID: "kitty", WorkspaceID: "ws-1", Terminal: &model.Terminal{
+				CWD:  "/home/desk/src/app",
+				Nvim: &model.Nvim{CWD: "/home/desk/src/app", Buffers: []string{"main.go"}},
+			}},
+			{Key: "w-files", AppID: "org.gnome.Nautilus", WorkspaceID: "ws-2"},
+			{Key: "w-other", AppID: "kitty", WorkspaceID: "ws-2", Terminal: &model.Terminal{CWD: "/srv/data", SessionTag: "data"}},
+		},
+	}
+	planner := NewPlanner(PlannerConfig{
+		AppAllowlist: map[string]string{"thunar": "thunar"},
+		Terminal:     TerminalConfig{Command: "kitty", RestoreNvim: true},
+		Remap: Remap{
+			CWDPrefixes: []CWDRemap{{From: "/home/desk", To: "/home/laptop"}, {From: "/home/desk/src", To: "/work"}},
+			Workspaces:  map[string]string{"dev": "code", "4": "2"},
+			AppIDs:      map[string]string{"org.gnome.nautilus": "thunar"},
+		},
+		FS: stubFS{"/work/app", "/srv/data"},
+	})
+
+	plan := planner.Build(state)
+	term := itemByKey(t, plan, "w-term")
+	if term.Status != StatusReady || term.WorkspaceID != "code" || term.CWD != "/work/app" {
+		t.Fatalf("unexpected remapped terminal item %+v", term)
+	}
+	if !strings.Contains(term.Command, "/work/app") || strings.Contains(term.Command, "/home/desk") {
+		t.Fatalf("expected command to use the remapped cwd, got %q", term.Command)
+	}
+	if strings.Join(term.Remapped, "; ") != "workspace dev -> code; cwd /home/desk/src/app -> /work/app" {
+		t.Fatalf("unexpected remap notes %q", term.Remapped)
+	}
+
+	files := itemByKey(t, plan, "w-files")
+	if files.Status != StatusReady || files.AppID != "thunar" || files.Command != "thunar" || files.WorkspaceID != "2" {
+		t.Fatalf("unexpected remapped app item %+v", files)
+	}
+	if strings.Join(files.Remapped, "; ") != "workspace 4 -> 2; app_id org.gnome.Nautilus -> thunar" {
+		t.Fatalf("unexpected remap notes %q", files.Remapped)
+	}
+
+	other := itemByKey(t, plan, "w-other")
+	if len(other.Remapped) != 1 || other.CWD != "/srv/data" {
+		t.Fatalf("expected only the workspace remap for w-other, got %+v", other)
+	}
+}
+
+func TestPlannerFallsBackWhenRemappedCWDIsMissing(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-1", AppID: "kitty", Terminal: &model.Terminal{CWD: "/home/desk/gone", SessionTag: "main"}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", ZellijAttachOrCreate: true},
+		Remap:    Remap{CWDPrefixes: []CWDRemap{{From: "/home/desk/", To: "/home/laptop"}}},
+		FS:       stubFS{"/home/laptop"},
+	})
+
+	item := itemByKey(t, planner.Build(state), "w-1")
+	if item.Status != StatusDegraded || item.Reason != "remapped cwd /home/laptop/gone does not exist, using parent /home/laptop" || !item.Launchable() {
+		t.Fatalf("expected launchable degraded item in the remapped parent, got %+v", item)
+	}
+	if !strings.Contains(item.Command, `'/home/laptop'`) || len(item.Remapped) != 1 {
+		t.Fatalf("expected command in the parent and the remap note kept, got %+v", item)
+	}
+}
+
+func TestPlannerRemapsNvimPathsAndProcessArgs(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-nvim", AppID: "kitty", Terminal: &model.Terminal{
+			CWD:  "/home/desk/src/app",
+			Nvim: &model.Nvim{CWD: "/home/desk/src/app", Buffers: []string{"/home/desk/notes.md"}},
+		}},
+		{Key: "w-session", AppID: "kitty", Terminal: &model.Terminal{
+			CWD:  "/home/desk/src/app",
+			Nvim: &model.Nvim{CWD: "/home/desk/src/app", Session: "/home/desk/src/app/Session.vim"},
+		}},
+		{Key: "w-btop", AppID: "kitty", Terminal: &model.Terminal{
+			CWD:         "/home/desk/src/app",
+			ProcessTags: []string{"btop"},
+			ProcessArgs: map[string][]string{"btop": {"btop", "--config", "/home/desk/.config/btop.conf"}},
+		}},
+	}}
+	planner := NewPlanner(PlannerConfig{
+		Terminal: TerminalConfig{Command: "kitty", RestoreNvim: true},
+		Relaunch: map[string]string{"btop": ""},
+		Remap:    Remap{CWDPrefixes: []CWDRemap{{From: "/home/desk", To: "/home/laptop"}}},
+		FS:       stubFS{"/home/laptop/src/app", "/home/laptop/notes.md", "/home/laptop/src/app/Session.vim"},
+	})
+
+	plan := planner.Build(state)
+	for _, key := range []string{"w-nvim", "w-session", "w-btop"} {
+		if item := itemByKey(t, plan, key); strings.Contains(item.Command, "/home/desk") {
+			t.Fatalf("expected %s command to use remapped paths, got %q", key, item.Command)
+		}
+	}
+	if item := itemByKey(t, plan, "w-nvim"); !strings.Contains(item.Command, shellQuote("/home/laptop/notes.md")) {
+		t.Fatalf("expected remapped buffer, got %q", item.Command)
+	}
+	if item := itemByKey(t, plan, "w-session"); !strings.Contains(item.Command, shellQuote("/home/laptop/src/app/Session.vim")) {
+		t.Fatalf("expected remapped session file, got %q", item.Command)
+	}
+	btop := itemByKey(t, plan, "w-btop")
+	if !strings.Contains(btop.Command, "/home/laptop/.config/btop.conf") {
+		t.Fatalf("expected remapped process argument, got %q", btop.Command)
+	}
+	if strings.Join(btop.Remapped, "; ") != "cwd /home/desk/src/app -> /home/laptop/src/app; path /home/desk/.config/btop.conf -> /home/laptop/.config/btop.conf" {
+		t.Fatalf("unexpected remap notes %q", btop.Remapped)
+	}
+	if state.Windows[2].Terminal.ProcessArgs["btop"][2] != "/home/desk/.config/btop.conf" {
+		t.Fatal("expected captured process args left untouched")
+	}
+}
+
+func itemByKey(t *testing.T, plan Plan, key string) Item {
+	t.Helper()
+
+	for _, item := range plan.Items {
+		if item.WindowKey == key {
+			return item
+		}
+	}
+	t.Fatalf("no plan item for %s", key)
+	return Item{}
+}
@@ -11,14 +11,20 @@ type ShellRunner struct {
 }
 
 func (r ShellRunner) Run(ctx context.Context, command string) error {
+	_, err := r.run(ctx, command)
+	return err
+}
+
+func (r ShellRunner) RunWithPID(ctx context.Context, command string) (int, error) {
 	return r.run(ctx, command)
 }
 
-func (r ShellRunner) run(ctx context.Context, command string) error {
+func (r ShellRunner) run(ctx context.Context, command string) (int, error) {
 	cmd := exec.CommandContext(ctx, "sh", "-lc", command)
 	if err := cmd.Start(); err != nil {
-		return err
+		return 0, err
 	}
+	pid := cmd.Process.Pid
 
 	done := make(chan error, 1)
 	go func() {
@@ -32,14 +38,14 @@ func (r ShellRunner) run(ctx context.Context, command string) error {
 
 	select {
 	case err := <-done:
-		return err
+		return pid, err
 	case <-time.After(startupCheck):
-		return nil
+		return pid, nil
 	case <-ctx.Done():
 		if cmd.Process != nil {
 			_ = cmd.Process.Kill()
 		}
 		<-done
-		return ctx.Err()
+		return pid, ctx.Err()
 	}
 }
@@ -30,3 +30,16 @@ func TestShellRunnerReturnsErrorForImmediateFailure(t *testing.T) {
 		t.Fatal("expected launch failure error")
 	}
 }
+
+func TestShellRunnerReportsLaunchedPID(t *testing.T) {
+	t.Parallel()
+
+	runner := ShellRunner{StartupCheck: 20 * time.Millisecond}
+	pid, err := runner.RunWithPID(context.Background(), "sleep 0.1")
+	if err != nil {
+		t.Fatalf("run long-lived command: %v", err)
+	}
+	if pid <= 0 {
+		t.Fatalf("expected launched pid, got %d", pid)
+	}
+}
@@ -0,0 +1,224 @@
+package restore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type ReconcileStrategy string
+
+const (
+	ReconcileMove  ReconcileStrategy = "move"
+	ReconcileSpawn ReconcileStrategy = "spawn"
+)
+
+func ParseReconcileStrategy(raw string) ReconcileStrategy {
+	if ReconcileStrategy(strings.ToLower(strings.TrimSpace(raw))) == ReconcileSpawn {
+		return ReconcileSpawn
+	}
+	return ReconcileMove
+}
+
+type WorkspaceFocuser interface {
+	FocusWorkspace(ctx context.Context, workspaceRef string) error
+}
+
+type WindowStateReader interface {
+	ReadState(ctx context.Context) (model.State, error)
+}
+
+type SpawnConfig struct {
+	Focuser      WorkspaceFocuser
+	Windows      WindowStateReader
+	Mover        WindowMover
+	Lineage      ProcessLineage
+	WindowWait   time.Duration
+	PollInterval time.Duration
+}
+
+type WorkspaceSpawner struct {
+	executor     *Executor
+	focuser      WorkspaceFocuser
+	windows      WindowStateReader
+	mover        WindowMover
+	lineage      ProcessLineage
+	windowWait   time.Duration
+	pollInterval time.Duration
+}
+
+type FocusFailure struct {
+	WorkspaceRef string
+	Err          error
+}
+
+type SpawnReport struct {
+	Workspaces     int
+	FocusFailures  []FocusFailure
+	WindowTimeouts []string
+	Moves          []MoveRequest
+	MoveReport     MoveReport
+}
+
+type timedOutGroup struct {
+	items  []Item
+	before model.State
+}
+
+func NewWorkspaceSpawner(executor *Executor, config SpawnConfig) *WorkspaceSpawner {
+	windowWait := config.WindowWait
+	if windowWait <= 0 {
+		windowWait = 5 * time.Second
+	}
+	pollInterval := config.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 100 * time.Millisecond
+	}
+	return &WorkspaceSpawner{
+		executor:     executor,
+		focuser:      config.Focuser,
+		windows:      config.Windows,
+		mover:        config.Mover,
+		lineage:      config.Lineage,
+		windowWait:   windowWait,
+		pollInterval: pollInterval,
+	}
+}
+
+func (s *WorkspaceSpawner) Execute(ctx context.Context, plan Plan) (Result, SpawnReport) {
+	report := SpawnReport{}
+	order := make([]string, 0)
+	groups := make(map[string][]Item)
+	for _, item := range plan.Items {
+		if !item.Launchable() {
+			continue
+		}
+		ref := strings.TrimSpace(item.WorkspaceID)
+		if _, seen := groups[ref]; !seen {
+			order = append(order, ref)
+		}
+		groups[ref] = append(groups[ref], item)
+	}
+
+	resultsByKey := make(map[string]ItemResult, len(plan.Items))
+	var timedOut []timedOutGroup
+	for _, ref := range order {
+		items := groups[ref]
+		if ref != "" && s.focuser != nil {
+			report.Workspaces++
+			if err := s.focuser.FocusWorkspace(ctx, ref); err != nil {
+				report.FocusFailures = append(report.FocusFailures, FocusFailure{WorkspaceRef: ref, Err: err})
+			}
+		}
+
+		before := s.readState(ctx)
+		group := s.executor.Execute(ctx, Plan{Items: items})
+		for _, result := range group.Items {
+			resultsByKey[result.WindowKey] = result
+		}
+		if before != nil && group.Summary.Restored > 0 {
+			if !s.waitForWindows(ctx, *before, items, group) {
+				report.WindowTimeouts = append(report.WindowTimeouts, ref)
+				timedOut = append(timedOut, timedOutGroup{items: items, before: *before})
+			}
+		}
+	}
+
+	items := make([]ItemResult, 0, len(plan.Items))
+	for _, item := range plan.Items {
+		itemResult, launched := resultsByKey[item.WindowKey]
+		if !launched {
+			itemResult = ItemResult{WindowKey: item.WindowKey, Status: item.Status, Reason: item.Reason}
+		}
+		items = append(items, itemResult)
+	}
+	result := Result{Summary: summarizeResults(items), Items: items}
+	report.Moves, report.MoveReport = s.reconcileTimedOut(ctx, timedOut, result)
+	return result, report
+}
+
+func (s *WorkspaceSpawner) reconcileTimedOut(ctx context.Context, groups []timedOutGroup, result Result) ([]MoveRequest, MoveReport) {
+	if len(groups) == 0 || s.mover == nil || ctx.Err() != nil {
+		return nil, MoveReport{}
+	}
+	after := s.readState(ctx)
+	if after == nil {
+		return nil, MoveReport{}
+	}
+	var requests []MoveRequest
+	claimed := make(map[string]bool)
+	for _, group := range groups {
+		for _, request := range BuildMoveRequests(Plan{Items: group.items}, result, group.before, *after, s.lineage) {
+			if claimed[request.WindowKey] {
+				continue
+			}
+			claimed[request.WindowKey] = true
+			requests = append(requests, request)
+		}
+	}
+	return requests, ApplyMoveRequests(ctx, s.mover, requests)
+}
+
+func (s *WorkspaceSpawner) readState(ctx context.Context) *model.State {
+	if s.windows == nil {
+		return nil
+	}
+	state, err := s.windows.ReadState(ctx)
+	if err != nil {
+		return nil
+	}
+	return &state
+}
+
+func (s *WorkspaceSpawner) waitForWindows(ctx context.Context, before model.State, items []Item, group Result) bool {
+	known := make(map[string]struct{}, len(before.Windows))
+	for _, window := range before.Windows {
+		known[window.Key] = struct{}{}
+	}
+	appOf := make(map[string]string, len(items))
+	for _, item := range items {
+		appOf[item.WindowKey] = normalizeAppID(item.AppID)
+	}
+	expected := make(map[string]int)
+	for _, result := range group.Items {
+		if result.Status == StatusReady {
+			expected[appOf[result.WindowKey]]++
+		}
+	}
+
+	deadline := time.NewTimer(s.windowWait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	for {
+		if current := s.readState(ctx); current != nil {
+			opened := make(map[string]int, len(expected))
+			for _, window := range current.Windows {
+				if _, existed := known[window.Key]; !existed {
+					opened[normalizeAppID(window.AppID)]++
+				}
+			}
+			if allOpened(expected, opened) {
+				return true
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline.C:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+func allOpened(expected map[string]int, opened map[string]int) bool {
+	for appID, count := range expected {
+		if opened[appID] < count {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/hooks"
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestWorkspaceSpawnerFocusesEachWorkspaceBeforeLaunching(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{opens: map[string][]string{"launch-a": {"kitty"}, "launch-b": {"firefox"}, "launch-c": {"kitty"}}}
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", WorkspaceID: "2", Status: StatusReady, Command: "launch-a"},
+		{WindowKey: "w-2", AppID: "firefox", WorkspaceID: "5", Status: StatusReady, Command: "launch-b"},
+		{WindowKey: "w-3", AppID: "code", WorkspaceID: "2", Status: StatusSkipped, Reason: "app not allowlisted"},
+		{WindowKey: "w-4", AppID: "kitty", WorkspaceID: "2", Status: StatusReady, Command: "launch-c"},
+	}}
+
+	recorder := &recordingHooks{}
+	executor := NewExecutorWithConfig(session, ExecutorConfig{Hooks: recorder})
+	spawner := NewWorkspaceSpawner(executor, SpawnConfig{Focuser: session, Windows: session, WindowWait: time.Second, PollInterval: time.Millisecond})
+	result, report := spawner.Execute(context.Background(), plan)
+
+	runAfter := 0
+	for _, name := range recorder.names() {
+		if name == hooks.RestoreRunAfter {
+			runAfter++
+		}
+	}
+	if runAfter != 0 {
+		t.Fatalf("expected run_after left to the caller, got %d", runAfter)
+	}
+
+	want := []string{"focus 2", "run launch-a", "run launch-c", "focus 5", "run launch-b"}
+	if !reflect.DeepEqual(session.log, want) {
+		t.Fatalf("unexpected action order: %#v", session.log)
+	}
+	if report.Workspaces != 2 || len(report.FocusFailures) != 0 || len(report.WindowTimeouts) != 0 {
+		t.Fatalf("unexpected spawn report: %+v", report)
+	}
+	if result.Summary.Restored != 3 || result.Summary.Skipped != 1 || result.Summary.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", result.Summary)
+	}
+	keys := make([]string, 0, len(result.Items))
+	for _, item := range result.Items {
+		keys = append(keys, item.WindowKey)
+	}
+	if !reflect.DeepEqual(keys, []string{"w-1", "w-2", "w-3", "w-4"}) {
+		t.Fatalf("expected results in plan order, got %#v", keys)
+	}
+}
+
+func TestWorkspaceSpawnerReportsFocusFailureAndWindowTimeout(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{focusErr: errors.New("no such workspace")}
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", WorkspaceID: "9", Status: StatusReady, Command: "launch-a"},
+	}}
+
+	spawner := NewWorkspaceSpawner(NewExecutor(session), SpawnConfig{Focuser: session, Windows: session, WindowWait: 20 * time.Millisecond, PollInterval: time.Millisecond})
+	result, report := spawner.Execute(context.Background(), plan)
+
+	if len(report.FocusFailures) != 1 || report.FocusFailures[0].WorkspaceRef != "9" {
+		t.Fatalf("expected focus failure for workspace 9, got %+v", report.FocusFailures)
+	}
+	if len(report.WindowTimeouts) != 1 || report.WindowTimeouts[0] != "9" {
+		t.Fatalf("expected window timeout for workspace 9, got %+v", report.WindowTimeouts)
+	}
+	if result.Summary.Restored != 1 {
+		t.Fatalf("expected launch to still count as restored, got %+v", result.Summary)
+	}
+}
+
+func TestWorkspaceSpawnerCountsOnlyGroupAppsAndMovesLateWindows(t *testing.T) {
+	t.Parallel()
+
+	session := &fakeSession{opens: map[string][]string{
+		"launch-kitty":   {"slack"},
+		"launch-firefox": {"firefox", "kitty"},
+	}}
+	plan := Plan{Items: []Item{
+		{WindowKey: "w-1", AppID: "kitty", WorkspaceID: "2", Status: StatusReady, Command: "launch-kitty"},
+		{WindowKey: "w-2", AppID: "firefox", WorkspaceID: "5", Status: StatusReady, Command: "launch-firefox"},
+	}}
+
+	spawner := NewWorkspaceSpawner(NewExecutor(session), SpawnConfig{Focuser: session, Windows: session, Mover: session, WindowWait: 20 * time.Millisecond, PollInterval: time.Millisecond})
+	_, report := spawner.Execute(context.Background(), plan)
+
+	if !reflect.DeepEqual(report.WindowTimeouts, []string{"2"}) {
+		t.Fatalf("expected an unrelated app's window not to satisfy workspace 2, got %+v", report.WindowTimeouts)
+	}
+	if len(report.Moves) != 1 || report.Moves[0].WindowKey != "w:kitty:3" || report.Moves[0].ItemKey != "w-1" || report.Moves[0].WorkspaceRef != "2" || report.MoveReport.Applied != 1 {
+		t.Fatalf("expected the late kitty window moved back to workspace 2, got %+v / %+v", report.Moves, report.MoveReport)
+	}
+	want := []string{"focus 2", "run launch-kitty", "focus 5", "run launch-firefox", "move 3 2"}
+	if !reflect.DeepEqual(session.log, want) {
+		t.Fatalf("unexpected action order: %#v", session.log)
+	}
+}
+
+func TestParseReconcileStrategy(t *testing.T) {
+	t.Parallel()
+
+	if ParseReconcileStrategy(" Spawn ") != ReconcileSpawn {
+		t.Fatal("expected spawn strategy")
+	}
+	if ParseReconcileStrategy("") != ReconcileMove || ParseReconcileStrategy("bogus") != ReconcileMove {
+		t.Fatal("expected move strategy fallback")
+	}
+}
+
+type fakeSession struct {
+	mu       sync.Mutex
+	log      []string
+	windows  []model.Window
+	opens    map[string][]string
+	focusErr error
+}
+
+func (f *fakeSession) FocusWorkspace(_ context.Context, workspaceRef string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, "focus "+workspaceRef)
+	return f.focusErr
+}
+
+func (f *fakeSession) Run(_ context.Context, command string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, "run "+command)
+	for _, appID := range f.opens[command] {
+		f.windows = append(f.windows, model.Window{Key: fmt.Sprintf("w:%s:%d", appID, len(f.windows)+1), AppID: appID})
+	}
+	return nil
+}
+
+func (f *fakeSession) MoveToWorkspace(_ context.Context, windowID int, workspaceRef string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = append(f.log, fmt.Sprintf("move %d %s", windowID, workspaceRef))
+	return nil
+}
+
+func (f *fakeSession) ReadState(_ context.Context) (model.State, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return model.State{Windows: append([]model.Window(nil), f.windows...)}, nil
+}
@@ -0,0 +1,166 @@
+package restore
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+type TerminalKind string
+
+const (
+	TerminalKitty     TerminalKind = "kitty"
+	TerminalAlacritty TerminalKind = "alacritty"
+	TerminalFoot      TerminalKind = "foot"
+	TerminalWezTerm   TerminalKind = "wezterm"
+	TerminalGhostty   TerminalKind = "ghostty"
+)
+
+type TerminalLaunch struct {
+	CWD     string
+	Exec    string
+	Title   string
+	Class   string
+	Session string
+}
+
+type TerminalLauncher interface {
+	Kind() TerminalKind
+	Binary() string
+	AppID() string
+	Command(binary string, launch TerminalLaunch) string
+}
+
+var terminalLaunchers = map[TerminalKind]TerminalLauncher{
+	TerminalKitty:     kittyLauncher{},
+	TerminalAlacritty: alacrittyLauncher{},
+	TerminalFoot:      footLauncher{},
+	TerminalWezTerm:   wezTermLauncher{},
+	TerminalGhostty:   ghosttyLauncher{},
+}
+
+func LauncherForKind(kind TerminalKind) (TerminalLauncher, bool) {
+	launcher, ok := terminalLaunchers[TerminalKind(strings.ToLower(strings.TrimSpace(string(kind))))]
+	return launcher, ok
+}
+
+func launcherForCommand(command string) (TerminalLauncher, bool) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	return LauncherForKind(TerminalKind(filepath.Base(fields[0])))
+}
+
+type kittyLauncher struct{}
+
+func (kittyLauncher) Kind() TerminalKind { return TerminalKitty }
+func (kittyLauncher) Binary() string     { return "kitty" }
+func (kittyLauncher) AppID() string      { return "kitty" }
+
+func (kittyLauncher) Command(binary string, launch TerminalLaunch) string {
+	parts := []string{binary}
+	if launch.Class != "" {
+		parts = append(parts, "--class "+shellQuote(launch.Class))
+	}
+	if launch.Title != "" {
+		parts = append(parts, "--title "+shellQuote(launch.Title))
+	}
+	if launch.CWD != "" {
+		parts = append(parts, "--directory "+shellQuote(launch.CWD))
+	}
+	if launch.Session != "" {
+		parts = append(parts, "--session "+shellQuote(launch.Session))
+	} else if launch.Exec != "" {
+		parts = append(parts, "-e sh -lc "+shellQuote(launch.Exec))
+	}
+	return strings.Join(parts, " ")
+}
+
+type alacrittyLauncher struct{}
+
+func (alacrittyLauncher) Kind() TerminalKind { return TerminalAlacritty }
+func (alacrittyLauncher) Binary() string     { return "alacritty" }
+func (alacrittyLauncher) AppID() string      { return "alacritty" }
+
+func (alacrittyLauncher) Command(binary string, launch TerminalLaunch) string {
+	parts := []string{binary}
+	if launch.Class != "" {
+		parts = append(parts, "--class "+shellQuote(launch.Class))
+	}
+	if launch.Title != "" {
+		parts = append(parts, "--title "+shellQuote(launch.Title))
+	}
+	if launch.CWD != "" {
+		parts = append(parts, "--working-directory "+shellQuote(launch.CWD))
+	}
+	if launch.Exec != "" {
+		parts = append(parts, "-e sh -lc "+shellQuote(launch.Exec))
+	}
+	return strings.Join(parts, " ")
+}
+
+type footLauncher struct{}
+
+func (footLauncher) Kind() TerminalKind { return TerminalFoot }
+func (footLauncher) Binary() string     { return "foot" }
+func (footLauncher) AppID() string      { return "foot" }
+
+func (footLauncher) Command(binary string, launch TerminalLaunch) string {
+	parts := []string{binary}
+	if launch.Class != "" {
+		parts = append(parts, "--app-id "+shellQuote(launch.Class))
+	}
+	if launch.Title != "" {
+		parts = append(parts, "--title "+shellQuote(launch.Title))
+	}
+	if launch.CWD != "" {
+		parts = append(parts, "-D "+shellQuote(launch.CWD))
+	}
+	if launch.Exec != "" {
+		parts = append(parts, "sh -lc "+shellQuote(launch.Exec))
+	}
+	return strings.Join(parts, " ")
+}
+
+type wezTermLauncher struct{}
+
+func (wezTermLauncher) Kind() TerminalKind { return TerminalWezTerm }
+func (wezTermLauncher) Binary() string     { return "wezterm" }
+func (wezTermLauncher) AppID() string      { return "org.wezfurlong.wezterm" }
+
+func (wezTermLauncher) Command(binary string, launch TerminalLaunch) string {
+	parts := []string{binary, "start"}
+	if launch.Class != "" {
+		parts = append(parts, "--class "+shellQuote(launch.Class))
+	}
+	if launch.CWD != "" {
+		parts = append(parts, "--cwd "+shellQuote(launch.CWD))
+	}
+	if launch.Exec != "" {
+		parts = append(parts, "-- sh -lc "+shellQuote(launch.Exec))
+	}
+	return strings.Join(parts, " ")
+}
+
+type ghosttyLauncher struct{}
+
+func (ghosttyLauncher) Kind() TerminalKind { return TerminalGhostty }
+func (ghosttyLauncher) Binary() string     { return "ghostty" }
+func (ghosttyLauncher) AppID() string      { return "com.mitchellh.ghostty" }
+
+func (ghosttyLauncher) Command(binary string, launch TerminalLaunch) string {
+	parts := []string{binary}
+	if launch.Class != "" {
+		parts = append(parts, "--class="+shellQuote(launch.Class))
+	}
+	if launch.Title != "" {
+		parts = append(parts, "--title="+shellQuote(launch.Title))
+	}
+	if launch.CWD != "" {
+		parts = append(parts, "--working-directory="+shellQuote(launch.CWD))
+	}
+	if launch.Exec != "" {
+		parts = append(parts, "-e sh -lc "+shellQuote(launch.Exec))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,89 @@
+package restore
+
+import (
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
+)
+
+func TestTerminalLaunchersBuildEmulatorSpecificCommands(t *testing.T) {
+	t.Parallel()
+
+	launch := TerminalLaunch{CWD: "/tmp/project", Exec: "zellij attach --create x", Title: "work", Class: "scratch"}
+	tests := []struct {
+		kind TerminalKind
+		want string
+	}{
+		{kind: TerminalKitty, want: `kitty --class 'scratch' --title 'work' --directory '/tmp/project' -e sh -lc 'zellij attach --create x'`},
+		{kind: TerminalAlacritty, want: `alacritty --class 'scratch' --title 'work' --working-directory '/tmp/project' -e sh -lc 'zellij attach --create x'`},
+		{kind: TerminalFoot, want: `foot --app-id 'scratch' --title 'work' -D '/tmp/project' sh -lc 'zellij attach --create x'`},
+		{kind: TerminalWezTerm, want: `wezterm start --class 'scratch' --cwd '/tmp/project' -- sh -lc 'zellij attach --create x'`},
+		{kind: TerminalGhostty, want: `ghostty --class='scratch' --title='work' --working-directory='/tmp/project' -e sh -lc 'zellij attach --create x'`},
+	}
+
+	for _, tc := range tests {
+		launcher, ok := LauncherForKind(tc.kind)
+		if !ok {
+			t.Fatalf("expected launcher for %s", tc.kind)
+		}
+		if got := launcher.Command(launcher.Binary(), launch); got != tc.want {
+			t.Fatalf("unexpected %s command:\n got %q\nwant %q", tc.kind, got, tc.want)
+		}
+	}
+}
+
+func TestPlannerLaunchesTerminalMatchingWindowAppID(t *testing.T) {
+	t.Parallel()
+
+	state := model.State{Windows: []model.Window{
+		{Key: "w-foot", AppID: "foot", Terminal: &model.Terminal{CWD: "/tmp/a"}},
+		{Key: "w-kitty", AppID: "kitty", Terminal: &model.Terminal{CWD: "/tmp/b"}},
+	}}
+	planner := NewPlanner(PlannerConfig{Terminal: TerminalConfig{Command: "/run/current-system/sw/bin/kitty"}, FS: existingDirs{}})
+	plan := planner.Build(state)
+
+	if got := commandOf(plan, "w-foot"); got != `foot -D '/tmp/a'` {
+		t.Fatalf("unexpected foot command %q", got)
+	}
+	if got := commandOf(plan, "w-kitty"); got != `/run/current-system/sw/bin/kitty --directory '/tmp/b'` {
+		t.Fatalf("expected configured kitty binary to be kept, got %q", got)
+	}
+}
+
+func TestPlannerUsesConfiguredTerminalKinds(t *testing.T) {
+	t.Parallel()
+
+	matcher, err := terminals.NewMatcher([]terminals.Rule{{Pattern: "kitty-*", Kind: "kitty"}, {Pattern: "*konsole", Kind: "konsole"}})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+	state := model.State{Windows: []model.Window{
+		{Key: "w-scratch", AppID: "kitty-scratch", Terminal: &model.Terminal{CWD: "/tmp/a"}},
+		{Key: "w-ghostty", AppID: "com.mitchellh.ghostty", Terminal: &model.Terminal{CWD: "/tmp/b"}},
+		{Key: "w-konsole", AppID: "org.kde.konsole", Terminal: &model.Terminal{CWD: "/tmp/c"}},
+		{Key: "w-konsole-bare", AppID: "org.kde.konsole"},
+	}}
+	plan := NewPlanner(PlannerConfig{Terminals: matcher, FS: existingDirs{}}).Build(state)
+
+	if got := commandOf(plan, "w-scratch"); got != `kitty --class 'kitty-scratch' --directory '/tmp/a'` {
+		t.Fatalf("unexpected custom kitty command %q", got)
+	}
+	if got := commandOf(plan, "w-ghostty"); got != `ghostty --working-directory='/tmp/b'` {
+		t.Fatalf("unexpected ghostty command %q", got)
+	}
+	for _, key := range []string{"w-konsole", "w-konsole-bare"} {
+		if item := itemOf(plan, key); item.Status != StatusDegraded || item.Reason != "no launcher for kind konsole" || item.Launchable() {
+			t.Fatalf("expected capture-only terminal kind to be degraded, got %+v", item)
+		}
+	}
+}
+
+func commandOf(plan Plan, key string) string {
+	for _, item := range plan.Items {
+		if item.WindowKey == key {
+			return item.Command
+		}
+	}
+	return ""
+}
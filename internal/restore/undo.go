@@ -0,0 +1,87 @@
+package restore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type WindowCloser interface {
+	CloseWindow(ctx context.Context, windowID int) error
+}
+
+type CreatedWindow struct {
+	Key      string
+	WindowID int
+	AppID    string
+}
+
+type CloseFailure struct {
+	Window CreatedWindow
+	Err    error
+}
+
+type CloseReport struct {
+	Attempted int
+	Closed    int
+	Missing   int
+	Failures  []CloseFailure
+}
+
+func LaunchedWindows(plan Plan, result Result, before model.State, after model.State, lineage ProcessLineage) []CreatedWindow {
+	launched := make(map[string]struct{}, len(result.Items))
+	for _, item := range result.Items {
+		if item.Status == StatusReady {
+			launched[item.WindowKey] = struct{}{}
+		}
+	}
+	targets := make([]Item, 0, len(launched))
+	for _, item := range plan.Items {
+		if _, ok := launched[item.WindowKey]; ok && item.Launchable() {
+			targets = append(targets, item)
+		}
+	}
+
+	matches := matchNewWindows(targets, result, before, after, lineage)
+	created := make([]CreatedWindow, 0, len(matches))
+	for _, match := range matches {
+		if match.Confidence != MatchReadiness && match.Confidence != MatchPID {
+			continue
+		}
+		created = append(created, CreatedWindow{Key: match.WindowKey, WindowID: match.WindowID, AppID: match.AppID})
+	}
+	sort.Slice(created, func(i, j int) bool { return created[i].WindowID < created[j].WindowID })
+	return created
+}
+
+func CloseWindows(ctx context.Context, closer WindowCloser, windows []CreatedWindow, current *model.State) CloseReport {
+	report := CloseReport{}
+	if closer == nil {
+		return report
+	}
+
+	var open map[string]struct{}
+	if current != nil {
+		open = make(map[string]struct{}, len(current.Windows))
+		for _, window := range current.Windows {
+			open[window.Key] = struct{}{}
+		}
+	}
+
+	for _, window := range windows {
+		if open != nil {
+			if _, ok := open[window.Key]; !ok {
+				report.Missing++
+				continue
+			}
+		}
+		report.Attempted++
+		if err := closer.CloseWindow(ctx, window.WindowID); err != nil {
+			report.Failures = append(report.Failures, CloseFailure{Window: window, Err: err})
+			continue
+		}
+		report.Closed++
+	}
+	return report
+}
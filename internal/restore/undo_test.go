@@ -0,0 +1,85 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestLaunchedWindowsOnlyJournalsWindowsMatchedToLaunchedItems(t *testing.T) {
+	t.Parallel()
+
+	plan := Plan{Items: []Item{
+		{WindowKey: "saved-kitty-1", AppID: "kitty", Status: StatusReady},
+		{WindowKey: "saved-kitty-2", AppID: "kitty", Status: StatusReady},
+		{WindowKey: "saved-kitty-3", AppID: "kitty", Status: StatusReady},
+		{WindowKey: "saved-kitty-4", AppID: "kitty", Status: StatusReady},
+		{WindowKey: "saved-code-1", AppID: "code", Status: StatusReady},
+		{WindowKey: "saved-firefox-1", AppID: "firefox", Status: StatusSkipped},
+	}}
+	result := Result{Items: []ItemResult{
+		{WindowKey: "saved-kitty-1", Status: StatusReady, OpenedWindowKey: "w:kitty:32"},
+		{WindowKey: "saved-kitty-2", Status: StatusTimeout, PID: 600},
+		{WindowKey: "saved-kitty-3", Status: StatusReady, PID: 500},
+		{WindowKey: "saved-kitty-4", Status: StatusReady},
+		{WindowKey: "saved-code-1", Status: StatusFailed},
+		{WindowKey: "saved-firefox-1", Status: StatusSkipped},
+	}}
+	before := model.State{Windows: []model.Window{{Key: "w:kitty:10", AppID: "kitty"}}}
+	after := model.State{Windows: []model.Window{
+		{Key: "w:kitty:10", AppID: "kitty"},
+		{Key: "w:kitty:30", AppID: "kitty"},
+		{Key: "w:kitty:32", AppID: "kitty"},
+		{Key: "w:kitty:33", AppID: "kitty"},
+		{Key: "w:kitty:35", AppID: "kitty", PID: 501},
+		{Key: "w:kitty:36", AppID: "kitty", PID: 601},
+		{Key: "w:code:31", AppID: "code"},
+		{Key: "w:firefox:34", AppID: "firefox"},
+	}}
+
+	created := LaunchedWindows(plan, result, before, after, stubLineage{501: 500, 601: 600})
+	if len(created) != 2 || created[0].Key != "w:kitty:32" || created[1].Key != "w:kitty:35" {
+		t.Fatalf("expected only the readiness and pid lineage matches, got %#v", created)
+	}
+	if created[0].WindowID != 32 || created[0].AppID != "kitty" {
+		t.Fatalf("expected window id and app id recorded, got %#v", created[0])
+	}
+}
+
+func TestCloseWindowsSkipsAlreadyClosedAndContinuesOnFailure(t *testing.T) {
+	t.Parallel()
+
+	windows := []CreatedWindow{
+		{Key: "w:kitty:30", WindowID: 30},
+		{Key: "w:kitty:31", WindowID: 31},
+		{Key: "w:kitty:32", WindowID: 32},
+	}
+	current := &model.State{Windows: []model.Window{{Key: "w:kitty:30"}, {Key: "w:kitty:32"}}}
+	closer := &stubCloser{failOn: map[int]error{30: errors.New("boom")}}
+
+	report := CloseWindows(context.Background(), closer, windows, current)
+	if report.Attempted != 2 || report.Closed != 1 || report.Missing != 1 || len(report.Failures) != 1 {
+		t.Fatalf("unexpected close report: %+v", report)
+	}
+	if report.Failures[0].Window.WindowID != 30 {
+		t.Fatalf("expected failure for window 30, got %#v", report.Failures[0])
+	}
+	if len(closer.calls) != 2 || closer.calls[1] != 32 {
+		t.Fatalf("unexpected close calls: %#v", closer.calls)
+	}
+}
+
+type stubCloser struct {
+	failOn map[int]error
+	calls  []int
+}
+
+func (s *stubCloser) CloseWindow(_ context.Context, windowID int) error {
+	s.calls = append(s.calls, windowID)
+	if err, ok := s.failOn[windowID]; ok {
+		return err
+	}
+	return nil
+}
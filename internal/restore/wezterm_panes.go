@@ -0,0 +1,71 @@
+package restore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func (p *Planner) wezTermScript(window model.Window) (string, string, error) {
+	lines := []string{"p0=$WEZTERM_PANE"}
+	firstCWD := ""
+	firstExec := "exec ${SHELL:-sh} -l"
+	focus := ""
+	next := 0
+	for tabIndex, tab := range window.Terminal.Tabs {
+		tabPane := ""
+		for paneIndex, pane := range tab.Panes {
+			exec, hasExec, err := p.paneExec(window, pane)
+			if err != nil {
+				return "", "", err
+			}
+
+			var variable string
+			if next == 0 {
+				variable = "p0"
+				firstCWD = strings.TrimSpace(pane.CWD)
+				if hasExec {
+					firstExec = "exec sh -lc " + shellQuote(exec)
+				}
+			} else {
+				variable = fmt.Sprintf("p%d", next)
+				var parts []string
+				if paneIndex == 0 {
+					parts = []string{"wezterm cli spawn --pane-id \"$p0\""}
+				} else {
+					direction := "--bottom"
+					if pane.Split == model.SplitRight {
+						direction = "--right"
+					}
+					parts = []string{fmt.Sprintf("wezterm cli split-pane --pane-id \"$p%d\" %s", next-1, direction)}
+				}
+				if cwd := strings.TrimSpace(pane.CWD); cwd != "" {
+					parts = append(parts, "--cwd "+shellQuote(cwd))
+				}
+				if hasExec {
+					parts = append(parts, "-- sh -lc "+shellQuote(exec))
+				}
+				lines = append(lines, fmt.Sprintf("%s=$(%s)", variable, strings.Join(parts, " ")))
+			}
+			next++
+			if tabPane == "" {
+				tabPane = variable
+			}
+			if pane.Active && (tab.Active || (tabIndex == 0 && focus == "")) {
+				focus = variable
+			}
+		}
+		if title := strings.TrimSpace(tab.Title); title != "" && tabPane != "" {
+			lines = append(lines, fmt.Sprintf("wezterm cli set-tab-title --pane-id \"$%s\" %s", tabPane, shellQuote(title)))
+		}
+	}
+	if next == 0 {
+		return "", "", fmt.Errorf("no panes recorded")
+	}
+	if focus != "" {
+		lines = append(lines, fmt.Sprintf("wezterm cli activate-pane --pane-id \"$%s\"", focus))
+	}
+	lines = append(lines, firstExec)
+	return strings.Join(lines, "\n") + "\n", firstCWD, nil
+}
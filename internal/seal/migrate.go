@@ -0,0 +1,269 @@
+package seal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+type Summary struct {
+	EventsRewritten    int
+	SnapshotsRewritten int
+	LayoutsRewritten   int
+	RestoresRewritten  int
+}
+
+type offsetPair struct {
+	old int64
+	new int64
+}
+
+func Encrypt(root string, source KeySource, keyring KeyringReader) (Summary, error) {
+	var codec *Codec
+	meta, err := ReadMeta(root)
+	switch {
+	case err == nil && !meta.Migrating:
+		return Summary{}, ErrAlreadyEncrypted
+	case err == nil:
+		codec, err = LoadWithKeyring(root, keyring)
+		if err != nil {
+			return Summary{}, err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		key, err := ReadKey(source, keyring)
+		if err != nil {
+			return Summary{}, err
+		}
+		codec, err = New(key, source)
+		if err != nil {
+			return Summary{}, err
+		}
+		if err := writeMeta(root, codec, true); err != nil {
+			return Summary{}, fmt.Errorf("write encryption meta: %w", err)
+		}
+	default:
+		return Summary{}, err
+	}
+	summary, err := migrate(root, codec.withPlaintext(), codec)
+	if err != nil {
+		return Summary{}, err
+	}
+	if err := writeMeta(root, codec, false); err != nil {
+		return Summary{}, fmt.Errorf("write encryption meta: %w", err)
+	}
+	return summary, nil
+}
+
+func Decrypt(root string, keyring KeyringReader) (Summary, error) {
+	codec, err := LoadWithKeyring(root, keyring)
+	if err != nil {
+		return Summary{}, err
+	}
+	if codec == nil {
+		return Summary{}, ErrNotEncrypted
+	}
+	if err := writeMeta(root, codec, true); err != nil {
+		return Summary{}, fmt.Errorf("write encryption meta: %w", err)
+	}
+	summary, err := migrate(root, codec.withPlaintext(), nil)
+	if err != nil {
+		return Summary{}, err
+	}
+	if err := os.Remove(MetaPath(root)); err != nil {
+		return Summary{}, fmt.Errorf("remove encryption meta: %w", err)
+	}
+	return summary, nil
+}
+
+func migrate(root string, from *Codec, to *Codec) (Summary, error) {
+	eventsRewritten, offsets, err := migrateEvents(filepath.Join(root, "events.jsonl"), from, to)
+	if err != nil {
+		return Summary{}, err
+	}
+	snapshotsRewritten, err := migrateSnapshots(filepath.Join(root, "snapshots"), from, to, offsets)
+	if err != nil {
+		return Summary{}, err
+	}
+	layoutsRewritten, err := migrateFiles(filepath.Join(root, "layouts"), ".kdl", from, to)
+	if err != nil {
+		return Summary{}, err
+	}
+	restoresRewritten, err := migrateFiles(filepath.Join(root, "restores"), ".json", from, to)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Summary{
+		EventsRewritten:    eventsRewritten,
+		SnapshotsRewritten: snapshotsRewritten,
+		LayoutsRewritten:   layoutsRewritten,
+		RestoresRewritten:  restoresRewritten,
+	}, nil
+}
+
+func migrateEvents(path string, from *Codec, to *Codec) (int, []offsetPair, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("open events file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var out bytes.Buffer
+	var offsets []offsetPair
+	var oldOffset int64
+	rewritten := 0
+	scanner := NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		oldOffset += int64(len(line)) + 1
+		plain, err := from.Open(line)
+		if err != nil {
+			return 0, nil, fmt.Errorf("events line %d: %w", len(offsets)+1, err)
+		}
+		payload, err := to.Seal(plain)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !bytes.Equal(payload, line) {
+			rewritten++
+		}
+		out.Write(payload)
+		out.WriteByte('\n')
+		offsets = append(offsets, offsetPair{old: oldOffset, new: int64(out.Len())})
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, nil, fmt.Errorf("scan events: %w", err)
+	}
+	if rewritten == 0 {
+		return 0, offsets, nil
+	}
+	if err := writeFileAtomic(path, out.Bytes()); err != nil {
+		return 0, nil, fmt.Errorf("rewrite events: %w", err)
+	}
+	return rewritten, offsets, nil
+}
+
+func migrateSnapshots(dir string, from *Codec, to *Codec, offsets []offsetPair) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read snapshots dir: %w", err)
+	}
+
+	rewritten := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		payload, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("read snapshot: %w", err)
+		}
+		plain, err := from.Open(payload)
+		if err != nil {
+			return 0, fmt.Errorf("snapshot %s: %w", entry.Name(), err)
+		}
+		plain, err = remapSnapshotOffset(plain, offsets)
+		if err != nil {
+			return 0, fmt.Errorf("snapshot %s: %w", entry.Name(), err)
+		}
+		sealed, err := to.Seal(plain)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(sealed, payload) {
+			continue
+		}
+		if err := writeFileAtomic(path, sealed); err != nil {
+			return 0, fmt.Errorf("rewrite snapshot: %w", err)
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+func migrateFiles(dir string, ext string, from *Codec, to *Codec) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read %s dir: %w", filepath.Base(dir), err)
+	}
+
+	rewritten := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		payload, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("read %s: %w", entry.Name(), err)
+		}
+		plain, err := from.Open(payload)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		sealed, err := to.Seal(plain)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(sealed, payload) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return 0, fmt.Errorf("stat %s: %w", entry.Name(), err)
+		}
+		if err := writeFileAtomic(path, sealed); err != nil {
+			return 0, fmt.Errorf("rewrite %s: %w", entry.Name(), err)
+		}
+		if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+			return 0, fmt.Errorf("restore mtime of %s: %w", entry.Name(), err)
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+func remapSnapshotOffset(payload []byte, offsets []offsetPair) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	raw, ok := fields["last_event_offset"]
+	if !ok || len(offsets) == 0 {
+		return payload, nil
+	}
+	offset, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("decode last_event_offset: %w", err)
+	}
+	remapped := remapOffset(offsets, offset)
+	if remapped == offset {
+		return payload, nil
+	}
+	fields["last_event_offset"] = json.RawMessage(strconv.FormatInt(remapped, 10))
+	return json.Marshal(fields)
+}
+
+func remapOffset(offsets []offsetPair, offset int64) int64 {
+	i := sort.Search(len(offsets), func(i int) bool { return offsets[i].old > offset })
+	if i == 0 {
+		return 0
+	}
+	return offsets[i-1].new
+}
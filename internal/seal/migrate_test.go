@@ -0,0 +1,195 @@
+package seal
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecryptRoundTripRemapsSnapshotOffsets(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	lines := []string{
+		`{"v":1,"event_type":"state_full","state":{"windows":[{"title":"secret"}]}}`,
+		`{"v":1,"event_type":"window_patch","window_key":"w-1","patch":{"title":"other"}}`,
+	}
+	plainEvents := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(root, "events.jsonl"), []byte(plainEvents), 0o600); err != nil {
+		t.Fatalf("write events: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "snapshots"), 0o755); err != nil {
+		t.Fatalf("make snapshots dir: %v", err)
+	}
+	snapshotPath := filepath.Join(root, "snapshots", "1700000000.json")
+	firstOffset := int64(len(lines[0]) + 1)
+	snapshot := `{"v":1,"last_event_offset":` + strconv.FormatInt(firstOffset, 10) + `,"state":{"windows":[{"title":"secret"}]}}`
+	if err := os.WriteFile(snapshotPath, []byte(snapshot), 0o600); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	layoutPath := filepath.Join(root, "layouts", "abc.kdl")
+	journalPath := filepath.Join(root, "restores", "20260215T102000.000Z.json")
+	sideFiles := map[string]string{
+		layoutPath:  "layout {\n    pane cwd=\"/home/me/secret\"\n}\n",
+		journalPath: `{"v":1,"windows":[{"key":"w:kitty:30","app_id":"secret"}]}`,
+	}
+	for path, content := range sideFiles {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("make dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	keyFile := filepath.Join(root, "redeem.key")
+	if err := GenerateKeyFile(keyFile); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	summary, err := Encrypt(root, KeySource{File: keyFile}, nil)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if summary.EventsRewritten != 2 || summary.SnapshotsRewritten != 1 || summary.LayoutsRewritten != 1 || summary.RestoresRewritten != 1 {
+		t.Fatalf("unexpected encrypt summary %+v", summary)
+	}
+	for path := range sideFiles {
+		sealed, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if !IsSealed(sealed) || strings.Contains(string(sealed), "secret") {
+			t.Fatalf("expected %s sealed, got %q", path, sealed)
+		}
+	}
+
+	sealedEvents, err := os.ReadFile(filepath.Join(root, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	sealedSnapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if strings.Contains(string(sealedEvents), "secret") || strings.Contains(string(sealedSnapshot), "secret") {
+		t.Fatal("expected plaintext to be gone after encrypt")
+	}
+	codec, err := Load(root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	sealedLines := strings.Split(strings.TrimSuffix(string(sealedEvents), "\n"), "\n")
+	opened, err := codec.Open(sealedSnapshot)
+	if err != nil {
+		t.Fatalf("open snapshot: %v", err)
+	}
+	var decoded struct {
+		LastEventOffset int64 `json:"last_event_offset"`
+	}
+	if err := json.Unmarshal(opened, &decoded); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if decoded.LastEventOffset != int64(len(sealedLines[0])+1) {
+		t.Fatalf("expected offset remapped to %d, got %d", len(sealedLines[0])+1, decoded.LastEventOffset)
+	}
+
+	summary, err = Decrypt(root, nil)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if summary.EventsRewritten != 2 || summary.SnapshotsRewritten != 1 || summary.LayoutsRewritten != 1 || summary.RestoresRewritten != 1 {
+		t.Fatalf("unexpected decrypt summary %+v", summary)
+	}
+	for path, content := range sideFiles {
+		plain, err := os.ReadFile(path)
+		if err != nil || string(plain) != content {
+			t.Fatalf("expected %s restored, got %q %v", path, plain, err)
+		}
+	}
+	restored, err := os.ReadFile(filepath.Join(root, "events.jsonl"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+	if string(restored) != plainEvents {
+		t.Fatalf("expected events restored byte for byte, got %q", restored)
+	}
+	restoredSnapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if err := json.Unmarshal(restoredSnapshot, &decoded); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if decoded.LastEventOffset != firstOffset {
+		t.Fatalf("expected offset %d after decrypt, got %d", firstOffset, decoded.LastEventOffset)
+	}
+	if _, err := os.Stat(MetaPath(root)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected encryption meta removed, got %v", err)
+	}
+	if _, err := Decrypt(root, nil); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+func TestEncryptResumesInterruptedMigration(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	keyFile := filepath.Join(root, "redeem.key")
+	if err := GenerateKeyFile(keyFile); err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	key, err := ReadKey(KeySource{File: keyFile}, nil)
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
+	codec, err := New(key, KeySource{File: keyFile})
+	if err != nil {
+		t.Fatalf("new codec: %v", err)
+	}
+	sealed, err := codec.Seal([]byte(`{"v":1,"event_type":"window_patch","window_key":"w-1"}`))
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	plain := `{"v":1,"event_type":"window_patch","window_key":"w-2"}`
+	if err := os.WriteFile(filepath.Join(root, "events.jsonl"), []byte(string(sealed)+"\n"+plain+"\n"), 0o600); err != nil {
+		t.Fatalf("write events: %v", err)
+	}
+	if err := writeMeta(root, codec, true); err != nil {
+		t.Fatalf("write meta: %v", err)
+	}
+
+	loaded, err := LoadWithKeyring(root, nil)
+	if err != nil {
+		t.Fatalf("load during migration: %v", err)
+	}
+	if _, err := loaded.Open([]byte(plain)); err != nil {
+		t.Fatalf("expected plaintext to be readable during migration, got %v", err)
+	}
+
+	summary, err := Encrypt(root, KeySource{}, nil)
+	if err != nil {
+		t.Fatalf("resume encrypt: %v", err)
+	}
+	if summary.EventsRewritten != 2 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	meta, err := ReadMeta(root)
+	if err != nil || meta.Migrating {
+		t.Fatalf("expected finished migration, got %+v %v", meta, err)
+	}
+	if _, err := Encrypt(root, KeySource{File: keyFile}, nil); !errors.Is(err, ErrAlreadyEncrypted) {
+		t.Fatalf("expected ErrAlreadyEncrypted, got %v", err)
+	}
+	loaded, err = LoadWithKeyring(root, nil)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if _, err := loaded.Open([]byte(plain)); !errors.Is(err, ErrUnsealed) {
+		t.Fatalf("expected forged plaintext to be rejected, got %v", err)
+	}
+}
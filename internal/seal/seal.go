@@ -0,0 +1,312 @@
+package seal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	Cipher      = "nacl-secretbox"
+	KeySize     = 32
+	MaxLineSize = 256 * 1024 * 1024
+	nonceSize   = 24
+)
+
+var (
+	ErrKeyRequired      = errors.New("store is encrypted but no key is available")
+	ErrWrongKey         = errors.New("encryption key does not match the store")
+	ErrAlreadyEncrypted = errors.New("store is already encrypted")
+	ErrNotEncrypted     = errors.New("store is not encrypted")
+	ErrStaleCodec       = errors.New("store encryption changed since it was opened")
+	ErrUnsealed         = errors.New("unsealed payload in an encrypted store")
+)
+
+var prefix = []byte("enc:v1:")
+
+const checkPlaintext = "terminal-redeemer"
+
+type KeySource struct {
+	File    string `json:"key_file,omitempty"`
+	Keyring string `json:"keyring,omitempty"`
+}
+
+func (s KeySource) String() string {
+	if s.Keyring != "" {
+		return "keyring:" + s.Keyring
+	}
+	return "file:" + s.File
+}
+
+type Meta struct {
+	V      int    `json:"v"`
+	Cipher string `json:"cipher"`
+	KeySource
+	Check     string `json:"check"`
+	Migrating bool   `json:"migrating,omitempty"`
+}
+
+type KeyringReader interface {
+	ReadKey(name string) ([]byte, error)
+}
+
+type Keyctl struct {
+	Timeout time.Duration
+}
+
+func (k Keyctl) ReadKey(name string) ([]byte, error) {
+	timeout := k.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "keyctl", "pipe", "%user:"+name).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("keyctl pipe %%user:%s: %w: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("keyctl pipe %%user:%s: %w", name, err)
+	}
+	return out, nil
+}
+
+type Codec struct {
+	key        [KeySize]byte
+	source     KeySource
+	check      string
+	allowPlain bool
+}
+
+func New(key []byte, source KeySource) (*Codec, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+	codec := &Codec{source: source}
+	copy(codec.key[:], key)
+	return codec, nil
+}
+
+func (c *Codec) Source() KeySource {
+	if c == nil {
+		return KeySource{}
+	}
+	return c.source
+}
+
+func (c *Codec) Seal(plain []byte) ([]byte, error) {
+	if c == nil {
+		return plain, nil
+	}
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := secretbox.Seal(nonce[:], plain, &nonce, &c.key)
+	out := make([]byte, len(prefix)+base64.RawStdEncoding.EncodedLen(len(sealed)))
+	copy(out, prefix)
+	base64.RawStdEncoding.Encode(out[len(prefix):], sealed)
+	return out, nil
+}
+
+func (c *Codec) Open(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		if c != nil && !c.allowPlain {
+			return nil, ErrUnsealed
+		}
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrKeyRequired
+	}
+	sealed := make([]byte, base64.RawStdEncoding.DecodedLen(len(data)-len(prefix)))
+	n, err := base64.RawStdEncoding.Decode(sealed, data[len(prefix):])
+	if err != nil {
+		return nil, fmt.Errorf("decode sealed payload: %w", err)
+	}
+	sealed = sealed[:n]
+	if len(sealed) < nonceSize+secretbox.Overhead {
+		return nil, errors.New("sealed payload too short")
+	}
+	var nonce [nonceSize]byte
+	copy(nonce[:], sealed[:nonceSize])
+	plain, ok := secretbox.Open(nil, sealed[nonceSize:], &nonce, &c.key)
+	if !ok {
+		return nil, errors.New("open sealed payload: authentication failed")
+	}
+	return plain, nil
+}
+
+func NewScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+	return scanner
+}
+
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, prefix)
+}
+
+func MetaPath(root string) string {
+	return filepath.Join(root, "meta", "encryption.json")
+}
+
+func Load(root string) (*Codec, error) {
+	return LoadWithKeyring(root, Keyctl{})
+}
+
+func LoadWithKeyring(root string, keyring KeyringReader) (*Codec, error) {
+	meta, err := ReadMeta(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if meta.Cipher != Cipher {
+		return nil, fmt.Errorf("unsupported store cipher %q", meta.Cipher)
+	}
+	key, err := ReadKey(meta.KeySource, keyring)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeyRequired, err)
+	}
+	codec, err := New(key, meta.KeySource)
+	if err != nil {
+		return nil, err
+	}
+	check, err := codec.Open([]byte(meta.Check))
+	if err != nil || string(check) != checkPlaintext {
+		return nil, ErrWrongKey
+	}
+	codec.check = meta.Check
+	codec.allowPlain = meta.Migrating
+	return codec, nil
+}
+
+func (c *Codec) withPlaintext() *Codec {
+	lenient := *c
+	lenient.allowPlain = true
+	return &lenient
+}
+
+func Current(root string, codec *Codec) error {
+	meta, err := ReadMeta(root)
+	if errors.Is(err, os.ErrNotExist) {
+		if codec != nil {
+			return ErrStaleCodec
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if codec == nil || codec.check != meta.Check {
+		return ErrStaleCodec
+	}
+	return nil
+}
+
+func ReadMeta(root string) (Meta, error) {
+	payload, err := os.ReadFile(MetaPath(root))
+	if err != nil {
+		return Meta{}, err
+	}
+	var meta Meta
+	if err := json.Unmarshal(payload, &meta); err != nil {
+		return Meta{}, fmt.Errorf("decode encryption meta: %w", err)
+	}
+	return meta, nil
+}
+
+func writeMeta(root string, codec *Codec, migrating bool) error {
+	check, err := codec.Seal([]byte(checkPlaintext))
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(Meta{V: 1, Cipher: Cipher, KeySource: codec.source, Check: string(check), Migrating: migrating})
+	if err != nil {
+		return fmt.Errorf("marshal encryption meta: %w", err)
+	}
+	codec.check = string(check)
+	if err := os.MkdirAll(filepath.Dir(MetaPath(root)), 0o755); err != nil {
+		return fmt.Errorf("create meta dir: %w", err)
+	}
+	return writeFileAtomic(MetaPath(root), payload)
+}
+
+func ReadKey(source KeySource, keyring KeyringReader) ([]byte, error) {
+	var raw []byte
+	var err error
+	switch {
+	case source.Keyring != "":
+		raw, err = keyring.ReadKey(source.Keyring)
+	case source.File != "":
+		raw, err = os.ReadFile(source.File)
+	default:
+		return nil, errors.New("no key file or keyring entry configured")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(raw)
+}
+
+func ParseKey(raw []byte) ([]byte, error) {
+	if len(raw) == KeySize {
+		return raw, nil
+	}
+	text := strings.TrimSpace(string(raw))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key must be %d raw bytes, or %d bytes encoded as hex or base64", KeySize, KeySize)
+}
+
+func GenerateKeyFile(path string) error {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create key dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("create key file: %w", err)
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write key file: %w", err)
+	}
+	return f.Close()
+}
+
+func writeFileAtomic(path string, payload []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package seal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	codec, err := New(bytes.Repeat([]byte{7}, KeySize), KeySource{File: "/keys/redeem"})
+	if err != nil {
+		t.Fatalf("new codec: %v", err)
+	}
+
+	plain := []byte(`{"v":1,"title":"secret"}`)
+	sealed, err := codec.Seal(plain)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("secret")) || bytes.ContainsRune(sealed, '\n') {
+		t.Fatalf("unexpected sealed payload %q", sealed)
+	}
+	again, err := codec.Seal(plain)
+	if err != nil {
+		t.Fatalf("seal again: %v", err)
+	}
+	if bytes.Equal(again, sealed) {
+		t.Fatal("expected a fresh nonce per seal")
+	}
+
+	opened, err := codec.Open(sealed)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if !bytes.Equal(opened, plain) {
+		t.Fatalf("expected %q, got %q", plain, opened)
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-2] ^= 1
+	if _, err := codec.Open(tampered); err == nil {
+		t.Fatal("expected tampered payload to fail authentication")
+	}
+}
+
+func TestCodecRejectsUnsealedPayloadOutsideMigration(t *testing.T) {
+	t.Parallel()
+
+	codec, err := New(bytes.Repeat([]byte{7}, KeySize), KeySource{File: "/keys/redeem"})
+	if err != nil {
+		t.Fatalf("new codec: %v", err)
+	}
+	forged := []byte(`{"v":1,"event_type":"state_full"}`)
+	if _, err := codec.Open(forged); !errors.Is(err, ErrUnsealed) {
+		t.Fatalf("expected ErrUnsealed, got %v", err)
+	}
+	opened, err := codec.withPlaintext().Open(forged)
+	if err != nil || !bytes.Equal(opened, forged) {
+		t.Fatalf("expected migration codec to pass plaintext through, got %q %v", opened, err)
+	}
+}
+
+func TestNilCodecPassesPlaintextThrough(t *testing.T) {
+	t.Parallel()
+
+	var codec *Codec
+	plain := []byte(`{"v":1}`)
+	sealed, err := codec.Seal(plain)
+	if err != nil || !bytes.Equal(sealed, plain) {
+		t.Fatalf("expected plaintext passthrough, got %q %v", sealed, err)
+	}
+	if _, err := codec.Open([]byte("enc:v1:AAAA")); !errors.Is(err, ErrKeyRequired) {
+		t.Fatalf("expected ErrKeyRequired, got %v", err)
+	}
+}
+
+func TestParseKeyAcceptsRawHexAndBase64(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{0xab}, KeySize)
+	for _, raw := range [][]byte{
+		key,
+		[]byte(hex.EncodeToString(key) + "\n"),
+		[]byte(base64.StdEncoding.EncodeToString(key) + "\n"),
+	} {
+		got, err := ParseKey(raw)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Fatalf("parse %q: got %x %v", raw, got, err)
+		}
+	}
+	if _, err := ParseKey([]byte("too-short")); err == nil {
+		t.Fatal("expected short key to be rejected")
+	}
+}
+
+func TestLoadReadsKeyFromMetaSource(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	if codec, err := Load(root); err != nil || codec != nil {
+		t.Fatalf("expected plaintext store without meta, got %v %v", codec, err)
+	}
+
+	keyring := stubKeyring{keys: map[string][]byte{"redeem": []byte(hex.EncodeToString(bytes.Repeat([]byte{1}, KeySize)))}}
+	if _, err := Encrypt(root, KeySource{Keyring: "redeem"}, keyring); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	codec, err := LoadWithKeyring(root, keyring)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if codec == nil || codec.Source().Keyring != "redeem" {
+		t.Fatalf("expected keyring codec, got %#v", codec)
+	}
+	if _, err := Encrypt(root, KeySource{Keyring: "redeem"}, keyring); !errors.Is(err, ErrAlreadyEncrypted) {
+		t.Fatalf("expected ErrAlreadyEncrypted, got %v", err)
+	}
+
+	keyring.keys["redeem"] = bytes.Repeat([]byte{2}, KeySize)
+	if _, err := LoadWithKeyring(root, keyring); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected ErrWrongKey, got %v", err)
+	}
+	delete(keyring.keys, "redeem")
+	if _, err := LoadWithKeyring(root, keyring); !errors.Is(err, ErrKeyRequired) {
+		t.Fatalf("expected ErrKeyRequired, got %v", err)
+	}
+}
+
+func TestCurrentDetectsEncryptionChangedAfterLoad(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	keyring := stubKeyring{keys: map[string][]byte{"redeem": bytes.Repeat([]byte{1}, KeySize)}}
+	if err := Current(root, nil); err != nil {
+		t.Fatalf("expected plaintext store current, got %v", err)
+	}
+	if _, err := Encrypt(root, KeySource{Keyring: "redeem"}, keyring); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if err := Current(root, nil); !errors.Is(err, ErrStaleCodec) {
+		t.Fatalf("expected plaintext codec stale after encrypt, got %v", err)
+	}
+	codec, err := LoadWithKeyring(root, keyring)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := Current(root, codec); err != nil {
+		t.Fatalf("expected loaded codec current, got %v", err)
+	}
+
+	if _, err := Decrypt(root, keyring); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if err := Current(root, codec); !errors.Is(err, ErrStaleCodec) {
+		t.Fatalf("expected codec stale after decrypt, got %v", err)
+	}
+	if _, err := Encrypt(root, KeySource{Keyring: "redeem"}, keyring); err != nil {
+		t.Fatalf("encrypt again: %v", err)
+	}
+	if err := Current(root, codec); !errors.Is(err, ErrStaleCodec) {
+		t.Fatalf("expected codec from the previous encryption stale, got %v", err)
+	}
+}
+
+func TestGenerateKeyFileRefusesToOverwrite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "keys", "redeem.key")
+	if err := GenerateKeyFile(path); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat key: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 key file, got %v", info.Mode().Perm())
+	}
+	if _, err := ReadKey(KeySource{File: path}, nil); err != nil {
+		t.Fatalf("read generated key: %v", err)
+	}
+	if err := GenerateKeyFile(path); err == nil {
+		t.Fatal("expected existing key file to be kept")
+	}
+}
+
+type stubKeyring struct {
+	keys map[string][]byte
+}
+
+func (s stubKeyring) ReadKey(name string) ([]byte, error) {
+	key, ok := s.keys[name]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return key, nil
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/seal"
 )
 
 var ErrNoSnapshot = errors.New("no snapshot at or before timestamp")
@@ -46,15 +49,24 @@ func (s Snapshot) Validate() error {
 }
 
 type Store struct {
-	dir string
+	dir   string
+	codec *seal.Codec
 }
 
 func NewStore(root string) (*Store, error) {
+	codec, err := seal.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	return NewStoreWithCodec(root, codec)
+}
+
+func NewStoreWithCodec(root string, codec *seal.Codec) (*Store, error) {
 	dir := filepath.Join(root, "snapshots")
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, fmt.Errorf("create snapshots dir: %w", err)
 	}
-	return &Store{dir: dir}, nil
+	return &Store{dir: dir, codec: codec}, nil
 }
 
 func (s *Store) Write(snapshot Snapshot) (string, error) {
@@ -66,6 +78,10 @@ func (s *Store) Write(snapshot Snapshot) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("marshal snapshot: %w", err)
 	}
+	payload, err = s.codec.Seal(payload)
+	if err != nil {
+		return "", fmt.Errorf("seal snapshot: %w", err)
+	}
 
 	path := filepath.Join(s.dir, fmt.Sprintf("%d.json", snapshot.CreatedAt.Unix()))
 	if err := os.WriteFile(path, payload, 0o600); err != nil {
@@ -80,6 +96,10 @@ func (s *Store) Read(path string) (Snapshot, error) {
 	if err != nil {
 		return Snapshot{}, fmt.Errorf("read snapshot: %w", err)
 	}
+	payload, err = s.codec.Open(payload)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("open snapshot: %w", err)
+	}
 
 	var snapshot Snapshot
 	if err := json.Unmarshal(payload, &snapshot); err != nil {
@@ -93,13 +113,20 @@ func (s *Store) Read(path string) (Snapshot, error) {
 }
 
 func (s *Store) LoadNearest(at time.Time) (Snapshot, string, error) {
+	return s.LoadNearestMatching(at, nil)
+}
+
+func (s *Store) LoadNearestMatching(at time.Time, match func(Snapshot) bool) (Snapshot, string, error) {
 	entries, err := os.ReadDir(s.dir)
 	if err != nil {
 		return Snapshot{}, "", fmt.Errorf("read snapshots dir: %w", err)
 	}
 
-	var bestTS int64 = -1
-	var bestPath string
+	type candidate struct {
+		ts   int64
+		path string
+	}
+	var candidates []candidate
 	for _, entry := range entries {
 		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
 			continue
@@ -108,22 +135,22 @@ func (s *Store) LoadNearest(at time.Time) (Snapshot, string, error) {
 		if err != nil {
 			continue
 		}
-		if ts <= at.Unix() && ts > bestTS {
-			bestTS = ts
-			bestPath = filepath.Join(s.dir, entry.Name())
+		if ts <= at.Unix() {
+			candidates = append(candidates, candidate{ts: ts, path: filepath.Join(s.dir, entry.Name())})
 		}
 	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].ts > candidates[j].ts })
 
-	if bestTS < 0 {
-		return Snapshot{}, "", ErrNoSnapshot
-	}
-
-	snapshot, err := s.Read(bestPath)
-	if err != nil {
-		return Snapshot{}, "", err
+	for _, candidate := range candidates {
+		snapshot, err := s.Read(candidate.path)
+		if err != nil {
+			return Snapshot{}, "", err
+		}
+		if match == nil || match(snapshot) {
+			return snapshot, candidate.path, nil
+		}
 	}
-
-	return snapshot, bestPath, nil
+	return Snapshot{}, "", ErrNoSnapshot
 }
 
 func ShouldSnapshot(totalEvents int, snapshotEvery int) bool {
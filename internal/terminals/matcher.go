@@ -0,0 +1,112 @@
+package terminals
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type Rule struct {
+	Pattern string
+	Kind    string
+}
+
+var builtinRules = []Rule{
+	{Pattern: "kitty", Kind: "kitty"},
+	{Pattern: "alacritty", Kind: "alacritty"},
+	{Pattern: "foot", Kind: "foot"},
+	{Pattern: "footclient", Kind: "foot"},
+	{Pattern: "wezterm", Kind: "wezterm"},
+	{Pattern: "org.wezfurlong.wezterm", Kind: "wezterm"},
+	{Pattern: "ghostty", Kind: "ghostty"},
+	{Pattern: "com.mitchellh.ghostty", Kind: "ghostty"},
+}
+
+var terminalHints = []string{"term", "kitty", "alacritty", "foot", "wezterm", "ghostty", "konsole", "tilix", "urxvt", "contour", "blackbox"}
+
+type Matcher struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	kind  string
+	glob  string
+	regex *regexp.Regexp
+}
+
+func Default() *Matcher {
+	matcher, err := NewMatcher(nil)
+	if err != nil {
+		panic(err)
+	}
+	return matcher
+}
+
+func NewMatcher(rules []Rule) (*Matcher, error) {
+	all := make([]Rule, 0, len(rules)+len(builtinRules))
+	all = append(all, rules...)
+	all = append(all, builtinRules...)
+
+	matcher := &Matcher{rules: make([]compiledRule, 0, len(all))}
+	for _, rule := range all {
+		pattern := strings.TrimSpace(rule.Pattern)
+		kind := strings.ToLower(strings.TrimSpace(rule.Kind))
+		if pattern == "" {
+			return nil, fmt.Errorf("terminal pattern is empty")
+		}
+		if kind == "" {
+			return nil, fmt.Errorf("terminal kind is empty for pattern %s", pattern)
+		}
+
+		compiled := compiledRule{kind: kind}
+		if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			regex, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid terminal pattern %s: %w", pattern, err)
+			}
+			compiled.regex = regex
+		} else {
+			compiled.glob = strings.ToLower(pattern)
+			if _, err := path.Match(compiled.glob, ""); err != nil {
+				return nil, fmt.Errorf("invalid terminal pattern %s: %w", pattern, err)
+			}
+		}
+		matcher.rules = append(matcher.rules, compiled)
+	}
+	return matcher, nil
+}
+
+func (m *Matcher) Match(appID string) (string, bool) {
+	appID = strings.ToLower(strings.TrimSpace(appID))
+	if appID == "" {
+		return "", false
+	}
+	for _, rule := range m.rules {
+		if rule.regex != nil {
+			if rule.regex.MatchString(appID) {
+				return rule.kind, true
+			}
+			continue
+		}
+		if ok, _ := path.Match(rule.glob, appID); ok {
+			return rule.kind, true
+		}
+	}
+	return "", false
+}
+
+func (m *Matcher) IsTerminal(appID string) bool {
+	_, ok := m.Match(appID)
+	return ok
+}
+
+func LooksLikeTerminal(appID string) bool {
+	appID = strings.ToLower(strings.TrimSpace(appID))
+	for _, hint := range terminalHints {
+		if strings.Contains(appID, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package terminals
+
+import "testing"
+
+func TestMatcherBuiltinsAndCustomRules(t *testing.T) {
+	t.Parallel()
+
+	matcher, err := NewMatcher([]Rule{
+		{Pattern: "kitty-*", Kind: "kitty"},
+		{Pattern: `/^org\.kde\.konsole$/`, Kind: "Konsole"},
+	})
+	if err != nil {
+		t.Fatalf("new matcher: %v", err)
+	}
+
+	tests := []struct {
+		appID string
+		kind  string
+		ok    bool
+	}{
+		{appID: "kitty", kind: "kitty", ok: true},
+		{appID: "Alacritty", kind: "alacritty", ok: true},
+		{appID: "com.mitchellh.ghostty", kind: "ghostty", ok: true},
+		{appID: "kitty-scratch", kind: "kitty", ok: true},
+		{appID: "org.kde.konsole", kind: "konsole", ok: true},
+		{appID: "firefox", ok: false},
+		{appID: "", ok: false},
+	}
+	for _, tc := range tests {
+		kind, ok := matcher.Match(tc.appID)
+		if ok != tc.ok || kind != tc.kind {
+			t.Fatalf("Match(%q) = %q,%v; want %q,%v", tc.appID, kind, ok, tc.kind, tc.ok)
+		}
+	}
+}
+
+func TestMatcherRejectsInvalidPatterns(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []Rule{
+		{Pattern: "[", Kind: "kitty"},
+		{Pattern: "/(/", Kind: "kitty"},
+		{Pattern: "kitty-*", Kind: ""},
+		{Pattern: " ", Kind: "kitty"},
+	} {
+		if _, err := NewMatcher([]Rule{rule}); err == nil {
+			t.Fatalf("expected error for rule %#v", rule)
+		}
+	}
+}
+
+func TestLooksLikeTerminal(t *testing.T) {
+	t.Parallel()
+
+	if !LooksLikeTerminal("org.kde.konsole") || !LooksLikeTerminal("gnome-terminal-server") {
+		t.Fatal("expected terminal-looking app ids to be detected")
+	}
+	if LooksLikeTerminal("firefox") {
+		t.Fatal("expected firefox not to look like a terminal")
+	}
+}
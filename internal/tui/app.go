@@ -6,6 +6,7 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jmo/terminal-redeemer/internal/model"
 	"github.com/jmo/terminal-redeemer/internal/restore"
 )
 
@@ -47,24 +48,32 @@ func NewAppWithPlanLoader(plan restore.Plan, timestamps []time.Time, selectedAt
 }
 
 func Run(plan restore.Plan, timestamps []time.Time) (restore.Plan, bool, error) {
-	return RunWithPlanLoader(plan, timestamps, time.Time{}, nil)
+	filtered, _, confirmed, err := RunWithPlanLoader(plan, timestamps, time.Time{}, nil)
+	return filtered, confirmed, err
 }
 
-func RunWithPlanLoader(plan restore.Plan, timestamps []time.Time, selectedAt time.Time, loadPlan func(time.Time) (restore.Plan, error)) (restore.Plan, bool, error) {
+func RunWithPlanLoader(plan restore.Plan, timestamps []time.Time, selectedAt time.Time, loadPlan func(time.Time) (restore.Plan, error)) (restore.Plan, time.Time, bool, error) {
 	app := NewAppWithPlanLoader(plan, timestamps, selectedAt, loadPlan)
 	program := tea.NewProgram(app)
 	finalModel, err := program.Run()
 	if err != nil {
-		return restore.Plan{}, false, err
+		return restore.Plan{}, time.Time{}, false, err
 	}
 	final, ok := finalModel.(*App)
 	if !ok {
-		return restore.Plan{}, false, fmt.Errorf("unexpected tui final model type")
+		return restore.Plan{}, time.Time{}, false, fmt.Errorf("unexpected tui final model type")
 	}
 	if final.runErr != nil {
-		return restore.Plan{}, false, final.runErr
+		return restore.Plan{}, time.Time{}, false, final.runErr
 	}
-	return FilterPlan(final.model.plan, final.model.SelectedMap()), final.confirm, nil
+	return FilterPlan(final.model.plan, final.model.SelectedMap()), final.SelectedAt(selectedAt), final.confirm, nil
+}
+
+func (a *App) SelectedAt(fallback time.Time) time.Time {
+	if selected := a.model.SelectedTimestamp(); !selected.IsZero() {
+		return selected
+	}
+	return fallback
 }
 
 func (a *App) Init() tea.Cmd { return nil }
@@ -250,13 +259,38 @@ func (a *App) rowLabel(row row) string {
 		if !ok {
 			return "    window " + row.windowKey
 		}
+		details := []string{string(item.Status)}
 		if item.Status == restore.StatusReady {
-			return fmt.Sprintf("    window %s (%s)", item.WindowKey, item.Status)
+			if item.Session != "" {
+				details = append(details, fmt.Sprintf("session %s", item.Session))
+			}
+		} else {
+			reason := strings.TrimSpace(item.Reason)
+			if reason == "" {
+				reason = "no reason"
+			}
+			details[0] += ": " + reason
 		}
-		reason := strings.TrimSpace(item.Reason)
-		if reason == "" {
-			reason = "no reason"
+		if git := gitLabel(item.Git); git != "" {
+			details = append(details, git)
 		}
-		return fmt.Sprintf("    window %s (%s: %s)", item.WindowKey, item.Status, reason)
+		return fmt.Sprintf("    window %s (%s)", item.WindowKey, strings.Join(details, ", "))
+	}
+}
+
+func gitLabel(git *model.Git) string {
+	if git == nil {
+		return ""
+	}
+	branch := git.Branch
+	if branch == "" {
+		branch = "detached"
+	}
+	if git.Dirty {
+		branch += "*"
+	}
+	if git.Worktree != "" {
+		return fmt.Sprintf("git %s in worktree %s of %s", branch, git.Worktree, git.Root)
 	}
+	return fmt.Sprintf("git %s in %s", branch, git.Root)
 }
@@ -6,7 +6,7 @@ func FilterPlan(plan restore.Plan, selected map[string]bool) restore.Plan {
 	filtered := restore.Plan{Items: make([]restore.Item, 0, len(plan.Items))}
 	for _, item := range plan.Items {
 		out := item
-		if out.Status == restore.StatusReady && !selected[out.WindowKey] {
+		if out.Launchable() && !selected[out.WindowKey] {
 			out.Status = restore.StatusSkipped
 			out.Reason = "excluded in tui"
 			out.Command = ""
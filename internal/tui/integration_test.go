@@ -7,6 +7,7 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jmo/terminal-redeemer/internal/model"
 	"github.com/jmo/terminal-redeemer/internal/restore"
 )
 
@@ -123,6 +124,29 @@ func TestFilterPlanExecutionParityForSelectionEdgeCases(t *testing.T) {
 	}
 }
 
+func TestFilterPlanKeepsParentFallbackItemsSelectable(t *testing.T) {
+	t.Parallel()
+
+	plan := restore.Plan{Items: []restore.Item{
+		{WindowKey: "w-parent", Status: restore.StatusDegraded, Reason: "cwd missing, using parent /home/me", CWDFallback: "/home/me", Command: "kitty --directory /home/me"},
+		{WindowKey: "w-parent-unselected", Status: restore.StatusDegraded, Reason: "cwd missing, using parent /srv", CWDFallback: "/srv", Command: "kitty --directory /srv"},
+	}}
+	model := NewModel(plan, nil)
+	if !model.IsSelectable("w-parent") || !model.IsSelected("w-parent") {
+		t.Fatalf("expected parent fallback item to be selectable and selected by default")
+	}
+	model.ToggleWindow("w-parent-unselected")
+
+	filtered := FilterPlan(plan, model.SelectedMap())
+	result := restore.NewExecutor(recordingRunner{}).Execute(context.Background(), filtered)
+	if result.Summary.Restored != 1 || result.Summary.Skipped != 1 {
+		t.Fatalf("unexpected summary: %+v", result.Summary)
+	}
+	if filtered.Items[1].Reason != "excluded in tui" {
+		t.Fatalf("expected deselected fallback item excluded, got %+v", filtered.Items[1])
+	}
+}
+
 type recordingRunner struct {
 	failOn string
 }
@@ -133,3 +157,25 @@ func (r recordingRunner) Run(_ context.Context, command string) error {
 	}
 	return nil
 }
+
+func TestWindowRowsShowGitMetadata(t *testing.T) {
+	t.Parallel()
+
+	app := NewApp(restore.Plan{Items: []restore.Item{
+		{WindowKey: "w-app", WorkspaceID: "1", AppID: "kitty", Status: restore.StatusReady, Command: "kitty", Session: restore.SessionAttached, Git: &model.Git{Root: "/src/app", Branch: "main", Dirty: true}},
+		{WindowKey: "w-tree", WorkspaceID: "1", AppID: "kitty", Status: restore.StatusDegraded, Reason: "git branch spike no longer exists in /src/app", Git: &model.Git{Root: "/src/app", Branch: "spike", Worktree: "/src/spike"}},
+	}}, nil)
+
+	labels := map[string]string{}
+	for _, row := range app.rows {
+		if row.kind == rowWindow {
+			labels[row.windowKey] = app.rowLabel(row)
+		}
+	}
+	if got := labels["w-app"]; got != "    window w-app (ready, session attached, git main* in /src/app)" {
+		t.Fatalf("unexpected ready row %q", got)
+	}
+	if got := labels["w-tree"]; got != "    window w-tree (degraded: git branch spike no longer exists in /src/app, git spike in worktree /src/spike of /src/app)" {
+		t.Fatalf("unexpected degraded row %q", got)
+	}
+}
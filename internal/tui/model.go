@@ -60,7 +60,7 @@ func (m *Model) setPlan(plan restore.Plan) {
 
 	for _, item := range plan.Items {
 		m.itemByKey[item.WindowKey] = item
-		if item.Status == restore.StatusReady {
+		if item.Launchable() {
 			m.selected[item.WindowKey] = true
 		}
 		m.itemsByWS[item.WorkspaceID] = append(m.itemsByWS[item.WorkspaceID], item.WindowKey)
@@ -136,7 +136,7 @@ func (m *Model) ToggleApp(workspaceID string, appID string) {
 
 func (m *Model) ToggleWindow(windowKey string) {
 	item, ok := m.itemByKey[windowKey]
-	if !ok || item.Status != restore.StatusReady {
+	if !ok || !item.Launchable() {
 		return
 	}
 	m.selected[windowKey] = !m.selected[windowKey]
@@ -150,7 +150,7 @@ func (m *Model) toggleByKeys(keys []string) {
 	hasSelectable := false
 	for _, key := range keys {
 		item, ok := m.itemByKey[key]
-		if !ok || item.Status != restore.StatusReady {
+		if !ok || !item.Launchable() {
 			continue
 		}
 		hasSelectable = true
@@ -164,7 +164,7 @@ func (m *Model) toggleByKeys(keys []string) {
 	}
 	for _, key := range keys {
 		item, ok := m.itemByKey[key]
-		if !ok || item.Status != restore.StatusReady {
+		if !ok || !item.Launchable() {
 			continue
 		}
 		m.selected[key] = !allSelected
@@ -177,7 +177,7 @@ func (m *Model) IsSelected(windowKey string) bool {
 
 func (m *Model) IsSelectable(windowKey string) bool {
 	item, ok := m.itemByKey[windowKey]
-	return ok && item.Status == restore.StatusReady
+	return ok && item.Launchable()
 }
 
 func (m *Model) WorkspaceIDs() []string {
@@ -236,7 +236,7 @@ func (m *Model) selectionStateForKeys(keys []string) SelectionState {
 	selected := 0
 	for _, key := range keys {
 		item, ok := m.itemByKey[key]
-		if !ok || item.Status != restore.StatusReady {
+		if !ok || !item.Launchable() {
 			continue
 		}
 		selectable++
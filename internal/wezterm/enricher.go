@@ -0,0 +1,114 @@
+package wezterm
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+	"github.com/jmo/terminal-redeemer/internal/terminals"
+)
+
+const socketEnv = "WEZTERM_UNIX_SOCKET"
+
+type Lister interface {
+	List(socket string) ([]byte, error)
+}
+
+type EnvLookup interface {
+	LookupEnv(pid int, name string) (string, bool)
+}
+
+type CommandLister struct {
+	Binary  string
+	Timeout time.Duration
+}
+
+func (l CommandLister) List(socket string) ([]byte, error) {
+	binary := strings.TrimSpace(l.Binary)
+	if binary == "" {
+		binary = "wezterm"
+	}
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, binary, "cli", "--no-auto-start", "list", "--format", "json")
+	if socket != "" {
+		cmd.Env = append(os.Environ(), socketEnv+"="+socket)
+	}
+	return cmd.Output()
+}
+
+type Config struct {
+	Terminals *terminals.Matcher
+}
+
+type Enricher struct {
+	lister Lister
+	env    EnvLookup
+	config Config
+}
+
+func NewEnricher(lister Lister, env EnvLookup, config Config) *Enricher {
+	if lister == nil {
+		lister = CommandLister{}
+	}
+	if config.Terminals == nil {
+		config.Terminals = terminals.Default()
+	}
+	return &Enricher{lister: lister, env: env, config: config}
+}
+
+func (e *Enricher) EnrichWindow(window model.Window) (model.Window, error) {
+	if kind, ok := e.config.Terminals.Match(window.AppID); !ok || kind != "wezterm" {
+		return window, nil
+	}
+
+	socket := ""
+	if e.env != nil && window.PID > 0 {
+		if value, ok := e.env.LookupEnv(window.PID, socketEnv); ok {
+			socket = strings.TrimSpace(value)
+		}
+	}
+	raw, err := e.lister.List(socket)
+	if err != nil {
+		return model.Window{}, err
+	}
+	panes, err := ParseList(raw)
+	if err != nil {
+		return model.Window{}, err
+	}
+	windowPanes, ok := WindowPanes(panes, window.Title)
+	if !ok || len(windowPanes) < 2 {
+		return window, nil
+	}
+	tabs := ModelTabs(windowPanes)
+
+	out := window
+	terminal := model.Terminal{}
+	if window.Terminal != nil {
+		terminal = *window.Terminal
+	}
+	terminal.Tabs = tabs
+	if terminal.CWD == "" {
+		terminal.CWD = firstPaneCWD(tabs)
+	}
+	out.Terminal = &terminal
+	return out, nil
+}
+
+func firstPaneCWD(tabs []model.Tab) string {
+	for _, tab := range tabs {
+		for _, pane := range tab.Panes {
+			if pane.CWD != "" {
+				return pane.CWD
+			}
+		}
+	}
+	return ""
+}
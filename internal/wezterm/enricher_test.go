@@ -0,0 +1,111 @@
+package wezterm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+func TestModelTabsFromFixture(t *testing.T) {
+	t.Parallel()
+
+	panes, err := ParseList(readFixture(t))
+	if err != nil {
+		t.Fatalf("parse list: %v", err)
+	}
+	windowPanes, ok := WindowPanes(panes, "nvim")
+	if !ok || len(windowPanes) != 4 {
+		t.Fatalf("expected 4 panes for matching window, got %d ok=%v", len(windowPanes), ok)
+	}
+
+	tabs := ModelTabs(windowPanes)
+	if len(tabs) != 2 || tabs[0].Title != "code" || !tabs[0].Active || tabs[1].Active {
+		t.Fatalf("unexpected tabs: %#v", tabs)
+	}
+	code := tabs[0].Panes
+	if len(code) != 3 || code[0].CWD != "/home/jmo/src/redeemer" || !code[0].Active || code[0].Split != "" {
+		t.Fatalf("unexpected first pane: %#v", code)
+	}
+	if code[1].Split != model.SplitRight || code[1].CWD != "/home/jmo/src/redeemer/internal" || code[2].Split != model.SplitBottom {
+		t.Fatalf("unexpected split directions: %#v", code)
+	}
+	if tabs[1].Panes[0].CWD != "/home/jmo/My Notes" {
+		t.Fatalf("expected decoded file url cwd, got %q", tabs[1].Panes[0].CWD)
+	}
+}
+
+func TestEnricherCapturesPanesForMatchingWindow(t *testing.T) {
+	t.Parallel()
+
+	lister := &stubLister{payload: readFixture(t)}
+	enricher := NewEnricher(lister, stubEnv{"WEZTERM_UNIX_SOCKET": "/run/user/1000/wezterm/gui-sock-42"}, Config{})
+
+	got, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "org.wezfurlong.wezterm", PID: 42, Title: "nvim"})
+	if err != nil {
+		t.Fatalf("enrich window: %v", err)
+	}
+	if lister.socket != "/run/user/1000/wezterm/gui-sock-42" {
+		t.Fatalf("expected socket from window env, got %q", lister.socket)
+	}
+	if got.Terminal == nil || len(got.Terminal.Tabs) != 2 || got.Terminal.CWD != "/home/jmo/src/redeemer" {
+		t.Fatalf("expected captured tabs, got %#v", got.Terminal)
+	}
+}
+
+func TestEnricherLeavesSinglePaneAndOtherTerminalsAlone(t *testing.T) {
+	t.Parallel()
+
+	enricher := NewEnricher(&stubLister{payload: readFixture(t)}, stubEnv{}, Config{})
+	got, err := enricher.EnrichWindow(model.Window{Key: "w-2", AppID: "wezterm", PID: 42, Title: "scratch"})
+	if err != nil || got.Terminal != nil {
+		t.Fatalf("expected single pane window untouched, got %#v err=%v", got.Terminal, err)
+	}
+
+	lister := &stubLister{err: errors.New("should not be called")}
+	got, err = NewEnricher(lister, stubEnv{}, Config{}).EnrichWindow(model.Window{Key: "w-3", AppID: "kitty", PID: 42})
+	if err != nil || got.Terminal != nil {
+		t.Fatalf("expected kitty window to be ignored, got %#v err=%v", got.Terminal, err)
+	}
+}
+
+func TestEnricherListFailureReturnsError(t *testing.T) {
+	t.Parallel()
+
+	enricher := NewEnricher(&stubLister{err: errors.New("no running wezterm")}, stubEnv{}, Config{})
+	if _, err := enricher.EnrichWindow(model.Window{Key: "w-1", AppID: "wezterm", PID: 42}); err == nil {
+		t.Fatal("expected list error")
+	}
+}
+
+func readFixture(t *testing.T) []byte {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "list.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return payload
+}
+
+type stubLister struct {
+	payload []byte
+	err     error
+	socket  string
+}
+
+func (s *stubLister) List(socket string) ([]byte, error) {
+	s.socket = socket
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.payload, nil
+}
+
+type stubEnv map[string]string
+
+func (s stubEnv) LookupEnv(_ int, name string) (string, bool) {
+	value, ok := s[name]
+	return value, ok
+}
@@ -0,0 +1,121 @@
+package wezterm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jmo/terminal-redeemer/internal/model"
+)
+
+type Pane struct {
+	WindowID    int    `json:"window_id"`
+	TabID       int    `json:"tab_id"`
+	PaneID      int    `json:"pane_id"`
+	Workspace   string `json:"workspace"`
+	Title       string `json:"title"`
+	CWD         string `json:"cwd"`
+	IsActive    bool   `json:"is_active"`
+	TabTitle    string `json:"tab_title"`
+	WindowTitle string `json:"window_title"`
+	LeftCol     int    `json:"left_col"`
+	TopRow      int    `json:"top_row"`
+}
+
+func ParseList(raw []byte) ([]Pane, error) {
+	var panes []Pane
+	if err := json.Unmarshal(raw, &panes); err != nil {
+		return nil, fmt.Errorf("decode wezterm list: %w", err)
+	}
+	return panes, nil
+}
+
+func WindowPanes(panes []Pane, title string) ([]Pane, bool) {
+	byWindow := map[int][]Pane{}
+	order := []int{}
+	for _, pane := range panes {
+		if _, ok := byWindow[pane.WindowID]; !ok {
+			order = append(order, pane.WindowID)
+		}
+		byWindow[pane.WindowID] = append(byWindow[pane.WindowID], pane)
+	}
+	if len(order) == 1 {
+		return byWindow[order[0]], true
+	}
+
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, false
+	}
+	matched := -1
+	for _, id := range order {
+		if strings.TrimSpace(byWindow[id][0].WindowTitle) != title {
+			continue
+		}
+		if matched >= 0 {
+			return nil, false
+		}
+		matched = id
+	}
+	if matched < 0 {
+		return nil, false
+	}
+	return byWindow[matched], true
+}
+
+func ModelTabs(panes []Pane) []model.Tab {
+	byTab := map[int][]Pane{}
+	order := []int{}
+	for _, pane := range panes {
+		if _, ok := byTab[pane.TabID]; !ok {
+			order = append(order, pane.TabID)
+		}
+		byTab[pane.TabID] = append(byTab[pane.TabID], pane)
+	}
+
+	out := make([]model.Tab, 0, len(order))
+	for _, id := range order {
+		tabPanes := byTab[id]
+		sort.SliceStable(tabPanes, func(i, j int) bool {
+			if tabPanes[i].TopRow != tabPanes[j].TopRow {
+				return tabPanes[i].TopRow < tabPanes[j].TopRow
+			}
+			return tabPanes[i].LeftCol < tabPanes[j].LeftCol
+		})
+
+		tab := model.Tab{Title: strings.TrimSpace(tabPanes[0].TabTitle), Panes: make([]model.Pane, 0, len(tabPanes))}
+		for i, pane := range tabPanes {
+			converted := model.Pane{
+				Title:  strings.TrimSpace(pane.Title),
+				CWD:    cwdFromURL(pane.CWD),
+				Active: pane.IsActive,
+			}
+			if i > 0 {
+				converted.Split = model.SplitBottom
+				if pane.TopRow == tabPanes[i-1].TopRow {
+					converted.Split = model.SplitRight
+				}
+			}
+			if pane.IsActive && strings.TrimSpace(pane.Title) == strings.TrimSpace(pane.WindowTitle) {
+				tab.Active = true
+			}
+			tab.Panes = append(tab.Panes, converted)
+		}
+		out = append(out, tab)
+	}
+	return out
+}
+
+func cwdFromURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "file://") {
+		return raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return parsed.Path
+}
@@ -6,10 +6,13 @@ let
     stateDir = cfg.stateDir;
     host = cfg.host;
     profile = cfg.profile;
+    compositor = cfg.compositor;
     capture = {
       interval = cfg.capture.interval;
       snapshotEvery = cfg.capture.snapshotEvery;
       niriCommand = cfg.capture.niriCommand;
+      onEvents = cfg.capture.onEvents;
+      eventDebounce = cfg.capture.eventDebounce;
     };
     retention = {
       days = cfg.retention.days;
@@ -18,22 +21,62 @@ let
       whitelist = cfg.processWhitelist;
       whitelistExtra = cfg.processWhitelistExtra;
       includeSessionTag = cfg.processIncludeSessionTag;
+      kittyTabs = cfg.processKittyTabs;
+      weztermPanes = cfg.processWezTermPanes;
+      zellijLayouts = cfg.processZellijLayouts;
+      nvim = cfg.processNvim;
+      git = cfg.processGit;
     };
     restore = {
       appAllowlist = cfg.restore.appAllowlist;
       appMode = cfg.restore.appMode;
+      appTitlePatterns = cfg.restore.appTitlePatterns;
+      relaunch = cfg.restore.relaunch;
       reconcileWorkspaceMoves = cfg.restore.reconcileWorkspaceMoves;
       workspaceReconcileDelay = cfg.restore.workspaceReconcileDelay;
+      reconcileStrategy = cfg.restore.reconcileStrategy;
+      spawnWindowTimeout = cfg.restore.spawnWindowTimeout;
+      remap = {
+        cwdPrefixes = cfg.restore.remap.cwdPrefixes;
+        workspaces = cfg.restore.remap.workspaces;
+        appIds = cfg.restore.remap.appIds;
+      };
       terminal = {
         command = cfg.terminal.command;
         zellijAttachOrCreate = cfg.terminal.zellijAttachOrCreate;
+        tmuxAttachOrCreate = cfg.terminal.tmuxAttachOrCreate;
+        restoreNvim = cfg.terminal.restoreNvim;
+        missingCwd = cfg.terminal.missingCwd;
+        verifyGitBranch = cfg.terminal.verifyGitBranch;
+        restoreGitWorktree = cfg.terminal.restoreGitWorktree;
       };
     };
+    terminals = cfg.terminals;
+    daemon = {
+      socket = cfg.daemon.socket;
+      connect = cfg.daemon.connect;
+    };
+    metrics = {
+      listen = cfg.metrics.listen;
+      textfile = cfg.metrics.textfile;
+    };
+    log = {
+      level = cfg.log.level;
+      format = cfg.log.format;
+    };
+    hooks = cfg.hooks;
+    redact = {
+      titles = cfg.redact.titles;
+      cwdPrefixes = cfg.redact.cwdPrefixes;
+      excludeAppIds = cfg.redact.excludeAppIds;
+    };
   } // cfg.extraConfig;
   settingsFile = settingsFormat.generate "terminal-redeemer-config.yaml" renderedConfig;
   configPath = "${config.xdg.configHome}/terminal-redeemer/config.yaml";
   captureExecStart = "${lib.getExe cfg.package} --config ${lib.escapeShellArg configPath} capture once";
   pruneExecStart = "${lib.getExe cfg.package} --config ${lib.escapeShellArg configPath} prune run";
+  daemonExecStart = "${lib.getExe cfg.package} --config ${lib.escapeShellArg configPath} daemon run";
+  captureTimerEnabled = cfg.capture.enable && !cfg.daemon.enable;
 in {
   options.programs.terminal-redeemer = {
     enable = lib.mkEnableOption "terminal-redeemer";
@@ -66,6 +109,12 @@ in {
       description = "Profile segment under host partition.";
     };
 
+    compositor = lib.mkOption {
+      type = lib.types.enum [ "niri" "sway" "hyprland" ];
+      default = "niri";
+      description = "Compositor backend used to capture and arrange windows.";
+    };
+
     capture = {
       enable = lib.mkOption {
         type = lib.types.bool;
@@ -90,6 +139,18 @@ in {
         default = "niri msg -j windows";
         description = "Command used to collect Niri JSON snapshots.";
       };
+
+      onEvents = lib.mkOption {
+        type = lib.types.bool;
+        default = false;
+        description = "Also capture after compositor window/workspace events in capture run.";
+      };
+
+      eventDebounce = lib.mkOption {
+        type = lib.types.str;
+        default = "2s";
+        description = "Quiet period after compositor events before capturing.";
+      };
     };
 
     retention.days = lib.mkOption {
@@ -124,6 +185,36 @@ in {
       description = "Whether to include session tag extraction for terminals.";
     };
 
+    processKittyTabs = lib.mkOption {
+      type = lib.types.bool;
+      default = false;
+      description = "Whether to capture kitty tabs and splits via kitty remote control.";
+    };
+
+    processWezTermPanes = lib.mkOption {
+      type = lib.types.bool;
+      default = false;
+      description = "Whether to capture wezterm tabs and panes via wezterm cli.";
+    };
+
+    processZellijLayouts = lib.mkOption {
+      type = lib.types.bool;
+      default = true;
+      description = "Whether to save zellij session layouts so killed sessions can be recreated.";
+    };
+
+    processNvim = lib.mkOption {
+      type = lib.types.bool;
+      default = false;
+      description = "Whether to record open nvim buffers and sessions over msgpack-RPC.";
+    };
+
+    processGit = lib.mkOption {
+      type = lib.types.bool;
+      default = false;
+      description = "Whether to record git repo root, branch, worktree and dirty flag for terminal cwds.";
+    };
+
     restore.appAllowlist = lib.mkOption {
       type = lib.types.attrsOf lib.types.str;
       default = { };
@@ -136,6 +227,18 @@ in {
       description = "App ID to restore mode mapping (for example: per_window or oneshot).";
     };
 
+    restore.appTitlePatterns = lib.mkOption {
+      type = lib.types.attrsOf lib.types.str;
+      default = { };
+      description = "App ID to title regex whose captures are available to allowlist command templates.";
+    };
+
+    restore.relaunch = lib.mkOption {
+      type = lib.types.attrsOf lib.types.str;
+      default = { };
+      description = "Process tag to command template re-executed inside restored terminals (empty string re-runs the captured argv).";
+    };
+
     restore.reconcileWorkspaceMoves = lib.mkOption {
       type = lib.types.bool;
       default = true;
@@ -148,6 +251,49 @@ in {
       description = "Delay before workspace move reconciliation runs.";
     };
 
+    restore.reconcileStrategy = lib.mkOption {
+      type = lib.types.enum [ "move" "spawn" ];
+      default = "move";
+      description = "Workspace placement strategy: move windows after launch, or spawn them on a focused target workspace.";
+    };
+
+    restore.spawnWindowTimeout = lib.mkOption {
+      type = lib.types.str;
+      default = "5s";
+      description = "How long the spawn strategy waits for a workspace's windows to appear.";
+    };
+
+    restore.remap = {
+      cwdPrefixes = lib.mkOption {
+        type = lib.types.listOf (lib.types.submodule {
+          options = {
+            from = lib.mkOption {
+              type = lib.types.str;
+              description = "Captured cwd prefix to rewrite.";
+            };
+            to = lib.mkOption {
+              type = lib.types.str;
+              description = "Prefix used on this machine instead.";
+            };
+          };
+        });
+        default = [ ];
+        description = "cwd prefix rewrites applied to restored terminals; the longest matching prefix wins.";
+      };
+
+      workspaces = lib.mkOption {
+        type = lib.types.attrsOf lib.types.str;
+        default = { };
+        description = "Captured workspace name (or index when unnamed) to workspace reference used on restore.";
+      };
+
+      appIds = lib.mkOption {
+        type = lib.types.attrsOf lib.types.str;
+        default = { };
+        description = "Captured app_id to app_id used for allowlist lookup and terminal matching on restore.";
+      };
+    };
+
     terminal.command = lib.mkOption {
       type = lib.types.str;
       default = "kitty";
@@ -160,6 +306,186 @@ in {
       description = "Use zellij attach-or-create strategy during restore.";
     };
 
+    terminal.tmuxAttachOrCreate = lib.mkOption {
+      type = lib.types.bool;
+      default = true;
+      description = "Use tmux new-session -A strategy during restore.";
+    };
+
+    terminal.restoreNvim = lib.mkOption {
+      type = lib.types.bool;
+      default = true;
+      description = "Reopen recorded nvim sessions or buffers in restored terminals.";
+    };
+
+    terminal.missingCwd = lib.mkOption {
+      type = lib.types.enum [ "parent" "skip" ];
+      default = "parent";
+      description = "When a terminal's cwd no longer exists: open it in the nearest existing parent, or skip it.";
+    };
+
+    terminal.verifyGitBranch = lib.mkOption {
+      type = lib.types.bool;
+      default = false;
+      description = "Mark terminals whose recorded git branch no longer exists as degraded instead of launching them.";
+    };
+
+    terminal.restoreGitWorktree = lib.mkOption {
+      type = lib.types.bool;
+      default = false;
+      description = "Recreate a missing linked git worktree before launching a terminal in it.";
+    };
+
+    terminals = lib.mkOption {
+      type = lib.types.listOf (lib.types.submodule {
+        options = {
+          appId = lib.mkOption {
+            type = lib.types.str;
+            description = "App ID glob, or regex wrapped in slashes.";
+          };
+          kind = lib.mkOption {
+            type = lib.types.str;
+            description = "Terminal emulator kind used to pick the launcher (kitty, alacritty, foot, wezterm or ghostty); other kinds are capture-only.";
+          };
+        };
+      });
+      default = [ ];
+      description = "Extra app_id patterns treated as terminals during capture and restore.";
+    };
+
+    daemon = {
+      enable = lib.mkEnableOption "the terminal-redeemer daemon (replaces the capture timer)";
+
+      socket = lib.mkOption {
+        type = lib.types.str;
+        default = "";
+        description = "Control socket path; empty means <stateDir>/daemon.sock.";
+      };
+
+      connect = lib.mkOption {
+        type = lib.types.bool;
+        default = true;
+        description = "Let CLI commands use a running daemon.";
+      };
+    };
+
+    metrics = {
+      listen = lib.mkOption {
+        type = lib.types.str;
+        default = "";
+        description = "Address for the Prometheus /metrics listener of capture run and the daemon; empty disables it.";
+      };
+
+      textfile = lib.mkOption {
+        type = lib.types.str;
+        default = "";
+        description = "Path rewritten with Prometheus textfile-collector metrics after each capture; empty disables it.";
+      };
+    };
+
+    log = {
+      level = lib.mkOption {
+        type = lib.types.enum [ "debug" "info" "warn" "error" ];
+        default = "info";
+        description = "Diagnostic log level.";
+      };
+
+      format = lib.mkOption {
+        type = lib.types.enum [ "text" "json" ];
+        default = "text";
+        description = "Diagnostic log format written to stderr (and the journal for user services).";
+      };
+    };
+
+    hooks = lib.mkOption {
+      type = lib.types.listOf (lib.types.submodule {
+        options = {
+          event = lib.mkOption {
+            type = lib.types.enum [
+              "capture.state_changed"
+              "capture.snapshot_written"
+              "capture.window_appeared"
+              "restore.item_before"
+              "restore.item_after"
+              "restore.run_after"
+              "prune.before"
+              "prune.after"
+            ];
+            description = "Lifecycle event that triggers the hook.";
+          };
+          command = lib.mkOption {
+            type = lib.types.str;
+            description = "Shell command run with the event JSON on stdin.";
+          };
+          appId = lib.mkOption {
+            type = lib.types.str;
+            default = "";
+            description = "Optional app_id glob limiting window and restore item events.";
+          };
+          timeout = lib.mkOption {
+            type = lib.types.str;
+            default = "10s";
+            description = "How long the hook may run before it is killed.";
+          };
+        };
+      });
+      default = [ ];
+      description = "Commands run on capture, restore and prune lifecycle events.";
+    };
+
+    redact = {
+      titles = lib.mkOption {
+        type = lib.types.listOf (lib.types.submodule {
+          options = {
+            appId = lib.mkOption {
+              type = lib.types.str;
+              description = "app_id glob the rule applies to (`*` for every app).";
+            };
+            mode = lib.mkOption {
+              type = lib.types.enum [ "drop" "hash" "replace" ];
+              description = "Drop the title, replace it with a short SHA-256 hash, or regex-replace it.";
+            };
+            pattern = lib.mkOption {
+              type = lib.types.str;
+              default = "";
+              description = "Regular expression used by the replace mode.";
+            };
+            replacement = lib.mkOption {
+              type = lib.types.str;
+              default = "";
+              description = "Replacement text for matches of pattern.";
+            };
+          };
+        });
+        default = [ ];
+        description = "Per-app_id title redaction rules; the first matching rule wins.";
+      };
+
+      cwdPrefixes = lib.mkOption {
+        type = lib.types.listOf (lib.types.submodule {
+          options = {
+            prefix = lib.mkOption {
+              type = lib.types.str;
+              description = "Directory prefix to mask in captured cwds.";
+            };
+            replacement = lib.mkOption {
+              type = lib.types.str;
+              default = "";
+              description = "Text that replaces the prefix (empty means /redacted).";
+            };
+          };
+        });
+        default = [ ];
+        description = "cwd prefixes masked before state is written.";
+      };
+
+      excludeAppIds = lib.mkOption {
+        type = lib.types.listOf lib.types.str;
+        default = [ ];
+        description = "app_id globs whose windows are never captured.";
+      };
+    };
+
     extraConfig = lib.mkOption {
       type = lib.types.attrs;
       default = { };
@@ -180,7 +506,7 @@ in {
 
     xdg.configFile."terminal-redeemer/config.yaml".source = settingsFile;
 
-    systemd.user.services.terminal-redeemer-capture = lib.mkIf cfg.capture.enable {
+    systemd.user.services.terminal-redeemer-capture = lib.mkIf captureTimerEnabled {
       Unit = {
         Description = "terminal-redeemer capture";
       };
@@ -190,7 +516,7 @@ in {
       };
     };
 
-    systemd.user.timers.terminal-redeemer-capture = lib.mkIf cfg.capture.enable {
+    systemd.user.timers.terminal-redeemer-capture = lib.mkIf captureTimerEnabled {
       Unit = {
         Description = "terminal-redeemer periodic capture";
       };
@@ -202,6 +528,20 @@ in {
       Install.WantedBy = [ "timers.target" ];
     };
 
+    systemd.user.services.terminal-redeemer-daemon = lib.mkIf cfg.daemon.enable {
+      Unit = {
+        Description = "terminal-redeemer daemon";
+        PartOf = [ "graphical-session.target" ];
+        After = [ "graphical-session.target" ];
+      };
+      Service = {
+        ExecStart = daemonExecStart;
+        Restart = "on-failure";
+        RestartSec = 5;
+      };
+      Install.WantedBy = [ "graphical-session.target" ];
+    };
+
     systemd.user.services.terminal-redeemer-prune = lib.mkIf cfg.retention.prune.enable {
       Unit = {
         Description = "terminal-redeemer retention prune";